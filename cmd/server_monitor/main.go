@@ -7,13 +7,17 @@ import (
 	"github.com/go-errors/errors"
 	"github.com/samber/lo"
 	"github.com/siaikin/home-dashboard/internal/app/server_monitor"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
 	"github.com/siaikin/home-dashboard/internal/pkg/comfy_log"
 	"github.com/siaikin/home-dashboard/internal/pkg/configuration"
 	"github.com/siaikin/home-dashboard/internal/pkg/database"
 	"github.com/siaikin/home-dashboard/internal/pkg/overseer"
 	"github.com/siaikin/home-dashboard/internal/pkg/overseer/fetcher"
+	"github.com/siaikin/home-dashboard/internal/pkg/sessions"
 	"github.com/siaikin/home-dashboard/internal/pkg/verison_info"
 	"golang.org/x/net/context"
+	"gorm.io/gorm"
 	"log"
 	"os"
 	"os/signal"
@@ -29,6 +33,9 @@ var (
 
 var logger = comfy_log.New("[server_monitor]")
 
+// reset2FA 离线重置指定用户的 2FA 后退出, 用于管理员丢失身份验证器时恢复访问.
+var reset2FA = flag.String("reset-2fa", "", "reset 2fa of the named user and exit")
+
 func init() {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 
@@ -64,6 +71,14 @@ func main() {
 	database.SetSourceFilePath("home-dashboard.db")
 	db := database.GetDB()
 
+	if len(*reset2FA) > 0 {
+		if err := resetUser2FA(db, *reset2FA); err != nil {
+			logger.Fatal("reset 2fa of user %s failed. %v\n", *reset2FA, err)
+		}
+		logger.Info("2fa of user %s has been reset, please login with password and bind again\n", *reset2FA)
+		return
+	}
+
 	// 生成 overseer 配置
 	overseerConfig, err := makeOverseerConfig()
 	if err != nil {
@@ -116,6 +131,24 @@ func main() {
 	logger.Info("server stopped\n")
 }
 
+// resetUser2FA 重置用户的 2FA 并使用户的所有 session 失效.
+func resetUser2FA(db *gorm.DB, username string) error {
+	if err := monitor_db.Initial(db); err != nil {
+		return err
+	}
+
+	if err := monitor_service.Reset2FA(username); err != nil {
+		return err
+	}
+
+	// 已登录的 session 中保存了旧的 2FA 状态, 需要重新登录
+	if err := sessions.RevokeAll(username); err != nil {
+		logger.Warn("revoke sessions of user %s failed. %v\n", username, err)
+	}
+
+	return nil
+}
+
 func makeOverseerConfig() (overseer.Config, error) {
 	config := configuration.Get()
 	overseerConfig := overseer.Config{
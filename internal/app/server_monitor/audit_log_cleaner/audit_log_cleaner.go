@@ -0,0 +1,43 @@
+package audit_log_cleaner
+
+import (
+	"context"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/comfy_log"
+	"github.com/siaikin/home-dashboard/internal/pkg/configuration"
+	"time"
+)
+
+var logger = comfy_log.New("[audit_log_cleaner]")
+
+// 检查审计日志的时间间隔.
+const checkInterval = time.Hour
+
+// Loop 定期删除超过保留时间的审计日志.
+func Loop(ctx context.Context) {
+	retention := configuration.Get().ServerMonitor.Audit.Retention * time.Second
+	if retention <= 0 {
+		retention = time.Hour * 24 * 90
+	}
+
+	go func() {
+		defer logger.Info("stop audit log clean loop\n")
+
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		for {
+			if purged, err := monitor_service.PurgeExpiredAuditLogs(retention); err != nil {
+				logger.Error("purge expired audit logs failed, %w\n", err)
+			} else if purged > 0 {
+				logger.Info("purged %d expired audit logs\n", purged)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -0,0 +1,280 @@
+package docker_discoverer
+
+import (
+	"context"
+	"github.com/go-errors/errors"
+	"github.com/samber/lo"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/comfy_log"
+	"github.com/siaikin/home-dashboard/internal/pkg/configuration"
+	"github.com/siaikin/home-dashboard/internal/pkg/docker_engine"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification"
+	url2 "net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var logger = comfy_log.New("[docker_discoverer]")
+
+// DefaultLabelPrefix 默认的标签前缀.
+const DefaultLabelPrefix = "home-dashboard"
+
+// 支持的标签, 使用时需要加上前缀, 如 home-dashboard.url.
+const (
+	labelTitle       = "title"
+	labelUrl         = "url"
+	labelIcon        = "icon"
+	labelSection     = "section"
+	labelDescription = "description"
+	// labelTarget 快捷方式的打开方式, 可选值为 self, new 和 embed.
+	labelTarget = "target"
+	// labelEnable 为 false 时忽略该容器.
+	labelEnable = "enable"
+)
+
+// 关注的容器事件. 容器停止时总会产生 die 事件, 因此不需要关注 stop 事件.
+var watchedActions = []string{"start", "die", "destroy"}
+
+// 重新连接 Docker Engine 的最大间隔.
+const maxReconnectInterval = time.Minute
+
+// MessageType Docker 集成创建, 更新或归档快捷方式后发送的消息类型.
+const MessageType = "dockerShortcut"
+
+// Syncer 根据 Docker 容器标签同步快捷方式.
+type Syncer struct {
+	client      *docker_engine.Client
+	labelPrefix string
+	// 保证同一时间只有一次同步.
+	mutex sync.Mutex
+}
+
+// NewSyncer 创建 Syncer. labelPrefix 为空时使用 DefaultLabelPrefix.
+func NewSyncer(client *docker_engine.Client, labelPrefix string) *Syncer {
+	return &Syncer{
+		client:      client,
+		labelPrefix: lo.Ternary(len(labelPrefix) > 0, labelPrefix, DefaultLabelPrefix),
+	}
+}
+
+var defaultSyncer *Syncer
+
+// Loop 连接 Docker Engine, 同步正在运行的容器并监听容器事件. 连接断开后自动重连. 未启用 Docker 集成时直接返回.
+func Loop(ctx context.Context) {
+	config := configuration.Get().ServerMonitor.Docker
+	if !config.Enable {
+		return
+	}
+
+	client, err := docker_engine.New(config.Host)
+	if err != nil {
+		logger.Error("create docker engine client failed, %w\n", err)
+		return
+	}
+	defaultSyncer = NewSyncer(client, config.LabelPrefix)
+
+	go func() {
+		defer logger.Info("stop watching docker events\n")
+
+		interval := time.Second
+		for {
+			startedAt := time.Now()
+			err := defaultSyncer.Watch(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+
+			// 连接保持了一段时间才断开时, 重新从最小间隔开始重连.
+			if time.Since(startedAt) > maxReconnectInterval {
+				interval = time.Second
+			}
+			logger.Warn("watch docker events failed, reconnect after %s, %w\n", interval, err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+			interval = lo.Min([]time.Duration{interval * 2, maxReconnectInterval})
+		}
+	}()
+}
+
+// Sync 立即同步一次正在运行的容器, 用于手动触发同步. 未启用 Docker 集成时返回错误.
+func Sync(ctx context.Context) error {
+	if defaultSyncer == nil {
+		return errors.Errorf("docker integration is not enabled")
+	}
+
+	return defaultSyncer.Sync(ctx)
+}
+
+// Watch 订阅容器事件, 随后同步一次正在运行的容器, 然后持续处理容器事件直到连接断开或 ctx 被取消.
+// 先订阅事件再同步, 以免遗漏同步期间发生的事件.
+func (s *Syncer) Watch(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events, errs := s.client.Events(ctx, watchedActions)
+
+	if err := s.Sync(ctx); err != nil {
+		return err
+	}
+
+	for event := range events {
+		s.handleEvent(event)
+	}
+
+	if err := <-errs; err != nil {
+		return err
+	}
+
+	return errors.Errorf("docker event stream closed")
+}
+
+// Sync 为所有正在运行且带有 url 标签的容器创建或更新快捷方式, 并归档已不再运行的容器的快捷方式.
+func (s *Syncer) Sync(ctx context.Context) error {
+	containers, err := s.client.ListContainers(ctx, false, []string{s.label(labelUrl)})
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	running := make(map[string]bool)
+	for _, container := range containers {
+		spec, ok := s.parseLabels(container.Name(), container.Id, container.Labels)
+		if !ok {
+			continue
+		}
+
+		running[spec.ContainerName] = true
+		if _, err := monitor_service.CreateOrUpdateDockerContainerShortcut(spec); err != nil {
+			logger.Error("sync shortcut of container %s failed, %w\n", spec.ContainerName, err)
+		}
+	}
+
+	shortcuts, err := monitor_service.ListDockerContainerShortcutsByQuery(monitor_model.DockerContainerShortcut{})
+	if err != nil {
+		return err
+	}
+	for _, shortcut := range shortcuts {
+		if shortcut.Archived || running[shortcut.ContainerName] {
+			continue
+		}
+
+		if err := monitor_service.ArchiveDockerContainerShortcut(shortcut.ContainerName); err != nil {
+			logger.Error("archive shortcut of container %s failed, %w\n", shortcut.ContainerName, err)
+		}
+	}
+
+	logger.Info("synced %d docker containers\n", len(running))
+	notification.Send(MessageType, map[string]any{})
+
+	return nil
+}
+
+func (s *Syncer) handleEvent(event docker_engine.Event) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	name := event.Actor.Attributes["name"]
+	if len(name) <= 0 {
+		return
+	}
+
+	switch event.Action {
+	case "start":
+		// 容器事件的 Attributes 中包含容器的所有标签.
+		spec, ok := s.parseLabels(name, event.Actor.ID, event.Actor.Attributes)
+		if !ok {
+			return
+		}
+
+		if _, err := monitor_service.CreateOrUpdateDockerContainerShortcut(spec); err != nil {
+			logger.Error("sync shortcut of container %s failed, %w\n", name, err)
+			return
+		}
+		logger.Info("container %s started, shortcut synced\n", name)
+	case "die", "destroy":
+		if err := monitor_service.ArchiveDockerContainerShortcut(name); errors.Is(err, monitor_service.ErrorNotFound) {
+			return
+		} else if err != nil {
+			logger.Error("archive shortcut of container %s failed, %w\n", name, err)
+			return
+		}
+		logger.Info("container %s stopped, shortcut archived\n", name)
+	default:
+		return
+	}
+
+	notification.Send(MessageType, map[string]any{})
+}
+
+func (s *Syncer) label(name string) string {
+	return s.labelPrefix + "." + name
+}
+
+// parseLabels 将容器标签解析为快捷方式. 容器没有名称, 没有合法的 url 标签或 enable 标签为 false 时返回 false.
+func (s *Syncer) parseLabels(containerName string, containerId string, labels map[string]string) (monitor_service.DockerContainerShortcutSpec, bool) {
+	if len(containerName) <= 0 {
+		return monitor_service.DockerContainerShortcutSpec{}, false
+	}
+
+	url := strings.TrimSpace(labels[s.label(labelUrl)])
+	if parsed, err := url2.Parse(url); err != nil || !parsed.IsAbs() {
+		return monitor_service.DockerContainerShortcutSpec{}, false
+	}
+	if strings.EqualFold(strings.TrimSpace(labels[s.label(labelEnable)]), "false") {
+		return monitor_service.DockerContainerShortcutSpec{}, false
+	}
+
+	item := monitor_model.ShortcutItem{
+		Title:       strings.TrimSpace(labels[s.label(labelTitle)]),
+		Description: strings.TrimSpace(labels[s.label(labelDescription)]),
+		URL:         url,
+		Target:      monitor_model.ShortcutItemTargetTypeNewTab,
+	}
+	if len(item.Title) <= 0 {
+		item.Title = containerName
+	}
+
+	switch strings.ToLower(strings.TrimSpace(labels[s.label(labelTarget)])) {
+	case "self":
+		item.Target = monitor_model.ShortcutItemTargetTypeSelfTab
+	case "embed":
+		item.Target = monitor_model.ShortcutItemTargetTypeEmbed
+	}
+
+	fillIcon(&item, strings.TrimSpace(labels[s.label(labelIcon)]))
+
+	return monitor_service.DockerContainerShortcutSpec{
+		ContainerName: containerName,
+		ContainerId:   containerId,
+		Item:          item,
+		SectionName:   strings.TrimSpace(labels[s.label(labelSection)]),
+	}, true
+}
+
+// fillIcon 根据 icon 标签设置快捷方式的图标. icon 可以是图片地址或 simple-icons 的 slug, 都不是时使用标题的首字母.
+func fillIcon(item *monitor_model.ShortcutItem, icon string) {
+	if parsed, err := url2.Parse(icon); err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") {
+		item.IconType = monitor_model.ShortcutItemIconTypeUrl
+		item.IconUrl = icon
+		return
+	}
+
+	if len(icon) > 0 {
+		if icons, err := monitor_service.ListShortcutIconsByQuery(1, monitor_model.ShortcutIcon{Slug: strings.ToLower(icon)}); err == nil && len(*icons) > 0 {
+			item.IconType = monitor_model.ShortcutItemIconTypeIcon
+			item.IconID = (*icons)[0].ID
+			return
+		}
+	}
+
+	item.IconType = monitor_model.ShortcutItemIconTypeText
+	item.IconText = strings.ToUpper(string([]rune(item.Title)[:1]))
+}
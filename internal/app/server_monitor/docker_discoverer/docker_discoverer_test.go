@@ -0,0 +1,168 @@
+package docker_discoverer
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/database"
+	"github.com/siaikin/home-dashboard/internal/pkg/docker_engine"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeEngine 模拟 Docker Engine API, 只实现了 /containers/json 和 /events.
+type fakeEngine struct {
+	mutex      sync.Mutex
+	containers []docker_engine.Container
+	events     chan docker_engine.Event
+}
+
+func (e *fakeEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/containers/json":
+		e.mutex.Lock()
+		defer e.mutex.Unlock()
+
+		_ = json.NewEncoder(w).Encode(e.containers)
+	case "/events":
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case event, ok := <-e.events:
+				if !ok {
+					return
+				}
+				_ = json.NewEncoder(w).Encode(event)
+				w.(http.Flusher).Flush()
+			}
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func findItem(t *testing.T, title string) *monitor_model.ShortcutItem {
+	t.Helper()
+
+	items, err := monitor_service.ListShortcutItemsByQuery(monitor_model.ShortcutItem{Title: title}, []string{"Sections"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*items) <= 0 {
+		return nil
+	}
+
+	return &(*items)[0]
+}
+
+// waitFor 轮询 condition 直到其返回 true 或超时.
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second * 5)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return
+		}
+		time.Sleep(time.Millisecond * 20)
+	}
+
+	t.Fatal("condition not satisfied before timeout")
+}
+
+func TestSyncer(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := monitor_service.CreateOrUpdateShortcutSections([]monitor_model.ShortcutSection{{Name: "Default Folder", Default: true}}); err != nil {
+		t.Fatal(err)
+	}
+
+	engine := &fakeEngine{
+		containers: []docker_engine.Container{
+			{
+				Id:    "grafana-id",
+				Names: []string{"/grafana"},
+				Labels: map[string]string{
+					"home-dashboard.title":   "Grafana",
+					"home-dashboard.url":     "http://grafana.lan",
+					"home-dashboard.section": "Monitoring",
+				},
+			},
+			{Id: "plain-id", Names: []string{"/plain"}, Labels: map[string]string{"home-dashboard.url": "http://plain.lan"}},
+			{Id: "disabled-id", Names: []string{"/disabled"}, Labels: map[string]string{"home-dashboard.url": "http://disabled.lan", "home-dashboard.enable": "false"}},
+		},
+		events: make(chan docker_engine.Event),
+	}
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+
+	client, err := docker_engine.New(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	syncer := NewSyncer(client, "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() {
+		_ = syncer.Watch(ctx)
+	}()
+
+	waitFor(t, func() bool {
+		return findItem(t, "Grafana") != nil && findItem(t, "plain") != nil
+	})
+
+	grafana := findItem(t, "Grafana")
+	if len(grafana.Sections) != 1 || grafana.Sections[0].Name != "Monitoring" {
+		t.Errorf("grafana should be placed in the Monitoring section, got %+v", grafana.Sections)
+	}
+	if plain := findItem(t, "plain"); len(plain.Sections) != 1 || !plain.Sections[0].Default {
+		t.Errorf("container without section label should be placed in the default section, got %+v", plain.Sections)
+	}
+	if findItem(t, "disabled") != nil {
+		t.Error("container with enable=false should be ignored")
+	}
+
+	// 容器停止后归档快捷方式, 自动创建的分组中没有其他快捷方式时一并归档.
+	engine.events <- docker_engine.Event{Type: "container", Action: "die", Actor: docker_engine.EventActor{ID: "grafana-id", Attributes: map[string]string{"name": "grafana"}}}
+	waitFor(t, func() bool {
+		return findItem(t, "Grafana") == nil
+	})
+	if sections, _ := monitor_service.ListShortcutSectionsByQuery(0, monitor_model.ShortcutSection{Name: "Monitoring"}, nil); len(*sections) != 0 {
+		t.Error("auto created section should be archived with its last shortcut")
+	}
+
+	// 容器重新启动后恢复快捷方式和分组, 并应用新的标签.
+	engine.events <- docker_engine.Event{Type: "container", Action: "start", Actor: docker_engine.EventActor{ID: "grafana-id-2", Attributes: map[string]string{
+		"name":                   "grafana",
+		"home-dashboard.title":   "Grafana",
+		"home-dashboard.url":     "http://grafana.lan:3000",
+		"home-dashboard.section": "Monitoring",
+	}}}
+	waitFor(t, func() bool {
+		item := findItem(t, "Grafana")
+		return item != nil && item.URL == "http://grafana.lan:3000"
+	})
+	if restored := findItem(t, "Grafana"); restored.ID != grafana.ID || len(restored.Sections) != 1 || restored.Sections[0].ID != grafana.Sections[0].ID {
+		t.Errorf("shortcut and section should be restored, got %+v", restored)
+	}
+
+	shortcuts, err := monitor_service.ListDockerContainerShortcutsByQuery(monitor_model.DockerContainerShortcut{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shortcuts) != 2 {
+		t.Errorf("expect 2 container shortcuts, got %+v", shortcuts)
+	}
+}
@@ -0,0 +1,118 @@
+package monitor_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-errors/errors"
+	"github.com/samber/lo"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/authority"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ApiTokenRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// ExpiresAt 过期时间(毫秒时间戳), 为 0 时表示永不过期.
+	ExpiresAt int64 `json:"expiresAt"`
+}
+
+// CreateApiToken 为当前登录用户创建 API 令牌. 令牌明文只在创建时返回一次.
+// @Summary CreateApiToken
+// @Description CreateApiToken
+// @Tags CreateApiToken
+// @Accept json
+// @Produce json
+// @Param body body ApiTokenRequest true "body"
+// @Router user/token/create [post]
+func CreateApiToken(c *gin.Context) {
+	var body ApiTokenRequest
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	}
+
+	body.Name = strings.TrimSpace(body.Name)
+	if len(body.Name) <= 0 {
+		respondEntityValidationError(c, "name is required")
+		return
+	} else if len(body.Scopes) <= 0 {
+		respondEntityValidationError(c, "at least one scope is required")
+		return
+	} else if invalid, _ := lo.Difference(body.Scopes, authority.Scopes); len(invalid) > 0 {
+		respondEntityValidationError(c, "invalid scopes "+strings.Join(invalid, ", "))
+		return
+	} else if body.ExpiresAt < 0 || (body.ExpiresAt > 0 && body.ExpiresAt <= time.Now().UnixMilli()) {
+		respondEntityValidationError(c, "expiresAt should be in the future")
+		return
+	}
+
+	user := c.MustGet(currentUserKey).(monitor_model.User)
+	token, created, err := monitor_service.CreateApiToken(monitor_model.ApiToken{
+		UserId:    user.ID,
+		Name:      body.Name,
+		Scopes:    lo.Uniq(body.Scopes),
+		ExpiresAt: body.ExpiresAt,
+	})
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":    token,
+		"apiToken": created,
+	})
+}
+
+// ListApiTokens 获取当前登录用户的所有 API 令牌, 不包含令牌明文.
+// @Summary ListApiTokens
+// @Description ListApiTokens
+// @Tags ListApiTokens
+// @Produce json
+// @Success 200 {array} monitor_model.ApiToken
+// @Router user/token/list [get]
+func ListApiTokens(c *gin.Context) {
+	user := c.MustGet(currentUserKey).(monitor_model.User)
+
+	tokens, err := monitor_service.ListApiTokens(user.ID)
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": tokens,
+		"scopes": authority.Scopes,
+	})
+}
+
+// RevokeApiToken 撤销当前登录用户的 API 令牌, 撤销后令牌立即失效.
+// @Summary RevokeApiToken
+// @Description RevokeApiToken
+// @Tags RevokeApiToken
+// @Produce json
+// @Param id path number true "令牌 id"
+// @Router user/token/revoke/{id} [delete]
+func RevokeApiToken(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		respondEntityValidationError(c, "id should be number")
+		return
+	}
+
+	user := c.MustGet(currentUserKey).(monitor_model.User)
+	if err := monitor_service.RevokeApiToken(user.ID, uint(id)); errors.Is(err, monitor_service.ErrorNotFound) {
+		respondEntityNotFoundError(c, "api token %d not found", id)
+		return
+	} else if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
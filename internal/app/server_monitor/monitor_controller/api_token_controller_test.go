@@ -0,0 +1,129 @@
+package monitor_controller
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/authority"
+	"github.com/siaikin/home-dashboard/internal/pkg/database"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newApiTokenRouter() *gin.Engine {
+	router := newUserRouter()
+	authority.SetTokenVerifier(monitor_service.VerifyApiToken)
+
+	authorized := router.Group("", authority.AuthorizeMiddleware(), ActiveUserMiddleware(), authority.Authorize2FAMiddleware())
+	authorized.POST("user/token/create", CreateApiToken)
+	authorized.GET("user/token/list", ListApiTokens)
+	authorized.DELETE("user/token/revoke/:id", RevokeApiToken)
+
+	currentUsername := func(c *gin.Context) {
+		user, _ := authority.GetUser(c)
+		c.String(http.StatusOK, user.Username)
+	}
+	authority.Scoped(authorized, authority.ScopeStatsRead).GET("token-test/stats", currentUsername)
+	authority.Scoped(authorized, authority.ScopeUpgrade).POST("token-test/upgrade", currentUsername)
+	authorized.GET("token-test/session-only", currentUsername)
+
+	return router
+}
+
+func serveToken(router *gin.Engine, token string, method string, url string) (int, string) {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(method, url, nil)
+	request.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(recorder, request)
+
+	return recorder.Code, recorder.Body.String()
+}
+
+func TestApiToken(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+	if err := monitor_service.CreateUser(monitor_model.User{User: authority.User{Username: "token-user", Password: "token"}, Role: monitor_model.RoleGuest}); err != nil {
+		t.Fatal(err)
+	}
+
+	router := newApiTokenRouter()
+
+	if code, _ := serveUser(router, "token-user", http.MethodPost, "/user/token/create", `{"name":"script","scopes":["unknown"]}`); code != http.StatusBadRequest {
+		t.Errorf("unknown scope should be rejected, got %d", code)
+	}
+	if code, _ := serveUser(router, "token-user", http.MethodPost, "/user/token/create", `{"name":"script","scopes":["stats:read"],"expiresAt":1}`); code != http.StatusBadRequest {
+		t.Errorf("expired token should be rejected, got %d", code)
+	}
+
+	code, body := serveUser(router, "token-user", http.MethodPost, "/user/token/create", `{"name":"script","scopes":["stats:read"]}`)
+	var created struct {
+		Token    string                 `json:"token"`
+		ApiToken monitor_model.ApiToken `json:"apiToken"`
+	}
+	_ = json.Unmarshal(body, &created)
+	if code != http.StatusOK || len(created.Token) <= 0 {
+		t.Fatalf("create api token responded %d, %s", code, body)
+	}
+
+	// 数据库中只存储令牌的哈希值
+	stored := monitor_model.ApiToken{}
+	monitor_db.GetDB().First(&stored, created.ApiToken.ID)
+	if stored.TokenHash == created.Token || stored.TokenHash != authority.HashApiToken(created.Token) {
+		t.Errorf("token should be stored as hash, got %s", stored.TokenHash)
+	}
+
+	if code, body := serveToken(router, created.Token, http.MethodGet, "/token-test/stats"); code != http.StatusOK || body != "token-user" {
+		t.Errorf("token with scope should be accepted, got %d, %s", code, body)
+	}
+	if code, _ := serveToken(router, created.Token, http.MethodPost, "/token-test/upgrade"); code != http.StatusForbidden {
+		t.Errorf("token without scope should be rejected, got %d", code)
+	}
+	if code, _ := serveToken(router, created.Token, http.MethodGet, "/token-test/session-only"); code != http.StatusForbidden {
+		t.Errorf("unscoped route should not be accessible with token, got %d", code)
+	}
+	if code, _ := serveToken(router, created.Token, http.MethodGet, "/user/token/list"); code != http.StatusForbidden {
+		t.Errorf("token should not manage tokens, got %d", code)
+	}
+	if code, _ := serveToken(router, "hdp_invalid", http.MethodGet, "/token-test/stats"); code != http.StatusUnauthorized {
+		t.Errorf("invalid token should be unauthorized, got %d", code)
+	}
+	// 登录会话不受权限范围限制
+	if code, _ := serveUser(router, "token-user", http.MethodPost, "/token-test/upgrade", ""); code != http.StatusOK {
+		t.Errorf("session should not be limited by scopes, got %d", code)
+	}
+
+	// 过期的令牌
+	monitor_db.GetDB().Model(&stored).UpdateColumn("expires_at", time.Now().Add(-time.Second).UnixMilli())
+	if code, _ := serveToken(router, created.Token, http.MethodGet, "/token-test/stats"); code != http.StatusUnauthorized {
+		t.Errorf("expired token should be unauthorized, got %d", code)
+	}
+	monitor_db.GetDB().Model(&stored).UpdateColumn("expires_at", 0)
+
+	code, body = serveUser(router, "token-user", http.MethodGet, "/user/token/list", "")
+	var listed struct {
+		Tokens []monitor_model.ApiToken `json:"tokens"`
+	}
+	_ = json.Unmarshal(body, &listed)
+	if code != http.StatusOK || len(listed.Tokens) != 1 || listed.Tokens[0].LastUsedAt <= 0 {
+		t.Errorf("unexpected api tokens %d, %s", code, body)
+	}
+
+	// 撤销后令牌立即失效
+	id := strconv.Itoa(int(created.ApiToken.ID))
+	if code, _ := serveUser(router, "token-user", http.MethodDelete, "/user/token/revoke/"+id, ""); code != http.StatusOK {
+		t.Errorf("revoke api token responded %d", code)
+	}
+	if code, _ := serveToken(router, created.Token, http.MethodGet, "/token-test/stats"); code != http.StatusUnauthorized {
+		t.Errorf("revoked token should be unauthorized, got %d", code)
+	}
+	if code, _ := serveUser(router, "token-user", http.MethodDelete, "/user/token/revoke/"+id, ""); code != http.StatusNotFound {
+		t.Errorf("revoked token should not be found, got %d", code)
+	}
+}
@@ -0,0 +1,226 @@
+package monitor_controller
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/authority"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const auditEntryKey = "auditEntry"
+
+// 审计日志列表默认和最多每页返回的记录数.
+const (
+	defaultAuditLogPageSize = 50
+	maxAuditLogPageSize     = 500
+)
+
+// auditEntry 当前请求的审计信息, 由各接口通过 auditAction, auditActor 和 auditTarget 补充.
+type auditEntry struct {
+	// record 为 true 时即使是只读请求也会被记录, 如通过 GET 请求完成的单点登录.
+	record     bool
+	action     string
+	actor      string
+	targetType string
+	targetId   string
+	before     string
+	after      string
+}
+
+// AuditMiddleware 将修改数据的请求(POST, PUT, PATCH, DELETE)和调用了 auditAction 的请求记录到审计日志中.
+// 请求体不会被记录, 操作前后的摘要由各接口通过 auditTarget 提供. excludedPrefixes 下的请求只记录调用了 auditAction 的请求.
+func AuditMiddleware(excludedPrefixes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		entry := &auditEntry{}
+		c.Set(auditEntryKey, entry)
+
+		// 登出等接口会清除 session 中的用户信息, 因此先获取处理请求前的用户
+		actor := ""
+		if user, ok := authority.GetUser(c); ok {
+			actor = user.Username
+		}
+
+		c.Next()
+
+		if !entry.record {
+			if !isMutatingMethod(c.Request.Method) {
+				return
+			}
+			for _, prefix := range excludedPrefixes {
+				if strings.HasPrefix(c.Request.URL.Path, prefix) {
+					return
+				}
+			}
+		}
+
+		// 使用 API 令牌的请求在处理过程中才能获取到用户
+		if len(entry.actor) > 0 {
+			actor = entry.actor
+		} else if user, ok := authority.GetUser(c); ok && len(user.Username) > 0 {
+			actor = user.Username
+		}
+
+		route := c.FullPath()
+		if len(route) <= 0 {
+			route = c.Request.URL.Path
+		}
+
+		log := monitor_model.AuditLog{
+			Actor:      actor,
+			Ip:         c.ClientIP(),
+			UserAgent:  c.Request.UserAgent(),
+			Method:     c.Request.Method,
+			Route:      route,
+			Action:     entry.action,
+			TargetType: entry.targetType,
+			TargetId:   entry.targetId,
+			Before:     entry.before,
+			After:      entry.after,
+			Status:     c.Writer.Status(),
+			Outcome:    monitor_model.AuditLogOutcomeSuccess,
+		}
+		if len(log.Action) <= 0 {
+			log.Action = log.Method + " " + log.Route
+		}
+		if log.Status >= http.StatusBadRequest || len(c.Errors) > 0 {
+			log.Outcome = monitor_model.AuditLogOutcomeFailure
+			if last := c.Errors.Last(); last != nil {
+				log.Error = last.Error()
+			}
+		}
+
+		if err := monitor_service.CreateAuditLog(log); err != nil {
+			logger.Error("create audit log of %s %s failed, %s\n", log.Method, log.Route, err)
+		}
+	}
+}
+
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// getAuditEntry 获取当前请求的审计信息, 请求未经过 AuditMiddleware 时返回 nil.
+func getAuditEntry(c *gin.Context) *auditEntry {
+	if entry, ok := c.Get(auditEntryKey); ok {
+		return entry.(*auditEntry)
+	}
+
+	return nil
+}
+
+// auditAction 设置当前请求在审计日志中的操作名称, 并确保请求会被记录.
+func auditAction(c *gin.Context, action string) {
+	if entry := getAuditEntry(c); entry != nil {
+		entry.action = action
+		entry.record = true
+	}
+}
+
+// auditActor 设置执行操作的用户名, 用于登录等处理请求前后都无法从 session 中获取用户的场景.
+func auditActor(c *gin.Context, username string) {
+	if entry := getAuditEntry(c); entry != nil {
+		entry.actor = username
+	}
+}
+
+// auditTarget 设置操作的目标实体及其操作前后的摘要. before 或 after 为 nil 时不记录, 调用方需要移除其中的敏感字段.
+func auditTarget(c *gin.Context, targetType string, targetId any, before any, after any) {
+	if entry := getAuditEntry(c); entry != nil {
+		entry.targetType = targetType
+		entry.targetId = fmt.Sprint(targetId)
+		entry.before = monitor_service.AuditSummary(before)
+		entry.after = monitor_service.AuditSummary(after)
+	}
+}
+
+// ListAuditLogs 分页获取审计日志, 按时间倒序排列
+// @Summary ListAuditLogs
+// @Description ListAuditLogs
+// @Tags ListAuditLogs
+// @Produce json
+// @Param page query number false "页码, 从 1 开始, 默认为 1"
+// @Param pageSize query number false "每页的记录数, 默认为 50, 最大为 500"
+// @Param actor query string false "操作者的用户名"
+// @Param action query string false "操作名称"
+// @Param route query string false "路由"
+// @Param targetType query string false "目标实体的类型"
+// @Param targetId query string false "目标实体的 id"
+// @Param outcome query string false "操作结果, success 或 failure"
+// @Param from query number false "开始时间(毫秒时间戳)"
+// @Param to query number false "结束时间(毫秒时间戳)"
+// @Router audit/logs [get]
+func ListAuditLogs(c *gin.Context) {
+	page, ok := queryPositiveInt(c, "page", 1, 0)
+	if !ok {
+		return
+	}
+	pageSize, ok := queryPositiveInt(c, "pageSize", defaultAuditLogPageSize, maxAuditLogPageSize)
+	if !ok {
+		return
+	}
+	from, ok := queryPositiveInt(c, "from", 0, 0)
+	if !ok {
+		return
+	}
+	to, ok := queryPositiveInt(c, "to", 0, 0)
+	if !ok {
+		return
+	}
+
+	outcome := c.Query("outcome")
+	if len(outcome) > 0 && outcome != monitor_model.AuditLogOutcomeSuccess && outcome != monitor_model.AuditLogOutcomeFailure {
+		respondEntityValidationError(c, "outcome should be %s or %s", monitor_model.AuditLogOutcomeSuccess, monitor_model.AuditLogOutcomeFailure)
+		return
+	}
+
+	logs, total, err := monitor_service.ListAuditLogs(monitor_service.AuditLogFilter{
+		Actor:      c.Query("actor"),
+		Action:     c.Query("action"),
+		Route:      c.Query("route"),
+		TargetType: c.Query("targetType"),
+		TargetId:   c.Query("targetId"),
+		Outcome:    outcome,
+		From:       int64(from),
+		To:         int64(to),
+	}, page, pageSize)
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"logs":     logs,
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+	})
+}
+
+// queryPositiveInt 获取查询参数中的正整数, 未传递时返回 defaultValue. max 大于 0 时限制最大值. 参数无效时响应错误并返回 false.
+func queryPositiveInt(c *gin.Context, key string, defaultValue int, max int) (int, bool) {
+	value := c.Query(key)
+	if len(value) <= 0 {
+		return defaultValue, true
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed <= 0 || (max > 0 && parsed > max) {
+		if max > 0 {
+			respondEntityValidationError(c, "%s should be number between 1 and %d", key, max)
+		} else {
+			respondEntityValidationError(c, "%s should be positive number", key)
+		}
+		return 0, false
+	}
+
+	return parsed, true
+}
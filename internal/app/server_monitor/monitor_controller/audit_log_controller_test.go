@@ -0,0 +1,223 @@
+package monitor_controller
+
+import (
+	"encoding/json"
+	"fmt"
+	ginSessions "github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/authority"
+	"github.com/siaikin/home-dashboard/internal/pkg/configuration"
+	"github.com/siaikin/home-dashboard/internal/pkg/database"
+	"github.com/siaikin/home-dashboard/internal/pkg/sessions"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newAuditLogRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(sessions.GetSessionMiddleware(), func(c *gin.Context) {
+		if username := c.GetHeader("X-Test-User"); len(username) > 0 {
+			ginSessions.Default(c).Set(authority.InfoKey, authority.User{Username: username})
+		}
+	}, AuditMiddleware())
+
+	router.POST("auth", Authorize)
+	authority.SetPermissionChecker(CheckPermission)
+	authorized := router.Group("", authority.AuthorizeMiddleware(), ActiveUserMiddleware())
+	usersManage := authorized.Group("", authority.PermissionMiddleware(authority.PermissionUsersManage))
+	usersManage.GET("user/list", ListUsers)
+	usersManage.PUT("user/update/:id", UpdateUser)
+	usersManage.DELETE("user/delete/:id", DeleteUser)
+	authorized.Group("", authority.PermissionMiddleware(authority.PermissionAuditView)).GET("audit/logs", ListAuditLogs)
+
+	return router
+}
+
+type auditLogPage struct {
+	Logs  []monitor_model.AuditLog `json:"logs"`
+	Total int64                    `json:"total"`
+}
+
+func TestAuditLog(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+	if err := monitor_service.GenerateBuiltInRoles(); err != nil {
+		t.Fatal(err)
+	}
+	monitor_db.GetDB().Where("1 = 1").Delete(&monitor_model.AuditLog{})
+	defer monitor_db.GetDB().Where("1 = 1").Delete(&monitor_model.AuditLog{})
+
+	// 测试中会登录失败, 不启用登录暴力破解防护
+	protection := &configuration.Get().ServerMonitor.LoginProtection
+	original := *protection
+	defer func() { *protection = original }()
+	protection.Enable = false
+
+	if err := monitor_service.CreateUser(monitor_model.User{User: authority.User{Username: "audit-admin"}, Role: monitor_model.RoleAdministrator}); err != nil {
+		t.Fatal(err)
+	}
+	if err := monitor_service.CreateUser(monitor_model.User{User: authority.User{Username: "audit-bob", Password: "bob"}, Role: monitor_model.RoleGuest}); err != nil {
+		t.Fatal(err)
+	}
+	defer monitor_db.GetDB().Unscoped().Where("username LIKE ?", "audit-%").Delete(&monitor_model.User{})
+	bob, _ := monitor_service.GetUserByName("audit-bob")
+
+	router := newAuditLogRouter()
+	list := func(query string) auditLogPage {
+		code, body := serveUser(router, "audit-admin", http.MethodGet, "/audit/logs?"+query, "")
+		var page auditLogPage
+		if err := json.Unmarshal(body, &page); code != http.StatusOK || err != nil {
+			t.Fatalf("list audit logs responded %d, %s", code, body)
+		}
+		return page
+	}
+
+	// 登录成功和失败都会被记录, 登录失败时没有操作者
+	serveLogin(router, "203.0.113.1", "audit-bob", "wrong")
+	serveLogin(router, "203.0.113.1", "audit-bob", "bob")
+	logins := list("action=auth.login")
+	if logins.Total != 2 ||
+		logins.Logs[0].Actor != "audit-bob" || logins.Logs[0].Outcome != monitor_model.AuditLogOutcomeSuccess ||
+		logins.Logs[1].Actor != "" || logins.Logs[1].Outcome != monitor_model.AuditLogOutcomeFailure || logins.Logs[1].TargetId != "audit-bob" ||
+		logins.Logs[1].Status != http.StatusBadRequest || logins.Logs[1].Ip != "203.0.113.1" || len(logins.Logs[1].Error) <= 0 {
+		t.Errorf("unexpected login audit logs %+v", logins)
+	}
+
+	// 修改数据的请求记录操作前后的摘要, 摘要中不包含密码; 只读请求不记录
+	if code, body := serveUser(router, "audit-admin", http.MethodPut, fmt.Sprintf("/user/update/%d", bob.ID), `{"role":1,"password":"new-secret"}`); code != http.StatusOK {
+		t.Fatalf("update user responded %d, %s", code, body)
+	}
+	serveUser(router, "audit-admin", http.MethodGet, "/user/list", "")
+	if code, body := serveUser(router, "audit-admin", http.MethodDelete, fmt.Sprintf("/user/delete/%d", bob.ID), ""); code != http.StatusOK {
+		t.Fatalf("delete user responded %d, %s", code, body)
+	}
+
+	changes := list("actor=audit-admin")
+	if changes.Total != 2 {
+		t.Fatalf("unexpected audit logs of audit-admin %+v", changes)
+	}
+	deleted, updated := changes.Logs[0], changes.Logs[1]
+	if updated.Action != "user.update" || updated.Route != "/user/update/:id" || updated.TargetType != "user" || updated.TargetId != fmt.Sprint(bob.ID) ||
+		!strings.Contains(updated.Before, `"role":2`) || !strings.Contains(updated.After, `"role":1`) || !strings.Contains(updated.After, `"passwordReset":true`) {
+		t.Errorf("unexpected update audit log %+v", updated)
+	}
+	if strings.Contains(updated.After, "new-secret") || strings.Contains(updated.Before+updated.After, `"password":"$`) {
+		t.Errorf("audit log should not contain password, got %s", updated.After)
+	}
+	if deleted.Action != "user.delete" || !strings.Contains(deleted.Before, `"username":"audit-bob"`) || len(deleted.After) > 0 {
+		t.Errorf("unexpected delete audit log %+v", deleted)
+	}
+
+	// 分页和过滤
+	if page := list("pageSize=1&page=2"); page.Total != 4 || len(page.Logs) != 1 || page.Logs[0].Action != "user.update" {
+		t.Errorf("unexpected second page %+v", page)
+	}
+	if page := list("outcome=failure"); page.Total != 1 {
+		t.Errorf("unexpected failed audit logs %+v", page)
+	}
+	if page := list(fmt.Sprintf("targetType=user&targetId=%d&to=%d", bob.ID, time.Now().Add(-time.Hour).UnixMilli())); page.Total != 0 {
+		t.Errorf("time range should filter out all audit logs, got %+v", page)
+	}
+	if code, _ := serveUser(router, "audit-admin", http.MethodGet, "/audit/logs?pageSize=1000", ""); code != http.StatusBadRequest {
+		t.Errorf("too large page size should be rejected, got %d", code)
+	}
+
+	// 审计日志只能追加, 超过保留时间后被删除
+	if err := monitor_db.GetDB().Exec("UPDATE audit_logs SET actor = ?", "mallory").Error; err == nil {
+		t.Errorf("audit logs should not be updatable")
+	}
+	if err := monitor_service.CreateAuditLog(monitor_model.AuditLog{CreatedAt: time.Now().Add(-48 * time.Hour).UnixMilli(), Action: "expired"}); err != nil {
+		t.Fatal(err)
+	}
+	if purged, err := monitor_service.PurgeExpiredAuditLogs(24 * time.Hour); err != nil || purged != 1 {
+		t.Errorf("expired audit log should be purged, got %d, %v", purged, err)
+	}
+}
+
+func TestAuditConfigurationChange(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+	monitor_db.GetDB().Where("action = ?", "configuration.update").Delete(&monitor_model.AuditLog{})
+	defer monitor_db.GetDB().Where("action = ?", "configuration.update").Delete(&monitor_model.AuditLog{})
+
+	previous, current := configuration.Configuration{}, configuration.Configuration{}
+	previous.ServerMonitor.ThirdParty.GitHub.PersonalAccessToken = "ghp_previous_token"
+	previous.ServerMonitor.ThirdParty.Wakapi.ApiKey = "wakapi-previous-key"
+	current.ServerMonitor.ThirdParty.GitHub.Enable = true
+	current.ServerMonitor.ThirdParty.GitHub.PersonalAccessToken = "ghp_current_token"
+	current.ServerMonitor.ThirdParty.Wakapi.ApiKey = "wakapi-current-key"
+	current.ServerMonitor.Update.Fetchers.GitHub.PersonalAccessToken = "ghp_update_token"
+	current.ServerMonitor.Oidc.ClientSecret = "oidc-secret"
+	current.ServerMonitor.Administrator.Password = "admin-password"
+
+	if err := monitor_service.AuditConfigurationChange(previous, current); err != nil {
+		t.Fatal(err)
+	}
+
+	logs, total, err := monitor_service.ListAuditLogs(monitor_service.AuditLogFilter{Action: "configuration.update"}, 1, 10)
+	if err != nil || total != 1 {
+		t.Fatalf("expected 1 configuration audit log, got %d %v", total, err)
+	}
+	bytes, _ := json.Marshal(logs[0])
+	for _, secret := range []string{"ghp_previous_token", "ghp_current_token", "ghp_update_token", "wakapi-previous-key", "wakapi-current-key", "oidc-secret", "admin-password"} {
+		if strings.Contains(string(bytes), secret) {
+			t.Errorf("configuration audit log should not contain secret %s, got %s", secret, bytes)
+		}
+	}
+	if !strings.Contains(string(bytes), "thirdParty") {
+		t.Errorf("configuration audit log should record the changed section, got %s", bytes)
+	}
+
+	// 只修改敏感信息时不记录
+	previous.ServerMonitor.ThirdParty = current.ServerMonitor.ThirdParty
+	previous.ServerMonitor.Update = current.ServerMonitor.Update
+	previous.ServerMonitor.Oidc = current.ServerMonitor.Oidc
+	current.ServerMonitor.ThirdParty.GitHub.PersonalAccessToken = "ghp_rotated_token"
+	if err := monitor_service.AuditConfigurationChange(previous, current); err != nil {
+		t.Fatal(err)
+	}
+	if _, total, _ := monitor_service.ListAuditLogs(monitor_service.AuditLogFilter{Action: "configuration.update"}, 1, 10); total != 1 {
+		t.Errorf("changing only secrets should not be recorded, got %d logs", total)
+	}
+}
+
+func TestRedactStoredConfigurations(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟旧版本存储的包含敏感信息的配置记录
+	stored := monitor_model.StoredConfiguration{}
+	stored.Configuration.ServerMonitor.ThirdParty.GitHub.PersonalAccessToken = "ghp_stored_token"
+	stored.Configuration.ServerMonitor.ThirdParty.Wakapi.ApiKey = "wakapi-stored-key"
+	stored.Configuration.ServerMonitor.ThirdParty.Wakapi.ApiUrl = "https://wakapi.example.com"
+	if result := monitor_db.GetDB().Create(&stored); result.Error != nil {
+		t.Fatal(result.Error)
+	}
+	defer monitor_db.GetDB().Unscoped().Delete(&stored)
+
+	if err := monitor_service.RedactStoredConfigurations(); err != nil {
+		t.Fatal(err)
+	}
+
+	redacted := monitor_model.StoredConfiguration{}
+	if result := monitor_db.GetDB().Where("id = ?", stored.ID).Limit(1).Find(&redacted); result.Error != nil {
+		t.Fatal(result.Error)
+	}
+	thirdParty := redacted.Configuration.ServerMonitor.ThirdParty
+	if len(thirdParty.GitHub.PersonalAccessToken) > 0 || len(thirdParty.Wakapi.ApiKey) > 0 || thirdParty.Wakapi.ApiUrl != "https://wakapi.example.com" {
+		t.Errorf("stored configuration should be redacted, got %+v", thirdParty)
+	}
+}
@@ -3,12 +3,13 @@ package monitor_controller
 import (
 	"bytes"
 	"github.com/gin-contrib/sessions"
-	ginSessions "github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
 	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
 	"github.com/siaikin/home-dashboard/internal/pkg/authority"
 	"github.com/siaikin/home-dashboard/internal/pkg/comfy_errors"
 	"github.com/siaikin/home-dashboard/internal/pkg/comfy_log"
+	comfySessions "github.com/siaikin/home-dashboard/internal/pkg/sessions"
 	"image/png"
 	"net/http"
 )
@@ -33,17 +34,52 @@ type AuthorizeRequest struct {
 func Authorize(context *gin.Context) {
 	var body AuthorizeRequest
 
+	auditAction(context, "auth.login")
 	if err := context.ShouldBindJSON(&body); err != nil {
 		respondEntityValidationError(context, err.Error())
 		return
 	}
+	auditTarget(context, "user", body.Username, nil, nil)
+
+	attempt, ok := beginLoginAttempt(context, monitor_model.LoginAttemptKindPassword, body.Username)
+	if !ok {
+		return
+	}
 
 	user, err := monitor_service.GetUserByName(body.Username)
-	if err != nil || user.Password != body.Password {
+	if err != nil {
+		finishLoginAttempt(context, attempt, false)
+		respondLoginError(context, "username or password invalid")
+		return
+	}
+	if ok, err := monitor_service.VerifyUserPassword(user, body.Password); err != nil {
+		respondUnknownError(context, err.Error())
+		return
+	} else if !ok {
+		finishLoginAttempt(context, attempt, false)
 		respondLoginError(context, "username or password invalid")
 		return
 	}
+	if user.Disabled {
+		finishLoginAttempt(context, attempt, false)
+		respondLoginError(context, "user %s is disabled", user.Username)
+		return
+	}
+	finishLoginAttempt(context, attempt, true)
+
+	if !saveLoginSession(context, user) {
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"role":      user.Role,
+		"username":  user.Username,
+		"enable2FA": user.Enable2FA,
+	})
+}
 
+// saveLoginSession 将登录的用户信息保存到 session 中, 保存失败时响应错误并返回 false.
+func saveLoginSession(context *gin.Context, user monitor_model.User) bool {
 	session := sessions.Default(context)
 
 	// 如果用户开启了 2FA, 则将 [authority.TotpValidatedKey] 设置为 false, 以供中间件校验拦截.
@@ -51,22 +87,26 @@ func Authorize(context *gin.Context) {
 		session.Set(authority.TotpValidatedKey, false)
 	}
 
-	session.Set(authority.InfoKey, user.User)
-	session.Options(ginSessions.Options{
-		// 24 hours
-		MaxAge: 60 * 60 * 24,
-	})
+	// session 中不存储密码
+	info := user.User
+	info.Password = ""
+	session.Set(authority.InfoKey, info)
+	// 24 hours
+	session.Options(comfySessions.CookieOptions(60 * 60 * 24))
 
 	if err := session.Save(); err != nil {
 		abortWithError(context, http.StatusInternalServerError, comfy_errors.NewResponseError(comfy_errors.SessionStoreError, "session save failed. %w", err))
-		return
+		return false
+	}
+	if err := comfySessions.Touch(context, user.Username); err != nil {
+		logger.Warn("record session activity failed, %s\n", err)
 	}
 
-	context.JSON(http.StatusOK, gin.H{
-		"role":      user.Role,
-		"username":  user.Username,
-		"enable2FA": user.Enable2FA,
-	})
+	auditAction(context, "auth.login")
+	auditActor(context, user.Username)
+	auditTarget(context, "user", user.Username, nil, nil)
+
+	return true
 }
 
 // Unauthorize 登出并删除 session
@@ -84,10 +124,12 @@ func Unauthorize(context *gin.Context) {
 		return
 	}
 
+	auditAction(context, "auth.logout")
+
 	// this will mark the session as "written" and hopefully remove the username
 	session.Set(authority.InfoKey, nil)
 	// 设置 MaxAge 为 -1 将删除 session
-	session.Options(sessions.Options{MaxAge: -1})
+	session.Options(comfySessions.CookieOptions(-1))
 
 	if err := session.Save(); err != nil {
 		abortWithError(context, http.StatusInternalServerError, comfy_errors.NewResponseError(comfy_errors.SessionStoreError, "session save failed"))
@@ -118,8 +160,9 @@ func GetCurrentUser(c *gin.Context) {
 
 }
 
-// Disable2FA 禁用 2FA
+// Disable2FA 解绑身份验证器应用. 仍有 WebAuthn 凭据时保持开启 2FA, 凭据需要通过 [DeleteWebAuthnCredential] 删除.
 func Disable2FA(c *gin.Context) {
+	auditAction(c, "auth.2fa.disable")
 	session := sessions.Default(c)
 	user := session.Get(authority.InfoKey).(authority.User)
 
@@ -132,23 +175,43 @@ func Disable2FA(c *gin.Context) {
 	if storedUser.Enable2FA == false {
 		return
 	}
+	if !require2FAValidated(c, storedUser) {
+		return
+	}
+
+	// 只解绑身份验证器应用, 仍有 WebAuthn 凭据时保持开启 2FA
+	credentials, err := monitor_service.ListWebAuthnCredentials(storedUser.ID)
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
 
-	storedUser.Enable2FA = false
+	before := withoutUserSecret(storedUser)
+	storedUser.Enable2FA = len(credentials) > 0
 	storedUser.Secret2FA = ""
 	if err := monitor_service.UpdateUser(storedUser); err != nil {
 		abortWithError(c, http.StatusInternalServerError, comfy_errors.NewResponseError(comfy_errors.UnknownError, "cannot update user. %w", err))
 		return
 	}
+	// 完全关闭 2FA 后恢复码不再有意义
+	if !storedUser.Enable2FA {
+		if err := monitor_service.DeleteRecoveryCodes(storedUser.ID); err != nil {
+			respondUnknownError(c, err.Error())
+			return
+		}
+	}
 
-	// 禁用 2FA 后, 需要重新登录
+	// 禁用 2FA 后, 需要重新登录. 登出会覆盖操作名称, 需要重新设置
 	Unauthorize(c)
+	auditAction(c, "auth.2fa.disable")
+	auditTarget(c, "user", storedUser.ID, before, withoutUserSecret(storedUser))
 }
 
 type Validate2FARequest struct {
 	Code string `form:"code"`
 }
 
-// Validate2FA 校验 2FA 验证码
+// Validate2FA 校验 2FA 验证码. 验证码可以是身份验证器应用生成的验证码, 也可以是一次性恢复码.
 func Validate2FA(c *gin.Context) {
 	var body Validate2FARequest
 	if err := c.ShouldBindJSON(&body); err != nil {
@@ -165,19 +228,55 @@ func Validate2FA(c *gin.Context) {
 		return
 	}
 
+	auditAction(c, "auth.2fa.validate")
+	auditTarget(c, "user", storedUser.ID, nil, nil)
+
+	attempt, ok := beginLoginAttempt(c, monitor_model.LoginAttemptKind2FA, storedUser.Username)
+	if !ok {
+		return
+	}
+
 	// 校验 2FA 验证码, 如果正确, 将 session 中的 [authority.TotpValidatedKey] 设置为 true.
-	if authority.TOTP.Validate(body.Code, storedUser.Secret2FA) {
+	if len(storedUser.Secret2FA) > 0 && authority.TOTP.Validate(body.Code, storedUser.Secret2FA) {
+		finishLoginAttempt(c, attempt, true)
 		session.Set(authority.TotpValidatedKey, true)
 		saveSession(c, session)
-	} else {
+		return
+	}
+
+	// 不是有效的验证码时尝试作为恢复码使用
+	if ok, err := monitor_service.UseRecoveryCode(storedUser.ID, body.Code); err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	} else if !ok {
+		finishLoginAttempt(c, attempt, false)
 		respondLoginError(c, "2fa code invalid")
 		return
 	}
+	finishLoginAttempt(c, attempt, true)
+
+	session.Set(authority.TotpValidatedKey, true)
+	saveSession(c, session)
+
+	remaining, err := monitor_service.CountUnusedRecoveryCodes(storedUser.ID)
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+	logger.Warn("user %s passed 2fa with a recovery code, %d remaining\n", storedUser.Username, remaining)
+	auditTarget(c, "user", storedUser.ID, nil, gin.H{"recoveryCodeUsed": true, "recoveryCodesRemaining": remaining})
+
+	c.JSON(http.StatusOK, gin.H{
+		"recoveryCodeUsed":       true,
+		"recoveryCodesRemaining": remaining,
+	})
 }
 
-// Binding2FAByAuthenticatorApp 绑定用于双因素身份验证的身份验证器应用
+// Binding2FAByAuthenticatorApp 绑定用于双因素身份验证的身份验证器应用. 绑定成功后生成新的恢复码, 恢复码明文只在此时返回一次.
 func Binding2FAByAuthenticatorApp(c *gin.Context) {
 	var body Validate2FARequest
+
+	auditAction(c, "auth.2fa.bind")
 	if err := c.ShouldBindJSON(&body); err != nil {
 		respondLoginError(c, err.Error())
 		return
@@ -193,8 +292,12 @@ func Binding2FAByAuthenticatorApp(c *gin.Context) {
 			respondLoginError(c, err.Error())
 			return
 		}
+		if !require2FAValidated(c, storedUser) {
+			return
+		}
 
 		// 更新 Secret2FA
+		before := withoutUserSecret(storedUser)
 		storedUser.Secret2FA = user.Secret2FA
 		storedUser.Enable2FA = true
 		if err := monitor_service.UpdateUser(storedUser); err != nil {
@@ -202,8 +305,21 @@ func Binding2FAByAuthenticatorApp(c *gin.Context) {
 			return
 		}
 
-		// 绑定成功后, 重新登录
+		codes, err := monitor_service.RegenerateRecoveryCodes(storedUser.ID)
+		if err != nil {
+			respondUnknownError(c, "cannot generate recovery codes. %w", err)
+			return
+		}
+
+		// 绑定成功后, 重新登录. 登出会覆盖操作名称, 需要重新设置
 		Unauthorize(c)
+		auditAction(c, "auth.2fa.bind")
+		auditTarget(c, "user", storedUser.ID, before, withoutUserSecret(storedUser))
+		if c.IsAborted() {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"recoveryCodes": codes})
 	} else {
 		respondLoginError(c, "2fa code invalid")
 		return
@@ -216,6 +332,15 @@ func Generate2FABindingQRCode(c *gin.Context) {
 
 	user := session.Get(authority.InfoKey).(authority.User)
 
+	storedUser, err := monitor_service.GetUserByName(user.Username)
+	if err != nil {
+		respondLoginError(c, "cannot get current user. %w", err)
+		return
+	}
+	if !require2FAValidated(c, storedUser) {
+		return
+	}
+
 	qrCode, secret, err := authority.TOTP.GenerateBindingQRCode(user.Username)
 	if err != nil {
 		respondUnknownError(c, "generate qr code failed")
@@ -237,6 +362,60 @@ func Generate2FABindingQRCode(c *gin.Context) {
 	}
 }
 
+// require2FAValidated 已开启 2FA 的用户需要当前 session 通过 2FA 校验后才能重新绑定或解绑 2FA.
+// session 中的用户信息可能已经过期(如在其他 session 中开启了 2FA), 因此以数据库中的用户为准.
+func require2FAValidated(c *gin.Context, storedUser monitor_model.User) bool {
+	if storedUser.Enable2FA && sessions.Default(c).Get(authority.TotpValidatedKey) != true {
+		abortWithError(c, http.StatusUnauthorized, comfy_errors.NewResponseError(comfy_errors.LoginRequestError, "2fa not validated"))
+		return false
+	}
+
+	return true
+}
+
+// GetRecoveryCodesStatus 获取当前登录用户剩余未使用的恢复码数量
+// @Summary GetRecoveryCodesStatus
+// @Description GetRecoveryCodesStatus
+// @Tags 2FA
+// @Produce json
+// @Router auth/2fa/recovery-codes [get]
+func GetRecoveryCodesStatus(c *gin.Context) {
+	user := c.MustGet(currentUserKey).(monitor_model.User)
+
+	remaining, err := monitor_service.CountUnusedRecoveryCodes(user.ID)
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"remaining": remaining})
+}
+
+// RegenerateRecoveryCodes 为当前登录用户重新生成恢复码, 旧的恢复码全部失效. 恢复码明文只在此时返回一次.
+// @Summary RegenerateRecoveryCodes
+// @Description RegenerateRecoveryCodes
+// @Tags 2FA
+// @Produce json
+// @Router auth/2fa/recovery-codes/regenerate [post]
+func RegenerateRecoveryCodes(c *gin.Context) {
+	user := c.MustGet(currentUserKey).(monitor_model.User)
+	if !user.Enable2FA {
+		respondEntityValidationError(c, "2fa is not enabled")
+		return
+	}
+
+	auditAction(c, "auth.2fa.recovery-codes.regenerate")
+	auditTarget(c, "user", user.ID, nil, nil)
+
+	codes, err := monitor_service.RegenerateRecoveryCodes(user.ID)
+	if err != nil {
+		respondUnknownError(c, "cannot generate recovery codes. %w", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recoveryCodes": codes})
+}
+
 func saveSession(c *gin.Context, session sessions.Session) {
 	if err := session.Save(); err != nil {
 		abortWithError(c, http.StatusInternalServerError, comfy_errors.NewResponseError(comfy_errors.SessionStoreError, "session save failed"))
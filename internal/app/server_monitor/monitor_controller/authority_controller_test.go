@@ -0,0 +1,212 @@
+package monitor_controller
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/pquerna/otp/totp"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/authority"
+	"github.com/siaikin/home-dashboard/internal/pkg/configuration"
+	"github.com/siaikin/home-dashboard/internal/pkg/database"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newRecoveryCodeRouter() *gin.Engine {
+	router := newUserRouter()
+
+	router.POST("auth", Authorize)
+
+	authorized := router.Group("", authority.AuthorizeMiddleware(), ActiveUserMiddleware())
+	authorized.POST("auth/2fa/validate", Validate2FA)
+
+	authorizedAnd2faValidated := authorized.Group("", authority.Authorize2FAMiddleware())
+	authorizedAnd2faValidated.GET("auth/2fa/qrcode", Generate2FABindingQRCode)
+	authorizedAnd2faValidated.POST("auth/2fa/bind/app", Binding2FAByAuthenticatorApp)
+	authorizedAnd2faValidated.POST("auth/2fa/disable", Disable2FA)
+	authorizedAnd2faValidated.GET("auth/2fa/recovery-codes", GetRecoveryCodesStatus)
+	authorizedAnd2faValidated.POST("auth/2fa/recovery-codes/regenerate", RegenerateRecoveryCodes)
+
+	return router
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+
+	// 测试中会连续多次校验失败, 不启用登录暴力破解防护
+	protection := &configuration.Get().ServerMonitor.LoginProtection
+	original := *protection
+	defer func() { *protection = original }()
+	protection.Enable = false
+
+	key, err := totp.Generate(totp.GenerateOpts{Issuer: "home-dashboard", AccountName: "recovery-alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := monitor_service.CreateUser(monitor_model.User{User: authority.User{Username: "recovery-alice", Password: "alice", Enable2FA: true, Secret2FA: key.Secret()}, Role: monitor_model.RoleGuest}); err != nil {
+		t.Fatal(err)
+	}
+	defer monitor_db.GetDB().Unscoped().Where("username LIKE ?", "recovery-%").Delete(&monitor_model.User{})
+	alice, _ := monitor_service.GetUserByName("recovery-alice")
+
+	codes, err := monitor_service.RegenerateRecoveryCodes(alice.ID)
+	if err != nil || len(codes) != authority.RecoveryCodeCount {
+		t.Fatalf("unexpected recovery codes %v, %v", codes, err)
+	}
+
+	router := newRecoveryCodeRouter()
+	login := func() *http.Cookie {
+		recorder, cookie := serveWebAuthn(router, http.MethodPost, "/auth", `{"username":"recovery-alice","password":"alice"}`, nil)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("password login responded %d, %s", recorder.Code, recorder.Body.String())
+		}
+		return cookie
+	}
+
+	// 恢复码可以代替验证码通过 2FA 校验, 输入时忽略大小写和连字符
+	cookie := login()
+	if recorder, _ := serveWebAuthn(router, http.MethodGet, "/auth/2fa/recovery-codes", "", cookie); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("2fa should be required after password login, got %d", recorder.Code)
+	}
+	if recorder, _ := serveWebAuthn(router, http.MethodPost, "/auth/2fa/validate", `{"code":"aaaa-bbbb-cccc-dddd"}`, cookie); recorder.Code != http.StatusBadRequest {
+		t.Errorf("invalid recovery code should be rejected, got %d", recorder.Code)
+	}
+	recorder, cookie := serveWebAuthn(router, http.MethodPost, "/auth/2fa/validate", `{"code":"`+strings.ToUpper(strings.ReplaceAll(codes[0], "-", ""))+`"}`, cookie)
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `"recoveryCodesRemaining":9`) {
+		t.Fatalf("validate with recovery code responded %d, %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder, _ := serveWebAuthn(router, http.MethodGet, "/auth/2fa/recovery-codes", "", cookie); recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `"remaining":9`) {
+		t.Errorf("recovery code status responded %d, %s", recorder.Code, recorder.Body.String())
+	}
+
+	// 恢复码只能使用一次
+	cookie = login()
+	if recorder, _ := serveWebAuthn(router, http.MethodPost, "/auth/2fa/validate", `{"code":"`+codes[0]+`"}`, cookie); recorder.Code != http.StatusBadRequest {
+		t.Errorf("used recovery code should be rejected, got %d", recorder.Code)
+	}
+
+	// 身份验证器应用的验证码仍然可用
+	code, _ := totp.GenerateCode(key.Secret(), time.Now())
+	recorder, cookie = serveWebAuthn(router, http.MethodPost, "/auth/2fa/validate", `{"code":"`+code+`"}`, cookie)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("validate with totp code responded %d, %s", recorder.Code, recorder.Body.String())
+	}
+
+	// 重新生成后旧的恢复码失效
+	recorder, _ = serveWebAuthn(router, http.MethodPost, "/auth/2fa/recovery-codes/regenerate", "", cookie)
+	var regenerated struct {
+		RecoveryCodes []string `json:"recoveryCodes"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &regenerated); recorder.Code != http.StatusOK || err != nil || len(regenerated.RecoveryCodes) != authority.RecoveryCodeCount {
+		t.Fatalf("regenerate recovery codes responded %d, %s", recorder.Code, recorder.Body.String())
+	}
+	if ok, _ := monitor_service.UseRecoveryCode(alice.ID, codes[1]); ok {
+		t.Errorf("old recovery code should be invalidated after regeneration")
+	}
+	if ok, _ := monitor_service.UseRecoveryCode(alice.ID, regenerated.RecoveryCodes[0]); !ok {
+		t.Errorf("regenerated recovery code should be valid")
+	}
+
+	// 离线重置 2FA
+	if err := monitor_service.Reset2FA("recovery-alice"); err != nil {
+		t.Fatal(err)
+	}
+	alice, _ = monitor_service.GetUserByName("recovery-alice")
+	if remaining, _ := monitor_service.CountUnusedRecoveryCodes(alice.ID); alice.Enable2FA || len(alice.Secret2FA) > 0 || remaining != 0 {
+		t.Errorf("2fa should be reset, got enable2FA %t, secret %q, %d recovery codes", alice.Enable2FA, alice.Secret2FA, remaining)
+	}
+	if recorder, _ := serveWebAuthn(router, http.MethodGet, "/auth/2fa/recovery-codes", "", login()); recorder.Code != http.StatusOK {
+		t.Errorf("password login should not require 2fa after reset, got %d", recorder.Code)
+	}
+	if err := monitor_service.Reset2FA("recovery-nobody"); err != monitor_service.ErrorNotFound {
+		t.Errorf("reset unknown user should return ErrorNotFound, got %v", err)
+	}
+}
+
+func TestRebind2FARequiresValidation(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+
+	protection := &configuration.Get().ServerMonitor.LoginProtection
+	original := *protection
+	defer func() { *protection = original }()
+	protection.Enable = false
+
+	key, err := totp.Generate(totp.GenerateOpts{Issuer: "home-dashboard", AccountName: "rebind-bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := monitor_service.CreateUser(monitor_model.User{User: authority.User{Username: "rebind-bob", Password: "bob", Enable2FA: true, Secret2FA: key.Secret()}, Role: monitor_model.RoleGuest}); err != nil {
+		t.Fatal(err)
+	}
+	if err := monitor_service.CreateUser(monitor_model.User{User: authority.User{Username: "rebind-carol", Password: "carol"}, Role: monitor_model.RoleGuest}); err != nil {
+		t.Fatal(err)
+	}
+	defer monitor_db.GetDB().Unscoped().Where("username LIKE ?", "rebind-%").Delete(&monitor_model.User{})
+
+	router := newRecoveryCodeRouter()
+	login := func(body string) *http.Cookie {
+		recorder, cookie := serveWebAuthn(router, http.MethodPost, "/auth", body, nil)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("password login responded %d, %s", recorder.Code, recorder.Body.String())
+		}
+		return cookie
+	}
+
+	// 只通过密码登录时不能重新绑定或解绑 2FA
+	cookie := login(`{"username":"rebind-bob","password":"bob"}`)
+	code, _ := totp.GenerateCode(key.Secret(), time.Now())
+	for _, request := range []struct{ method, url, body string }{
+		{http.MethodGet, "/auth/2fa/qrcode", ""},
+		{http.MethodPost, "/auth/2fa/bind/app", `{"code":"` + code + `"}`},
+		{http.MethodPost, "/auth/2fa/disable", ""},
+	} {
+		if recorder, _ := serveWebAuthn(router, request.method, request.url, request.body, cookie); recorder.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s should require 2fa, got %d", request.method, request.url, recorder.Code)
+		}
+	}
+	if bob, _ := monitor_service.GetUserByName("rebind-bob"); !bob.Enable2FA || bob.Secret2FA != key.Secret() {
+		t.Errorf("2fa should not be changed without validation")
+	}
+
+	// 通过 2FA 校验后可以解绑
+	recorder, cookie := serveWebAuthn(router, http.MethodPost, "/auth/2fa/validate", `{"code":"`+code+`"}`, cookie)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("validate responded %d, %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder, _ := serveWebAuthn(router, http.MethodGet, "/auth/2fa/qrcode", "", cookie); recorder.Code != http.StatusOK {
+		t.Errorf("qrcode should be available after 2fa validation, got %d", recorder.Code)
+	}
+	if recorder, _ := serveWebAuthn(router, http.MethodPost, "/auth/2fa/disable", "", cookie); recorder.Code != http.StatusOK {
+		t.Errorf("disable should be allowed after 2fa validation, got %d, %s", recorder.Code, recorder.Body.String())
+	}
+	if bob, _ := monitor_service.GetUserByName("rebind-bob"); bob.Enable2FA {
+		t.Errorf("2fa should be disabled")
+	}
+
+	// 未开启 2FA 的用户可以首次绑定
+	cookie = login(`{"username":"rebind-carol","password":"carol"}`)
+	if recorder, _ := serveWebAuthn(router, http.MethodGet, "/auth/2fa/qrcode", "", cookie); recorder.Code != http.StatusOK {
+		t.Errorf("first time binding should not require 2fa, got %d", recorder.Code)
+	}
+
+	// session 中的用户信息过期时以数据库中的用户为准
+	carol, _ := monitor_service.GetUserByName("rebind-carol")
+	carol.Enable2FA = true
+	carol.Secret2FA = key.Secret()
+	if err := monitor_service.UpdateUser(carol); err != nil {
+		t.Fatal(err)
+	}
+	if recorder, _ := serveWebAuthn(router, http.MethodGet, "/auth/2fa/qrcode", "", cookie); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("stale session should not rebind 2fa, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,132 @@
+package monitor_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-errors/errors"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/service_discoverer"
+	"net/http"
+	"strconv"
+)
+
+// ListDiscoveredServices 获取自动发现的服务. 默认只返回等待处理的服务(收件箱).
+// @Summary ListDiscoveredServices
+// @Description ListDiscoveredServices
+// @Tags ListDiscoveredServices
+// @Produce json
+// @Param status query string false "pending, accepted 或 dismissed, 默认为 pending"
+// @Success 200 {array} monitor_model.DiscoveredService
+// @Router shortcut/discovery/list [get]
+func ListDiscoveredServices(c *gin.Context) {
+	var query struct {
+		Status monitor_model.DiscoveredServiceStatus `form:"status"`
+	}
+
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	}
+	if len(query.Status) <= 0 {
+		query.Status = monitor_model.DiscoveredServiceStatusPending
+	}
+
+	services, err := monitor_service.ListDiscoveredServicesByQuery(monitor_model.DiscoveredService{Status: query.Status})
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"services":  services,
+		"running":   service_discoverer.Running(),
+		"lastRunAt": service_discoverer.LastRunAt(),
+	})
+}
+
+// RunServiceDiscovery 手动触发服务发现. 扫描在后台进行, 完成后可以通过 ListDiscoveredServices 获取结果.
+// @Summary RunServiceDiscovery
+// @Description RunServiceDiscovery
+// @Tags RunServiceDiscovery
+// @Produce json
+// @Success 200
+// @Router shortcut/discovery/run [post]
+func RunServiceDiscovery(c *gin.Context) {
+	if err := service_discoverer.Start(); errors.Is(err, service_discoverer.ErrorRunning) {
+		respondEntityAlreadyExistError(c, err.Error())
+		return
+	} else if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// AcceptDiscoveredService 将自动发现的服务添加为快捷方式. sectionIds 为空时加入默认分组.
+// @Summary AcceptDiscoveredService
+// @Description AcceptDiscoveredService
+// @Tags AcceptDiscoveredService
+// @Accept json
+// @Produce json
+// @Param id path number true "id"
+// @Success 200 {object} monitor_model.ShortcutItem
+// @Router shortcut/discovery/accept/{id} [put]
+func AcceptDiscoveredService(c *gin.Context) {
+	var body struct {
+		SectionIds []uint `json:"sectionIds"`
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		respondEntityValidationError(c, "id should be number")
+		return
+	}
+	// 请求体可以为空.
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			respondEntityValidationError(c, err.Error())
+			return
+		}
+	}
+
+	item, err := monitor_service.AcceptDiscoveredService(uint(id), body.SectionIds)
+	if errors.Is(err, monitor_service.ErrorNotFound) {
+		respondEntityNotFoundError(c, "discovered service %d or sections %v not found", id, body.SectionIds)
+		return
+	} else if errors.Is(err, monitor_service.ErrorAlreadyAccepted) {
+		respondEntityAlreadyExistError(c, err.Error())
+		return
+	} else if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+// DismissDiscoveredService 忽略自动发现的服务, 再次发现该服务时不会重新出现在收件箱中.
+// @Summary DismissDiscoveredService
+// @Description DismissDiscoveredService
+// @Tags DismissDiscoveredService
+// @Produce json
+// @Param id path number true "id"
+// @Success 200
+// @Router shortcut/discovery/dismiss/{id} [put]
+func DismissDiscoveredService(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		respondEntityValidationError(c, "id should be number")
+		return
+	}
+
+	if err := monitor_service.DismissDiscoveredService(uint(id)); errors.Is(err, monitor_service.ErrorNotFound) {
+		respondEntityNotFoundError(c, "discovered service %d not found", id)
+		return
+	} else if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
@@ -0,0 +1,44 @@
+package monitor_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/docker_discoverer"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"net/http"
+)
+
+// ListDockerContainerShortcuts 获取根据 Docker 容器标签自动创建的快捷方式记录, 包括已归档的记录.
+// @Summary ListDockerContainerShortcuts
+// @Description ListDockerContainerShortcuts
+// @Tags ListDockerContainerShortcuts
+// @Produce json
+// @Success 200 {array} monitor_model.DockerContainerShortcut
+// @Router shortcut/docker/list [get]
+func ListDockerContainerShortcuts(c *gin.Context) {
+	shortcuts, err := monitor_service.ListDockerContainerShortcutsByQuery(monitor_model.DockerContainerShortcut{})
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"shortcuts": shortcuts,
+	})
+}
+
+// SyncDockerContainerShortcuts 立即根据正在运行的 Docker 容器同步快捷方式.
+// @Summary SyncDockerContainerShortcuts
+// @Description SyncDockerContainerShortcuts
+// @Tags SyncDockerContainerShortcuts
+// @Produce json
+// @Success 200
+// @Router shortcut/docker/sync [post]
+func SyncDockerContainerShortcuts(c *gin.Context) {
+	if err := docker_discoverer.Sync(c.Request.Context()); err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
@@ -0,0 +1,78 @@
+package monitor_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-errors/errors"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/comfy_errors"
+	"github.com/siaikin/home-dashboard/internal/pkg/configuration"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// 登录尝试列表默认和最多返回的记录数.
+const (
+	defaultLoginAttemptLimit = 100
+	maxLoginAttemptLimit     = 1000
+)
+
+// ListLoginAttempts 获取最近的登录尝试记录和当前被锁定的用户名和 IP
+// @Summary ListLoginAttempts
+// @Description ListLoginAttempts
+// @Tags ListLoginAttempts
+// @Produce json
+// @Param failedOnly query bool false "是否只返回失败的记录"
+// @Param limit query number false "返回的记录数, 默认为 100, 最大为 1000"
+// @Router user/login-attempts [get]
+func ListLoginAttempts(c *gin.Context) {
+	limit := defaultLoginAttemptLimit
+	if value := c.Query("limit"); len(value) > 0 {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > maxLoginAttemptLimit {
+			respondEntityValidationError(c, "limit should be number between 1 and %d", maxLoginAttemptLimit)
+			return
+		}
+		limit = parsed
+	}
+
+	attempts, err := monitor_service.ListLoginAttempts(c.Query("failedOnly") == "true", limit)
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+	lockouts, err := monitor_service.ListLoginLockouts(configuration.Get().ServerMonitor.LoginProtection)
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"attempts": attempts,
+		"lockouts": lockouts,
+	})
+}
+
+// beginLoginAttempt 记录一次登录尝试. 用户名或 IP 需要等待或被锁定时响应 429 并返回 false.
+func beginLoginAttempt(c *gin.Context, kind monitor_model.LoginAttemptKind, username string) (monitor_model.LoginAttempt, bool) {
+	attempt, retryAfter, err := monitor_service.BeginLoginAttempt(configuration.Get().ServerMonitor.LoginProtection, kind, username, c.ClientIP())
+	if errors.Is(err, monitor_service.ErrorLoginThrottled) || errors.Is(err, monitor_service.ErrorLoginLocked) {
+		seconds := int64(math.Ceil(retryAfter.Seconds()))
+		c.Header("Retry-After", strconv.FormatInt(seconds, 10))
+		abortWithError(c, http.StatusTooManyRequests, comfy_errors.NewResponseError(comfy_errors.TooManyRequestsError, "%s, retry after %d seconds", err, seconds))
+		return attempt, false
+	} else if err != nil {
+		respondUnknownError(c, err.Error())
+		return attempt, false
+	}
+
+	return attempt, true
+}
+
+// finishLoginAttempt 记录登录尝试的结果. 记录失败不影响登录流程.
+func finishLoginAttempt(c *gin.Context, attempt monitor_model.LoginAttempt, success bool) {
+	if err := monitor_service.FinishLoginAttempt(configuration.Get().ServerMonitor.LoginProtection, attempt, success); err != nil {
+		logger.Warn("record login attempt of %s from %s failed, %s\n", attempt.Username, c.ClientIP(), err)
+	}
+}
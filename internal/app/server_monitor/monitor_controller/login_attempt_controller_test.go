@@ -0,0 +1,153 @@
+package monitor_controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/authority"
+	"github.com/siaikin/home-dashboard/internal/pkg/configuration"
+	"github.com/siaikin/home-dashboard/internal/pkg/database"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newLoginAttemptRouter() *gin.Engine {
+	router := newUserRouter()
+
+	router.POST("auth", Authorize)
+	administrator := router.Group("", authority.AuthorizeMiddleware(), ActiveUserMiddleware(), authority.PermissionMiddleware(authority.PermissionUsersManage))
+	administrator.GET("user/login-attempts", ListLoginAttempts)
+
+	return router
+}
+
+// serveLogin 以 ip 作为客户端 IP 使用用户名密码登录.
+func serveLogin(router *gin.Engine, ip string, username string, password string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/auth", strings.NewReader(fmt.Sprintf(`{"username":%q,"password":%q}`, username, password)))
+	request.Header.Set("X-Forwarded-For", ip)
+	router.ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+// ageLoginAttempts 将所有登录尝试记录提前 d, 以跳过失败后需要等待的时间. created_at 字段不允许通过模型更新, 需要使用 SQL.
+func ageLoginAttempts(d time.Duration) {
+	monitor_db.GetDB().Exec("UPDATE login_attempts SET created_at = created_at - ?", d.Milliseconds())
+}
+
+func TestLoginProtection(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+	// 清除其他测试留下的登录尝试记录
+	monitor_db.GetDB().Unscoped().Where("1 = 1").Delete(&monitor_model.LoginAttempt{})
+	defer monitor_db.GetDB().Unscoped().Where("1 = 1").Delete(&monitor_model.LoginAttempt{})
+
+	config := &configuration.Get().ServerMonitor.LoginProtection
+	original := *config
+	defer func() { *config = original }()
+	*config = configuration.ServerMonitorLoginProtectionConfiguration{
+		Enable:             true,
+		Window:             900,
+		MaxAttemptsPerUser: 3,
+		MaxAttemptsPerIp:   4,
+		BackoffBase:        1,
+		BackoffMax:         10,
+		LockoutDuration:    900,
+	}
+
+	for _, username := range []string{"attempt-alice", "attempt-bob"} {
+		if err := monitor_service.CreateUser(monitor_model.User{User: authority.User{Username: username, Password: "secret"}, Role: monitor_model.RoleGuest}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := monitor_service.CreateUser(monitor_model.User{User: authority.User{Username: "attempt-admin"}, Role: monitor_model.RoleAdministrator}); err != nil {
+		t.Fatal(err)
+	}
+	defer monitor_db.GetDB().Unscoped().Where("username LIKE ?", "attempt-%").Delete(&monitor_model.User{})
+
+	router := newLoginAttemptRouter()
+
+	// 每次失败后需要等待的时间按指数增长
+	if recorder := serveLogin(router, "203.0.113.1", "attempt-alice", "wrong"); recorder.Code != http.StatusBadRequest {
+		t.Fatalf("wrong password responded %d", recorder.Code)
+	}
+	if recorder := serveLogin(router, "203.0.113.1", "attempt-alice", "secret"); recorder.Code != http.StatusTooManyRequests || recorder.Header().Get("Retry-After") != "1" {
+		t.Errorf("login right after a failure should be throttled for 1s, got %d, Retry-After %s", recorder.Code, recorder.Header().Get("Retry-After"))
+	}
+	ageLoginAttempts(15 * time.Second)
+	serveLogin(router, "203.0.113.1", "attempt-alice", "wrong")
+	if recorder := serveLogin(router, "203.0.113.1", "attempt-alice", "secret"); recorder.Code != http.StatusTooManyRequests || recorder.Header().Get("Retry-After") != "2" {
+		t.Errorf("login after the second failure should be throttled for 2s, got %d, Retry-After %s", recorder.Code, recorder.Header().Get("Retry-After"))
+	}
+
+	// 登录成功后用户名重新开始统计
+	ageLoginAttempts(15 * time.Second)
+	if recorder := serveLogin(router, "203.0.113.1", "attempt-alice", "secret"); recorder.Code != http.StatusOK {
+		t.Fatalf("login after waiting responded %d, %s", recorder.Code, recorder.Body.String())
+	}
+
+	// 同一用户名连续失败达到上限后锁定, 即使来自不同的 IP, 并发送警告通知
+	listener := notification.GetListener()
+	defer listener.Close()
+	for i := 0; i < 3; i++ {
+		ageLoginAttempts(15 * time.Second)
+		if recorder := serveLogin(router, fmt.Sprintf("203.0.113.%d", 10+i), "attempt-alice", "wrong"); recorder.Code != http.StatusBadRequest {
+			t.Fatalf("wrong password responded %d", recorder.Code)
+		}
+	}
+	select {
+	case message := <-listener.Ch():
+		notifications, _ := message.Data["notifications"].([]notification.UserNotification)
+		if message.Type != notification.UserNotificationReceivedMessageType || len(notifications) != 1 || notifications[0].Kind != notification.UserNotificationKindWarning || !strings.Contains(notifications[0].Title, "attempt-alice") {
+			t.Errorf("unexpected lockout notification %+v", message)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("lockout notification should be sent")
+	}
+	ageLoginAttempts(15 * time.Second)
+	if recorder := serveLogin(router, "203.0.113.20", "attempt-alice", "secret"); recorder.Code != http.StatusTooManyRequests || recorder.Header().Get("Retry-After") != "885" {
+		t.Errorf("locked user should be rejected, got %d, Retry-After %s", recorder.Code, recorder.Header().Get("Retry-After"))
+	}
+
+	// 同一 IP 连续失败达到上限后锁定, 即使使用不同的用户名
+	for i := 0; i < 4; i++ {
+		ageLoginAttempts(15 * time.Second)
+		serveLogin(router, "203.0.113.99", fmt.Sprintf("attempt-unknown-%d", i), "wrong")
+	}
+	ageLoginAttempts(15 * time.Second)
+	if recorder := serveLogin(router, "203.0.113.99", "attempt-bob", "secret"); recorder.Code != http.StatusTooManyRequests {
+		t.Errorf("locked ip should be rejected, got %d", recorder.Code)
+	}
+	if recorder := serveLogin(router, "203.0.113.100", "attempt-bob", "secret"); recorder.Code != http.StatusOK {
+		t.Errorf("other ip should not be affected, got %d", recorder.Code)
+	}
+
+	// 管理员可以查看失败的登录尝试和被锁定的用户名, IP
+	code, body := serveUser(router, "attempt-admin", http.MethodGet, "/user/login-attempts?failedOnly=true", "")
+	var result struct {
+		Attempts []monitor_model.LoginAttempt   `json:"attempts"`
+		Lockouts []monitor_service.LoginLockout `json:"lockouts"`
+	}
+	if err := json.Unmarshal(body, &result); code != http.StatusOK || err != nil {
+		t.Fatalf("list login attempts responded %d, %s", code, body)
+	}
+	if len(result.Attempts) != 9 || result.Attempts[0].Success || result.Attempts[0].Ip != "203.0.113.99" {
+		t.Errorf("unexpected failed login attempts %s", body)
+	}
+	if len(result.Lockouts) != 2 || result.Lockouts[0].Username != "attempt-alice" || result.Lockouts[1].Ip != "203.0.113.99" {
+		t.Errorf("unexpected lockouts %s", body)
+	}
+	if code, _ := serveUser(router, "attempt-bob", http.MethodGet, "/user/login-attempts", ""); code != http.StatusForbidden {
+		t.Errorf("guest should not list login attempts, got %d", code)
+	}
+}
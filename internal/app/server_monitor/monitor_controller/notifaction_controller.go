@@ -1,7 +1,6 @@
 package monitor_controller
 
 import (
-	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/copier"
 	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_process_realtime"
@@ -39,24 +38,26 @@ func Notification(c *gin.Context) {
 
 	c.SSEvent("message", "notification channel connected")
 
-	session := sessions.Default(c)
-
 	// 通知信道连接成功时, 立即发送一次实时统计信息. 以便客户端能够立即显示统计信息.
 	// 立即发送的实时统计信息包含系统实时统计信息, 进程实时统计信息以及第三方模块的实时统计信息.
-	var collectStatConfig = getCollectStatConfig(session)
+	var collectStatConfig = getCollectStatConfig(c)
+	// 没有 process.view 权限时不发送进程实时统计信息
+	processViewable, _ := authority.HasPermission(c, authority.PermissionProcessView)
 	sendSystemRealtimeStatMessage(c, collectStatConfig, notification.Message{
 		Type: monitor_realtime.MessageType,
 		Data: map[string]interface{}{
 			monitor_realtime.MessageType: monitor_realtime.GetCachedSystemRealtimeStat(),
 		},
 	})
-	processes, _ := monitor_process_realtime.GetRealtimeStat(-1)
-	sendProcessRealtimeStatMessage(c, collectStatConfig, notification.Message{
-		Type: monitor_process_realtime.MessageType,
-		Data: map[string]interface{}{
-			monitor_process_realtime.MessageType: processes,
-		},
-	})
+	if processViewable {
+		processes, _ := monitor_process_realtime.GetRealtimeStat(-1)
+		sendProcessRealtimeStatMessage(c, collectStatConfig, notification.Message{
+			Type: monitor_process_realtime.MessageType,
+			Data: map[string]interface{}{
+				monitor_process_realtime.MessageType: processes,
+			},
+		})
+	}
 
 	// 检查是否有新版本可用并发送更新通知
 	if overseerInst, err := overseer.Get(); err != nil {
@@ -91,8 +92,8 @@ func Notification(c *gin.Context) {
 			return false
 		}
 
-		// 2. 获取保存在 session 消息通知配置
-		collectStatConfig = getCollectStatConfig(session)
+		// 2. 获取当前用户的消息通知配置
+		collectStatConfig = getCollectStatConfig(c)
 
 		// 3. 根据获取到的消息的类型, 发送对应的实时统计信息
 		switch message.Type {
@@ -102,7 +103,7 @@ func Notification(c *gin.Context) {
 			}
 			break
 		case monitor_process_realtime.MessageType:
-			if collectStatConfig.Process.Enable {
+			if collectStatConfig.Process.Enable && processViewable {
 				sendProcessRealtimeStatMessage(c, collectStatConfig, message)
 			}
 			break
@@ -126,10 +127,8 @@ func ModifyCollectStat(context *gin.Context) {
 		return
 	}
 
-	session := sessions.Default(context)
-
-	statConfig := getCollectStatConfig(session)
-	user := getAuthInfo(session)
+	statConfig := getCollectStatConfig(context)
+	user := getAuthInfo(context)
 
 	if err := copier.CopyWithOption(&statConfig, &body, copier.Option{
 		DeepCopy: true,
@@ -144,16 +143,14 @@ func ModifyCollectStat(context *gin.Context) {
 }
 
 func GetCollectStat(context *gin.Context) {
-	session := sessions.Default(context)
-
-	statConfig := getCollectStatConfig(session)
+	statConfig := getCollectStatConfig(context)
 
 	context.JSON(http.StatusOK, statConfig)
 }
 
-// getCollectStatConfig 通过 session 中的 username 获取统计数据收集配置
-func getCollectStatConfig(session sessions.Session) CollectStatConfig {
-	user := getAuthInfo(session)
+// getCollectStatConfig 通过当前用户的 username 获取统计数据收集配置
+func getCollectStatConfig(c *gin.Context) CollectStatConfig {
+	user := getAuthInfo(c)
 
 	cachedConfig, ok := collectConfigCache.Get(user.Username)
 	if !ok {
@@ -166,8 +163,8 @@ func getCollectStatConfig(session sessions.Session) CollectStatConfig {
 	return collectConfig
 }
 
-func getAuthInfo(session sessions.Session) authority.User {
-	config, _ := session.Get(authority.InfoKey).(authority.User)
+func getAuthInfo(c *gin.Context) authority.User {
+	config, _ := authority.GetUser(c)
 
 	return config
 }
@@ -0,0 +1,233 @@
+package monitor_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-errors/errors"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/notification_delivery"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification_channel"
+	"net/http"
+	"strconv"
+)
+
+// 投递记录列表默认和最多每页返回的记录数.
+const (
+	defaultNotificationDeliveryPageSize = 50
+	maxNotificationDeliveryPageSize     = 500
+)
+
+type NotificationChannelRequest struct {
+	Name   string                      `json:"name"`
+	Type   notification_channel.Type   `json:"type"`
+	Enable bool                        `json:"enable"`
+	Config notification_channel.Config `json:"config"`
+	// UserId 渠道所属的用户, 投递时遵循该用户的免打扰时段. 为 0 时表示共享的渠道.
+	UserId uint `json:"userId"`
+}
+
+// ListNotificationChannels 获取所有通知渠道, 响应中不包含密码和令牌
+// @Summary ListNotificationChannels
+// @Description ListNotificationChannels
+// @Tags NotificationChannel
+// @Produce json
+// @Success 200 {array} monitor_model.NotificationChannel
+// @Router notification/channel/list [get]
+func ListNotificationChannels(c *gin.Context) {
+	channels, err := monitor_service.ListNotificationChannels(false)
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	for i := range channels {
+		channels[i] = monitor_service.WithoutNotificationChannelSecret(channels[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{"channels": channels, "types": notification_channel.Types})
+}
+
+// CreateNotificationChannel 创建通知渠道
+// @Summary CreateNotificationChannel
+// @Description CreateNotificationChannel
+// @Tags NotificationChannel
+// @Accept json
+// @Produce json
+// @Param channel body NotificationChannelRequest true "body"
+// @Success 200 {object} monitor_model.NotificationChannel
+// @Router notification/channel/create [post]
+func CreateNotificationChannel(c *gin.Context) {
+	var body NotificationChannelRequest
+
+	auditAction(c, "notification.channel.create")
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	}
+
+	channel, err := monitor_service.CreateNotificationChannel(monitor_model.NotificationChannel{Name: body.Name, Type: body.Type, Enable: body.Enable, Config: body.Config, UserId: body.UserId})
+	if !respondNotificationChannelError(c, err) {
+		return
+	}
+
+	channel = monitor_service.WithoutNotificationChannelSecret(channel)
+	auditTarget(c, "notificationChannel", channel.ID, nil, channel)
+	c.JSON(http.StatusOK, channel)
+}
+
+// UpdateNotificationChannel 修改通知渠道. 配置中为空的密码和令牌保留原有的值
+// @Summary UpdateNotificationChannel
+// @Description UpdateNotificationChannel
+// @Tags NotificationChannel
+// @Accept json
+// @Produce json
+// @Param id path number true "id"
+// @Param channel body NotificationChannelRequest true "body"
+// @Success 200 {object} monitor_model.NotificationChannel
+// @Router notification/channel/update/{id} [put]
+func UpdateNotificationChannel(c *gin.Context) {
+	var body NotificationChannelRequest
+
+	auditAction(c, "notification.channel.update")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		respondEntityValidationError(c, "id should be number")
+		return
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	}
+
+	before, err := monitor_service.GetNotificationChannel(uint(id))
+	if !respondNotificationChannelError(c, err) {
+		return
+	}
+
+	channel, err := monitor_service.UpdateNotificationChannel(monitor_model.NotificationChannel{Model: monitor_model.Model{ID: uint(id)}, Name: body.Name, Type: body.Type, Enable: body.Enable, Config: body.Config, UserId: body.UserId})
+	if !respondNotificationChannelError(c, err) {
+		return
+	}
+
+	channel = monitor_service.WithoutNotificationChannelSecret(channel)
+	auditTarget(c, "notificationChannel", channel.ID, monitor_service.WithoutNotificationChannelSecret(before), channel)
+	c.JSON(http.StatusOK, channel)
+}
+
+// DeleteNotificationChannel 删除通知渠道及其投递记录
+// @Summary DeleteNotificationChannel
+// @Description DeleteNotificationChannel
+// @Tags NotificationChannel
+// @Produce json
+// @Param id path number true "id"
+// @Router notification/channel/delete/{id} [delete]
+func DeleteNotificationChannel(c *gin.Context) {
+	auditAction(c, "notification.channel.delete")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		respondEntityValidationError(c, "id should be number")
+		return
+	}
+
+	before, err := monitor_service.GetNotificationChannel(uint(id))
+	if !respondNotificationChannelError(c, err) {
+		return
+	}
+	auditTarget(c, "notificationChannel", id, monitor_service.WithoutNotificationChannelSecret(before), nil)
+
+	if !respondNotificationChannelError(c, monitor_service.DeleteNotificationChannel(uint(id))) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// SendTestNotification 立即通过通知渠道发送一条测试通知, 不会重试. 发送失败时响应 502 和失败原因
+// @Summary SendTestNotification
+// @Description SendTestNotification
+// @Tags NotificationChannel
+// @Produce json
+// @Param id path number true "id"
+// @Success 200 {object} monitor_model.NotificationDelivery
+// @Router notification/channel/test/{id} [post]
+func SendTestNotification(c *gin.Context) {
+	auditAction(c, "notification.channel.test")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		respondEntityValidationError(c, "id should be number")
+		return
+	}
+
+	channel, err := monitor_service.GetNotificationChannel(uint(id))
+	if !respondNotificationChannelError(c, err) {
+		return
+	}
+	auditTarget(c, "notificationChannel", id, nil, nil)
+
+	delivery, err := notification_delivery.SendTest(c.Request.Context(), channel)
+	if delivery.ID <= 0 {
+		respondUnknownError(c, err.Error())
+		return
+	} else if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadGateway, delivery)
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// ListNotificationDeliveries 分页获取通知的投递记录, 按时间倒序排列
+// @Summary ListNotificationDeliveries
+// @Description ListNotificationDeliveries
+// @Tags NotificationChannel
+// @Produce json
+// @Param channelId query number false "通知渠道的 id, 不传时返回所有渠道的记录"
+// @Param page query number false "页码, 从 1 开始, 默认为 1"
+// @Param pageSize query number false "每页的记录数, 默认为 50, 最大为 500"
+// @Router notification/channel/deliveries [get]
+func ListNotificationDeliveries(c *gin.Context) {
+	channelId, ok := queryPositiveInt(c, "channelId", 0, 0)
+	if !ok {
+		return
+	}
+	page, ok := queryPositiveInt(c, "page", 1, 0)
+	if !ok {
+		return
+	}
+	pageSize, ok := queryPositiveInt(c, "pageSize", defaultNotificationDeliveryPageSize, maxNotificationDeliveryPageSize)
+	if !ok {
+		return
+	}
+
+	deliveries, total, err := monitor_service.ListNotificationDeliveries(uint(channelId), page, pageSize)
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries": deliveries,
+		"total":      total,
+		"page":       page,
+		"pageSize":   pageSize,
+	})
+}
+
+// respondNotificationChannelError 根据通知渠道相关的错误响应对应的状态码, 没有错误时返回 true.
+func respondNotificationChannelError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, monitor_service.ErrorNotFound):
+		respondEntityNotFoundError(c, "notification channel %s not found", c.Param("id"))
+	case errors.Is(err, monitor_service.ErrorNotificationChannelNameExists):
+		respondEntityAlreadyExistError(c, err.Error())
+	case errors.Is(err, notification_channel.ErrorInvalidConfig):
+		respondEntityValidationError(c, err.Error())
+	default:
+		respondUnknownError(c, err.Error())
+	}
+
+	return false
+}
@@ -0,0 +1,178 @@
+package monitor_controller
+
+import (
+	"encoding/json"
+	"fmt"
+	ginSessions "github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/authority"
+	"github.com/siaikin/home-dashboard/internal/pkg/database"
+	"github.com/siaikin/home-dashboard/internal/pkg/sessions"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newNotificationChannelRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(sessions.GetSessionMiddleware(), func(c *gin.Context) {
+		if username := c.GetHeader("X-Test-User"); len(username) > 0 {
+			ginSessions.Default(c).Set(authority.InfoKey, authority.User{Username: username})
+		}
+	})
+
+	authority.SetPermissionChecker(CheckPermission)
+	authorized := router.Group("", authority.AuthorizeMiddleware(), ActiveUserMiddleware())
+	channelsManage := authorized.Group("", authority.PermissionMiddleware(authority.PermissionNotificationChannelsManage))
+	channelsManage.GET("notification/channel/list", ListNotificationChannels)
+	channelsManage.POST("notification/channel/create", CreateNotificationChannel)
+	channelsManage.PUT("notification/channel/update/:id", UpdateNotificationChannel)
+	channelsManage.DELETE("notification/channel/delete/:id", DeleteNotificationChannel)
+	channelsManage.POST("notification/channel/test/:id", SendTestNotification)
+	channelsManage.GET("notification/channel/deliveries", ListNotificationDeliveries)
+
+	return router
+}
+
+func TestNotificationChannel(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+	if err := monitor_service.GenerateBuiltInRoles(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := monitor_service.CreateUser(monitor_model.User{User: authority.User{Username: "channel-admin"}, Role: monitor_model.RoleAdministrator}); err != nil {
+		t.Fatal(err)
+	}
+	if err := monitor_service.CreateUser(monitor_model.User{User: authority.User{Username: "channel-guest"}, Role: monitor_model.RoleGuest}); err != nil {
+		t.Fatal(err)
+	}
+	defer monitor_db.GetDB().Unscoped().Where("username LIKE ?", "channel-%").Delete(&monitor_model.User{})
+
+	received := make(chan *http.Request, 1)
+	gotify := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r
+		if r.Header.Get("X-Gotify-Key") != "app-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer gotify.Close()
+
+	router := newNotificationChannelRouter()
+
+	if code, _ := serveUser(router, "channel-guest", http.MethodGet, "/notification/channel/list", ""); code != http.StatusForbidden {
+		t.Errorf("guest should not manage notification channels, got %d", code)
+	}
+	if code, _ := serveUser(router, "channel-admin", http.MethodPost, "/notification/channel/create", `{"name":"channel-invalid","type":"gotify","config":{"gotify":{"server":"not a url"}}}`); code != http.StatusBadRequest {
+		t.Errorf("invalid config should be rejected, got %d", code)
+	}
+
+	// 响应中不包含令牌, 更新时令牌为空则保留原有的值
+	code, body := serveUser(router, "channel-admin", http.MethodPost, "/notification/channel/create", fmt.Sprintf(`{"name":"channel-gotify","type":"gotify","enable":true,"config":{"gotify":{"server":%q,"token":"app-token"}}}`, gotify.URL))
+	var channel monitor_model.NotificationChannel
+	if err := json.Unmarshal(body, &channel); code != http.StatusOK || err != nil || strings.Contains(string(body), "app-token") {
+		t.Fatalf("create notification channel responded %d, %s", code, body)
+	}
+	defer func() { _ = monitor_service.DeleteNotificationChannel(channel.ID) }()
+
+	if code, body := serveUser(router, "channel-admin", http.MethodPut, fmt.Sprintf("/notification/channel/update/%d", channel.ID), fmt.Sprintf(`{"name":"channel-gotify","type":"gotify","enable":false,"config":{"gotify":{"server":%q}}}`, gotify.URL)); code != http.StatusOK {
+		t.Fatalf("update notification channel responded %d, %s", code, body)
+	}
+	if stored, _ := monitor_service.GetNotificationChannel(channel.ID); stored.Enable || stored.Config.Gotify.Token != "app-token" {
+		t.Errorf("token should be kept when not changed, got %+v", stored)
+	}
+
+	// 发送测试通知并记录投递结果
+	if code, body := serveUser(router, "channel-admin", http.MethodPost, fmt.Sprintf("/notification/channel/test/%d", channel.ID), ""); code != http.StatusOK {
+		t.Fatalf("test notification channel responded %d, %s", code, body)
+	}
+	if request := <-received; request.URL.Path != "/message" {
+		t.Errorf("test notification should be sent to gotify, got %s", request.URL.Path)
+	}
+
+	monitor_db.GetDB().Model(&channel).Update("config", `{"gotify":{"server":"`+gotify.URL+`","token":"wrong"}}`)
+	if code, body := serveUser(router, "channel-admin", http.MethodPost, fmt.Sprintf("/notification/channel/test/%d", channel.ID), ""); code != http.StatusBadGateway || !strings.Contains(string(body), "401") {
+		t.Errorf("failed test notification should respond 502, got %d, %s", code, body)
+	}
+	<-received
+
+	code, body = serveUser(router, "channel-admin", http.MethodGet, fmt.Sprintf("/notification/channel/deliveries?channelId=%d", channel.ID), "")
+	var deliveries struct {
+		Deliveries []monitor_model.NotificationDelivery `json:"deliveries"`
+		Total      int64                                `json:"total"`
+	}
+	if err := json.Unmarshal(body, &deliveries); code != http.StatusOK || err != nil || deliveries.Total != 2 ||
+		deliveries.Deliveries[0].Status != monitor_model.NotificationDeliveryStatusFailure || deliveries.Deliveries[1].Status != monitor_model.NotificationDeliveryStatusSuccess {
+		t.Errorf("unexpected deliveries %d, %s", code, body)
+	}
+
+	if code, _ := serveUser(router, "channel-admin", http.MethodDelete, fmt.Sprintf("/notification/channel/delete/%d", channel.ID), ""); code != http.StatusOK {
+		t.Errorf("delete notification channel responded %d", code)
+	}
+	if _, total, _ := monitor_service.ListNotificationDeliveries(channel.ID, 1, 10); total != 0 {
+		t.Errorf("deliveries should be deleted with channel, got %d", total)
+	}
+}
+
+func TestNotificationChannelWebhookHeaders(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+	if err := monitor_service.GenerateBuiltInRoles(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := monitor_service.CreateUser(monitor_model.User{User: authority.User{Username: "channel-header-admin"}, Role: monitor_model.RoleAdministrator}); err != nil {
+		t.Fatal(err)
+	}
+	defer monitor_db.GetDB().Unscoped().Where("username LIKE ?", "channel-header-%").Delete(&monitor_model.User{})
+
+	router := newNotificationChannelRouter()
+
+	// 响应中只保留请求头的名称
+	code, body := serveUser(router, "channel-header-admin", http.MethodPost, "/notification/channel/create", `{"name":"channel-header-webhook","type":"webhook","enable":true,"config":{"webhook":{"url":"http://127.0.0.1/hook","headers":{"Authorization":"Bearer header-token"}}}}`)
+	var channel monitor_model.NotificationChannel
+	if err := json.Unmarshal(body, &channel); code != http.StatusOK || err != nil || strings.Contains(string(body), "header-token") {
+		t.Fatalf("create notification channel responded %d, %s", code, body)
+	}
+	defer func() { _ = monitor_service.DeleteNotificationChannel(channel.ID) }()
+	if _, ok := channel.Config.Webhook.Headers["Authorization"]; !ok {
+		t.Errorf("header name should be kept, got %+v", channel.Config.Webhook.Headers)
+	}
+
+	if code, body := serveUser(router, "channel-header-admin", http.MethodGet, "/notification/channel/list", ""); code != http.StatusOK || strings.Contains(string(body), "header-token") {
+		t.Errorf("list should not contain header values, got %d, %s", code, body)
+	}
+
+	// 请求头的值为空时保留原有的值, 新增的请求头正常保存
+	if code, body := serveUser(router, "channel-header-admin", http.MethodPut, fmt.Sprintf("/notification/channel/update/%d", channel.ID), `{"name":"channel-header-webhook","type":"webhook","enable":true,"config":{"webhook":{"url":"http://127.0.0.1/hook","headers":{"Authorization":"","X-Extra":"extra"}}}}`); code != http.StatusOK || strings.Contains(string(body), "header-token") {
+		t.Fatalf("update notification channel responded %d, %s", code, body)
+	}
+	stored, _ := monitor_service.GetNotificationChannel(channel.ID)
+	if headers := stored.Config.Webhook.Headers; headers["Authorization"] != "Bearer header-token" || headers["X-Extra"] != "extra" {
+		t.Errorf("header value should be kept when not changed, got %+v", headers)
+	}
+
+	// 移除的请求头不再保留
+	if code, body := serveUser(router, "channel-header-admin", http.MethodPut, fmt.Sprintf("/notification/channel/update/%d", channel.ID), `{"name":"channel-header-webhook","type":"webhook","enable":true,"config":{"webhook":{"url":"http://127.0.0.1/hook","headers":{"X-Extra":""}}}}`); code != http.StatusOK {
+		t.Fatalf("update notification channel responded %d, %s", code, body)
+	}
+	stored, _ = monitor_service.GetNotificationChannel(channel.ID)
+	if headers := stored.Config.Webhook.Headers; len(headers) != 1 || headers["X-Extra"] != "extra" {
+		t.Errorf("removed header should not be kept, got %+v", headers)
+	}
+
+	// 隐藏敏感字段不修改原配置
+	if masked := monitor_service.WithoutNotificationChannelSecret(stored); masked.Config.Webhook.Headers["X-Extra"] != "" || stored.Config.Webhook.Headers["X-Extra"] != "extra" {
+		t.Errorf("masking should not modify the original config, got %+v", stored.Config.Webhook.Headers)
+	}
+}
@@ -0,0 +1,224 @@
+package monitor_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-errors/errors"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification"
+	"net/http"
+	"strconv"
+)
+
+type NotificationRouteRequest struct {
+	Name           string                                `json:"name"`
+	Enable         bool                                  `json:"enable"`
+	Priority       int                                   `json:"priority"`
+	Origins        []notification.UserNotificationOrigin `json:"origins"`
+	Kinds          []notification.UserNotificationKind   `json:"kinds"`
+	TitlePattern   string                                `json:"titlePattern"`
+	CaptionPattern string                                `json:"captionPattern"`
+	Action         monitor_model.NotificationRouteAction `json:"action"`
+	ChannelIds     []uint                                `json:"channelIds"`
+	DowngradeTo    notification.UserNotificationKind     `json:"downgradeTo"`
+}
+
+func (r NotificationRouteRequest) toModel(id uint) monitor_model.NotificationRoute {
+	return monitor_model.NotificationRoute{
+		Model:          monitor_model.Model{ID: id},
+		Name:           r.Name,
+		Enable:         r.Enable,
+		Priority:       r.Priority,
+		Origins:        r.Origins,
+		Kinds:          r.Kinds,
+		TitlePattern:   r.TitlePattern,
+		CaptionPattern: r.CaptionPattern,
+		Action:         r.Action,
+		ChannelIds:     r.ChannelIds,
+		DowngradeTo:    r.DowngradeTo,
+	}
+}
+
+type NotificationQuietHoursRequest struct {
+	Enable   bool   `json:"enable"`
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Timezone string `json:"timezone"`
+}
+
+// ListNotificationRoutes 获取所有通知路由规则, 按匹配顺序排列
+// @Summary ListNotificationRoutes
+// @Description ListNotificationRoutes
+// @Tags NotificationRoute
+// @Produce json
+// @Success 200 {array} monitor_model.NotificationRoute
+// @Router notification/route/list [get]
+func ListNotificationRoutes(c *gin.Context) {
+	routes, err := monitor_service.ListNotificationRoutes(false)
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"routes": routes})
+}
+
+// CreateNotificationRoute 创建通知路由规则
+// @Summary CreateNotificationRoute
+// @Description CreateNotificationRoute
+// @Tags NotificationRoute
+// @Accept json
+// @Produce json
+// @Param route body NotificationRouteRequest true "body"
+// @Success 200 {object} monitor_model.NotificationRoute
+// @Router notification/route/create [post]
+func CreateNotificationRoute(c *gin.Context) {
+	var body NotificationRouteRequest
+
+	auditAction(c, "notification.route.create")
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	}
+
+	route, err := monitor_service.CreateNotificationRoute(body.toModel(0))
+	if !respondNotificationRouteError(c, err) {
+		return
+	}
+
+	auditTarget(c, "notificationRoute", route.ID, nil, route)
+	c.JSON(http.StatusOK, route)
+}
+
+// UpdateNotificationRoute 修改通知路由规则
+// @Summary UpdateNotificationRoute
+// @Description UpdateNotificationRoute
+// @Tags NotificationRoute
+// @Accept json
+// @Produce json
+// @Param id path number true "id"
+// @Param route body NotificationRouteRequest true "body"
+// @Success 200 {object} monitor_model.NotificationRoute
+// @Router notification/route/update/{id} [put]
+func UpdateNotificationRoute(c *gin.Context) {
+	var body NotificationRouteRequest
+
+	auditAction(c, "notification.route.update")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		respondEntityValidationError(c, "id should be number")
+		return
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	}
+
+	before, err := monitor_service.GetNotificationRoute(uint(id))
+	if !respondNotificationRouteError(c, err) {
+		return
+	}
+
+	route, err := monitor_service.UpdateNotificationRoute(body.toModel(uint(id)))
+	if !respondNotificationRouteError(c, err) {
+		return
+	}
+
+	auditTarget(c, "notificationRoute", route.ID, before, route)
+	c.JSON(http.StatusOK, route)
+}
+
+// DeleteNotificationRoute 删除通知路由规则
+// @Summary DeleteNotificationRoute
+// @Description DeleteNotificationRoute
+// @Tags NotificationRoute
+// @Produce json
+// @Param id path number true "id"
+// @Router notification/route/delete/{id} [delete]
+func DeleteNotificationRoute(c *gin.Context) {
+	auditAction(c, "notification.route.delete")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		respondEntityValidationError(c, "id should be number")
+		return
+	}
+
+	before, err := monitor_service.GetNotificationRoute(uint(id))
+	if !respondNotificationRouteError(c, err) {
+		return
+	}
+	auditTarget(c, "notificationRoute", id, before, nil)
+
+	if !respondNotificationRouteError(c, monitor_service.DeleteNotificationRoute(uint(id))) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// GetNotificationQuietHours 获取当前登录用户的免打扰时段
+// @Summary GetNotificationQuietHours
+// @Description GetNotificationQuietHours
+// @Tags NotificationRoute
+// @Produce json
+// @Success 200 {object} monitor_model.NotificationQuietHours
+// @Router user/quiet-hours [get]
+func GetNotificationQuietHours(c *gin.Context) {
+	user := c.MustGet(currentUserKey).(monitor_model.User)
+
+	quietHours, err := monitor_service.GetNotificationQuietHours(user.ID)
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, quietHours)
+}
+
+// UpdateNotificationQuietHours 修改当前登录用户的免打扰时段. 免打扰时段内, 除了 error 类型的通知外, 不会投递到该用户的通知渠道
+// @Summary UpdateNotificationQuietHours
+// @Description UpdateNotificationQuietHours
+// @Tags NotificationRoute
+// @Accept json
+// @Produce json
+// @Param quietHours body NotificationQuietHoursRequest true "body"
+// @Success 200 {object} monitor_model.NotificationQuietHours
+// @Router user/quiet-hours [put]
+func UpdateNotificationQuietHours(c *gin.Context) {
+	var body NotificationQuietHoursRequest
+
+	user := c.MustGet(currentUserKey).(monitor_model.User)
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	}
+
+	quietHours, err := monitor_service.SaveNotificationQuietHours(monitor_model.NotificationQuietHours{
+		UserId:   user.ID,
+		Enable:   body.Enable,
+		Start:    body.Start,
+		End:      body.End,
+		Timezone: body.Timezone,
+	})
+	if !respondNotificationRouteError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, quietHours)
+}
+
+// respondNotificationRouteError 根据通知路由规则相关的错误响应对应的状态码, 没有错误时返回 true.
+func respondNotificationRouteError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, monitor_service.ErrorNotFound):
+		respondEntityNotFoundError(c, "notification route %s not found", c.Param("id"))
+	case errors.Is(err, monitor_service.ErrorInvalidNotificationRoute):
+		respondEntityValidationError(c, err.Error())
+	default:
+		respondUnknownError(c, err.Error())
+	}
+
+	return false
+}
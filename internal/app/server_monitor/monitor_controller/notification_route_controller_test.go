@@ -0,0 +1,85 @@
+package monitor_controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/authority"
+	"github.com/siaikin/home-dashboard/internal/pkg/database"
+	"net/http"
+	"testing"
+)
+
+func TestNotificationRoute(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+	if err := monitor_service.GenerateBuiltInRoles(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := monitor_service.CreateUser(monitor_model.User{User: authority.User{Username: "route-admin"}, Role: monitor_model.RoleAdministrator}); err != nil {
+		t.Fatal(err)
+	}
+	defer monitor_db.GetDB().Unscoped().Where("username LIKE ?", "route-%").Delete(&monitor_model.User{})
+	admin, _ := monitor_service.GetUserByName("route-admin")
+	defer monitor_db.GetDB().Unscoped().Where("user_id = ?", admin.ID).Delete(&monitor_model.NotificationQuietHours{})
+
+	router := newNotificationChannelRouter()
+	authorized := router.Group("", authority.AuthorizeMiddleware(), ActiveUserMiddleware())
+	authorized.GET("user/quiet-hours", GetNotificationQuietHours)
+	authorized.PUT("user/quiet-hours", UpdateNotificationQuietHours)
+	routesManage := authorized.Group("", authority.PermissionMiddleware(authority.PermissionNotificationChannelsManage))
+	routesManage.GET("notification/route/list", ListNotificationRoutes)
+	routesManage.POST("notification/route/create", CreateNotificationRoute)
+	routesManage.PUT("notification/route/update/:id", UpdateNotificationRoute)
+	routesManage.DELETE("notification/route/delete/:id", DeleteNotificationRoute)
+
+	for _, body := range []string{
+		`{"name":"unknown action","action":"forward"}`,
+		`{"name":"invalid pattern","titlePattern":"(","action":"suppress"}`,
+		`{"name":"missing channel","action":"route","channelIds":[404]}`,
+		`{"name":"unknown kind","action":"downgrade","downgradeTo":"fatal"}`,
+	} {
+		if code, _ := serveUser(router, "route-admin", http.MethodPost, "/notification/route/create", body); code != http.StatusBadRequest {
+			t.Errorf("invalid route %s should be rejected, got %d", body, code)
+		}
+	}
+
+	code, body := serveUser(router, "route-admin", http.MethodPost, "/notification/route/create", `{"name":"mute github","enable":true,"origins":["github"],"action":"suppress"}`)
+	var route monitor_model.NotificationRoute
+	if err := json.Unmarshal(body, &route); code != http.StatusOK || err != nil {
+		t.Fatalf("create notification route responded %d, %s", code, body)
+	}
+	if code, body := serveUser(router, "route-admin", http.MethodPut, fmt.Sprintf("/notification/route/update/%d", route.ID), `{"name":"downgrade github","enable":true,"priority":5,"origins":["github"],"action":"downgrade","downgradeTo":"info"}`); code != http.StatusOK {
+		t.Fatalf("update notification route responded %d, %s", code, body)
+	}
+	if stored, _ := monitor_service.GetNotificationRoute(route.ID); stored.Priority != 5 || stored.Action != monitor_model.NotificationRouteActionDowngrade || stored.Origins[0] != "github" {
+		t.Errorf("unexpected updated route %+v", stored)
+	}
+	if code, _ := serveUser(router, "route-admin", http.MethodDelete, fmt.Sprintf("/notification/route/delete/%d", route.ID), ""); code != http.StatusOK {
+		t.Errorf("delete notification route responded %d", code)
+	}
+	if code, _ := serveUser(router, "route-admin", http.MethodDelete, fmt.Sprintf("/notification/route/delete/%d", route.ID), ""); code != http.StatusNotFound {
+		t.Errorf("deleted notification route should not be found, got %d", code)
+	}
+
+	// 免打扰时段属于当前登录的用户
+	if code, _ := serveUser(router, "route-admin", http.MethodPut, "/user/quiet-hours", `{"enable":true,"start":"25:00","end":"07:00"}`); code != http.StatusBadRequest {
+		t.Errorf("invalid quiet hours should be rejected, got %d", code)
+	}
+	if code, _ := serveUser(router, "route-admin", http.MethodPut, "/user/quiet-hours", `{"enable":true,"start":"22:00","end":"07:00","timezone":"Mars/Olympus"}`); code != http.StatusBadRequest {
+		t.Errorf("unknown timezone should be rejected, got %d", code)
+	}
+	if code, body := serveUser(router, "route-admin", http.MethodPut, "/user/quiet-hours", `{"enable":true,"start":"22:00","end":"07:00","timezone":"Asia/Shanghai"}`); code != http.StatusOK {
+		t.Fatalf("update quiet hours responded %d, %s", code, body)
+	}
+	code, body = serveUser(router, "route-admin", http.MethodGet, "/user/quiet-hours", "")
+	var quietHours monitor_model.NotificationQuietHours
+	if err := json.Unmarshal(body, &quietHours); code != http.StatusOK || err != nil || !quietHours.Enable || quietHours.UserId != admin.ID || quietHours.Start != "22:00" {
+		t.Errorf("unexpected quiet hours %d, %s", code, body)
+	}
+}
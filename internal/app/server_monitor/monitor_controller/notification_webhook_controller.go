@@ -0,0 +1,234 @@
+package monitor_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-errors/errors"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/comfy_errors"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification_webhook"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ReceiveNotificationWebhookPrefix 入站 Webhook 接收通知的路由前缀. 外部系统会频繁调用, 只有令牌校验失败的请求会记录到审计日志中.
+const ReceiveNotificationWebhookPrefix = "/v1/web/notification/webhook/receive"
+
+// maxNotificationWebhookBodySize 入站 Webhook 请求体的最大字节数.
+const maxNotificationWebhookBodySize = 1 << 20
+
+type NotificationWebhookRequest struct {
+	Name   string                      `json:"name"`
+	Enable bool                        `json:"enable"`
+	Format notification_webhook.Format `json:"format"`
+}
+
+// ListNotificationWebhooks 获取所有入站 Webhook, 响应中不包含令牌
+// @Summary ListNotificationWebhooks
+// @Description ListNotificationWebhooks
+// @Tags NotificationWebhook
+// @Produce json
+// @Success 200 {array} monitor_model.NotificationWebhook
+// @Router notification/webhook/list [get]
+func ListNotificationWebhooks(c *gin.Context) {
+	webhooks, err := monitor_service.ListNotificationWebhooks()
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhooks": webhooks, "formats": notification_webhook.Formats})
+}
+
+// CreateNotificationWebhook 创建入站 Webhook. 令牌明文只在创建时返回一次
+// @Summary CreateNotificationWebhook
+// @Description CreateNotificationWebhook
+// @Tags NotificationWebhook
+// @Accept json
+// @Produce json
+// @Param webhook body NotificationWebhookRequest true "body"
+// @Router notification/webhook/create [post]
+func CreateNotificationWebhook(c *gin.Context) {
+	var body NotificationWebhookRequest
+
+	auditAction(c, "notification.webhook.create")
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	}
+
+	token, webhook, err := monitor_service.CreateNotificationWebhook(monitor_model.NotificationWebhook{Name: strings.TrimSpace(body.Name), Enable: body.Enable, Format: body.Format})
+	if !respondNotificationWebhookError(c, err) {
+		return
+	}
+
+	auditTarget(c, "notificationWebhook", webhook.ID, nil, webhook)
+	c.JSON(http.StatusOK, gin.H{"token": token, "webhook": webhook})
+}
+
+// UpdateNotificationWebhook 修改入站 Webhook, 令牌不变
+// @Summary UpdateNotificationWebhook
+// @Description UpdateNotificationWebhook
+// @Tags NotificationWebhook
+// @Accept json
+// @Produce json
+// @Param id path number true "id"
+// @Param webhook body NotificationWebhookRequest true "body"
+// @Success 200 {object} monitor_model.NotificationWebhook
+// @Router notification/webhook/update/{id} [put]
+func UpdateNotificationWebhook(c *gin.Context) {
+	var body NotificationWebhookRequest
+
+	auditAction(c, "notification.webhook.update")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		respondEntityValidationError(c, "id should be number")
+		return
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	}
+
+	before, err := monitor_service.GetNotificationWebhook(uint(id))
+	if !respondNotificationWebhookError(c, err) {
+		return
+	}
+
+	webhook, err := monitor_service.UpdateNotificationWebhook(monitor_model.NotificationWebhook{Model: monitor_model.Model{ID: uint(id)}, Name: strings.TrimSpace(body.Name), Enable: body.Enable, Format: body.Format})
+	if !respondNotificationWebhookError(c, err) {
+		return
+	}
+
+	auditTarget(c, "notificationWebhook", webhook.ID, before, webhook)
+	c.JSON(http.StatusOK, webhook)
+}
+
+// RegenerateNotificationWebhookToken 重新生成入站 Webhook 的令牌, 原有的令牌立即失效. 新的令牌明文只返回一次
+// @Summary RegenerateNotificationWebhookToken
+// @Description RegenerateNotificationWebhookToken
+// @Tags NotificationWebhook
+// @Produce json
+// @Param id path number true "id"
+// @Router notification/webhook/token/{id} [post]
+func RegenerateNotificationWebhookToken(c *gin.Context) {
+	auditAction(c, "notification.webhook.token")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		respondEntityValidationError(c, "id should be number")
+		return
+	}
+
+	token, webhook, err := monitor_service.RegenerateNotificationWebhookToken(uint(id))
+	if !respondNotificationWebhookError(c, err) {
+		return
+	}
+
+	auditTarget(c, "notificationWebhook", webhook.ID, nil, nil)
+	c.JSON(http.StatusOK, gin.H{"token": token, "webhook": webhook})
+}
+
+// DeleteNotificationWebhook 删除入站 Webhook, 已经收到的通知不会被删除
+// @Summary DeleteNotificationWebhook
+// @Description DeleteNotificationWebhook
+// @Tags NotificationWebhook
+// @Produce json
+// @Param id path number true "id"
+// @Router notification/webhook/delete/{id} [delete]
+func DeleteNotificationWebhook(c *gin.Context) {
+	auditAction(c, "notification.webhook.delete")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		respondEntityValidationError(c, "id should be number")
+		return
+	}
+
+	before, err := monitor_service.GetNotificationWebhook(uint(id))
+	if !respondNotificationWebhookError(c, err) {
+		return
+	}
+	auditTarget(c, "notificationWebhook", id, before, nil)
+
+	if !respondNotificationWebhookError(c, monitor_service.DeleteNotificationWebhook(uint(id))) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// ReceiveNotificationWebhook 接收外部系统发送的通知, 不需要登录. 令牌通过 Authorization: Bearer <token> 请求头或 token 查询参数传递.
+// 请求体按 Webhook 配置的格式解析, 相同 UniqueId 的通知会更新已有的通知, 已恢复的告警标记为已读.
+// @Summary ReceiveNotificationWebhook
+// @Description ReceiveNotificationWebhook
+// @Tags NotificationWebhook
+// @Accept json
+// @Produce json
+// @Param id path number true "id"
+// @Param token query string false "令牌, 也可以通过 Authorization 请求头传递"
+// @Router notification/webhook/receive/{id} [post]
+func ReceiveNotificationWebhook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		respondEntityValidationError(c, "id should be number")
+		return
+	}
+
+	token := c.Query("token")
+	if header := c.GetHeader("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		token = strings.TrimPrefix(header, "Bearer ")
+	}
+
+	webhook, err := monitor_service.VerifyNotificationWebhookToken(uint(id), token)
+	if errors.Is(err, monitor_service.ErrorInvalidNotificationWebhookToken) {
+		auditAction(c, "notification.webhook.reject")
+		auditTarget(c, "notificationWebhook", id, nil, nil)
+		abortWithError(c, http.StatusUnauthorized, comfy_errors.NewResponseError(comfy_errors.LoginRequestError, err.Error()))
+		return
+	} else if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxNotificationWebhookBodySize))
+	if err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	}
+
+	userNotifications, err := notification_webhook.Parse(webhook.Format, body)
+	if err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	}
+
+	// 添加前缀, 避免与其他 Webhook 和模块的 UniqueId 冲突
+	for i := range userNotifications {
+		userNotifications[i].UniqueId = "webhook-" + strconv.FormatUint(id, 10) + "-" + userNotifications[i].UniqueId
+		userNotifications[i].Origin = monitor_service.NotificationWebhookOrigin(webhook)
+	}
+	// 与其他模块的通知一样, 存储和投递到通知渠道都由通知的订阅者完成
+	notification.SendUserNotifications(userNotifications)
+
+	c.JSON(http.StatusOK, gin.H{"received": len(userNotifications)})
+}
+
+// respondNotificationWebhookError 根据入站 Webhook 相关的错误响应对应的状态码, 没有错误时返回 true.
+func respondNotificationWebhookError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, monitor_service.ErrorNotFound):
+		respondEntityNotFoundError(c, "notification webhook %s not found", c.Param("id"))
+	case errors.Is(err, monitor_service.ErrorNotificationWebhookNameExists):
+		respondEntityAlreadyExistError(c, err.Error())
+	case errors.Is(err, monitor_service.ErrorInvalidNotificationWebhook):
+		respondEntityValidationError(c, err.Error())
+	default:
+		respondUnknownError(c, err.Error())
+	}
+
+	return false
+}
@@ -0,0 +1,182 @@
+package monitor_controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/authority"
+	"github.com/siaikin/home-dashboard/internal/pkg/database"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotificationWebhook(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+	if err := monitor_service.GenerateBuiltInRoles(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := monitor_service.CreateUser(monitor_model.User{User: authority.User{Username: "webhook-admin"}, Role: monitor_model.RoleAdministrator}); err != nil {
+		t.Fatal(err)
+	}
+	if err := monitor_service.CreateUser(monitor_model.User{User: authority.User{Username: "webhook-guest"}, Role: monitor_model.RoleGuest}); err != nil {
+		t.Fatal(err)
+	}
+	defer monitor_db.GetDB().Unscoped().Where("username LIKE ?", "webhook-%").Delete(&monitor_model.User{})
+	defer monitor_db.GetDB().Unscoped().Where("origin LIKE ?", "webhook:%").Delete(&monitor_model.StoredNotification{})
+
+	// 收到的通知通过 notification.SendUserNotifications 发送给订阅者
+	listener := notification.GetListener()
+	defer listener.Close()
+
+	router := newNotificationChannelRouter()
+	router.POST("notification/webhook/receive/:id", ReceiveNotificationWebhook)
+	authorized := router.Group("", authority.AuthorizeMiddleware(), ActiveUserMiddleware())
+	webhooksManage := authorized.Group("", authority.PermissionMiddleware(authority.PermissionNotificationChannelsManage))
+	webhooksManage.GET("notification/webhook/list", ListNotificationWebhooks)
+	webhooksManage.POST("notification/webhook/create", CreateNotificationWebhook)
+	webhooksManage.PUT("notification/webhook/update/:id", UpdateNotificationWebhook)
+	webhooksManage.POST("notification/webhook/token/:id", RegenerateNotificationWebhookToken)
+	webhooksManage.DELETE("notification/webhook/delete/:id", DeleteNotificationWebhook)
+
+	if code, _ := serveUser(router, "webhook-guest", http.MethodPost, "/notification/webhook/create", `{"name":"ci","enable":true,"format":"generic"}`); code != http.StatusForbidden {
+		t.Errorf("guest should not create webhooks, got %d", code)
+	}
+	if code, _ := serveUser(router, "webhook-admin", http.MethodPost, "/notification/webhook/create", `{"name":"ci","enable":true,"format":"xml"}`); code != http.StatusBadRequest {
+		t.Errorf("unknown format should be rejected, got %d", code)
+	}
+
+	create := func(body string) (string, monitor_model.NotificationWebhook) {
+		code, response := serveUser(router, "webhook-admin", http.MethodPost, "/notification/webhook/create", body)
+		var created struct {
+			Token   string                            `json:"token"`
+			Webhook monitor_model.NotificationWebhook `json:"webhook"`
+		}
+		if err := json.Unmarshal(response, &created); code != http.StatusOK || err != nil {
+			t.Fatalf("create webhook responded %d, %s", code, response)
+		}
+		return created.Token, created.Webhook
+	}
+	ciToken, ci := create(`{"name":"ci","enable":true,"format":"generic"}`)
+	alertToken, alertmanager := create(`{"name":"alertmanager","enable":true,"format":"alertmanager"}`)
+	defer monitor_db.GetDB().Unscoped().Where("id IN ?", []uint{ci.ID, alertmanager.ID}).Delete(&monitor_model.NotificationWebhook{})
+
+	if code, _ := serveUser(router, "webhook-admin", http.MethodPost, "/notification/webhook/create", `{"name":"ci","enable":true,"format":"generic"}`); code != http.StatusBadRequest {
+		t.Errorf("duplicated name should be rejected, got %d", code)
+	}
+	if code, body := serveUser(router, "webhook-admin", http.MethodGet, "/notification/webhook/list", ""); code != http.StatusOK || strings.Contains(string(body), ciToken) || strings.Contains(string(body), "tokenHash") {
+		t.Errorf("list webhooks should not contain tokens, got %d %s", code, body)
+	}
+
+	receive := func(id uint, token string, body string) int {
+		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/notification/webhook/receive/%d", id), strings.NewReader(body))
+		if len(token) > 0 {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+	// store 与 user_notification 的订阅者一样存储收到的通知
+	store := func() {
+		select {
+		case message := <-listener.Ch():
+			userNotifications := message.Data["notifications"].([]notification.UserNotification)
+			storedNotifications := make([]monitor_model.StoredNotification, len(userNotifications))
+			for i, userNotification := range userNotifications {
+				storedNotifications[i] = monitor_model.StoredNotification{UserNotification: userNotification}
+			}
+			if err := monitor_service.CreateOrUpdateNotifications(storedNotifications); err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("notifications are not sent")
+		}
+	}
+	stored := func(uniqueId string) monitor_model.StoredNotification {
+		notifications, err := monitor_service.LatestNNotificationByQuery(0, notification.UserNotification{UniqueId: uniqueId})
+		if err != nil || len(*notifications) != 1 {
+			t.Fatalf("expected 1 notification %s, got %+v %v", uniqueId, *notifications, err)
+		}
+		return (*notifications)[0]
+	}
+
+	// 令牌错误或属于其他 Webhook 时拒绝
+	for _, token := range []string{"", "hdw_wrong", alertToken} {
+		if code := receive(ci.ID, token, `{"title":"Build failed"}`); code != http.StatusUnauthorized {
+			t.Errorf("token %q should be rejected, got %d", token, code)
+		}
+	}
+	if code := receive(ci.ID, ciToken, `{"caption":"missing title"}`); code != http.StatusBadRequest {
+		t.Errorf("invalid payload should be rejected, got %d", code)
+	}
+
+	// 通用格式, 令牌也可以通过查询参数传递
+	request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/notification/webhook/receive/%d?token=%s", ci.ID, ciToken), strings.NewReader(`{"uniqueId":"build-42","title":"Build 42 failed","status":"firing"}`))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("receive generic notification responded %d, %s", recorder.Code, recorder.Body)
+	}
+	store()
+	uniqueId := fmt.Sprintf("webhook-%d-build-42", ci.ID)
+	if n := stored(uniqueId); !n.Unread || n.Kind != notification.UserNotificationKindError || n.Origin != "webhook:ci" {
+		t.Errorf("unexpected firing notification %+v", n)
+	}
+
+	// 恢复时更新同一条通知为已读
+	if code := receive(ci.ID, ciToken, `{"uniqueId":"build-42","title":"Build 42 fixed","status":"resolved"}`); code != http.StatusOK {
+		t.Fatalf("receive resolved notification responded %d", code)
+	}
+	store()
+	if n := stored(uniqueId); n.Unread || n.Kind != notification.UserNotificationKindSuccess || n.Title != "Build 42 fixed" {
+		t.Errorf("resolved notification should update the stored one, got %+v", n)
+	}
+
+	// Alertmanager 格式
+	if code := receive(alertmanager.ID, alertToken, `{"status":"firing","alerts":[{"status":"firing","labels":{"alertname":"HighLoad","severity":"warning"},"annotations":{"summary":"load is high"},"startsAt":"2024-01-02T03:04:05Z","fingerprint":"abc"}]}`); code != http.StatusOK {
+		t.Fatalf("receive alertmanager notification responded %d", code)
+	}
+	store()
+	if n := stored(fmt.Sprintf("webhook-%d-abc-1704164645000", alertmanager.ID)); !n.Unread || n.Kind != notification.UserNotificationKindWarning || n.Caption != "load is high" || n.Origin != "webhook:alertmanager" {
+		t.Errorf("unexpected alertmanager notification %+v", n)
+	}
+
+	// 停用和重新生成令牌后原有的令牌失效
+	if code, body := serveUser(router, "webhook-admin", http.MethodPut, fmt.Sprintf("/notification/webhook/update/%d", ci.ID), `{"name":"ci","enable":false,"format":"generic"}`); code != http.StatusOK {
+		t.Fatalf("update webhook responded %d, %s", code, body)
+	}
+	if code := receive(ci.ID, ciToken, `{"title":"Build failed"}`); code != http.StatusUnauthorized {
+		t.Errorf("disabled webhook should reject notifications, got %d", code)
+	}
+	code, body := serveUser(router, "webhook-admin", http.MethodPost, fmt.Sprintf("/notification/webhook/token/%d", alertmanager.ID), "")
+	var regenerated struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(body, &regenerated); code != http.StatusOK || err != nil || regenerated.Token == alertToken {
+		t.Fatalf("regenerate token responded %d, %s", code, body)
+	}
+	if code := receive(alertmanager.ID, alertToken, `{"alerts":[{"labels":{"alertname":"x"}}]}`); code != http.StatusUnauthorized {
+		t.Errorf("old token should be rejected, got %d", code)
+	}
+	if code := receive(alertmanager.ID, regenerated.Token, `{"alerts":[{"labels":{"alertname":"x"}}]}`); code != http.StatusOK {
+		t.Errorf("new token should be accepted, got %d", code)
+	}
+	store()
+
+	if code, _ := serveUser(router, "webhook-admin", http.MethodDelete, fmt.Sprintf("/notification/webhook/delete/%d", ci.ID), ""); code != http.StatusOK {
+		t.Errorf("delete webhook responded %d", code)
+	}
+	if code := receive(ci.ID, ciToken, `{"title":"Build failed"}`); code != http.StatusUnauthorized {
+		t.Errorf("deleted webhook should reject notifications, got %d", code)
+	}
+}
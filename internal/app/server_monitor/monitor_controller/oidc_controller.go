@@ -0,0 +1,140 @@
+package monitor_controller
+
+import (
+	"context"
+	"encoding/gob"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/authority"
+	"github.com/siaikin/home-dashboard/internal/pkg/comfy_errors"
+	"github.com/siaikin/home-dashboard/internal/pkg/configuration"
+	"net/http"
+)
+
+// session 中存储 OpenID Connect 授权请求参数和登录后跳转地址的 key.
+const (
+	oidcAuthRequestKey = "oidcAuthRequest"
+	oidcRedirectKey    = "oidcRedirect"
+)
+
+func init() {
+	gob.Register(authority.OidcAuthRequest{})
+}
+
+// GetOidcInfo 获取 OpenID Connect 登录是否启用及登录按钮上显示的名称.
+// @Summary GetOidcInfo
+// @Description GetOidcInfo
+// @Tags GetOidcInfo
+// @Produce json
+// @Router auth/oidc [get]
+func GetOidcInfo(c *gin.Context) {
+	config := configuration.Get().ServerMonitor.Oidc
+
+	c.JSON(http.StatusOK, gin.H{
+		"enable": config.Enable,
+		"name":   config.Name,
+	})
+}
+
+// OidcLogin 跳转到 OpenID Connect 提供方的授权页面.
+// @Summary OidcLogin
+// @Description OidcLogin
+// @Tags OidcLogin
+// @Param redirect query string false "登录成功后跳转的地址, 只允许站内的相对路径, 默认为 /"
+// @Router auth/oidc/login [get]
+func OidcLogin(c *gin.Context) {
+	client, ok := getOidcClient(c)
+	if !ok {
+		return
+	}
+
+	request, err := client.NewAuthRequest()
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	// 只允许跳转到站内地址, 避免开放重定向
+	redirect := localRedirect(c.Query("redirect"))
+
+	session := sessions.Default(c)
+	session.Set(oidcAuthRequestKey, request)
+	session.Set(oidcRedirectKey, redirect)
+	if err := session.Save(); err != nil {
+		abortWithError(c, http.StatusInternalServerError, comfy_errors.NewResponseError(comfy_errors.SessionStoreError, "session save failed. %w", err))
+		return
+	}
+
+	c.Redirect(http.StatusFound, client.AuthCodeURL(request))
+}
+
+// OidcCallback OpenID Connect 提供方授权后的回调, 校验 ID Token 并登录.
+// @Summary OidcCallback
+// @Description OidcCallback
+// @Tags OidcCallback
+// @Param code query string true "授权码"
+// @Param state query string true "state"
+// @Router auth/oidc/callback [get]
+func OidcCallback(c *gin.Context) {
+	auditAction(c, "auth.login")
+	client, ok := getOidcClient(c)
+	if !ok {
+		return
+	}
+
+	session := sessions.Default(c)
+	request, ok := session.Get(oidcAuthRequestKey).(authority.OidcAuthRequest)
+	redirect, _ := session.Get(oidcRedirectKey).(string)
+	// 授权请求参数只能使用一次
+	session.Delete(oidcAuthRequestKey)
+	session.Delete(oidcRedirectKey)
+	if err := session.Save(); err != nil {
+		abortWithError(c, http.StatusInternalServerError, comfy_errors.NewResponseError(comfy_errors.SessionStoreError, "session save failed. %w", err))
+		return
+	}
+
+	if errorCode := c.Query("error"); len(errorCode) > 0 {
+		respondLoginError(c, "oidc authorization failed, %s: %s", errorCode, c.Query("error_description"))
+		return
+	} else if !ok || len(request.State) <= 0 || c.Query("state") != request.State {
+		respondLoginError(c, "oidc state mismatch")
+		return
+	}
+
+	identity, err := client.Exchange(c, c.Query("code"), request)
+	if err != nil {
+		respondLoginError(c, err.Error())
+		return
+	}
+
+	user, err := monitor_service.ResolveOidcUser(client.Config(), identity)
+	if err != nil {
+		respondLoginError(c, "oidc user %s cannot login, %s", identity.Username, err.Error())
+		return
+	}
+
+	if !saveLoginSession(c, user) {
+		return
+	}
+
+	c.Redirect(http.StatusFound, redirect)
+}
+
+// getOidcClient 获取 OpenID Connect 客户端, 未启用或提供方不可用时响应错误并返回 false.
+func getOidcClient(c *gin.Context) (*authority.OidcClient, bool) {
+	config := configuration.Get().ServerMonitor.Oidc
+	if !config.Enable {
+		respondEntityNotFoundError(c, "oidc login is not enabled")
+		return nil, false
+	}
+
+	// 客户端会被缓存并在之后的请求中使用, 不能使用请求的 context
+	client, err := monitor_service.GetOidcClient(context.Background(), config)
+	if err != nil {
+		abortWithError(c, http.StatusBadGateway, comfy_errors.NewResponseError(comfy_errors.UnknownError, err.Error()))
+		return nil, false
+	}
+
+	return client, true
+}
@@ -0,0 +1,304 @@
+package monitor_controller
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/go-jose/go-jose/v3"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/authority"
+	"github.com/siaikin/home-dashboard/internal/pkg/configuration"
+	"github.com/siaikin/home-dashboard/internal/pkg/database"
+	"github.com/siaikin/home-dashboard/internal/pkg/sessions"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeOidcProvider 测试用的 OpenID Connect 提供方, 授权页面直接以 username 和 groups 登录并跳转回调地址.
+type fakeOidcProvider struct {
+	*httptest.Server
+	key      *rsa.PrivateKey
+	clientId string
+	username string
+	groups   []string
+	// subject ID Token 的 sub, 为空时为 subject-<username>
+	subject string
+	// codes 授权码到 code_challenge 和 nonce 的映射
+	codes sync.Map
+}
+
+type fakeOidcCode struct {
+	challenge string
+	nonce     string
+}
+
+func newFakeOidcProvider(t *testing.T, clientId string) *fakeOidcProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	provider := &fakeOidcProvider{key: key, clientId: clientId}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{
+			"issuer":                                provider.URL,
+			"authorization_endpoint":                provider.URL + "/authorize",
+			"token_endpoint":                        provider.URL + "/token",
+			"jwks_uri":                              provider.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: &key.PublicKey, KeyID: "test", Algorithm: "RS256", Use: "sig"}}})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("code_challenge_method") != "S256" || len(query.Get("code_challenge")) <= 0 {
+			http.Error(w, "pkce required", http.StatusBadRequest)
+			return
+		}
+
+		code := strconv.FormatInt(time.Now().UnixNano(), 36)
+		provider.codes.Store(code, fakeOidcCode{challenge: query.Get("code_challenge"), nonce: query.Get("nonce")})
+
+		redirect, _ := url.Parse(query.Get("redirect_uri"))
+		redirect.RawQuery = url.Values{"code": {code}, "state": {query.Get("state")}}.Encode()
+		http.Redirect(w, r, redirect.String(), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		value, ok := provider.codes.LoadAndDelete(r.PostFormValue("code"))
+		sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != value.(fakeOidcCode).challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			writeJSON(w, map[string]any{"error": "invalid_grant"})
+			return
+		}
+
+		writeJSON(w, map[string]any{
+			"access_token": "access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     provider.signIdToken(t, value.(fakeOidcCode).nonce),
+		})
+	})
+	provider.Server = httptest.NewServer(mux)
+
+	return provider
+}
+
+func (p *fakeOidcProvider) signIdToken(t *testing.T, nonce string) string {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: p.key}, (&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subject := p.subject
+	if len(subject) <= 0 {
+		subject = "subject-" + p.username
+	}
+
+	claims, _ := json.Marshal(map[string]any{
+		"iss":                p.URL,
+		"sub":                subject,
+		"aud":                p.clientId,
+		"iat":                time.Now().Unix(),
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"nonce":              nonce,
+		"preferred_username": p.username,
+		"groups":             p.groups,
+	})
+	signed, err := signer.Sign(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _ := signed.CompactSerialize()
+
+	return token
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func newOidcRouter() *gin.Engine {
+	router := newUserRouter()
+
+	router.GET("auth/oidc/login", OidcLogin)
+	router.GET("auth/oidc/callback", OidcCallback)
+	router.GET("oidc-test/me", authority.AuthorizeMiddleware(), ActiveUserMiddleware(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, c.MustGet(currentUserKey))
+	})
+
+	return router
+}
+
+func serveCookie(router *gin.Engine, url string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, url, nil)
+	if cookie != nil {
+		request.AddCookie(cookie)
+	}
+	router.ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+// latestSessionCookie 获取响应中设置的 session Cookie, 没有时返回 cookie.
+func latestSessionCookie(recorder *httptest.ResponseRecorder, cookie *http.Cookie) *http.Cookie {
+	for _, c := range recorder.Result().Cookies() {
+		if c.Name == sessions.GetSessionName() {
+			cookie = c
+		}
+	}
+
+	return cookie
+}
+
+// oidcLogin 走完整的授权码流程, 返回回调接口的响应和最新的 session Cookie.
+func oidcLogin(t *testing.T, router *gin.Engine, redirect string) (*httptest.ResponseRecorder, *http.Cookie) {
+	t.Helper()
+
+	login := serveCookie(router, "/auth/oidc/login?redirect="+url.QueryEscape(redirect), nil)
+	if login.Code != http.StatusFound {
+		t.Fatalf("oidc login responded %d, %s", login.Code, login.Body.String())
+	}
+	cookie := latestSessionCookie(login, nil)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	response, err := client.Get(login.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = response.Body.Close()
+	if response.StatusCode != http.StatusFound {
+		t.Fatalf("oidc provider authorize responded %d", response.StatusCode)
+	}
+
+	callback, _ := url.Parse(response.Header.Get("Location"))
+	recorder := serveCookie(router, callback.RequestURI(), cookie)
+
+	return recorder, latestSessionCookie(recorder, cookie)
+}
+
+func TestOidcLogin(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := newFakeOidcProvider(t, "home-dashboard")
+	defer provider.Close()
+
+	config := &configuration.Get().ServerMonitor.Oidc
+	original := *config
+	defer func() { *config = original }()
+	*config = configuration.ServerMonitorOidcConfiguration{
+		Enable:              true,
+		Issuer:              provider.URL,
+		ClientId:            "home-dashboard",
+		ClientSecret:        "secret",
+		RedirectUrl:         "http://dashboard.test/auth/oidc/callback",
+		AllowedGroups:       []string{"family", "admins"},
+		AdministratorGroups: []string{"admins"},
+		AutoCreateUser:      true,
+	}
+
+	// 另一个管理员, 使 oidc-alice 可以被降级. 测试结束后删除本测试创建的用户, 以免影响其他测试
+	if err := monitor_service.CreateUser(monitor_model.User{User: authority.User{Username: "oidc-admin"}, Role: monitor_model.RoleAdministrator}); err != nil {
+		t.Fatal(err)
+	}
+	defer monitor_db.GetDB().Unscoped().Where("username LIKE ?", "oidc-%").Delete(&monitor_model.User{})
+
+	router := newOidcRouter()
+
+	// 自动创建用户并根据所属组设置角色
+	provider.username, provider.groups = "oidc-alice", []string{"family", "admins"}
+	recorder, cookie := oidcLogin(t, router, "/dashboard")
+	if recorder.Code != http.StatusFound || recorder.Header().Get("Location") != "/dashboard" {
+		t.Fatalf("oidc callback responded %d, %s", recorder.Code, recorder.Body.String())
+	}
+	me := serveCookie(router, "/oidc-test/me", cookie)
+	var user monitor_model.User
+	_ = json.Unmarshal(me.Body.Bytes(), &user)
+	if me.Code != http.StatusOK || user.Username != "oidc-alice" || user.Role != monitor_model.RoleAdministrator {
+		t.Fatalf("unexpected current user %d, %s", me.Code, me.Body.String())
+	}
+
+	// 自动创建的用户没有密码, 不能通过用户名密码登录
+	if len(user.Password) > 0 {
+		t.Errorf("oidc user should have no password")
+	}
+
+	// 离开管理员组后降级为访客
+	provider.username, provider.groups = "oidc-alice", []string{"family"}
+	if recorder, _ := oidcLogin(t, router, "//evil.example.com"); recorder.Code != http.StatusFound || recorder.Header().Get("Location") != "/" {
+		t.Errorf("external redirect should be replaced by /, got %d, %s", recorder.Code, recorder.Header().Get("Location"))
+	}
+	user = monitor_model.User{}
+	monitor_db.GetDB().Where("username = ?", "oidc-alice").First(&user)
+	if user.Role != monitor_model.RoleGuest {
+		t.Errorf("user should be demoted to guest, got role %d", user.Role)
+	}
+
+	// 第一次登录时绑定身份, 相同用户名的其他身份不能登录, 也不能修改用户的角色
+	if user.OidcIssuer != provider.URL || user.OidcSubject != "subject-oidc-alice" {
+		t.Errorf("oidc identity should be bound on first login, got %s %s", user.OidcIssuer, user.OidcSubject)
+	}
+	provider.username, provider.groups, provider.subject = "oidc-alice", []string{"family", "admins"}, "subject-attacker"
+	if recorder, _ := oidcLogin(t, router, "/"); recorder.Code != http.StatusBadRequest {
+		t.Errorf("another identity with the same username should be rejected, got %d", recorder.Code)
+	}
+	user = monitor_model.User{}
+	monitor_db.GetDB().Where("username = ?", "oidc-alice").First(&user)
+	if user.Role != monitor_model.RoleGuest || user.OidcSubject != "subject-oidc-alice" {
+		t.Errorf("rejected identity should not change the user, got role %d, subject %s", user.Role, user.OidcSubject)
+	}
+	provider.subject = ""
+
+	// 已存在且未绑定的用户在第一次登录时绑定
+	provider.username, provider.groups = "oidc-admin", []string{"admins"}
+	if recorder, _ := oidcLogin(t, router, "/"); recorder.Code != http.StatusFound {
+		t.Errorf("existing user should be bound on first login, got %d, %s", recorder.Code, recorder.Body.String())
+	}
+	provider.subject = "subject-attacker"
+	if recorder, _ := oidcLogin(t, router, "/"); recorder.Code != http.StatusBadRequest {
+		t.Errorf("bound user should reject another identity, got %d", recorder.Code)
+	}
+	provider.subject = ""
+
+	// 不在允许的组中的用户不能登录
+	provider.username, provider.groups = "oidc-bob", []string{"other"}
+	if recorder, _ := oidcLogin(t, router, "/"); recorder.Code != http.StatusBadRequest {
+		t.Errorf("user not in allowed groups should be rejected, got %d", recorder.Code)
+	}
+
+	// 未开启自动创建时不存在的用户不能登录
+	config.AutoCreateUser = false
+	provider.username, provider.groups = "oidc-carol", []string{"family"}
+	if recorder, _ := oidcLogin(t, router, "/"); recorder.Code != http.StatusBadRequest {
+		t.Errorf("unknown user should be rejected without auto creation, got %d", recorder.Code)
+	}
+
+	// state 不匹配的回调
+	login := serveCookie(router, "/auth/oidc/login", nil)
+	if recorder := serveCookie(router, "/auth/oidc/callback?code=x&state=forged", latestSessionCookie(login, nil)); recorder.Code != http.StatusBadRequest {
+		t.Errorf("forged state should be rejected, got %d", recorder.Code)
+	}
+
+	config.Enable = false
+	if recorder := serveCookie(router, "/auth/oidc/login", nil); recorder.Code != http.StatusNotFound {
+		t.Errorf("disabled oidc login should respond 404, got %d", recorder.Code)
+	}
+}
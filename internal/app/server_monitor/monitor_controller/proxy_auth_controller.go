@@ -0,0 +1,181 @@
+package monitor_controller
+
+import (
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/go-errors/errors"
+	"github.com/samber/lo"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/authority"
+	"github.com/siaikin/home-dashboard/internal/pkg/comfy_errors"
+	"github.com/siaikin/home-dashboard/internal/pkg/configuration"
+	comfySessions "github.com/siaikin/home-dashboard/internal/pkg/sessions"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	defaultProxyAuthUserHeader   = "Remote-User"
+	defaultProxyAuthGroupsHeader = "Remote-Groups"
+)
+
+// 转发认证接口响应中传递给目标应用的用户信息请求头.
+const (
+	forwardAuthUserHeader   = "Remote-User"
+	forwardAuthGroupsHeader = "Remote-Groups"
+)
+
+// TrustedHeaderLogin 使用受信任的反向代理传递的用户名请求头登录, 登录成功后跳转到 redirect 参数指定的站内地址, 默认为 /.
+// 只处理直接来自 [configuration.ServerMonitorProxyAuthConfiguration.TrustedProxies] 的请求.
+// 用户名请求头只在该接口中使用. nginx auth_request 和 Traefik ForwardAuth 的子请求会复制客户端的请求头,
+// 因此转发认证接口等其他接口都不能信任用户名请求头, 认证代理应将未登录的用户引导到该接口.
+// @Summary TrustedHeaderLogin
+// @Description TrustedHeaderLogin
+// @Tags TrustedHeaderLogin
+// @Param redirect query string false "登录成功后跳转的地址, 只允许站内的相对路径, 默认为 /"
+// @Router /auth/proxy [get]
+func TrustedHeaderLogin(c *gin.Context) {
+	config := configuration.Get().ServerMonitor.ProxyAuth
+	if !config.Enable {
+		respondEntityNotFoundError(c, "proxy auth is disabled")
+		return
+	}
+
+	username := strings.TrimSpace(c.GetHeader(lo.Ternary(len(config.UserHeader) > 0, config.UserHeader, defaultProxyAuthUserHeader)))
+	if len(username) <= 0 || !isTrustedProxy(c.RemoteIP(), config.TrustedProxies) {
+		abortWithError(c, http.StatusUnauthorized, comfy_errors.NewResponseError(comfy_errors.LoginRequestError, "user header is missing or not from a trusted proxy"))
+		return
+	}
+
+	redirect := localRedirect(c.Query("redirect"))
+
+	// 已经以该用户登录时不需要重新登录
+	if info, ok := sessions.Default(c).Get(authority.InfoKey).(authority.User); ok && info.Username == username {
+		c.Redirect(http.StatusFound, redirect)
+		return
+	}
+
+	groups := lo.FilterMap(strings.Split(c.GetHeader(lo.Ternary(len(config.GroupsHeader) > 0, config.GroupsHeader, defaultProxyAuthGroupsHeader)), ","), func(group string, _ int) (string, bool) {
+		group = strings.TrimSpace(group)
+		return group, len(group) > 0
+	})
+
+	user, err := monitor_service.ResolveExternalUser(monitor_service.ExternalUserPolicy{
+		AllowedGroups:       config.AllowedGroups,
+		AdministratorGroups: config.AdministratorGroups,
+		AutoCreateUser:      config.AutoCreateUser,
+	}, username, groups)
+	if err != nil {
+		abortWithError(c, http.StatusUnauthorized, comfy_errors.NewResponseError(comfy_errors.LoginRequestError, "trusted header user %s cannot login, %w", username, err))
+		return
+	}
+
+	if !saveLoginSession(c, user) {
+		return
+	}
+
+	c.Redirect(http.StatusFound, redirect)
+}
+
+// ForwardAuthVerify 供 nginx auth_request 和 Traefik ForwardAuth 调用的转发认证接口, 使用 home-dashboard 的登录状态保护其他应用.
+// 已登录且通过 2FA 校验(如果 2FA 开启)时响应 200, 并通过 Remote-User 和 Remote-Groups 响应头传递用户名和角色;
+// 否则响应 401. 配置了登录页面地址且请求参数 redirect=true 时, 未登录的请求将跳转到登录页面.
+// @Summary ForwardAuthVerify
+// @Description ForwardAuthVerify
+// @Tags ForwardAuthVerify
+// @Param role query string false "要求的角色名"
+// @Param permission query string false "要求的权限"
+// @Param redirect query bool false "未登录时是否跳转到登录页面"
+// @Router /v1/auth/verify [get]
+func ForwardAuthVerify(c *gin.Context) {
+	session := sessions.Default(c)
+
+	info, ok := session.Get(authority.InfoKey).(authority.User)
+	if !ok {
+		respondForwardAuthUnauthorized(c, "unauthorized request")
+		return
+	}
+
+	user, err := monitor_service.GetUserByName(info.Username)
+	if err != nil || user.Disabled {
+		respondForwardAuthUnauthorized(c, "user %s not found or disabled", info.Username)
+		return
+	}
+	if user.Enable2FA && session.Get(authority.TotpValidatedKey) != true {
+		respondForwardAuthUnauthorized(c, "2fa not validated")
+		return
+	}
+
+	// 用户角色的名称作为组名传递给目标应用
+	role, err := monitor_service.GetRole(uint(user.Role))
+	if err != nil && !errors.Is(err, monitor_service.ErrorNotFound) {
+		respondUnknownError(c, err.Error())
+		return
+	}
+	if name := c.Query("role"); len(name) > 0 && name != role.Name {
+		respondPermissionDeniedError(c, "role %s is required", name)
+		return
+	}
+	if permission := c.Query("permission"); len(permission) > 0 {
+		if ok, err := monitor_service.RoleHasPermission(user.Role, permission); err != nil {
+			respondUnknownError(c, err.Error())
+			return
+		} else if !ok {
+			respondPermissionDeniedError(c, "permission %s is required", permission)
+			return
+		}
+	}
+
+	if err := comfySessions.Touch(c, user.Username); err != nil {
+		logger.Warn("record session activity failed, %s\n", err)
+	}
+
+	c.Header(forwardAuthUserHeader, user.Username)
+	c.Header(forwardAuthGroupsHeader, role.Name)
+	c.Status(http.StatusOK)
+}
+
+// respondForwardAuthUnauthorized 响应 401, 或在配置了登录页面地址且请求参数 redirect=true 时跳转到登录页面.
+func respondForwardAuthUnauthorized(c *gin.Context, message string, a ...any) {
+	loginUrl := configuration.Get().ServerMonitor.ProxyAuth.LoginUrl
+	if len(loginUrl) <= 0 || c.Query("redirect") != "true" {
+		abortWithError(c, http.StatusUnauthorized, comfy_errors.NewResponseError(comfy_errors.LoginRequestError, message, a...))
+		return
+	}
+
+	target, err := url.Parse(loginUrl)
+	if err != nil {
+		respondUnknownError(c, "invalid login url. %w", err)
+		return
+	}
+
+	// 原始请求地址, Traefik 通过 X-Forwarded-* 请求头传递, nginx 需要在配置中设置这些请求头
+	if host := c.GetHeader("X-Forwarded-Host"); len(host) > 0 {
+		scheme := lo.Ternary(len(c.GetHeader("X-Forwarded-Proto")) > 0, c.GetHeader("X-Forwarded-Proto"), "https")
+		query := target.Query()
+		query.Set("redirect", scheme+"://"+host+c.GetHeader("X-Forwarded-Uri"))
+		target.RawQuery = query.Encode()
+	}
+
+	c.Redirect(http.StatusFound, target.String())
+	c.Abort()
+}
+
+// isTrustedProxy 判断 ip 是否属于 proxies 中的 IP 或 CIDR.
+func isTrustedProxy(ip string, proxies []string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	return lo.SomeBy(proxies, func(proxy string) bool {
+		if !strings.Contains(proxy, "/") {
+			return net.ParseIP(proxy).Equal(parsed)
+		}
+
+		_, cidr, err := net.ParseCIDR(proxy)
+		return err == nil && cidr.Contains(parsed)
+	})
+}
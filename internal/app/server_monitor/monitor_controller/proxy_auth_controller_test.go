@@ -0,0 +1,133 @@
+package monitor_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/configuration"
+	"github.com/siaikin/home-dashboard/internal/pkg/database"
+	"github.com/siaikin/home-dashboard/internal/pkg/sessions"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func newProxyAuthRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(sessions.GetSessionMiddleware())
+	router.GET("v1/web/auth/proxy", TrustedHeaderLogin)
+	router.GET("v1/auth/verify", ForwardAuthVerify)
+
+	return router
+}
+
+// serveProxyAuth 发送来自 remoteAddr 的请求, headers 为请求头.
+func serveProxyAuth(router *gin.Engine, remoteAddr string, url string, headers map[string]string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, url, nil)
+	request.RemoteAddr = remoteAddr
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+	if cookie != nil {
+		request.AddCookie(cookie)
+	}
+	router.ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+func TestProxyAuth(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+	if err := monitor_service.GenerateBuiltInRoles(); err != nil {
+		t.Fatal(err)
+	}
+	defer monitor_db.GetDB().Unscoped().Where("username LIKE ?", "proxy-%").Delete(&monitor_model.User{})
+
+	config := &configuration.Get().ServerMonitor.ProxyAuth
+	original := *config
+	defer func() { *config = original }()
+	*config = configuration.ServerMonitorProxyAuthConfiguration{
+		TrustedProxies:      []string{"10.0.0.1", "192.0.2.0/24"},
+		Enable:              true,
+		AllowedGroups:       []string{"family"},
+		AdministratorGroups: []string{"admins"},
+		AutoCreateUser:      true,
+		LoginUrl:            "https://dashboard.example.com/login",
+	}
+	sessionConfig := &configuration.Get().ServerMonitor.Session
+	originalSession := *sessionConfig
+	defer func() { *sessionConfig = originalSession }()
+	sessionConfig.CookieDomain = "example.com"
+
+	router := newProxyAuthRouter()
+	trusted, untrusted := "192.0.2.10:40000", "203.0.113.5:40000"
+
+	if recorder := serveProxyAuth(router, trusted, "/v1/auth/verify", nil, nil); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous request should be unauthorized, got %d", recorder.Code)
+	}
+	recorder := serveProxyAuth(router, trusted, "/v1/auth/verify?redirect=true", map[string]string{
+		"X-Forwarded-Proto": "https",
+		"X-Forwarded-Host":  "app.example.com",
+		"X-Forwarded-Uri":   "/path?a=1",
+	}, nil)
+	if location, _ := url.Parse(recorder.Header().Get("Location")); recorder.Code != http.StatusFound || location.Query().Get("redirect") != "https://app.example.com/path?a=1" {
+		t.Errorf("anonymous request should be redirected to login url, got %d, %s", recorder.Code, recorder.Header().Get("Location"))
+	}
+
+	// auth_request 和 ForwardAuth 的子请求会复制客户端的请求头, 转发认证接口不能使用用户名请求头登录
+	if recorder := serveProxyAuth(router, trusted, "/v1/auth/verify", map[string]string{"Remote-User": "proxy-dave", "Remote-Groups": "family,admins"}, nil); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("forward auth should not login with user header, got %d", recorder.Code)
+	}
+	if _, err := monitor_service.GetUserByName("proxy-dave"); err == nil {
+		t.Errorf("forward auth should not create user from user header")
+	}
+
+	// 来自不受信任的地址的用户名请求头将被忽略
+	if recorder := serveProxyAuth(router, untrusted, "/v1/web/auth/proxy", map[string]string{"Remote-User": "proxy-dave", "Remote-Groups": "family,admins"}, nil); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("header from untrusted address should be ignored, got %d", recorder.Code)
+	}
+
+	recorder = serveProxyAuth(router, "10.0.0.1:40000", "/v1/web/auth/proxy?redirect=//evil.example.com", map[string]string{"Remote-User": "proxy-dave", "Remote-Groups": "family, admins"}, nil)
+	if recorder.Code != http.StatusFound || recorder.Header().Get("Location") != "/" {
+		t.Fatalf("trusted header should login and redirect to local path, got %d, %v", recorder.Code, recorder.Header())
+	}
+	cookie := latestSessionCookie(recorder, nil)
+	if cookie == nil || cookie.Domain != "example.com" || cookie.Path != "/" {
+		t.Fatalf("session cookie should use the configured domain, got %+v", cookie)
+	}
+
+	// 登录后的 session 可以直接用于转发认证
+	recorder = serveProxyAuth(router, untrusted, "/v1/auth/verify?role=administrator", nil, cookie)
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Remote-User") != "proxy-dave" || recorder.Header().Get("Remote-Groups") != "administrator" {
+		t.Errorf("logged in session should pass forward auth, got %d, %v", recorder.Code, recorder.Header())
+	}
+
+	// 不在允许的组中的用户不能登录
+	if recorder := serveProxyAuth(router, trusted, "/v1/web/auth/proxy", map[string]string{"Remote-User": "proxy-frank", "Remote-Groups": "other"}, nil); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("user not in allowed groups should be unauthorized, got %d", recorder.Code)
+	}
+
+	// 访客不能访问要求管理员角色的应用
+	recorder = serveProxyAuth(router, trusted, "/v1/web/auth/proxy", map[string]string{"Remote-User": "proxy-erin", "Remote-Groups": "family"}, nil)
+	if recorder.Code != http.StatusFound {
+		t.Fatalf("guest should login, got %d", recorder.Code)
+	}
+	if recorder := serveProxyAuth(router, untrusted, "/v1/auth/verify?role=administrator", nil, latestSessionCookie(recorder, nil)); recorder.Code != http.StatusForbidden {
+		t.Errorf("guest should be forbidden for administrator role, got %d", recorder.Code)
+	}
+
+	// 开启 2FA 的用户需要先通过 2FA 校验
+	monitor_db.GetDB().Model(&monitor_model.User{}).Where("username = ?", "proxy-erin").Update("enable2_fa", true)
+	recorder = serveProxyAuth(router, trusted, "/v1/web/auth/proxy", map[string]string{"Remote-User": "proxy-erin", "Remote-Groups": "family"}, nil)
+	if recorder := serveProxyAuth(router, untrusted, "/v1/auth/verify", nil, latestSessionCookie(recorder, nil)); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("2fa not validated should be unauthorized, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,206 @@
+package monitor_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-errors/errors"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/authority"
+	"net/http"
+	"strconv"
+)
+
+type RoleRequest struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Permissions []authority.Permission `json:"permissions"`
+}
+
+// CheckPermission 检查当前登录用户的角色是否拥有 permission 权限. 用于 authority.SetPermissionChecker,
+// 需要在 ActiveUserMiddleware 之后使用.
+func CheckPermission(c *gin.Context, permission authority.Permission) (bool, error) {
+	user, ok := c.Get(currentUserKey)
+	if !ok {
+		return false, nil
+	}
+
+	return monitor_service.RoleHasPermission(user.(monitor_model.User).Role, permission)
+}
+
+// ensurePermissionsGranted 校验当前登录用户拥有 permissions 中的所有权限, 不满足时响应错误并返回 false.
+// 用户不能创建, 修改或分配超出自己权限的角色, 避免通过 users.manage 或 roles.manage 提升权限.
+func ensurePermissionsGranted(c *gin.Context, permissions []authority.Permission) bool {
+	user, ok := c.Get(currentUserKey)
+	if !ok {
+		respondPermissionDeniedError(c, "current user not found")
+		return false
+	}
+
+	err := monitor_service.EnsurePermissionsGranted(user.(monitor_model.User).Role, permissions)
+	if errors.Is(err, monitor_service.ErrorPermissionExceeded) {
+		respondPermissionDeniedError(c, err.Error())
+		return false
+	} else if err != nil {
+		respondUnknownError(c, err.Error())
+		return false
+	}
+
+	return true
+}
+
+// ensureRoleGranted 校验当前登录用户拥有角色 role 的所有权限, 即可以分配该角色或管理拥有该角色的用户. 不满足时响应错误并返回 false.
+func ensureRoleGranted(c *gin.Context, role monitor_model.UserRole) bool {
+	stored, err := monitor_service.GetRole(uint(role))
+	if err != nil && !errors.Is(err, monitor_service.ErrorNotFound) {
+		respondUnknownError(c, err.Error())
+		return false
+	}
+
+	return ensurePermissionsGranted(c, stored.Permissions)
+}
+
+// ListPermissions 获取所有可用的权限
+// @Summary ListPermissions
+// @Description ListPermissions
+// @Tags Role
+// @Produce json
+// @Router role/permissions [get]
+func ListPermissions(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"permissions": authority.Permissions})
+}
+
+// ListRoles 获取所有角色
+// @Summary ListRoles
+// @Description ListRoles
+// @Tags Role
+// @Produce json
+// @Success 200 {array} monitor_model.Role
+// @Router role/list [get]
+func ListRoles(c *gin.Context) {
+	roles, err := monitor_service.ListRoles()
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"roles": roles})
+}
+
+// CreateRole 创建角色, 角色的权限不能超出当前用户的权限
+// @Summary CreateRole
+// @Description CreateRole
+// @Tags Role
+// @Accept json
+// @Produce json
+// @Param role body RoleRequest true "body"
+// @Success 200 {object} monitor_model.Role
+// @Router role/create [post]
+func CreateRole(c *gin.Context) {
+	var body RoleRequest
+
+	auditAction(c, "role.create")
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	} else if !ensurePermissionsGranted(c, body.Permissions) {
+		return
+	}
+
+	role, err := monitor_service.CreateRole(monitor_model.Role{Name: body.Name, Description: body.Description, Permissions: body.Permissions})
+	if !respondRoleError(c, err) {
+		return
+	}
+
+	auditTarget(c, "role", role.ID, nil, role)
+	c.JSON(http.StatusOK, role)
+}
+
+// UpdateRole 修改角色的名称, 描述和权限. 内置的管理员角色不能修改, 内置角色不能改名. 修改前后角色的权限都不能超出当前用户的权限.
+// @Summary UpdateRole
+// @Description UpdateRole
+// @Tags Role
+// @Accept json
+// @Produce json
+// @Param id path number true "id"
+// @Param role body RoleRequest true "body"
+// @Success 200 {object} monitor_model.Role
+// @Router role/update/{id} [put]
+func UpdateRole(c *gin.Context) {
+	var body RoleRequest
+
+	auditAction(c, "role.update")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		respondEntityValidationError(c, "id should be number")
+		return
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	}
+
+	before, err := monitor_service.GetRole(uint(id))
+	if !respondRoleError(c, err) {
+		return
+	}
+	if !ensurePermissionsGranted(c, before.Permissions) || !ensurePermissionsGranted(c, body.Permissions) {
+		return
+	}
+
+	role, err := monitor_service.UpdateRole(monitor_model.Role{Model: monitor_model.Model{ID: uint(id)}, Name: body.Name, Description: body.Description, Permissions: body.Permissions})
+	if !respondRoleError(c, err) {
+		return
+	}
+
+	auditTarget(c, "role", role.ID, before, role)
+	c.JSON(http.StatusOK, role)
+}
+
+// DeleteRole 删除角色. 内置角色和仍被用户使用的角色不能删除.
+// @Summary DeleteRole
+// @Description DeleteRole
+// @Tags Role
+// @Produce json
+// @Param id path number true "id"
+// @Router role/delete/{id} [delete]
+func DeleteRole(c *gin.Context) {
+	auditAction(c, "role.delete")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		respondEntityValidationError(c, "id should be number")
+		return
+	}
+
+	before, err := monitor_service.GetRole(uint(id))
+	if !respondRoleError(c, err) {
+		return
+	}
+	auditTarget(c, "role", id, before, nil)
+	if !ensurePermissionsGranted(c, before.Permissions) {
+		return
+	}
+
+	if !respondRoleError(c, monitor_service.DeleteRole(uint(id))) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// respondRoleError 根据角色相关的错误响应对应的状态码, 没有错误时返回 true.
+func respondRoleError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, monitor_service.ErrorNotFound):
+		respondEntityNotFoundError(c, "role %s not found", c.Param("id"))
+	case errors.Is(err, monitor_service.ErrorRoleNameExists):
+		respondEntityAlreadyExistError(c, err.Error())
+	case errors.Is(err, monitor_service.ErrorBuiltInRole), errors.Is(err, monitor_service.ErrorRoleInUse), errors.Is(err, monitor_service.ErrorInvalidRole):
+		respondEntityValidationError(c, err.Error())
+	default:
+		respondUnknownError(c, err.Error())
+	}
+
+	return false
+}
@@ -0,0 +1,179 @@
+package monitor_controller
+
+import (
+	"encoding/json"
+	"fmt"
+	ginSessions "github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/authority"
+	"github.com/siaikin/home-dashboard/internal/pkg/database"
+	"github.com/siaikin/home-dashboard/internal/pkg/sessions"
+	"net/http"
+	"testing"
+)
+
+func newRoleRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(sessions.GetSessionMiddleware(), func(c *gin.Context) {
+		if username := c.GetHeader("X-Test-User"); len(username) > 0 {
+			ginSessions.Default(c).Set(authority.InfoKey, authority.User{Username: username})
+		}
+	})
+
+	authority.SetPermissionChecker(CheckPermission)
+	authorized := router.Group("", authority.AuthorizeMiddleware(), ActiveUserMiddleware())
+	authorized.GET("role/list", ListRoles)
+	rolesManage := authorized.Group("", authority.PermissionMiddleware(authority.PermissionRolesManage))
+	rolesManage.POST("role/create", CreateRole)
+	rolesManage.PUT("role/update/:id", UpdateRole)
+	rolesManage.DELETE("role/delete/:id", DeleteRole)
+	authorized.Group("", authority.PermissionMiddleware(authority.PermissionAuditView)).GET("audit/logs", ListAuditLogs)
+
+	return router
+}
+
+func TestRoles(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+	if err := monitor_service.GenerateBuiltInRoles(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := monitor_service.CreateUser(monitor_model.User{User: authority.User{Username: "role-admin"}, Role: monitor_model.RoleAdministrator}); err != nil {
+		t.Fatal(err)
+	}
+	defer monitor_db.GetDB().Unscoped().Where("username LIKE ?", "role-%").Delete(&monitor_model.User{})
+	defer monitor_db.GetDB().Unscoped().Where("name LIKE ?", "role-%").Delete(&monitor_model.Role{})
+
+	router := newRoleRouter()
+
+	// 创建只能查看审计日志的角色
+	code, body := serveUser(router, "role-admin", http.MethodPost, "/role/create", `{"name":"role-auditor","permissions":["audit.view"]}`)
+	var auditor monitor_model.Role
+	if err := json.Unmarshal(body, &auditor); code != http.StatusOK || err != nil {
+		t.Fatalf("create role responded %d, %s", code, body)
+	}
+	if code, _ := serveUser(router, "role-admin", http.MethodPost, "/role/create", `{"name":"role-auditor"}`); code != http.StatusBadRequest {
+		t.Errorf("duplicated role name should be rejected, got %d", code)
+	}
+	if code, _ := serveUser(router, "role-admin", http.MethodPost, "/role/create", `{"name":"role-unknown","permissions":["unknown"]}`); code != http.StatusBadRequest {
+		t.Errorf("unknown permission should be rejected, got %d", code)
+	}
+
+	if err := monitor_service.CreateUser(monitor_model.User{User: authority.User{Username: "role-auditor"}, Role: monitor_model.UserRole(auditor.ID)}); err != nil {
+		t.Fatal(err)
+	}
+	if code, _ := serveUser(router, "role-auditor", http.MethodGet, "/audit/logs", ""); code != http.StatusOK {
+		t.Errorf("auditor should be able to view audit logs, got %d", code)
+	}
+	if code, _ := serveUser(router, "role-auditor", http.MethodPost, "/role/create", `{"name":"role-other"}`); code != http.StatusForbidden {
+		t.Errorf("auditor should not be able to manage roles, got %d", code)
+	}
+
+	// 修改角色的权限后立即生效
+	if code, body := serveUser(router, "role-admin", http.MethodPut, fmt.Sprintf("/role/update/%d", auditor.ID), `{"name":"role-auditor","permissions":["roles.manage"]}`); code != http.StatusOK {
+		t.Fatalf("update role responded %d, %s", code, body)
+	}
+	if code, _ := serveUser(router, "role-auditor", http.MethodGet, "/audit/logs", ""); code != http.StatusForbidden {
+		t.Errorf("permission removed from role should be denied, got %d", code)
+	}
+
+	// 内置的管理员角色不能修改, 内置角色和仍被使用的角色不能删除
+	if code, _ := serveUser(router, "role-admin", http.MethodPut, fmt.Sprintf("/role/update/%d", monitor_model.RoleAdministrator), `{"name":"administrator"}`); code != http.StatusBadRequest {
+		t.Errorf("built-in administrator role should not be updatable, got %d", code)
+	}
+	if code, _ := serveUser(router, "role-admin", http.MethodPut, fmt.Sprintf("/role/update/%d", monitor_model.RoleGuest), `{"name":"visitor"}`); code != http.StatusBadRequest {
+		t.Errorf("built-in role should not be renamed, got %d", code)
+	}
+	if code, _ := serveUser(router, "role-admin", http.MethodDelete, fmt.Sprintf("/role/delete/%d", monitor_model.RoleGuest), ""); code != http.StatusBadRequest {
+		t.Errorf("built-in role should not be deletable, got %d", code)
+	}
+	if code, _ := serveUser(router, "role-admin", http.MethodDelete, fmt.Sprintf("/role/delete/%d", auditor.ID), ""); code != http.StatusBadRequest {
+		t.Errorf("role in use should not be deletable, got %d", code)
+	}
+
+	monitor_db.GetDB().Unscoped().Where("username = ?", "role-auditor").Delete(&monitor_model.User{})
+	if code, body := serveUser(router, "role-admin", http.MethodDelete, fmt.Sprintf("/role/delete/%d", auditor.ID), ""); code != http.StatusOK {
+		t.Errorf("delete role responded %d, %s", code, body)
+	}
+	if code, _ := serveUser(router, "role-admin", http.MethodDelete, fmt.Sprintf("/role/delete/%d", auditor.ID), ""); code != http.StatusNotFound {
+		t.Errorf("deleted role should not be found, got %d", code)
+	}
+}
+
+func TestRolePrivilegeEscalation(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+	if err := monitor_service.GenerateBuiltInRoles(); err != nil {
+		t.Fatal(err)
+	}
+
+	manager, err := monitor_service.CreateRole(monitor_model.Role{Name: "escalate-manager", Permissions: []authority.Permission{authority.PermissionUsersManage, authority.PermissionRolesManage, authority.PermissionShortcutsView}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer monitor_db.GetDB().Unscoped().Where("name LIKE ?", "escalate-%").Delete(&monitor_model.Role{})
+	if err := monitor_service.CreateUser(monitor_model.User{User: authority.User{Username: "escalate-manager"}, Role: monitor_model.UserRole(manager.ID)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := monitor_service.CreateUser(monitor_model.User{User: authority.User{Username: "escalate-admin", Password: "admin"}, Role: monitor_model.RoleAdministrator}); err != nil {
+		t.Fatal(err)
+	}
+	defer monitor_db.GetDB().Unscoped().Where("username LIKE ?", "escalate-%").Delete(&monitor_model.User{})
+	self, _ := monitor_service.GetUserByName("escalate-manager")
+	admin, _ := monitor_service.GetUserByName("escalate-admin")
+
+	router := newRoleRouter()
+	authorized := router.Group("", authority.AuthorizeMiddleware(), ActiveUserMiddleware())
+	usersManage := authorized.Group("", authority.PermissionMiddleware(authority.PermissionUsersManage))
+	usersManage.POST("user/create", CreateUser)
+	usersManage.PUT("user/update/:id", UpdateUser)
+	usersManage.PUT("user/disable/:id", DisableUser)
+	usersManage.DELETE("user/delete/:id", DeleteUser)
+
+	// 不能分配超出自己权限的角色, 包括给自己分配
+	for _, request := range []struct{ method, url, body string }{
+		{http.MethodPost, "/user/create", fmt.Sprintf(`{"username":"escalate-other","password":"other","role":%d}`, monitor_model.RoleAdministrator)},
+		{http.MethodPost, "/user/create", fmt.Sprintf(`{"username":"escalate-other","password":"other","role":%d}`, monitor_model.RoleGuest)},
+		{http.MethodPut, fmt.Sprintf("/user/update/%d", self.ID), fmt.Sprintf(`{"role":%d}`, monitor_model.RoleAdministrator)},
+		// 不能管理权限超出自己的用户
+		{http.MethodPut, fmt.Sprintf("/user/update/%d", admin.ID), fmt.Sprintf(`{"role":%d,"password":"taken"}`, monitor_model.RoleAdministrator)},
+		{http.MethodPut, fmt.Sprintf("/user/disable/%d", admin.ID), ""},
+		{http.MethodDelete, fmt.Sprintf("/user/delete/%d", admin.ID), ""},
+		// 不能创建或修改超出自己权限的角色
+		{http.MethodPost, "/role/create", `{"name":"escalate-auditor","permissions":["audit.view"]}`},
+		{http.MethodPut, fmt.Sprintf("/role/update/%d", manager.ID), `{"name":"escalate-manager","permissions":["users.manage","roles.manage","shortcuts.view","audit.view"]}`},
+		{http.MethodPut, fmt.Sprintf("/role/update/%d", monitor_model.RoleGuest), `{"name":"guest","permissions":[]}`},
+	} {
+		if code, body := serveUser(router, "escalate-manager", request.method, request.url, request.body); code != http.StatusForbidden {
+			t.Errorf("%s %s %s should be forbidden, got %d, %s", request.method, request.url, request.body, code, body)
+		}
+	}
+	if ok, _ := monitor_service.VerifyUserPassword(admin, "admin"); !ok {
+		t.Errorf("administrator password should not be changed")
+	}
+
+	// 权限范围内的操作不受影响
+	code, body := serveUser(router, "escalate-manager", http.MethodPost, "/role/create", `{"name":"escalate-viewer","permissions":["shortcuts.view"]}`)
+	var viewer monitor_model.Role
+	if err := json.Unmarshal(body, &viewer); code != http.StatusOK || err != nil {
+		t.Fatalf("create role within own permissions responded %d, %s", code, body)
+	}
+	if code, body := serveUser(router, "escalate-manager", http.MethodPost, "/user/create", fmt.Sprintf(`{"username":"escalate-viewer","password":"viewer","role":%d}`, viewer.ID)); code != http.StatusOK {
+		t.Errorf("assign role within own permissions responded %d, %s", code, body)
+	}
+
+	// 访客默认没有控制进程的权限
+	if ok, _ := monitor_service.RoleHasPermission(monitor_model.RoleGuest, authority.PermissionProcessControl); ok {
+		t.Errorf("guest should not control processes by default")
+	}
+}
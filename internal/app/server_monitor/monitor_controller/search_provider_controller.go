@@ -0,0 +1,231 @@
+package monitor_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-errors/errors"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/comfy_errors"
+	"net/http"
+	"strconv"
+)
+
+// CreateSearchProvider 创建搜索引擎.
+// @Summary CreateSearchProvider
+// @Description CreateSearchProvider
+// @Tags CreateSearchProvider
+// @Accept json
+// @Produce json
+// @Param searchProvider body monitor_model.SearchProvider true "body"
+// @Success 200 {object} monitor_model.SearchProvider
+// @Router search/provider/create [post]
+func CreateSearchProvider(c *gin.Context) {
+	var body monitor_model.SearchProvider
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	} else if body.ID != 0 {
+		respondEntityValidationError(c, "ID must be 0")
+		return
+	} else if err := monitor_service.ValidateSearchProvider(body); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	}
+
+	if !validateSearchProviderKeyword(c, body) {
+		return
+	}
+
+	if created, err := monitor_service.CreateOrUpdateSearchProviders([]monitor_model.SearchProvider{body}); err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	} else {
+		c.JSON(http.StatusOK, created[0])
+	}
+}
+
+// ListSearchProviders 获取搜索引擎, 默认的搜索引擎排在最前面.
+// @Summary ListSearchProviders
+// @Description ListSearchProviders
+// @Tags ListSearchProviders
+// @Produce json
+// @Success 200 {array} monitor_model.SearchProvider
+// @Router search/provider/list [get]
+func ListSearchProviders(c *gin.Context) {
+	providers, err := monitor_service.ListSearchProvidersByQuery(monitor_model.SearchProvider{})
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"providers": providers,
+	})
+}
+
+// UpdateSearchProvider 更新搜索引擎.
+// @Summary UpdateSearchProvider
+// @Description UpdateSearchProvider
+// @Tags UpdateSearchProvider
+// @Accept json
+// @Produce json
+// @Param id path number true "id"
+// @Param searchProvider body monitor_model.SearchProvider true "body"
+// @Success 200 {object} monitor_model.SearchProvider
+// @Router search/provider/update/{id} [put]
+func UpdateSearchProvider(c *gin.Context) {
+	var body monitor_model.SearchProvider
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	} else if ID, err := strconv.ParseUint(c.Param("id"), 10, 0); err != nil {
+		respondEntityValidationError(c, "id should be number")
+		return
+	} else {
+		body.ID = uint(ID)
+	}
+
+	if err := monitor_service.ValidateSearchProvider(body); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	}
+
+	if existed, err := monitor_service.GetSearchProvider(body.ID); errors.Is(err, monitor_service.ErrorNotFound) {
+		respondEntityNotFoundError(c, "search provider %d not found", body.ID)
+		return
+	} else if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	} else {
+		body.CreatedAt = existed.CreatedAt
+	}
+
+	if !validateSearchProviderKeyword(c, body) {
+		return
+	}
+
+	if affected, err := monitor_service.CreateOrUpdateSearchProviders([]monitor_model.SearchProvider{body}); err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	} else {
+		c.JSON(http.StatusOK, affected[0])
+	}
+}
+
+// DeleteSearchProvider 删除搜索引擎.
+// @Summary DeleteSearchProvider
+// @Description DeleteSearchProvider
+// @Tags DeleteSearchProvider
+// @Produce json
+// @Param id path number true "id"
+// @Router search/provider/delete/{id} [delete]
+func DeleteSearchProvider(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		respondEntityValidationError(c, "id should be number")
+		return
+	}
+
+	if err := monitor_service.DeleteSearchProviders([]uint{uint(id)}); err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// ListSearchSuggestions 代理请求搜索引擎的搜索建议接口, 避免浏览器的跨域限制.
+// @Summary ListSearchSuggestions
+// @Description ListSearchSuggestions
+// @Tags ListSearchSuggestions
+// @Produce json
+// @Param providerId query number true "搜索引擎 id"
+// @Param query query string true "搜索内容"
+// @Success 200 {array} string
+// @Router search/suggestion [get]
+func ListSearchSuggestions(c *gin.Context) {
+	var query struct {
+		ProviderId uint   `form:"providerId" binding:"required"`
+		Query      string `form:"query"`
+	}
+
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	}
+
+	provider, err := monitor_service.GetSearchProvider(query.ProviderId)
+	if errors.Is(err, monitor_service.ErrorNotFound) {
+		respondEntityNotFoundError(c, "search provider %d not found", query.ProviderId)
+		return
+	} else if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	suggestions, err := monitor_service.FetchSearchSuggestions(provider, query.Query)
+	if err != nil {
+		abortWithError(c, http.StatusBadGateway, comfy_errors.NewResponseError(comfy_errors.UnknownError, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"suggestions": suggestions,
+	})
+}
+
+// Search 统一搜索接口. 支持使用 !keyword 指定搜索引擎, 返回搜索结果页链接, 搜索建议和匹配的快捷方式.
+// @Summary Search
+// @Description Search
+// @Tags Search
+// @Produce json
+// @Param query query string true "搜索内容"
+// @Param max query number false "最多返回的快捷方式数量, 默认为 8"
+// @Success 200 {object} monitor_service.SearchQuery
+// @Router search/query [get]
+func Search(c *gin.Context) {
+	var query struct {
+		Query string `form:"query"`
+		Max   int    `form:"max"`
+	}
+
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	}
+	if query.Max <= 0 {
+		query.Max = 8
+	}
+
+	result, err := monitor_service.Search(query.Query, query.Max)
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// validateSearchProviderKeyword 校验快捷关键字是否已被其他搜索引擎使用, 已被使用时响应错误并返回 false.
+func validateSearchProviderKeyword(c *gin.Context, provider monitor_model.SearchProvider) bool {
+	if len(provider.Keyword) <= 0 {
+		return true
+	}
+
+	providers, err := monitor_service.ListSearchProvidersByQuery(monitor_model.SearchProvider{Keyword: provider.Keyword})
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return false
+	}
+
+	for _, p := range providers {
+		if p.ID != provider.ID {
+			respondEntityAlreadyExistError(c, "search provider with keyword %s already exists", provider.Keyword)
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,127 @@
+package monitor_controller
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/database"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func newSearchRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("search/provider/create", CreateSearchProvider)
+	router.GET("search/provider/list", ListSearchProviders)
+	router.PUT("search/provider/update/:id", UpdateSearchProvider)
+	router.DELETE("search/provider/delete/:id", DeleteSearchProvider)
+	router.GET("search/suggestion", ListSearchSuggestions)
+	router.GET("search/query", Search)
+
+	return router
+}
+
+func serveSearch(t *testing.T, router *gin.Engine, method string, url string, body string) (int, []byte) {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(method, url, strings.NewReader(body)))
+
+	return recorder.Code, recorder.Body.Bytes()
+}
+
+func TestSearch(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟 OpenSearch 格式的搜索建议接口
+	suggestionServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("q")
+		_ = json.NewEncoder(w).Encode([]any{query, []string{query + " one", query + " two"}})
+	}))
+	defer suggestionServer.Close()
+
+	router := newSearchRouter()
+
+	code, body := serveSearch(t, router, http.MethodPost, "/search/provider/create", `{"name":"Fake","keyword":"fk","urlTemplate":"https://search.example.com/?q={query}","suggestionUrlTemplate":"`+suggestionServer.URL+`/?q={query}","default":true}`)
+	if code != http.StatusOK {
+		t.Fatalf("create provider responded %d, %s", code, body)
+	}
+	var provider monitor_model.SearchProvider
+	_ = json.Unmarshal(body, &provider)
+
+	code, body = serveSearch(t, router, http.MethodPost, "/search/provider/create", `{"name":"Code","keyword":"code","urlTemplate":"https://code.example.com/search?q={query}","default":true}`)
+	if code != http.StatusOK {
+		t.Fatalf("create provider responded %d, %s", code, body)
+	}
+
+	// 快捷关键字不能重复, 链接模板必须包含占位符
+	if code, _ := serveSearch(t, router, http.MethodPost, "/search/provider/create", `{"name":"Dup","keyword":"fk","urlTemplate":"https://dup.example.com/?q={query}"}`); code != http.StatusBadRequest {
+		t.Errorf("duplicated keyword should be rejected, got %d", code)
+	}
+	if code, _ := serveSearch(t, router, http.MethodPost, "/search/provider/create", `{"name":"Bad","urlTemplate":"https://bad.example.com/"}`); code != http.StatusBadRequest {
+		t.Errorf("url template without placeholder should be rejected, got %d", code)
+	}
+
+	// 只能有一个默认的搜索引擎
+	if providers, _ := monitor_service.ListSearchProvidersByQuery(monitor_model.SearchProvider{Default: true}); len(providers) != 1 || providers[0].Keyword != "code" {
+		t.Errorf("only the last default provider should be default, got %+v", providers)
+	}
+
+	code, body = serveSearch(t, router, http.MethodGet, "/search/suggestion?providerId="+strconv.Itoa(int(provider.ID))+"&query=home", "")
+	if code != http.StatusOK || !strings.Contains(string(body), "home one") {
+		t.Errorf("unexpected suggestions %d, %s", code, body)
+	}
+
+	sections, err := monitor_service.CreateOrUpdateShortcutSections([]monitor_model.ShortcutSection{{Name: "Search Folder"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := monitor_service.CreateOrUpdateShortcutItems([]monitor_model.ShortcutItem{
+		{Title: "Search Media", URL: "http://media.lan", Sections: sections},
+		{Title: "Search Router", URL: "http://router.lan", Tags: "network", Sections: sections},
+		{Title: "Other", URL: "http://other.lan/search_100%", Sections: sections},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := monitor_service.CreateOrUpdateShortcutSectionItemUsages([]monitor_model.ShortcutSectionItemUsage{{SectionId: sections[0].ID, ItemId: items[1].ID, ClickCount: 3}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var result monitor_service.SearchQuery
+	code, body = serveSearch(t, router, http.MethodGet, "/search/query?query="+url.QueryEscape("!fk search"), "")
+	if code != http.StatusOK {
+		t.Fatalf("search responded %d, %s", code, body)
+	}
+	_ = json.Unmarshal(body, &result)
+
+	if result.Provider == nil || result.Provider.ID != provider.ID || result.Query != "search" || result.SearchUrl != "https://search.example.com/?q=search" {
+		t.Errorf("keyword should select the provider, got %+v", result)
+	}
+	if len(result.Suggestions) != 2 || result.Suggestions[0] != "search one" {
+		t.Errorf("unexpected suggestions %+v", result.Suggestions)
+	}
+	// 使用次数多的排在前面
+	if len(result.Items) != 3 || result.Items[0].ID != items[1].ID {
+		t.Errorf("unexpected items %+v", result.Items)
+	}
+
+	// 没有快捷关键字时使用默认的搜索引擎, 通配符按字面匹配
+	result = monitor_service.SearchQuery{}
+	_, body = serveSearch(t, router, http.MethodGet, "/search/query?query="+url.QueryEscape("h_1"), "")
+	_ = json.Unmarshal(body, &result)
+	if result.Provider == nil || result.Provider.Keyword != "code" || len(result.Items) != 1 || result.Items[0].ID != items[2].ID {
+		t.Errorf("unexpected search result %+v", result)
+	}
+}
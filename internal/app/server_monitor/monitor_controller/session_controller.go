@@ -0,0 +1,86 @@
+package monitor_controller
+
+import (
+	ginSessions "github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/siaikin/home-dashboard/internal/pkg/authority"
+	"github.com/siaikin/home-dashboard/internal/pkg/sessions"
+	"net/http"
+	"strconv"
+)
+
+// ListSessions 获取当前用户所有已登录的会话, 包括登录设备, IP 和最后活动时间.
+// @Summary ListSessions
+// @Description ListSessions
+// @Tags ListSessions
+// @Produce json
+// @Success 200 {array} sessions.Activity
+// @Router user/session/list [get]
+func ListSessions(c *gin.Context) {
+	user := ginSessions.Default(c).Get(authority.InfoKey).(authority.User)
+
+	activities, err := sessions.ListActivities(c, user.Username)
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": activities,
+	})
+}
+
+// RevokeSession 撤销当前用户的指定会话, 被撤销的会话需要重新登录.
+// @Summary RevokeSession
+// @Description RevokeSession
+// @Tags RevokeSession
+// @Produce json
+// @Param id path number true "会话 id"
+// @Router user/session/revoke/{id} [delete]
+func RevokeSession(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		respondEntityValidationError(c, "id should be number")
+		return
+	}
+
+	user := ginSessions.Default(c).Get(authority.InfoKey).(authority.User)
+	if err := sessions.Revoke(user.Username, []uint{uint(id)}); err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// RevokeOtherSessions 撤销当前用户除当前会话以外的所有会话.
+// @Summary RevokeOtherSessions
+// @Description RevokeOtherSessions
+// @Tags RevokeOtherSessions
+// @Produce json
+// @Router user/session/revoke-others [delete]
+func RevokeOtherSessions(c *gin.Context) {
+	user := ginSessions.Default(c).Get(authority.InfoKey).(authority.User)
+
+	if err := sessions.RevokeOthers(c, user.Username); err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// RotateSessionSecret 立即轮换 session 签名密钥. 旧密钥在宽限期内仍然有效, 已登录的用户不需要重新登录.
+// @Summary RotateSessionSecret
+// @Description RotateSessionSecret
+// @Tags RotateSessionSecret
+// @Produce json
+// @Router session/secret/rotate [post]
+func RotateSessionSecret(c *gin.Context) {
+	if err := sessions.RotateSecret(); err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
@@ -2,11 +2,13 @@ package monitor_controller
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/go-errors/errors"
 	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
 	"net/http"
+	"strings"
 )
 
-// RefreshShortcutIcons 刷新所有快捷方式的图标
+// RefreshShortcutIcons 使用打包的 simple-icons 数据集刷新所有快捷方式的图标
 // @Summary RefreshShortcutIcons
 // @Description RefreshShortcutIcons
 // @Tags RefreshShortcutIcons
@@ -21,3 +23,71 @@ func RefreshShortcutIcons(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{})
 }
+
+// SearchShortcutIcons 在品牌名称和别名中模糊搜索图标, 按匹配程度排序.
+// @Summary SearchShortcutIcons
+// @Description SearchShortcutIcons
+// @Tags SearchShortcutIcons
+// @Produce json
+// @Param keyword query string true "Keyword"
+// @Param limit query int false "最多返回的图标数量, 默认为 50"
+// @Success 200 {array} monitor_model.ShortcutIcon
+// @Router shortcut/icon/search [get]
+func SearchShortcutIcons(c *gin.Context) {
+	var query struct {
+		Keyword string `form:"keyword"`
+		Limit   int    `form:"limit"`
+	}
+
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	}
+	if query.Limit <= 0 {
+		query.Limit = 50
+	}
+
+	icons, err := monitor_service.SearchShortcutIcons(query.Keyword, query.Limit)
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"icons": icons,
+	})
+}
+
+// GetShortcutIconSvg 获取图标的 SVG 文件, slug 可以带有 .svg 后缀.
+// @Summary GetShortcutIconSvg
+// @Description GetShortcutIconSvg
+// @Tags GetShortcutIconSvg
+// @Produce image/svg+xml
+// @Param slug path string true "Icon slug"
+// @Success 200
+// @Router shortcut/icon/svg/{slug} [get]
+func GetShortcutIconSvg(c *gin.Context) {
+	slug := strings.TrimSuffix(c.Param("slug"), ".svg")
+
+	svg, version, err := monitor_service.GetShortcutIconSvg(slug)
+	if errors.Is(err, monitor_service.ErrorNotFound) {
+		respondEntityNotFoundError(c, "icon %s not found", slug)
+		return
+	} else if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	// 图标只会随数据集的版本变化.
+	etag := `"` + version + "-" + slug + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "public, max-age=604800")
+	// 禁止直接打开 SVG 时执行其中的脚本
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, "image/svg+xml", []byte(svg))
+}
@@ -1,30 +1,18 @@
 package monitor_controller
 
 import (
-	"bytes"
-	"compress/flate"
-	"compress/gzip"
-	"github.com/PuerkitoBio/goquery"
 	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
 	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
 	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
-	"github.com/siaikin/home-dashboard/internal/pkg/comfy_http_client"
-	"golang.org/x/net/html/charset"
-	"io"
 	"net/http"
 	url2 "net/url"
 	"strconv"
 	"strings"
-	"time"
 )
 
-var httpClient *comfy_http_client.Client
-
-func init() {
-	httpClient, _ = comfy_http_client.New("", http.Header{}, time.Minute*5)
-}
-
 // ExtractShortcutItemInfoFromURL 从 URL 中提取网站信息, 并以 monitor_model.ShortcutItem 的形式返回.
+// 同时返回按评分排序的候选图标(iconCandidates), 以供用户选择其他图标.
 // @Summary ExtractShortcutItemInfoFromURL
 // @Description ExtractShortcutItemInfoFromURL
 // @Tags ExtractShortcutItemInfoFromURL
@@ -51,98 +39,18 @@ func ExtractShortcutItemInfoFromURL(c *gin.Context) {
 		websiteUrl.Host = strings.Join([]string{"www", websiteUrl.Host}, ".")
 	}
 
-	item, err := extractWebsiteInfoFromUrl(websiteUrl, c.Request.Header)
+	info, err := monitor_service.ExtractWebsiteInfoFromUrl(websiteUrl, c.Request.Header)
 	if err != nil {
 		respondUnknownError(c, err.Error())
 		return
 	}
 
-	if alternatives, err := monitor_service.ListShortcutItemsByFuzzyQuery(monitor_model.ShortcutItem{Title: item.Title, URL: websiteUrl.Hostname()}, []string{"Title", "URL"}, []string{"Icon"}); err != nil {
+	if alternatives, err := monitor_service.ListShortcutItemsByFuzzyQuery(monitor_model.ShortcutItem{Title: info.Item.Title, URL: websiteUrl.Hostname()}, []string{"Title", "URL"}, []string{"Icon"}); err != nil {
 		respondUnknownError(c, err.Error())
 		return
 	} else {
-		c.JSON(http.StatusOK, gin.H{"alternatives": alternatives, "item": item})
-	}
-}
-
-func extractWebsiteInfoFromUrl(url *url2.URL, header http.Header) (*monitor_model.ShortcutItem, error) {
-	req, err := httpClient.Get(url.String())
-	if err != nil {
-		return nil, err
-	}
-	req.Header = header
-	// 不支持 brotli 压缩, 因为 Golang 没有原生的支持.
-	//req.Header.Set("Accept-Encoding", "gzip, deflate, br")
-	req.Header.Set("Accept-Encoding", "gzip, deflate")
-
-	res, err := httpClient.Send(req)
-	if err != nil {
-		return nil, err
-	}
-	defer func() {
-		_ = res.Body.Close()
-	}()
-
-	decompressedReader := res.Body
-	switch res.Header.Get("Content-Encoding") {
-	case "gzip":
-		decompressedReader, err = gzip.NewReader(res.Body)
-		if err != nil {
-			return nil, err
-		}
-	case "deflate":
-		decompressedReader = flate.NewReader(res.Body)
-	}
-
-	htmlBytes, _ := io.ReadAll(decompressedReader)
-	_, charsetValue, _ := charset.DetermineEncoding(htmlBytes, res.Header.Get("Content-Type"))
-	decodeReader, err := charset.NewReader(bytes.NewReader(htmlBytes), charsetValue)
-	if err != nil {
-		return nil, err
-	}
-
-	doc, err := goquery.NewDocumentFromReader(decodeReader)
-	if err != nil {
-		return nil, err
-	}
-
-	var bestIconUrl string
-	doc.Find("link[rel~='icon'][type='image/svg+xml']").Each(func(i int, s *goquery.Selection) {
-		if iconUrl, ok := s.Attr("href"); ok {
-			bestIconUrl = iconUrl
-		}
-	})
-	if len(bestIconUrl) <= 0 {
-		doc.Find("link[rel~='icon']").Each(func(i int, s *goquery.Selection) {
-			if iconUrl, ok := s.Attr("href"); ok {
-				bestIconUrl = iconUrl
-			}
-		})
-	}
-	if len(bestIconUrl) <= 0 {
-		bestIconUrl = "/favicon.ico"
-	}
-	if iconUrl, err := url2.Parse(bestIconUrl); err != nil {
-		return nil, err
-	} else {
-		bestIconUrl = url.ResolveReference(iconUrl).String()
+		c.JSON(http.StatusOK, gin.H{"alternatives": alternatives, "item": info.Item, "iconCandidates": info.IconCandidates, "themeColor": info.ThemeColor})
 	}
-
-	item := monitor_model.ShortcutItem{
-		Title:           doc.Find("title").Text(),
-		Description:     doc.Find("meta[name*='description']").AttrOr("content", ""),
-		URL:             url.String(),
-		IconType:        monitor_model.ShortcutItemIconTypeUrl,
-		IconUrl:         bestIconUrl,
-		Tags:            "",
-		Target:          monitor_model.ShortcutItemTargetTypeNewTab,
-		StatusCheck:     true,
-		StatusCheckUrl:  url.String(),
-		BackgroundColor: "",
-	}
-	item.IconCachedUrl = monitor_service.GetCachedShortcutItemImageIconUrl(item)
-
-	return &item, nil
 }
 
 // CreateShortcutItem 创建 shortcut item.
@@ -241,10 +149,18 @@ func UpdateShortcutItem(c *gin.Context) {
 		body.ID = uint(ID)
 	}
 
+	auditAction(c, "shortcut.item.update")
+	before, err := monitor_service.ListShortcutItemsByQuery(monitor_model.ShortcutItem{Model: monitor_model.Model{ID: body.ID}}, nil)
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
 	if updated, err := monitor_service.CreateOrUpdateShortcutItems([]monitor_model.ShortcutItem{body}); err != nil {
 		respondUnknownError(c, err.Error())
 		return
 	} else {
+		auditTarget(c, "shortcutItem", body.ID, *before, updated[0])
 		c.JSON(http.StatusOK, updated[0])
 	}
 }
@@ -268,6 +184,18 @@ func DeleteShortcutItem(c *gin.Context) {
 		ids = append(ids, uint(id))
 	}
 
+	auditAction(c, "shortcut.item.delete")
+	before := make([]monitor_model.ShortcutItem, 0, len(ids))
+	for _, id := range ids {
+		items, err := monitor_service.ListShortcutItemsByQuery(monitor_model.ShortcutItem{Model: monitor_model.Model{ID: id}}, nil)
+		if err != nil {
+			respondUnknownError(c, err.Error())
+			return
+		}
+		before = append(before, *items...)
+	}
+	auditTarget(c, "shortcutItem", strings.Join(lo.Map(ids, func(id uint, _ int) string { return strconv.FormatUint(uint64(id), 10) }), ","), before, nil)
+
 	if err := monitor_service.DeleteShortcutItems(ids); err != nil {
 		respondUnknownError(c, err.Error())
 		return
@@ -312,3 +240,38 @@ func RefreshCachedShortcutItemImageIcon(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{})
 }
+
+// BulkOperateShortcutItems 批量操作 shortcut item, 支持移动, 复制, 添加到分组, 从分组中移出和批量修改字段.
+// 所有操作在同一个事务中执行, 任意一个 shortcut item 操作失败时全部回滚. committed 表示事务是否已提交, results 为每个 shortcut item 的操作结果.
+// @Summary BulkOperateShortcutItems
+// @Description BulkOperateShortcutItems
+// @Tags BulkOperateShortcutItems
+// @Accept json
+// @Produce json
+// @Param operations body []monitor_service.ShortcutItemBulkOperation true "body"
+// @Success 200 {array} monitor_service.ShortcutItemBulkOperationResult
+// @Router shortcut/item/bulk [post]
+func BulkOperateShortcutItems(c *gin.Context) {
+	var body struct {
+		Operations []monitor_service.ShortcutItemBulkOperation `json:"operations" binding:"required,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	} else if len(body.Operations) <= 0 {
+		respondEntityValidationError(c, "operations should not be empty")
+		return
+	}
+
+	results, committed, err := monitor_service.BulkOperateShortcutItems(body.Operations)
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"committed": committed,
+		"results":   results,
+	})
+}
@@ -1,8 +1,19 @@
 package monitor_controller
 
 import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/database"
 	"net/http"
+	"net/http/httptest"
 	url2 "net/url"
+	"sort"
+	"strings"
 	"testing"
 )
 
@@ -48,7 +59,7 @@ func TestExtractWebsiteInfoFromUrl(t *testing.T) {
 				t.Error(err.Error())
 				return
 			}
-			item, err := extractWebsiteInfoFromUrl(_url, http.Header{
+			item, err := monitor_service.ExtractWebsiteInfoFromUrl(_url, http.Header{
 				"User-Agent":      []string{"Mozilla/5.0 (X11; Linux x86_64; rv:84.0) Gecko/20100101 Firefox/84.0"},
 				"Accept-Encoding": []string{"gzip, deflate"},
 				"Accept":          []string{"text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8"},
@@ -62,3 +73,166 @@ func TestExtractWebsiteInfoFromUrl(t *testing.T) {
 		})
 	}
 }
+
+type shortcutItemBulkResponse struct {
+	Committed bool                                              `json:"committed"`
+	Results   []monitor_service.ShortcutItemBulkOperationResult `json:"results"`
+}
+
+func serveShortcutItemBulk(t *testing.T, router *gin.Engine, operations string) shortcutItemBulkResponse {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/shortcut/item/bulk", strings.NewReader(`{"operations":`+operations+`}`)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("bulk operate shortcut items responded %d, %s", recorder.Code, recorder.Body.String())
+	}
+
+	response := shortcutItemBulkResponse{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	return response
+}
+
+// getShortcutItemWithSections 获取快捷方式及其所在分组的 id(升序).
+func getShortcutItemWithSections(t *testing.T, id uint) (monitor_model.ShortcutItem, []uint) {
+	t.Helper()
+
+	items, err := monitor_service.ListShortcutItemsByQuery(monitor_model.ShortcutItem{Model: monitor_model.Model{ID: id}}, []string{"Sections"})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(*items) != 1 {
+		t.Fatalf("item %d not found", id)
+	}
+
+	item := (*items)[0]
+	sectionIds := lo.Map(item.Sections, func(section monitor_model.ShortcutSection, _ int) uint {
+		return section.ID
+	})
+	sort.Slice(sectionIds, func(i, j int) bool { return sectionIds[i] < sectionIds[j] })
+
+	return item, sectionIds
+}
+
+func TestBulkOperateShortcutItems(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+
+	sections, err := monitor_service.CreateOrUpdateShortcutSections([]monitor_model.ShortcutSection{{Name: "Bulk A"}, {Name: "Bulk B"}, {Name: "Bulk C"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b, c := sections[0].ID, sections[1].ID, sections[2].ID
+	items, err := monitor_service.CreateOrUpdateShortcutItems([]monitor_model.ShortcutItem{
+		{Title: "bulk one", Tags: "one", Sections: []monitor_model.ShortcutSection{sections[0], sections[1]}},
+		{Title: "bulk two", Tags: "two", Sections: []monitor_model.ShortcutSection{sections[0]}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	one, two := items[0].ID, items[1].ID
+	if _, err := monitor_service.CreateOrUpdateShortcutSectionItemUsages([]monitor_model.ShortcutSectionItemUsage{{SectionId: a, ItemId: one, ClickCount: 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.POST("shortcut/item/bulk", BulkOperateShortcutItems)
+
+	// 任意一个快捷方式操作失败时, 同一请求中的所有操作都将回滚
+	response := serveShortcutItemBulk(t, router, fmt.Sprintf(`[
+		{"action":"assign","itemIds":[%d],"sectionIds":[%d]},
+		{"action":"copy","itemIds":[%d],"sectionIds":[%d]},
+		{"action":"update","itemIds":[%d,999999],"fields":{"tags":"rollback"}}
+	]`, two, c, two, c, one))
+	if response.Committed || len(response.Results) != 3 || !response.Results[0].Items[0].Success || response.Results[1].Items[0].CreatedItemId == 0 ||
+		!response.Results[2].Items[0].Success || response.Results[2].Items[1].Success || len(response.Results[2].Items[1].Error) <= 0 {
+		t.Fatalf("unexpected rollback response %+v", response)
+	}
+	if _, sectionIds := getShortcutItemWithSections(t, two); fmt.Sprint(sectionIds) != fmt.Sprint([]uint{a}) {
+		t.Errorf("assign should be rolled back, got sections %v", sectionIds)
+	}
+	if item, _ := getShortcutItemWithSections(t, one); item.Tags != "one" {
+		t.Errorf("update should be rolled back, got tags %s", item.Tags)
+	}
+	if copied, _ := monitor_service.ListShortcutItemsByQuery(monitor_model.ShortcutItem{Model: monitor_model.Model{ID: response.Results[1].Items[0].CreatedItemId}}, nil); len(*copied) != 0 {
+		t.Errorf("copied item should be rolled back, got %+v", *copied)
+	}
+
+	// 目标分组不存在时该操作的所有快捷方式都失败
+	response = serveShortcutItemBulk(t, router, fmt.Sprintf(`[{"action":"assign","itemIds":[%d,%d],"sectionIds":[%d,999999]}]`, one, two, c))
+	if response.Committed || lo.SomeBy(response.Results[0].Items, func(item monitor_service.ShortcutItemBulkItemResult) bool { return item.Success }) {
+		t.Errorf("missing section should fail all items, got %+v", response)
+	}
+
+	// 从指定分组移动时保留其他分组
+	response = serveShortcutItemBulk(t, router, fmt.Sprintf(`[{"action":"move","itemIds":[%d],"fromSectionId":%d,"sectionIds":[%d]}]`, one, a, c))
+	if !response.Committed {
+		t.Fatalf("move should be committed, got %+v", response)
+	}
+	if _, sectionIds := getShortcutItemWithSections(t, one); fmt.Sprint(sectionIds) != fmt.Sprint([]uint{b, c}) {
+		t.Errorf("item should be moved from section %d to %d, got sections %v", a, c, sectionIds)
+	}
+
+	// fromSectionId 为 0 时从所有分组中移出
+	response = serveShortcutItemBulk(t, router, fmt.Sprintf(`[{"action":"move","itemIds":[%d],"sectionIds":[%d]}]`, one, a))
+	if _, sectionIds := getShortcutItemWithSections(t, one); !response.Committed || fmt.Sprint(sectionIds) != fmt.Sprint([]uint{a}) {
+		t.Errorf("item should be moved to section %d only, got sections %v", a, sectionIds)
+	}
+
+	// 复制时创建新的快捷方式, 只添加到目标分组, 不复制使用情况, 原快捷方式不变
+	response = serveShortcutItemBulk(t, router, fmt.Sprintf(`[{"action":"copy","itemIds":[%d],"sectionIds":[%d,%d]}]`, one, b, c))
+	copiedId := response.Results[0].Items[0].CreatedItemId
+	if !response.Committed || copiedId == 0 || copiedId == one {
+		t.Fatalf("copy should create a new item, got %+v", response)
+	}
+	copied, copiedSectionIds := getShortcutItemWithSections(t, copiedId)
+	if copied.Title != "bulk one" || copied.Tags != "one" || fmt.Sprint(copiedSectionIds) != fmt.Sprint([]uint{b, c}) {
+		t.Errorf("unexpected copied item %+v, sections %v", copied, copiedSectionIds)
+	}
+	if count, _ := monitor_service.CountShortcutSectionItemUsage(monitor_model.ShortcutSectionItemUsage{ItemId: copiedId}); count != 0 {
+		t.Errorf("usages should not be copied, got %d", count)
+	}
+	if _, sectionIds := getShortcutItemWithSections(t, one); fmt.Sprint(sectionIds) != fmt.Sprint([]uint{a}) {
+		t.Errorf("original item should not be changed, got sections %v", sectionIds)
+	}
+
+	// 添加到分组不影响其他分组, 重复添加不会产生重复的关联
+	response = serveShortcutItemBulk(t, router, fmt.Sprintf(`[{"action":"assign","itemIds":[%d,%d],"sectionIds":[%d,%d]}]`, one, two, a, b))
+	if !response.Committed {
+		t.Fatalf("assign should be committed, got %+v", response)
+	}
+	for _, id := range []uint{one, two} {
+		if _, sectionIds := getShortcutItemWithSections(t, id); fmt.Sprint(sectionIds) != fmt.Sprint([]uint{a, b}) {
+			t.Errorf("item %d should be assigned to sections %d and %d, got %v", id, a, b, sectionIds)
+		}
+	}
+
+	// 从分组中移出只影响指定的分组
+	response = serveShortcutItemBulk(t, router, fmt.Sprintf(`[{"action":"unassign","itemIds":[%d,%d],"sectionIds":[%d]}]`, one, two, a))
+	if !response.Committed {
+		t.Fatalf("unassign should be committed, got %+v", response)
+	}
+	for _, id := range []uint{one, two} {
+		if _, sectionIds := getShortcutItemWithSections(t, id); fmt.Sprint(sectionIds) != fmt.Sprint([]uint{b}) {
+			t.Errorf("item %d should be unassigned from section %d, got %v", id, a, sectionIds)
+		}
+	}
+
+	// 只修改指定的字段
+	response = serveShortcutItemBulk(t, router, fmt.Sprintf(`[{"action":"update","itemIds":[%d,%d],"fields":{"statusCheck":true,"backgroundColor":"#fff"}}]`, one, two))
+	if !response.Committed {
+		t.Fatalf("update should be committed, got %+v", response)
+	}
+	for id, tags := range map[uint]string{one: "one", two: "two"} {
+		if item, sectionIds := getShortcutItemWithSections(t, id); !item.StatusCheck || item.BackgroundColor != "#fff" || item.Tags != tags || fmt.Sprint(sectionIds) != fmt.Sprint([]uint{b}) {
+			t.Errorf("unexpected updated item %+v, sections %v", item, sectionIds)
+		}
+	}
+	if response = serveShortcutItemBulk(t, router, fmt.Sprintf(`[{"action":"update","itemIds":[%d],"fields":{}}]`, one)); response.Committed {
+		t.Errorf("update without fields should fail, got %+v", response)
+	}
+}
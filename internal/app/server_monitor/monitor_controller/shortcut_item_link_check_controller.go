@@ -0,0 +1,101 @@
+package monitor_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-errors/errors"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/shortcut_link_checker"
+	"net/http"
+	"strconv"
+)
+
+// ListShortcutItemLinkChecks 获取快捷方式链接的检查报告.
+// 默认只返回链接失效或被永久重定向的快捷方式, 被永久重定向的快捷方式会给出建议的新地址(suggestedUrl).
+// @Summary ListShortcutItemLinkChecks
+// @Description ListShortcutItemLinkChecks
+// @Tags ListShortcutItemLinkChecks
+// @Produce json
+// @Param all query bool false "是否返回所有检查结果"
+// @Success 200 {array} monitor_model.ShortcutItemLinkCheck
+// @Router shortcut/item/link-check/report [get]
+func ListShortcutItemLinkChecks(c *gin.Context) {
+	var query struct {
+		All bool `form:"all"`
+	}
+
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	}
+
+	checks, err := monitor_service.ListShortcutItemLinkChecks(!query.All)
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"checks":    checks,
+		"running":   shortcut_link_checker.Running(),
+		"lastRunAt": shortcut_link_checker.LastRunAt(),
+	})
+}
+
+// RunShortcutItemLinkCheck 手动触发快捷方式链接检查. 检查在后台进行, 完成后可以通过 ListShortcutItemLinkChecks 获取检查报告.
+// @Summary RunShortcutItemLinkCheck
+// @Description RunShortcutItemLinkCheck
+// @Tags RunShortcutItemLinkCheck
+// @Produce json
+// @Success 200
+// @Router shortcut/item/link-check/run [post]
+func RunShortcutItemLinkCheck(c *gin.Context) {
+	if err := shortcut_link_checker.Start(); errors.Is(err, shortcut_link_checker.ErrorRunning) {
+		respondEntityAlreadyExistError(c, err.Error())
+		return
+	} else if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// AcceptShortcutItemLinkCheck 接受检查报告中建议的新地址, 更新快捷方式的链接并刷新缓存的图标.
+// 请求体中的 url 不为空时使用该地址代替建议的新地址.
+// @Summary AcceptShortcutItemLinkCheck
+// @Description AcceptShortcutItemLinkCheck
+// @Tags AcceptShortcutItemLinkCheck
+// @Accept json
+// @Produce json
+// @Param itemId path number true "item id"
+// @Success 200 {object} monitor_model.ShortcutItem
+// @Router shortcut/item/link-check/accept/{itemId} [put]
+func AcceptShortcutItemLinkCheck(c *gin.Context) {
+	var body struct {
+		Url string `json:"url"`
+	}
+
+	itemId, err := strconv.ParseUint(c.Param("itemId"), 10, 0)
+	if err != nil {
+		respondEntityValidationError(c, "itemId should be number")
+		return
+	}
+	// 请求体可以为空.
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			respondEntityValidationError(c, err.Error())
+			return
+		}
+	}
+
+	item, err := monitor_service.AcceptShortcutItemLinkCheck(uint(itemId), body.Url)
+	if errors.Is(err, monitor_service.ErrorNotFound) {
+		respondEntityNotFoundError(c, "link check of item %d not found", itemId)
+		return
+	} else if err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
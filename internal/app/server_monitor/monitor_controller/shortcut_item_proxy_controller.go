@@ -0,0 +1,62 @@
+package monitor_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/comfy_log"
+	"github.com/siaikin/home-dashboard/internal/pkg/embed_proxy"
+	"github.com/siaikin/home-dashboard/internal/pkg/sessions"
+	"net/http"
+	url2 "net/url"
+	"strconv"
+)
+
+var proxyLogger = comfy_log.New("[shortcut_item_proxy]")
+
+// ProxyShortcutItemPrefix 嵌入快捷方式的反向代理路由前缀.
+const ProxyShortcutItemPrefix = "/v1/proxy"
+
+// ProxyShortcutItem 将请求反向代理到快捷方式的地址, 使其可以嵌入 dashboard 的 iframe 中显示. 只代理打开方式为 embed 的快捷方式.
+// @Summary ProxyShortcutItem
+// @Description ProxyShortcutItem
+// @Tags ProxyShortcutItem
+// @Param itemId path int true "Shortcut Item ID"
+// @Param path path string true "Path of the shortcut item url"
+// @Router /v1/proxy/{itemId}/{path} [get]
+func ProxyShortcutItem(c *gin.Context) {
+	itemId, err := strconv.ParseUint(c.Param("itemId"), 10, 64)
+	if err != nil || itemId == 0 {
+		respondEntityValidationError(c, "invalid item id %s", c.Param("itemId"))
+		return
+	}
+
+	items, err := monitor_service.ListShortcutItemsByQuery(monitor_model.ShortcutItem{Model: monitor_model.Model{ID: uint(itemId)}}, nil)
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	} else if len(*items) <= 0 {
+		respondEntityNotFoundError(c, "shortcut item %d not found", itemId)
+		return
+	}
+
+	item := (*items)[0]
+	if item.Target != monitor_model.ShortcutItemTargetTypeEmbed {
+		respondEntityValidationError(c, "shortcut item %d is not an embedded shortcut", itemId)
+		return
+	}
+
+	target, err := url2.Parse(item.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") {
+		respondEntityValidationError(c, "shortcut item %d has an invalid url %s", itemId, item.URL)
+		return
+	}
+
+	proxy := embed_proxy.New(target, ProxyShortcutItemPrefix+"/"+strconv.FormatUint(itemId, 10), []string{sessions.GetSessionName()}, nil)
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		proxyLogger.Warn("proxy shortcut item %d to %s failed, %s\n", itemId, item.URL, err)
+		w.WriteHeader(http.StatusBadGateway)
+	}
+
+	proxy.ServeHTTP(c.Writer, c.Request)
+}
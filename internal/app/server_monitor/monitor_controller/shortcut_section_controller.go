@@ -78,6 +78,39 @@ func ListShortcutSections(c *gin.Context) {
 	})
 }
 
+// ListVirtualShortcutSections 获取根据使用情况计算得出的虚拟分组("最近使用" 和 "最常使用").
+// 虚拟分组不可编辑, 通过 virtual 字段与普通分组区分.
+// @Summary ListVirtualShortcutSections
+// @Description ListVirtualShortcutSections
+// @Tags ListVirtualShortcutSections
+// @Produce json
+// @Param max query number false "每个分组最多包含的快捷方式数量, 默认为 12"
+// @Success 200 {array} monitor_model.ShortcutSection
+// @Router shortcut/section/virtual/list [get]
+func ListVirtualShortcutSections(c *gin.Context) {
+	var query struct {
+		Max int `form:"max"`
+	}
+
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	}
+	if query.Max <= 0 {
+		query.Max = 12
+	}
+
+	sections, err := monitor_service.ListVirtualShortcutSections(query.Max)
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sections": sections,
+	})
+}
+
 // UpdateShortcutSection 更新快捷方式分组.
 // @Summary UpdateShortcutSection
 // @Description UpdateShortcutSection
@@ -100,10 +133,18 @@ func UpdateShortcutSection(c *gin.Context) {
 		body.ID = uint(ID)
 	}
 
+	auditAction(c, "shortcut.section.update")
+	before, err := monitor_service.ListShortcutSectionsByQuery(1, monitor_model.ShortcutSection{Model: monitor_model.Model{ID: body.ID}}, nil)
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
 	if affected, err := monitor_service.CreateOrUpdateShortcutSections([]monitor_model.ShortcutSection{body}); err != nil {
 		respondUnknownError(c, err.Error())
 		return
 	} else {
+		auditTarget(c, "shortcutSection", body.ID, *before, affected[0])
 		c.JSON(http.StatusOK, affected[0])
 	}
 }
@@ -121,6 +162,14 @@ func DeleteShortcutSection(c *gin.Context) {
 		return
 	}
 
+	auditAction(c, "shortcut.section.delete")
+	before, err := monitor_service.ListShortcutSectionsByQuery(1, monitor_model.ShortcutSection{Model: monitor_model.Model{ID: uint(id)}}, nil)
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+	auditTarget(c, "shortcutSection", id, *before, nil)
+
 	if err := monitor_service.DeleteShortcutSections([]uint{uint(id)}); err != nil {
 		respondUnknownError(c, err.Error())
 		return
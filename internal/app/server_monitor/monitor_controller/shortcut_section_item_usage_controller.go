@@ -7,7 +7,8 @@ import (
 	"net/http"
 )
 
-// CollectShortcutSectionItemUsages 收集 monitor_model.ShortcutItem 使用情况
+// CollectShortcutSectionItemUsages 收集 monitor_model.ShortcutItem 使用情况.
+// 每条记录的 lastClickedAt 为点击时间(毫秒时间戳), 未指定时以服务器收到请求的时间为准. 使用情况会同时按天汇总, 用于统计一段时间内的使用情况.
 // @Summary CollectShortcutSectionItemUsages
 // @Description CollectShortcutSectionItemUsages
 // @Tags CollectShortcutSectionItemUsages
@@ -32,3 +33,43 @@ func CollectShortcutSectionItemUsages(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{})
 	}
 }
+
+// ListShortcutSectionItemUsageStatistics 统计一段时间内 monitor_model.ShortcutItem 的使用情况.
+// @Summary ListShortcutSectionItemUsageStatistics
+// @Description ListShortcutSectionItemUsageStatistics
+// @Tags ListShortcutSectionItemUsageStatistics
+// @Produce json
+// @Param sectionId query number false "section id"
+// @Param itemId query number false "item id"
+// @Param start query number false "开始时间(毫秒时间戳)"
+// @Param end query number false "结束时间(毫秒时间戳)"
+// @Param groupByDay query bool false "是否按天统计"
+// @Success 200 {array} monitor_service.ShortcutSectionItemUsageStatistic
+// @Router shortcut/usage/statistics [get]
+func ListShortcutSectionItemUsageStatistics(c *gin.Context) {
+	var query struct {
+		SectionId  uint  `form:"sectionId"`
+		ItemId     uint  `form:"itemId"`
+		Start      int64 `form:"start"`
+		End        int64 `form:"end"`
+		GroupByDay bool  `form:"groupByDay"`
+	}
+
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	} else if query.End > 0 && query.Start > query.End {
+		respondEntityValidationError(c, "start should not be greater than end")
+		return
+	}
+
+	statistics, err := monitor_service.ListShortcutSectionItemUsageStatistics(monitor_model.ShortcutSectionItemDailyUsage{SectionId: query.SectionId, ItemId: query.ItemId}, query.Start, query.End, query.GroupByDay)
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"statistics": statistics,
+	})
+}
@@ -0,0 +1,197 @@
+package monitor_controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/now"
+	"github.com/samber/lo"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/database"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newShortcutUsageRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("shortcut/usage/collect", CollectShortcutSectionItemUsages)
+	router.GET("shortcut/usage/statistics", ListShortcutSectionItemUsageStatistics)
+
+	return router
+}
+
+func serveShortcutUsage(t *testing.T, router *gin.Engine, method string, url string, body string) []byte {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(method, url, strings.NewReader(body)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("%s %s responded %d, %s", method, url, recorder.Code, recorder.Body.String())
+	}
+
+	return recorder.Body.Bytes()
+}
+
+func collectShortcutUsages(t *testing.T, router *gin.Engine, usages ...monitor_model.ShortcutSectionItemUsage) {
+	t.Helper()
+
+	body, _ := json.Marshal(gin.H{"usages": usages})
+	serveShortcutUsage(t, router, http.MethodPost, "/shortcut/usage/collect", string(body))
+}
+
+func listShortcutUsageStatistics(t *testing.T, router *gin.Engine, query string) []monitor_service.ShortcutSectionItemUsageStatistic {
+	t.Helper()
+
+	var result struct {
+		Statistics []monitor_service.ShortcutSectionItemUsageStatistic `json:"statistics"`
+	}
+	if err := json.Unmarshal(serveShortcutUsage(t, router, http.MethodGet, "/shortcut/usage/statistics?"+query, ""), &result); err != nil {
+		t.Fatal(err)
+	}
+
+	return result.Statistics
+}
+
+func TestShortcutSectionItemDailyUsage(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+
+	sections, err := monitor_service.CreateOrUpdateShortcutSections([]monitor_model.ShortcutSection{{Name: "Usage Daily"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	section := sections[0]
+	items, err := monitor_service.CreateOrUpdateShortcutItems([]monitor_model.ShortcutItem{{Title: "usage daily", Sections: []monitor_model.ShortcutSection{section}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := items[0]
+
+	router := newShortcutUsageRouter()
+
+	// 同一天的多次点击汇总到同一条记录中, 不同天的点击分别记录
+	today := now.BeginningOfDay()
+	earlier := today.AddDate(0, 0, -2)
+	collectShortcutUsages(t, router,
+		monitor_model.ShortcutSectionItemUsage{SectionId: section.ID, ItemId: item.ID, ClickCount: 2, LastClickedAt: earlier.Add(10 * time.Hour).UnixMilli()},
+		monitor_model.ShortcutSectionItemUsage{SectionId: section.ID, ItemId: item.ID, ClickCount: 3, LastClickedAt: earlier.Add(11 * time.Hour).UnixMilli()},
+	)
+	collectShortcutUsages(t, router, monitor_model.ShortcutSectionItemUsage{SectionId: section.ID, ItemId: item.ID, ClickCount: 1, LastClickedAt: today.Add(time.Minute).UnixMilli()})
+	// 更早的点击不会覆盖当天最后一次点击的时间
+	collectShortcutUsages(t, router, monitor_model.ShortcutSectionItemUsage{SectionId: section.ID, ItemId: item.ID, ClickCount: 1, LastClickedAt: earlier.Add(9 * time.Hour).UnixMilli()})
+
+	dailyUsages := make([]monitor_model.ShortcutSectionItemDailyUsage, 0)
+	monitor_db.GetDB().Where("section_id = ? AND item_id = ?", section.ID, item.ID).Order("day").Find(&dailyUsages)
+	if len(dailyUsages) != 2 {
+		t.Fatalf("expect 2 daily usages, got %+v", dailyUsages)
+	}
+	if usage := dailyUsages[0]; usage.Day != earlier.UnixMilli() || usage.ClickCount != 6 || usage.LastClickedAt != earlier.Add(11*time.Hour).UnixMilli() {
+		t.Errorf("unexpected daily usage %+v", usage)
+	}
+	if usage := dailyUsages[1]; usage.Day != today.UnixMilli() || usage.ClickCount != 1 || usage.LastClickedAt != today.Add(time.Minute).UnixMilli() {
+		t.Errorf("unexpected daily usage %+v", usage)
+	}
+
+	// 总的使用情况同时累加
+	usages, _ := monitor_service.ListShortcutSectionItemUsagesByQuery(monitor_model.ShortcutSectionItemUsage{SectionId: section.ID, ItemId: item.ID}, nil)
+	if len(*usages) != 1 || (*usages)[0].ClickCount != 7 || (*usages)[0].LastClickedAt != today.Add(time.Minute).UnixMilli() {
+		t.Errorf("unexpected usage %+v", *usages)
+	}
+
+	// 按天统计时按点击次数倒序排列
+	statistics := listShortcutUsageStatistics(t, router, fmt.Sprintf("itemId=%d&groupByDay=true", item.ID))
+	if len(statistics) != 2 || statistics[0].Day != earlier.UnixMilli() || statistics[0].ClickCount != 6 || statistics[1].Day != today.UnixMilli() || statistics[1].ClickCount != 1 {
+		t.Errorf("unexpected daily statistics %+v", statistics)
+	}
+
+	statistics = listShortcutUsageStatistics(t, router, fmt.Sprintf("itemId=%d", item.ID))
+	if len(statistics) != 1 || statistics[0].Day != 0 || statistics[0].ClickCount != 7 {
+		t.Errorf("unexpected statistics %+v", statistics)
+	}
+
+	// start 所在的当天包含在统计范围内
+	statistics = listShortcutUsageStatistics(t, router, fmt.Sprintf("itemId=%d&start=%d", item.ID, today.Add(12*time.Hour).UnixMilli()))
+	if len(statistics) != 1 || statistics[0].ClickCount != 1 {
+		t.Errorf("unexpected statistics since today %+v", statistics)
+	}
+	statistics = listShortcutUsageStatistics(t, router, fmt.Sprintf("itemId=%d&end=%d", item.ID, earlier.Add(23*time.Hour).UnixMilli()))
+	if len(statistics) != 1 || statistics[0].ClickCount != 6 {
+		t.Errorf("unexpected statistics until %s %+v", earlier, statistics)
+	}
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/shortcut/usage/statistics?start=2&end=1", nil))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("start greater than end should be rejected, got %d", recorder.Code)
+	}
+}
+
+func TestFrequentlyUsedShortcutItems(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+
+	sections, err := monitor_service.CreateOrUpdateShortcutSections([]monitor_model.ShortcutSection{{Name: "Usage Frequent"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	section := sections[0]
+	items, err := monitor_service.CreateOrUpdateShortcutItems([]monitor_model.ShortcutItem{
+		{Title: "frequent recent", Sections: []monitor_model.ShortcutSection{section}},
+		{Title: "frequent old", Sections: []monitor_model.ShortcutSection{section}},
+		{Title: "frequent expired", Sections: []monitor_model.ShortcutSection{section}},
+		{Title: "frequent mixed", Sections: []monitor_model.ShortcutSection{section}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recent, old, expired, mixed := items[0], items[1], items[2], items[3]
+
+	router := newShortcutUsageRouter()
+
+	// 点击的权重每 FrequentlyUsedShortcutItemHalfLife 减半:
+	//  recent: 3 次 * 1 = 3
+	//  old: 10 次 * 0.5^(20/7) ≈ 1.4
+	//  mixed: 2 次 * 0.5^(3/7) + 2 次 * 1 ≈ 3.5
+	//  expired: 超出统计天数, 不参与排名
+	current := time.Now()
+	collectShortcutUsages(t, router,
+		monitor_model.ShortcutSectionItemUsage{SectionId: section.ID, ItemId: recent.ID, ClickCount: 3, LastClickedAt: current.UnixMilli()},
+		monitor_model.ShortcutSectionItemUsage{SectionId: section.ID, ItemId: old.ID, ClickCount: 10, LastClickedAt: current.AddDate(0, 0, -20).UnixMilli()},
+		monitor_model.ShortcutSectionItemUsage{SectionId: section.ID, ItemId: expired.ID, ClickCount: 100, LastClickedAt: current.AddDate(0, 0, -monitor_service.FrequentlyUsedShortcutItemDays-1).UnixMilli()},
+		monitor_model.ShortcutSectionItemUsage{SectionId: section.ID, ItemId: mixed.ID, ClickCount: 2, LastClickedAt: current.AddDate(0, 0, -3).UnixMilli()},
+		monitor_model.ShortcutSectionItemUsage{SectionId: section.ID, ItemId: mixed.ID, ClickCount: 2, LastClickedAt: current.UnixMilli()},
+	)
+
+	frequent, err := monitor_service.ListFrequentlyUsedShortcutItems(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 其他测试可能也记录了使用情况, 只比较本测试创建的快捷方式的顺序
+	ids := lo.FilterMap(frequent, func(item monitor_model.ShortcutItem, _ int) (uint, bool) {
+		return item.ID, lo.Contains([]uint{recent.ID, old.ID, expired.ID, mixed.ID}, item.ID)
+	})
+	if want := []uint{mixed.ID, recent.ID, old.ID}; fmt.Sprint(ids) != fmt.Sprint(want) {
+		t.Errorf("frequently used items should be ordered by decayed clicks, got %v, want %v", ids, want)
+	}
+
+	// 删除的快捷方式不参与排名
+	if err := monitor_service.DeleteShortcutItems([]uint{mixed.ID}); err != nil {
+		t.Fatal(err)
+	}
+	// 恢复快捷方式, 以免影响其他测试
+	defer monitor_db.GetDB().Unscoped().Model(&monitor_model.ShortcutItem{}).Where("id = ?", mixed.ID).Update("deleted_at", 0)
+	if frequent, _ = monitor_service.ListFrequentlyUsedShortcutItems(0); lo.ContainsBy(frequent, func(item monitor_model.ShortcutItem) bool { return item.ID == mixed.ID }) {
+		t.Errorf("deleted item should not be listed")
+	}
+}
@@ -0,0 +1,120 @@
+package monitor_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"net/http"
+	"strconv"
+)
+
+// ListShortcutTrash 获取回收站中的分组和快捷方式.
+// @Summary ListShortcutTrash
+// @Description ListShortcutTrash
+// @Tags ListShortcutTrash
+// @Produce json
+// @Success 200
+// @Router shortcut/trash/list [get]
+func ListShortcutTrash(c *gin.Context) {
+	sections, err := monitor_service.ListTrashedShortcutSections()
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	items, err := monitor_service.ListTrashedShortcutItems()
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sections": sections,
+		"items":    items,
+	})
+}
+
+type shortcutTrashTargets struct {
+	SectionIds []uint `json:"sectionIds" form:"sectionIds"`
+	ItemIds    []uint `json:"itemIds" form:"itemIds"`
+}
+
+// RestoreShortcutTrash 从回收站恢复分组和快捷方式.
+// @Summary RestoreShortcutTrash
+// @Description RestoreShortcutTrash
+// @Tags RestoreShortcutTrash
+// @Accept json
+// @Produce json
+// @Param targets body shortcutTrashTargets true "要恢复的分组和快捷方式"
+// @Success 200
+// @Router shortcut/trash/restore [put]
+func RestoreShortcutTrash(c *gin.Context) {
+	var body shortcutTrashTargets
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	}
+
+	// 先恢复分组, 以便快捷方式恢复到原来的分组中.
+	if err := monitor_service.RestoreShortcutSections(body.SectionIds); err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+	if err := monitor_service.RestoreShortcutItems(body.ItemIds); err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// PurgeShortcutTrash 彻底删除回收站中的分组和快捷方式.
+// @Summary PurgeShortcutTrash
+// @Description PurgeShortcutTrash
+// @Tags PurgeShortcutTrash
+// @Accept query
+// @Produce json
+// @Param sectionIds query []uint false "要彻底删除的分组"
+// @Param itemIds query []uint false "要彻底删除的快捷方式"
+// @Success 200
+// @Router shortcut/trash/purge [delete]
+func PurgeShortcutTrash(c *gin.Context) {
+	var query shortcutTrashTargets
+	for key, target := range map[string]*[]uint{"sectionIds": &query.SectionIds, "itemIds": &query.ItemIds} {
+		for _, idStr := range c.QueryArray(key) {
+			id, err := strconv.ParseUint(idStr, 10, 0)
+			if err != nil {
+				respondEntityValidationError(c, err.Error())
+				return
+			}
+
+			*target = append(*target, uint(id))
+		}
+	}
+
+	if err := monitor_service.PurgeShortcutSections(query.SectionIds); err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+	if err := monitor_service.PurgeShortcutItems(query.ItemIds); err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// EmptyShortcutTrash 清空回收站.
+// @Summary EmptyShortcutTrash
+// @Description EmptyShortcutTrash
+// @Tags EmptyShortcutTrash
+// @Produce json
+// @Success 200
+// @Router shortcut/trash/empty [delete]
+func EmptyShortcutTrash(c *gin.Context) {
+	if err := monitor_service.EmptyShortcutTrash(); err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
@@ -0,0 +1,120 @@
+package monitor_controller
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/database"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func newShortcutTrashRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("shortcut/trash/list", ListShortcutTrash)
+	router.PUT("shortcut/trash/restore", RestoreShortcutTrash)
+	router.DELETE("shortcut/trash/purge", PurgeShortcutTrash)
+	router.DELETE("shortcut/trash/empty", EmptyShortcutTrash)
+
+	return router
+}
+
+func serveShortcutTrash(t *testing.T, router *gin.Engine, method string, url string, body string) map[string]json.RawMessage {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(method, url, strings.NewReader(body)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("%s %s responded %d, %s", method, url, recorder.Code, recorder.Body.String())
+	}
+
+	result := make(map[string]json.RawMessage)
+	_ = json.Unmarshal(recorder.Body.Bytes(), &result)
+
+	return result
+}
+
+func countSectionItems(t *testing.T, query monitor_model.ShortcutSection) int {
+	t.Helper()
+
+	sections, err := monitor_service.ListShortcutSectionsByQuery(0, query, []string{"Items"})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(*sections) <= 0 {
+		return -1
+	}
+
+	return len((*sections)[0].Items)
+}
+
+func TestShortcutTrash(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+
+	sections, err := monitor_service.CreateOrUpdateShortcutSections([]monitor_model.ShortcutSection{{Name: "Trash Default", Default: true}, {Name: "Trash Folder"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	folder := sections[1]
+	items, err := monitor_service.CreateOrUpdateShortcutItems([]monitor_model.ShortcutItem{
+		{Title: "trash a", Sections: []monitor_model.ShortcutSection{folder}},
+		{Title: "trash b", Sections: []monitor_model.ShortcutSection{folder}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	itemA := strconv.Itoa(int(items[0].ID))
+
+	router := newShortcutTrashRouter()
+
+	// 删除快捷方式后可以恢复到原来的分组
+	if err := monitor_service.DeleteShortcutItems([]uint{items[0].ID}); err != nil {
+		t.Fatal(err)
+	}
+	if count := countSectionItems(t, monitor_model.ShortcutSection{Name: "Trash Folder"}); count != 1 {
+		t.Errorf("deleted item should be hidden, got %d items", count)
+	}
+
+	var trashedItems []monitor_model.ShortcutItem
+	_ = json.Unmarshal(serveShortcutTrash(t, router, http.MethodGet, "/shortcut/trash/list", "")["items"], &trashedItems)
+	if len(trashedItems) != 1 || trashedItems[0].ID != items[0].ID || len(trashedItems[0].Sections) != 1 {
+		t.Errorf("unexpected trashed items %+v", trashedItems)
+	}
+
+	serveShortcutTrash(t, router, http.MethodPut, "/shortcut/trash/restore", `{"itemIds":[`+itemA+`]}`)
+	if count := countSectionItems(t, monitor_model.ShortcutSection{Name: "Trash Folder"}); count != 2 {
+		t.Errorf("restored item should be back in its section, got %d items", count)
+	}
+
+	// 彻底删除分组后, 分组中的快捷方式移入回收站, 恢复后添加到默认分组
+	if err := monitor_service.DeleteShortcutSections([]uint{folder.ID}); err != nil {
+		t.Fatal(err)
+	}
+	serveShortcutTrash(t, router, http.MethodDelete, "/shortcut/trash/purge?sectionIds="+strconv.Itoa(int(folder.ID)), "")
+
+	trashedItems = nil
+	_ = json.Unmarshal(serveShortcutTrash(t, router, http.MethodGet, "/shortcut/trash/list", "")["items"], &trashedItems)
+	if len(trashedItems) != 2 {
+		t.Errorf("items of the purged section should be moved to trash, got %+v", trashedItems)
+	}
+
+	serveShortcutTrash(t, router, http.MethodPut, "/shortcut/trash/restore", `{"itemIds":[`+itemA+`]}`)
+	if count := countSectionItems(t, monitor_model.ShortcutSection{Default: true}); count != 1 {
+		t.Errorf("restored item should be added to the default section, got %d items", count)
+	}
+
+	// 清空回收站
+	serveShortcutTrash(t, router, http.MethodDelete, "/shortcut/trash/empty", "")
+	if trashed, _ := monitor_service.ListTrashedShortcutItems(); len(trashed) != 0 {
+		t.Errorf("trash should be emptied, got %+v", trashed)
+	}
+}
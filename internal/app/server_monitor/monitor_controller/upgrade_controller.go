@@ -28,12 +28,14 @@ type UpgradeRequest struct {
 func Upgrade(context *gin.Context) {
 	body := UpgradeRequest{}
 
+	auditAction(context, "upgrade")
 	if err := context.ShouldBindJSON(&body); err != nil {
 		respondUnknownError(context, err.Error())
 		return
 	}
 
 	logger.Info("receive upgrade request, fetcherName: %s, version: %s\n", body.FetcherName, body.Version)
+	auditTarget(context, "upgrade", body.FetcherName, gin.H{"version": verison_info.Version}, gin.H{"fetcherName": body.FetcherName, "version": body.Version})
 
 	context.Status(http.StatusAccepted)
 
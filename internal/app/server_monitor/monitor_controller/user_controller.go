@@ -0,0 +1,389 @@
+package monitor_controller
+
+import (
+	ginSessions "github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/go-errors/errors"
+	"github.com/samber/lo"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/authority"
+	"github.com/siaikin/home-dashboard/internal/pkg/comfy_errors"
+	"github.com/siaikin/home-dashboard/internal/pkg/sessions"
+	"net/http"
+	"strconv"
+)
+
+// currentUserKey gin.Context 中存储当前登录用户的 key, 值的类型为 monitor_model.User. 由 ActiveUserMiddleware 设置.
+const currentUserKey = "currentUser"
+
+// ActiveUserMiddleware 校验当前登录的用户是否仍然存在且未被禁用, 并将用户信息存储到 gin.Context 中.
+// 该中间件应该在 [authority.AuthorizeMiddleware] 之后使用.
+func ActiveUserMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		info, ok := authority.GetUser(c)
+		if !ok {
+			_ = c.AbortWithError(http.StatusUnauthorized, comfy_errors.NewResponseError(comfy_errors.LoginRequestError, "unauthorized request"))
+			return
+		}
+
+		user, err := monitor_service.GetUserByName(info.Username)
+		if errors.Is(err, monitor_service.ErrorNotFound) || (err == nil && user.Disabled) {
+			_ = c.AbortWithError(http.StatusUnauthorized, comfy_errors.NewResponseError(comfy_errors.LoginRequestError, "user %s not found or disabled", info.Username))
+			return
+		} else if err != nil {
+			respondUnknownError(c, err.Error())
+			c.Abort()
+			return
+		}
+
+		if err := sessions.Touch(c, user.Username); err != nil {
+			logger.Warn("record session activity failed, %s\n", err)
+		}
+
+		c.Set(currentUserKey, user)
+		c.Next()
+	}
+}
+
+type UserRequest struct {
+	Username string                 `json:"username"`
+	Password string                 `json:"password"`
+	Role     monitor_model.UserRole `json:"role"`
+}
+
+// CreateUser 创建用户.
+// @Summary CreateUser
+// @Description CreateUser
+// @Tags CreateUser
+// @Accept json
+// @Produce json
+// @Param user body UserRequest true "body"
+// @Success 200 {object} monitor_model.User
+// @Router user/create [post]
+func CreateUser(c *gin.Context) {
+	var body UserRequest
+
+	auditAction(c, "user.create")
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	} else if len(body.Username) <= 0 || len(body.Password) <= 0 {
+		respondEntityValidationError(c, "username and password are required")
+		return
+	} else if !validateUserRole(c, body.Role) || !ensureRoleGranted(c, body.Role) {
+		return
+	}
+
+	if count, err := monitor_service.CountUser(monitor_model.User{User: authority.User{Username: body.Username}}); err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	} else if count > 0 {
+		respondEntityAlreadyExistError(c, "user with name %s already exists", body.Username)
+		return
+	}
+
+	user := monitor_model.User{
+		User: authority.User{Username: body.Username, Password: body.Password},
+		Role: body.Role,
+	}
+	if err := monitor_service.CreateUser(user); err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	created, err := monitor_service.GetUserByName(body.Username)
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	auditTarget(c, "user", created.ID, nil, withoutUserSecret(created))
+	c.JSON(http.StatusOK, withoutUserSecret(created))
+}
+
+// ListUsers 获取所有用户.
+// @Summary ListUsers
+// @Description ListUsers
+// @Tags ListUsers
+// @Produce json
+// @Success 200 {array} monitor_model.User
+// @Router user/list [get]
+func ListUsers(c *gin.Context) {
+	users, err := monitor_service.ListUsersByQuery(monitor_model.User{})
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	for i := range users {
+		users[i] = withoutUserSecret(users[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users": users,
+	})
+}
+
+// UpdateUser 更新用户的角色, password 不为空时重置用户的密码. 用户名不可修改.
+// @Summary UpdateUser
+// @Description UpdateUser
+// @Tags UpdateUser
+// @Accept json
+// @Produce json
+// @Param id path number true "id"
+// @Param user body UserRequest true "body"
+// @Success 200 {object} monitor_model.User
+// @Router user/update/{id} [put]
+func UpdateUser(c *gin.Context) {
+	var body UserRequest
+
+	auditAction(c, "user.update")
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	} else if !validateUserRole(c, body.Role) {
+		return
+	}
+
+	user, ok := getUserByParam(c)
+	if !ok {
+		return
+	}
+	// 不能管理权限超出自己的用户, 也不能分配超出自己权限的角色
+	if !ensureRoleGranted(c, user.Role) || !ensureRoleGranted(c, body.Role) {
+		return
+	}
+
+	if len(body.Username) > 0 && body.Username != user.Username {
+		respondEntityValidationError(c, "username cannot be changed")
+		return
+	}
+
+	if body.Role != user.Role {
+		if err := monitor_service.EnsureAdministratorRemains(user); err != nil {
+			respondEntityValidationError(c, err.Error())
+			return
+		}
+	}
+
+	before := withoutUserSecret(user)
+	user.Role = body.Role
+	if len(body.Password) > 0 {
+		if err := monitor_service.SetUserPassword(&user, body.Password); err != nil {
+			respondUnknownError(c, err.Error())
+			return
+		}
+	}
+	// 摘要中不包含密码, 只记录是否重置了密码
+	auditTarget(c, "user", user.ID, before, gin.H{"user": withoutUserSecret(user), "passwordReset": len(body.Password) > 0})
+	if err := monitor_service.UpdateUser(user); err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, withoutUserSecret(user))
+}
+
+// DisableUser 禁用用户. 被禁用的用户无法登录, 已登录的会话也将失效.
+// @Summary DisableUser
+// @Description DisableUser
+// @Tags DisableUser
+// @Produce json
+// @Param id path number true "id"
+// @Success 200 {object} monitor_model.User
+// @Router user/disable/{id} [put]
+func DisableUser(c *gin.Context) {
+	setUserDisabled(c, true)
+}
+
+// EnableUser 启用被禁用的用户.
+// @Summary EnableUser
+// @Description EnableUser
+// @Tags EnableUser
+// @Produce json
+// @Param id path number true "id"
+// @Success 200 {object} monitor_model.User
+// @Router user/enable/{id} [put]
+func EnableUser(c *gin.Context) {
+	setUserDisabled(c, false)
+}
+
+// DeleteUser 删除用户. 不能删除当前登录的用户和最后一个可用的管理员.
+// @Summary DeleteUser
+// @Description DeleteUser
+// @Tags DeleteUser
+// @Produce json
+// @Param id path number true "id"
+// @Router user/delete/{id} [delete]
+func DeleteUser(c *gin.Context) {
+	auditAction(c, "user.delete")
+	user, ok := getUserByParam(c)
+	if !ok {
+		return
+	}
+	auditTarget(c, "user", user.ID, withoutUserSecret(user), nil)
+	if !ensureRoleGranted(c, user.Role) {
+		return
+	}
+
+	if current, ok := c.Get(currentUserKey); ok && current.(monitor_model.User).ID == user.ID {
+		respondEntityValidationError(c, "cannot delete current user")
+		return
+	}
+	if err := monitor_service.EnsureAdministratorRemains(user); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	}
+
+	if err := monitor_service.DeleteUser(user); err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+	if err := sessions.RevokeAll(user.Username); err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"oldPassword"`
+	NewPassword string `json:"newPassword"`
+}
+
+// ChangePassword 修改当前登录用户的密码.
+// @Summary ChangePassword
+// @Description ChangePassword
+// @Tags ChangePassword
+// @Accept json
+// @Produce json
+// @Param body body ChangePasswordRequest true "body"
+// @Router user/password [put]
+func ChangePassword(c *gin.Context) {
+	var body ChangePasswordRequest
+
+	auditAction(c, "user.password.change")
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	} else if len(body.NewPassword) <= 0 {
+		respondEntityValidationError(c, "new password is required")
+		return
+	}
+
+	info := ginSessions.Default(c).Get(authority.InfoKey).(authority.User)
+	user, err := monitor_service.GetUserByName(info.Username)
+	if err != nil {
+		respondUnknownError(c, "cannot get current user. %w", err)
+		return
+	}
+	auditTarget(c, "user", user.ID, nil, nil)
+
+	if ok, err := monitor_service.VerifyUserPassword(user, body.OldPassword); err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	} else if !ok {
+		respondLoginError(c, "old password invalid")
+		return
+	}
+
+	if err := monitor_service.SetUserPassword(&user, body.NewPassword); err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+	if err := monitor_service.UpdateUser(user); err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+	// 修改密码后其他设备需要重新登录
+	if err := sessions.RevokeOthers(c, user.Username); err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+func setUserDisabled(c *gin.Context, disabled bool) {
+	auditAction(c, lo.Ternary(disabled, "user.disable", "user.enable"))
+	user, ok := getUserByParam(c)
+	if !ok {
+		return
+	}
+	before := withoutUserSecret(user)
+	if !ensureRoleGranted(c, user.Role) {
+		return
+	}
+
+	if disabled {
+		if current, ok := c.Get(currentUserKey); ok && current.(monitor_model.User).ID == user.ID {
+			respondEntityValidationError(c, "cannot disable current user")
+			return
+		}
+		if err := monitor_service.EnsureAdministratorRemains(user); err != nil {
+			respondEntityValidationError(c, err.Error())
+			return
+		}
+	}
+
+	user.Disabled = disabled
+	auditTarget(c, "user", user.ID, before, withoutUserSecret(user))
+	if err := monitor_service.UpdateUser(user); err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+	if disabled {
+		if err := sessions.RevokeAll(user.Username); err != nil {
+			respondUnknownError(c, err.Error())
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, withoutUserSecret(user))
+}
+
+// getUserByParam 根据路径参数 id 获取用户, 获取失败时响应错误并返回 false.
+func getUserByParam(c *gin.Context) (monitor_model.User, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil || id == 0 {
+		respondEntityValidationError(c, "id should be positive number")
+		return monitor_model.User{}, false
+	}
+
+	user, err := monitor_service.GetUserById(uint(id))
+	if errors.Is(err, monitor_service.ErrorNotFound) {
+		respondEntityNotFoundError(c, "user %d not found", id)
+		return monitor_model.User{}, false
+	} else if err != nil {
+		respondUnknownError(c, err.Error())
+		return monitor_model.User{}, false
+	}
+
+	return user, true
+}
+
+// validateUserRole 校验角色是否存在, 不存在时响应错误并返回 false.
+func validateUserRole(c *gin.Context, role monitor_model.UserRole) bool {
+	if _, err := monitor_service.GetRole(uint(role)); errors.Is(err, monitor_service.ErrorNotFound) {
+		respondEntityValidationError(c, "role %d not found", role)
+		return false
+	} else if err != nil {
+		respondUnknownError(c, err.Error())
+		return false
+	}
+
+	return true
+}
+
+// withoutUserSecret 移除用户的敏感信息.
+func withoutUserSecret(user monitor_model.User) monitor_model.User {
+	user.Password = ""
+	user.Secret2FA = ""
+
+	return user
+}
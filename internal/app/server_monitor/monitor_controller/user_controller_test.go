@@ -0,0 +1,235 @@
+package monitor_controller
+
+import (
+	"encoding/json"
+	ginSessions "github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/go-errors/errors"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/authority"
+	"github.com/siaikin/home-dashboard/internal/pkg/database"
+	"github.com/siaikin/home-dashboard/internal/pkg/sessions"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newUserRouter 创建测试用的路由, 请求头 X-Test-User 中的用户名将作为当前登录的用户.
+func newUserRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(sessions.GetSessionMiddleware(), func(c *gin.Context) {
+		if username := c.GetHeader("X-Test-User"); len(username) > 0 {
+			ginSessions.Default(c).Set(authority.InfoKey, authority.User{Username: username})
+		}
+	})
+
+	authorized := router.Group("", authority.AuthorizeMiddleware(), ActiveUserMiddleware())
+	authorized.PUT("user/password", ChangePassword)
+
+	authority.SetPermissionChecker(CheckPermission)
+	administrator := authorized.Group("", authority.PermissionMiddleware(authority.PermissionUsersManage))
+	administrator.POST("user/create", CreateUser)
+	administrator.GET("user/list", ListUsers)
+	administrator.PUT("user/update/:id", UpdateUser)
+	administrator.PUT("user/disable/:id", DisableUser)
+	administrator.PUT("user/enable/:id", EnableUser)
+	administrator.DELETE("user/delete/:id", DeleteUser)
+
+	return router
+}
+
+func serveUser(router *gin.Engine, username string, method string, url string, body string) (int, []byte) {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(method, url, strings.NewReader(body))
+	request.Header.Set("X-Test-User", username)
+	router.ServeHTTP(recorder, request)
+
+	return recorder.Code, recorder.Body.Bytes()
+}
+
+func TestUserManagement(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+	if err := monitor_service.GenerateBuiltInRoles(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := monitor_service.CreateUser(monitor_model.User{User: authority.User{Username: "user-admin", Password: "admin"}, Role: monitor_model.RoleAdministrator}); err != nil {
+		t.Fatal(err)
+	}
+	admin, _ := monitor_service.GetUserByName("user-admin")
+
+	router := newUserRouter()
+
+	code, body := serveUser(router, "user-admin", http.MethodPost, "/user/create", `{"username":"user-guest","password":"guest","role":2}`)
+	if code != http.StatusOK || strings.Contains(string(body), `"password"`) {
+		t.Fatalf("create user responded %d, %s", code, body)
+	}
+	var guest monitor_model.User
+	_ = json.Unmarshal(body, &guest)
+	guestId := strconv.Itoa(int(guest.ID))
+
+	if code, _ := serveUser(router, "user-admin", http.MethodPost, "/user/create", `{"username":"user-guest","password":"guest","role":2}`); code != http.StatusBadRequest {
+		t.Errorf("duplicated username should be rejected, got %d", code)
+	}
+	if code, _ := serveUser(router, "user-admin", http.MethodPost, "/user/create", `{"username":"user-other","password":"other","role":3}`); code != http.StatusBadRequest {
+		t.Errorf("unknown role should be rejected, got %d", code)
+	}
+
+	// 访客不能访问用户管理接口, 但可以修改自己的密码
+	if code, _ := serveUser(router, "user-guest", http.MethodGet, "/user/list", ""); code != http.StatusForbidden {
+		t.Errorf("guest should not list users, got %d", code)
+	}
+	if code, _ := serveUser(router, "user-guest", http.MethodPut, "/user/password", `{"oldPassword":"wrong","newPassword":"changed"}`); code != http.StatusBadRequest {
+		t.Errorf("wrong old password should be rejected, got %d", code)
+	}
+	if code, body := serveUser(router, "user-guest", http.MethodPut, "/user/password", `{"oldPassword":"guest","newPassword":"changed"}`); code != http.StatusOK {
+		t.Errorf("change password responded %d, %s", code, body)
+	}
+	if user, _ := monitor_service.GetUserByName("user-guest"); !authority.IsPasswordHash(user.Password) {
+		t.Errorf("password should be hashed, got %s", user.Password)
+	} else if ok, _ := authority.VerifyPassword(user.Password, "changed"); !ok {
+		t.Errorf("password should be changed")
+	}
+
+	// 禁用后已登录的会话失效
+	if code, _ := serveUser(router, "user-admin", http.MethodPut, "/user/disable/"+guestId, ""); code != http.StatusOK {
+		t.Errorf("disable user responded %d", code)
+	}
+	if code, _ := serveUser(router, "user-guest", http.MethodPut, "/user/password", `{"oldPassword":"changed","newPassword":"guest"}`); code != http.StatusUnauthorized {
+		t.Errorf("disabled user should be unauthorized, got %d", code)
+	}
+	if code, _ := serveUser(router, "user-admin", http.MethodPut, "/user/enable/"+guestId, ""); code != http.StatusOK {
+		t.Errorf("enable user responded %d", code)
+	}
+
+	// 不能降级, 禁用或删除最后一个可用的管理员
+	adminId := strconv.Itoa(int(admin.ID))
+	if code, _ := serveUser(router, "user-admin", http.MethodPut, "/user/update/"+adminId, `{"role":2}`); code != http.StatusBadRequest {
+		t.Errorf("last administrator should not be demoted, got %d", code)
+	}
+	if code, _ := serveUser(router, "user-admin", http.MethodDelete, "/user/delete/"+adminId, ""); code != http.StatusBadRequest {
+		t.Errorf("current user should not be deleted, got %d", code)
+	}
+
+	// 将访客升级为管理员后可以降级原来的管理员
+	if code, body := serveUser(router, "user-admin", http.MethodPut, "/user/update/"+guestId, `{"role":1}`); code != http.StatusOK {
+		t.Errorf("promote user responded %d, %s", code, body)
+	}
+	if code, body := serveUser(router, "user-admin", http.MethodPut, "/user/update/"+adminId, `{"role":2}`); code != http.StatusOK {
+		t.Errorf("demote user responded %d, %s", code, body)
+	}
+
+	if code, _ := serveUser(router, "user-guest", http.MethodDelete, "/user/delete/"+adminId, ""); code != http.StatusOK {
+		t.Errorf("delete user responded %d", code)
+	}
+	if code, _ := serveUser(router, "user-admin", http.MethodGet, "/user/list", ""); code != http.StatusUnauthorized {
+		t.Errorf("deleted user should be unauthorized, got %d", code)
+	}
+}
+
+func TestHashLikePassword(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+	if err := monitor_service.GenerateBuiltInRoles(); err != nil {
+		t.Fatal(err)
+	}
+	if err := monitor_service.CreateUser(monitor_model.User{User: authority.User{Username: "hashlike-admin", Password: "admin"}, Role: monitor_model.RoleAdministrator}); err != nil {
+		t.Fatal(err)
+	}
+	defer monitor_db.GetDB().Unscoped().Where("username LIKE ?", "hashlike-%").Delete(&monitor_model.User{})
+
+	router := newUserRouter()
+	verify := func(password string) bool {
+		user, err := monitor_service.GetUserByName("hashlike-guest")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ok, err := monitor_service.VerifyUserPassword(user, password)
+		return err == nil && ok && user.Password != password
+	}
+
+	// 看起来像哈希值的密码也是明文密码, 需要哈希后存储
+	created := `$argon2id$v=19$m=19456,t=2,p=1$c2FsdA$aGFzaA`
+	code, body := serveUser(router, "hashlike-admin", http.MethodPost, "/user/create", `{"username":"hashlike-guest","password":"`+created+`","role":2}`)
+	if code != http.StatusOK {
+		t.Fatalf("create user responded %d, %s", code, body)
+	}
+	if !verify(created) {
+		t.Errorf("hash like password should be hashed on create")
+	}
+
+	var guest monitor_model.User
+	_ = json.Unmarshal(body, &guest)
+	reset := `$2b$10$abcdefghijklmnopqrstuuKjB1Yk0t3lNQ2r0z5d2Jp6E9Q6Gm1bC`
+	if code, body := serveUser(router, "hashlike-admin", http.MethodPut, "/user/update/"+strconv.Itoa(int(guest.ID)), `{"role":2,"password":"`+reset+`"}`); code != http.StatusOK {
+		t.Fatalf("reset password responded %d, %s", code, body)
+	}
+	if !verify(reset) {
+		t.Errorf("hash like password should be hashed on reset")
+	}
+
+	changed := `$argon2id$changed`
+	if code, body := serveUser(router, "hashlike-guest", http.MethodPut, "/user/password", `{"oldPassword":"`+reset+`","newPassword":"`+changed+`"}`); code != http.StatusOK {
+		t.Fatalf("change password responded %d, %s", code, body)
+	}
+	if !verify(changed) {
+		t.Errorf("hash like password should be hashed on change")
+	}
+}
+
+func TestUserIdZero(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+	if err := monitor_service.GenerateBuiltInRoles(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, username := range []string{"userzero-first", "userzero-admin"} {
+		if err := monitor_service.CreateUser(monitor_model.User{User: authority.User{Username: username}, Role: monitor_model.RoleAdministrator}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer monitor_db.GetDB().Unscoped().Where("username LIKE ?", "userzero-%").Delete(&monitor_model.User{})
+
+	// id 为 0 时 gorm 会忽略查询条件, 不能因此操作到第一个用户
+	if _, err := monitor_service.GetUserById(0); !errors.Is(err, monitor_service.ErrorNotFound) {
+		t.Errorf("user 0 should not be found, got %v", err)
+	}
+	if _, err := monitor_service.GetUserByName(""); !errors.Is(err, monitor_service.ErrorNotFound) {
+		t.Errorf("user with empty name should not be found, got %v", err)
+	}
+
+	var first monitor_model.User
+	monitor_db.GetDB().Order("id").First(&first)
+
+	router := newUserRouter()
+	requests := []struct{ method, url, body string }{
+		{http.MethodPut, "/user/update/0", `{"role":2}`},
+		{http.MethodPut, "/user/disable/0", ""},
+		{http.MethodDelete, "/user/delete/0", ""},
+	}
+	for _, request := range requests {
+		if code, body := serveUser(router, "userzero-admin", request.method, request.url, request.body); code != http.StatusBadRequest {
+			t.Errorf("%s %s should be rejected, got %d, %s", request.method, request.url, code, body)
+		}
+	}
+
+	var user monitor_model.User
+	monitor_db.GetDB().Unscoped().Where("id = ?", first.ID).First(&user)
+	if user.DeletedAt != 0 || user.Disabled != first.Disabled || user.Role != first.Role {
+		t.Errorf("first user should not be changed, got %+v", user)
+	}
+}
@@ -3,16 +3,32 @@ package monitor_controller
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification"
 	"github.com/siaikin/home-dashboard/third_party"
 	"net/http"
 	"strconv"
 )
 
+// 通知历史默认和最多每页返回的记录数.
+const (
+	defaultNotificationPageSize = 50
+	maxNotificationPageSize     = 500
+)
+
 type ListNotificationsRequest struct {
 	// Max 最大条数, 0 表示不限制.
 	Max int64 `form:"max"`
 }
 
+type NotificationIdsRequest struct {
+	Ids []uint `json:"ids" binding:"required"`
+}
+
+type ArchiveNotificationsRequest struct {
+	Ids      []uint `json:"ids" binding:"required"`
+	Archived bool   `json:"archived"`
+}
+
 // ListUnreadNotifications 列出指定条数的未读通知
 
 // ListUnreadNotifications 列出指定条数的未读通知
@@ -73,3 +89,138 @@ func MarkAllNotificationAsRead(c *gin.Context) {
 		return
 	}
 }
+
+// ListNotifications 分页获取通知历史(包括已读和已归档的通知), 按通知在来源处的创建时间倒序排列
+// @Summary ListNotifications
+// @Description ListNotifications
+// @Tags 通知
+// @Produce json
+// @Param page query number false "页码, 从 1 开始, 默认为 1"
+// @Param pageSize query number false "每页的记录数, 默认为 50, 最大为 500"
+// @Param origin query []string false "通知的来源, 可以传递多个"
+// @Param kind query []string false "通知的类型, 可以传递多个"
+// @Param unread query bool false "是否未读, 不传递时返回所有通知"
+// @Param archived query bool false "是否已归档, 不传递时返回所有通知"
+// @Param from query number false "开始时间(毫秒时间戳)"
+// @Param to query number false "结束时间(毫秒时间戳)"
+// @Param q query string false "搜索标题和描述的关键词, 多个关键词使用空格分隔"
+// @Router notification/list [get]
+func ListNotifications(c *gin.Context) {
+	page, ok := queryPositiveInt(c, "page", 1, 0)
+	if !ok {
+		return
+	}
+	pageSize, ok := queryPositiveInt(c, "pageSize", defaultNotificationPageSize, maxNotificationPageSize)
+	if !ok {
+		return
+	}
+	from, ok := queryPositiveInt(c, "from", 0, 0)
+	if !ok {
+		return
+	}
+	to, ok := queryPositiveInt(c, "to", 0, 0)
+	if !ok {
+		return
+	}
+	unread, ok := queryOptionalBool(c, "unread")
+	if !ok {
+		return
+	}
+	archived, ok := queryOptionalBool(c, "archived")
+	if !ok {
+		return
+	}
+
+	notifications, total, err := monitor_service.ListNotifications(monitor_service.NotificationFilter{
+		Origins:  c.QueryArray("origin"),
+		Kinds:    c.QueryArray("kind"),
+		Unread:   unread,
+		Archived: archived,
+		From:     int64(from),
+		To:       int64(to),
+		Search:   c.Query("q"),
+	}, page, pageSize)
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": notifications,
+		"total":         total,
+		"page":          page,
+		"pageSize":      pageSize,
+	})
+}
+
+// ArchiveNotifications 批量归档或取消归档通知
+// @Summary ArchiveNotifications
+// @Description ArchiveNotifications
+// @Tags 通知
+// @Accept json
+// @Produce json
+// @Param body body ArchiveNotificationsRequest true "body"
+// @Router notification/archive [patch]
+func ArchiveNotifications(c *gin.Context) {
+	var body ArchiveNotificationsRequest
+
+	auditAction(c, "notification.archive")
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	}
+	auditTarget(c, "notification", body.Ids, nil, body)
+
+	affected, err := monitor_service.ArchiveNotifications(body.Ids, body.Archived)
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	notification.Send("userNotification", map[string]any{})
+	c.JSON(http.StatusOK, gin.H{"affected": affected})
+}
+
+// DeleteNotifications 批量删除通知. 来源仍然未读的通知会在来源下次同步时重新出现, 只想隐藏时应该使用归档
+// @Summary DeleteNotifications
+// @Description DeleteNotifications
+// @Tags 通知
+// @Accept json
+// @Produce json
+// @Param body body NotificationIdsRequest true "body"
+// @Router notification/delete [post]
+func DeleteNotifications(c *gin.Context) {
+	var body NotificationIdsRequest
+
+	auditAction(c, "notification.delete")
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	}
+	auditTarget(c, "notification", body.Ids, nil, nil)
+
+	affected, err := monitor_service.DeleteNotifications(body.Ids)
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	notification.Send("userNotification", map[string]any{})
+	c.JSON(http.StatusOK, gin.H{"affected": affected})
+}
+
+// queryOptionalBool 获取查询参数中的布尔值, 未传递时返回 nil. 参数无效时响应错误并返回 false.
+func queryOptionalBool(c *gin.Context, key string) (*bool, bool) {
+	value := c.Query(key)
+	if len(value) <= 0 {
+		return nil, true
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		respondEntityValidationError(c, "%s should be true or false", key)
+		return nil, false
+	}
+
+	return &parsed, true
+}
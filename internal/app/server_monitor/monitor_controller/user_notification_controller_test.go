@@ -0,0 +1,184 @@
+package monitor_controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/authority"
+	"github.com/siaikin/home-dashboard/internal/pkg/database"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNotificationHistory(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+	if err := monitor_service.GenerateBuiltInRoles(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := monitor_service.CreateUser(monitor_model.User{User: authority.User{Username: "history-admin"}, Role: monitor_model.RoleAdministrator}); err != nil {
+		t.Fatal(err)
+	}
+	defer monitor_db.GetDB().Unscoped().Where("username LIKE ?", "history-%").Delete(&monitor_model.User{})
+
+	// 使用独立的来源, 避免受到其他测试创建的通知影响
+	origin := "history-test"
+	defer monitor_db.GetDB().Unscoped().Where("origin = ?", origin).Delete(&monitor_model.StoredNotification{})
+
+	now := time.Now().UnixMilli()
+	stored := make([]monitor_model.StoredNotification, 0)
+	for i, n := range []notification.UserNotification{
+		{Title: "Disk usage high", Caption: "/dev/sda1 is 95% full", Kind: notification.UserNotificationKindWarning, Unread: true},
+		{Title: "Backup finished", Caption: "nightly_backup completed", Kind: notification.UserNotificationKindSuccess},
+		{Title: "Disk failure", Caption: "/dev/sdb1 is not responding", Kind: notification.UserNotificationKindError},
+		{Title: "Upgrade available", Caption: "v2.0.0 is released", Kind: notification.UserNotificationKindInfo, Unread: true},
+	} {
+		n.UniqueId = fmt.Sprintf("%s-%d", origin, i)
+		n.Origin = origin
+		n.OriginCreateAt = now + int64(i)
+		stored = append(stored, monitor_model.StoredNotification{UserNotification: n})
+	}
+	if err := monitor_service.CreateOrUpdateNotifications(stored); err != nil {
+		t.Fatal(err)
+	}
+
+	router := newNotificationChannelRouter()
+	authorized := router.Group("", authority.AuthorizeMiddleware(), ActiveUserMiddleware())
+	authorized.GET("notification/list", ListNotifications)
+	authorized.PATCH("notification/archive", ArchiveNotifications)
+	authorized.POST("notification/delete", DeleteNotifications)
+
+	list := func(query string) ([]monitor_model.StoredNotification, int64) {
+		code, body := serveUser(router, "history-admin", http.MethodGet, "/notification/list?origin="+origin+"&"+query, "")
+		var response struct {
+			Notifications []monitor_model.StoredNotification `json:"notifications"`
+			Total         int64                              `json:"total"`
+		}
+		if err := json.Unmarshal(body, &response); code != http.StatusOK || err != nil {
+			t.Fatalf("list notifications with %s responded %d, %s", query, code, body)
+		}
+		return response.Notifications, response.Total
+	}
+
+	// 已读的通知也在历史中, 按创建时间倒序分页
+	if notifications, total := list("pageSize=3&page=1"); total != 4 || len(notifications) != 3 || notifications[0].Title != "Upgrade available" {
+		t.Errorf("unexpected first page %d %+v", total, notifications)
+	}
+	if notifications, _ := list("pageSize=3&page=2"); len(notifications) != 1 || notifications[0].Title != "Disk usage high" {
+		t.Errorf("unexpected second page %+v", notifications)
+	}
+
+	for query, expected := range map[string]int64{
+		"unread=false":                    2,
+		"kind=error&kind=warning":         2,
+		"q=disk":                          2,
+		"q=disk+sdb1":                     1,
+		"q=95%25":                         1,
+		"q=nightly_backup":                1,
+		"q=nightly%25backup":              0,
+		fmt.Sprintf("from=%d", now+2):     2,
+		fmt.Sprintf("to=%d", now+1):       2,
+		"unread=true&kind=info&q=upgrade": 1,
+	} {
+		if _, total := list(query); total != expected {
+			t.Errorf("expected %d notifications with %s, got %d", expected, query, total)
+		}
+	}
+	for _, query := range []string{"unread=maybe", "pageSize=1000", "page=0"} {
+		if code, _ := serveUser(router, "history-admin", http.MethodGet, "/notification/list?"+query, ""); code != http.StatusBadRequest {
+			t.Errorf("invalid query %s should be rejected, got %d", query, code)
+		}
+	}
+
+	// 归档
+	notifications, _ := list("q=disk")
+	ids := []uint{notifications[0].ID, notifications[1].ID}
+	if code, body := serveUser(router, "history-admin", http.MethodPatch, "/notification/archive", fmt.Sprintf(`{"ids":[%d,%d],"archived":true}`, ids[0], ids[1])); code != http.StatusOK {
+		t.Fatalf("archive notifications responded %d, %s", code, body)
+	}
+	if _, total := list("archived=true"); total != 2 {
+		t.Errorf("expected 2 archived notifications, got %d", total)
+	}
+	if _, total := list("archived=false"); total != 2 {
+		t.Errorf("expected 2 unarchived notifications, got %d", total)
+	}
+
+	// 删除
+	if code, _ := serveUser(router, "history-admin", http.MethodPost, "/notification/delete", `{}`); code != http.StatusBadRequest {
+		t.Errorf("delete without ids should be rejected, got %d", code)
+	}
+	if code, body := serveUser(router, "history-admin", http.MethodPost, "/notification/delete", fmt.Sprintf(`{"ids":[%d]}`, ids[0])); code != http.StatusOK {
+		t.Fatalf("delete notifications responded %d, %s", code, body)
+	}
+	if _, total := list(""); total != 3 {
+		t.Errorf("expected 3 notifications after delete, got %d", total)
+	}
+
+	// 保留时间只清理已读通知
+	if _, err := monitor_service.PurgeReadNotifications(-time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if notifications, total := list(""); total != 2 || notifications[0].Unread != true || notifications[1].Unread != true {
+		t.Errorf("only unread notifications should be kept, got %+v", notifications)
+	}
+	count := int64(0)
+	if err := monitor_db.GetDB().Unscoped().Model(&monitor_model.StoredNotification{}).Where("origin = ? AND unread = ?", origin, false).Count(&count).Error; err != nil || count != 0 {
+		t.Errorf("read notifications should be purged, %d left", count)
+	}
+}
+
+func TestCreateOrUpdateNotifications(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+	defer monitor_db.GetDB().Unscoped().Where("unique_id LIKE ?", "resend-%").Delete(&monitor_model.StoredNotification{})
+
+	find := func(uniqueId string) monitor_model.StoredNotification {
+		stored := monitor_model.StoredNotification{}
+		if result := monitor_db.GetDB().Where("unique_id = ?", uniqueId).Limit(1).Find(&stored); result.Error != nil || result.RowsAffected != 1 {
+			t.Fatalf("notification %s not found, %v", uniqueId, result.Error)
+		}
+		return stored
+	}
+	send := func(n notification.UserNotification) {
+		if err := monitor_service.CreateOrUpdateNotifications([]monitor_model.StoredNotification{{UserNotification: n}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// GitHub 每次拉取都会重新发送仍未读的通知, 本地已读的通知不能变为未读
+	github := notification.UserNotification{UniqueId: "resend-github", Title: "PR review requested", Origin: notification.UserNotificationOriginGithub, Kind: notification.UserNotificationKindInfo, Unread: true}
+	send(github)
+	if err := monitor_service.MarkNotificationAsRead(find(github.UniqueId).ID); err != nil {
+		t.Fatal(err)
+	}
+	read := find(github.UniqueId)
+	time.Sleep(time.Millisecond * 5)
+	send(github)
+	if resent := find(github.UniqueId); resent.Unread || resent.UpdatedAt != read.UpdatedAt {
+		t.Errorf("resent github notification should stay read and untouched, got %+v, before %+v", resent, read)
+	}
+
+	// 入站 Webhook 的通知随告警状态更新, 未变化时不更新
+	webhook := notification.UserNotification{UniqueId: "resend-webhook", Title: "Disk full", Origin: "webhook:alertmanager", Kind: notification.UserNotificationKindError, Unread: true}
+	send(webhook)
+	firing := find(webhook.UniqueId)
+	time.Sleep(time.Millisecond * 5)
+	send(webhook)
+	if resent := find(webhook.UniqueId); resent.UpdatedAt != firing.UpdatedAt {
+		t.Errorf("unchanged webhook notification should not be updated, got %+v", resent)
+	}
+	webhook.Unread, webhook.Kind = false, notification.UserNotificationKindSuccess
+	send(webhook)
+	if resolved := find(webhook.UniqueId); resolved.Unread || resolved.Kind != notification.UserNotificationKindSuccess {
+		t.Errorf("resolved webhook notification should be updated, got %+v", resolved)
+	}
+}
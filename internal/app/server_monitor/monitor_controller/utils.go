@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/siaikin/home-dashboard/internal/pkg/comfy_errors"
 	"net/http"
+	"strings"
 )
 
 func respondEntityAlreadyExistError(c *gin.Context, message string, a ...any) {
@@ -26,7 +27,20 @@ func respondEntityNotFoundError(c *gin.Context, message string, a ...any) {
 	abortWithError(c, http.StatusNotFound, comfy_errors.NewResponseError(comfy_errors.EntityNotFoundError, message, a...))
 }
 
+func respondPermissionDeniedError(c *gin.Context, message string, a ...any) {
+	abortWithError(c, http.StatusForbidden, comfy_errors.NewResponseError(comfy_errors.PermissionDeniedError, message, a...))
+}
+
 func abortWithError(c *gin.Context, code int, err error) {
 	c.Status(code)
 	_ = c.Error(err)
 }
+
+// localRedirect 只允许跳转到站内的相对路径, 其他地址(包括 //example.com 等协议相对地址)返回 /.
+func localRedirect(redirect string) string {
+	if !strings.HasPrefix(redirect, "/") || strings.HasPrefix(redirect, "//") || strings.HasPrefix(redirect, "/\\") {
+		return "/"
+	}
+
+	return redirect
+}
@@ -0,0 +1,403 @@
+package monitor_controller
+
+import (
+	"encoding/gob"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/go-errors/errors"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/samber/lo"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/authority"
+	"github.com/siaikin/home-dashboard/internal/pkg/configuration"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// webAuthnCeremonyKey session 中存储进行中的 WebAuthn 注册/验证流程的 key. 类型为 webAuthnCeremony
+const webAuthnCeremonyKey = "webAuthnCeremony"
+
+// WebAuthn 流程类型, 防止一个流程的 challenge 被用于另一个流程.
+const (
+	webAuthnCeremonyRegister = "register"
+	webAuthnCeremony2FA      = "2fa"
+	webAuthnCeremonyLogin    = "login"
+)
+
+type webAuthnCeremony struct {
+	Kind string
+	// Name 注册流程中凭据的名称
+	Name    string
+	Session webauthn.SessionData
+}
+
+func init() {
+	gob.Register(webAuthnCeremony{})
+}
+
+type WebAuthnRegisterRequest struct {
+	Name string `json:"name"`
+}
+
+// BeginWebAuthnRegistration 开始为当前登录用户注册 WebAuthn 凭据(安全密钥或通行密钥), 返回 navigator.credentials.create() 的参数.
+// @Summary BeginWebAuthnRegistration
+// @Description BeginWebAuthnRegistration
+// @Tags WebAuthn
+// @Accept json
+// @Produce json
+// @Param body body WebAuthnRegisterRequest true "body"
+// @Router auth/2fa/webauthn/register/begin [post]
+func BeginWebAuthnRegistration(c *gin.Context) {
+	var body WebAuthnRegisterRequest
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondEntityValidationError(c, err.Error())
+		return
+	}
+	body.Name = strings.TrimSpace(body.Name)
+	if len(body.Name) <= 0 {
+		respondEntityValidationError(c, "name is required")
+		return
+	}
+
+	instance, ok := newWebAuthn(c)
+	if !ok {
+		return
+	}
+
+	user, err := monitor_service.GetWebAuthnUser(c.MustGet(currentUserKey).(monitor_model.User))
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	// 排除已注册的凭据, 避免同一个认证器重复注册
+	exclusions := lo.Map(user.WebAuthnCredentials(), func(credential webauthn.Credential, _ int) protocol.CredentialDescriptor {
+		return credential.Descriptor()
+	})
+	options, data, err := instance.BeginRegistration(user, webauthn.WithExclusions(exclusions))
+	if err != nil {
+		respondUnknownError(c, "begin webauthn registration failed. %w", err)
+		return
+	}
+
+	if !saveWebAuthnCeremony(c, webAuthnCeremony{Kind: webAuthnCeremonyRegister, Name: body.Name, Session: *data}) {
+		return
+	}
+
+	c.JSON(http.StatusOK, options)
+}
+
+// FinishWebAuthnRegistration 校验 navigator.credentials.create() 的结果并保存凭据. 注册凭据后用户开启 2FA.
+// @Summary FinishWebAuthnRegistration
+// @Description FinishWebAuthnRegistration
+// @Tags WebAuthn
+// @Accept json
+// @Produce json
+// @Router auth/2fa/webauthn/register/finish [post]
+func FinishWebAuthnRegistration(c *gin.Context) {
+	auditAction(c, "auth.2fa.webauthn.register")
+	ceremony, ok := takeWebAuthnCeremony(c, webAuthnCeremonyRegister)
+	if !ok {
+		return
+	}
+
+	instance, ok := newWebAuthn(c)
+	if !ok {
+		return
+	}
+
+	user, err := monitor_service.GetWebAuthnUser(c.MustGet(currentUserKey).(monitor_model.User))
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	credential, err := instance.FinishRegistration(user, ceremony.Session, c.Request)
+	if err != nil {
+		respondLoginError(c, "webauthn registration invalid. %s", webAuthnErrorDetail(err))
+		return
+	}
+
+	created, err := monitor_service.CreateWebAuthnCredential(user.User, ceremony.Name, *credential)
+	if err != nil {
+		respondUnknownError(c, "cannot save webauthn credential. %w", err)
+		return
+	}
+
+	// 当前会话已经完成注册, 视为已通过 2FA 校验, 不需要重新登录
+	session := sessions.Default(c)
+	info := session.Get(authority.InfoKey).(authority.User)
+	info.Enable2FA = true
+	session.Set(authority.InfoKey, info)
+	session.Set(authority.TotpValidatedKey, true)
+	saveSession(c, session)
+
+	auditTarget(c, "webauthnCredential", created.ID, nil, created)
+	c.JSON(http.StatusOK, created)
+}
+
+// ListWebAuthnCredentials 获取当前登录用户已注册的 WebAuthn 凭据
+// @Summary ListWebAuthnCredentials
+// @Description ListWebAuthnCredentials
+// @Tags WebAuthn
+// @Produce json
+// @Success 200 {array} monitor_model.WebAuthnCredential
+// @Router auth/2fa/webauthn/credentials [get]
+func ListWebAuthnCredentials(c *gin.Context) {
+	user := c.MustGet(currentUserKey).(monitor_model.User)
+
+	credentials, err := monitor_service.ListWebAuthnCredentials(user.ID)
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, credentials)
+}
+
+// DeleteWebAuthnCredential 删除当前登录用户的 WebAuthn 凭据. 删除最后一个凭据且没有绑定身份验证器应用时关闭 2FA.
+// @Summary DeleteWebAuthnCredential
+// @Description DeleteWebAuthnCredential
+// @Tags WebAuthn
+// @Produce json
+// @Param id path number true "凭据 id"
+// @Router auth/2fa/webauthn/credentials/{id} [delete]
+func DeleteWebAuthnCredential(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		respondEntityValidationError(c, "id should be number")
+		return
+	}
+
+	user := c.MustGet(currentUserKey).(monitor_model.User)
+	auditAction(c, "auth.2fa.webauthn.delete")
+	auditTarget(c, "webauthnCredential", id, nil, nil)
+	enable2FA, err := monitor_service.DeleteWebAuthnCredential(user, uint(id))
+	if errors.Is(err, monitor_service.ErrorNotFound) {
+		respondEntityNotFoundError(c, "webauthn credential %d not found", id)
+		return
+	} else if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	session := sessions.Default(c)
+	info := session.Get(authority.InfoKey).(authority.User)
+	info.Enable2FA = enable2FA
+	session.Set(authority.InfoKey, info)
+	saveSession(c, session)
+
+	c.JSON(http.StatusOK, gin.H{"enable2FA": enable2FA})
+}
+
+// BeginWebAuthnValidate2FA 开始使用 WebAuthn 凭据进行 2FA 校验, 返回 navigator.credentials.get() 的参数.
+// @Summary BeginWebAuthnValidate2FA
+// @Description BeginWebAuthnValidate2FA
+// @Tags WebAuthn
+// @Produce json
+// @Router auth/2fa/webauthn/validate/begin [post]
+func BeginWebAuthnValidate2FA(c *gin.Context) {
+	instance, ok := newWebAuthn(c)
+	if !ok {
+		return
+	}
+
+	user, err := monitor_service.GetWebAuthnUser(c.MustGet(currentUserKey).(monitor_model.User))
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+	if len(user.Credentials) <= 0 {
+		respondLoginError(c, "user %s has no webauthn credential", user.Username)
+		return
+	}
+
+	options, data, err := instance.BeginLogin(user)
+	if err != nil {
+		respondUnknownError(c, "begin webauthn login failed. %w", err)
+		return
+	}
+
+	if !saveWebAuthnCeremony(c, webAuthnCeremony{Kind: webAuthnCeremony2FA, Session: *data}) {
+		return
+	}
+
+	c.JSON(http.StatusOK, options)
+}
+
+// FinishWebAuthnValidate2FA 校验 navigator.credentials.get() 的结果, 如果正确, 将 session 中的 [authority.TotpValidatedKey] 设置为 true.
+// @Summary FinishWebAuthnValidate2FA
+// @Description FinishWebAuthnValidate2FA
+// @Tags WebAuthn
+// @Accept json
+// @Produce json
+// @Router auth/2fa/webauthn/validate/finish [post]
+func FinishWebAuthnValidate2FA(c *gin.Context) {
+	auditAction(c, "auth.2fa.validate")
+	ceremony, ok := takeWebAuthnCeremony(c, webAuthnCeremony2FA)
+	if !ok {
+		return
+	}
+
+	instance, ok := newWebAuthn(c)
+	if !ok {
+		return
+	}
+
+	user, err := monitor_service.GetWebAuthnUser(c.MustGet(currentUserKey).(monitor_model.User))
+	if err != nil {
+		respondUnknownError(c, err.Error())
+		return
+	}
+
+	credential, err := instance.FinishLogin(user, ceremony.Session, c.Request)
+	if err != nil {
+		respondLoginError(c, "webauthn assertion invalid. %s", webAuthnErrorDetail(err))
+		return
+	}
+	if err := monitor_service.UpdateWebAuthnCredentialUsage(user, *credential); err != nil {
+		respondLoginError(c, "webauthn credential rejected. %w", err)
+		return
+	}
+
+	session := sessions.Default(c)
+	session.Set(authority.TotpValidatedKey, true)
+	saveSession(c, session)
+}
+
+// BeginWebAuthnLogin 开始使用通行密钥进行无密码登录, 返回 navigator.credentials.get() 的参数.
+// @Summary BeginWebAuthnLogin
+// @Description BeginWebAuthnLogin
+// @Tags WebAuthn
+// @Produce json
+// @Router auth/webauthn/login/begin [post]
+func BeginWebAuthnLogin(c *gin.Context) {
+	instance, ok := newWebAuthn(c)
+	if !ok {
+		return
+	}
+
+	// 通行密钥同时作为两个因素(持有的设备和用户验证), 因此要求用户验证
+	options, data, err := instance.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		respondUnknownError(c, "begin webauthn login failed. %w", err)
+		return
+	}
+
+	if !saveWebAuthnCeremony(c, webAuthnCeremony{Kind: webAuthnCeremonyLogin, Session: *data}) {
+		return
+	}
+
+	c.JSON(http.StatusOK, options)
+}
+
+// FinishWebAuthnLogin 校验 navigator.credentials.get() 的结果并登录. 通行密钥登录视为已通过 2FA 校验.
+// @Summary FinishWebAuthnLogin
+// @Description FinishWebAuthnLogin
+// @Tags WebAuthn
+// @Accept json
+// @Produce json
+// @Router auth/webauthn/login/finish [post]
+func FinishWebAuthnLogin(c *gin.Context) {
+	auditAction(c, "auth.login")
+	ceremony, ok := takeWebAuthnCeremony(c, webAuthnCeremonyLogin)
+	if !ok {
+		return
+	}
+
+	instance, ok := newWebAuthn(c)
+	if !ok {
+		return
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponse(c.Request)
+	if err != nil {
+		respondLoginError(c, "webauthn assertion invalid. %s", webAuthnErrorDetail(err))
+		return
+	}
+
+	var user monitor_service.WebAuthnUser
+	credential, err := instance.ValidateDiscoverableLogin(func(_, userHandle []byte) (webauthn.User, error) {
+		user, err = monitor_service.GetWebAuthnUserByHandle(userHandle)
+		return user, err
+	}, ceremony.Session, parsed)
+	if err != nil {
+		respondLoginError(c, "webauthn assertion invalid. %s", webAuthnErrorDetail(err))
+		return
+	}
+	if user.Disabled {
+		respondLoginError(c, "user %s is disabled", user.Username)
+		return
+	}
+	if err := monitor_service.UpdateWebAuthnCredentialUsage(user, *credential); err != nil {
+		respondLoginError(c, "webauthn credential rejected. %w", err)
+		return
+	}
+
+	if !saveLoginSession(c, user.User) {
+		return
+	}
+	session := sessions.Default(c)
+	session.Set(authority.TotpValidatedKey, true)
+	saveSession(c, session)
+
+	c.JSON(http.StatusOK, gin.H{
+		"role":      user.Role,
+		"username":  user.Username,
+		"enable2FA": user.Enable2FA,
+	})
+}
+
+// newWebAuthn 根据配置和请求的 Host, Origin 请求头创建 WebAuthn relying party, 创建失败时响应错误并返回 false.
+func newWebAuthn(c *gin.Context) (*webauthn.WebAuthn, bool) {
+	instance, err := authority.NewWebAuthn(configuration.Get().ServerMonitor.WebAuthn, c.Request.Host, c.GetHeader("Origin"))
+	if err != nil {
+		respondEntityValidationError(c, "webauthn is unavailable for this origin. %s", err)
+		return nil, false
+	}
+
+	return instance, true
+}
+
+// saveWebAuthnCeremony 将进行中的流程保存到 session 中, 保存失败时响应错误并返回 false.
+func saveWebAuthnCeremony(c *gin.Context, ceremony webAuthnCeremony) bool {
+	session := sessions.Default(c)
+	session.Set(webAuthnCeremonyKey, ceremony)
+	saveSession(c, session)
+
+	return !c.IsAborted()
+}
+
+// takeWebAuthnCeremony 取出并删除 session 中进行中的流程, 每个 challenge 只能使用一次. 流程不存在或类型不匹配时响应错误并返回 false.
+func takeWebAuthnCeremony(c *gin.Context, kind string) (webAuthnCeremony, bool) {
+	session := sessions.Default(c)
+
+	ceremony, ok := session.Get(webAuthnCeremonyKey).(webAuthnCeremony)
+	if ok {
+		session.Delete(webAuthnCeremonyKey)
+		saveSession(c, session)
+	}
+	if c.IsAborted() {
+		return ceremony, false
+	}
+	if !ok || ceremony.Kind != kind {
+		respondLoginError(c, "webauthn %s ceremony not started", kind)
+		return ceremony, false
+	}
+
+	return ceremony, true
+}
+
+// webAuthnErrorDetail 获取 WebAuthn 错误的详细信息, [protocol.Error] 的 Error() 只包含简要描述.
+func webAuthnErrorDetail(err error) string {
+	var protocolError *protocol.Error
+	if errors.As(err, &protocolError) && len(protocolError.DevInfo) > 0 {
+		return protocolError.Details + ", " + protocolError.DevInfo
+	}
+
+	return err.Error()
+}
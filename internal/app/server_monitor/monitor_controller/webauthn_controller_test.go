@@ -0,0 +1,298 @@
+package monitor_controller
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/authority"
+	"github.com/siaikin/home-dashboard/internal/pkg/database"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const (
+	webAuthnTestRPID   = "dashboard.test"
+	webAuthnTestOrigin = "http://dashboard.test"
+)
+
+// softAuthenticator 测试用的软件认证器, 使用 ECDSA P-256 密钥和 none 证明格式.
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialId []byte
+	counter      uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentialId := make([]byte, 16)
+	_, _ = rand.Read(credentialId)
+
+	return &softAuthenticator{key: key, credentialId: credentialId}
+}
+
+// authData 生成认证器数据. flags: UP(0x01) | UV(0x04), 注册时附加 AT(0x40) 和凭据公钥.
+func (a *softAuthenticator) authData(t *testing.T, attested bool) []byte {
+	rpIdHash := sha256.Sum256([]byte(webAuthnTestRPID))
+	data := append([]byte{}, rpIdHash[:]...)
+
+	a.counter++
+	counter := make([]byte, 4)
+	binary.BigEndian.PutUint32(counter, a.counter)
+
+	if !attested {
+		return append(append(data, 0x05), counter...)
+	}
+
+	publicKey, err := cbor.Marshal(map[int]any{
+		1:  2,
+		3:  -7,
+		-1: 1,
+		-2: a.key.PublicKey.X.FillBytes(make([]byte, 32)),
+		-3: a.key.PublicKey.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data = append(append(data, 0x45), counter...)
+	// aaguid
+	data = append(data, make([]byte, 16)...)
+	data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialId)))
+	data = append(data, a.credentialId...)
+
+	return append(data, publicKey...)
+}
+
+func clientDataJSON(ceremony string, challenge string) []byte {
+	data, _ := json.Marshal(map[string]any{"type": ceremony, "challenge": challenge, "origin": webAuthnTestOrigin})
+	return data
+}
+
+// create 模拟 navigator.credentials.create(), 返回提交给注册接口的请求体.
+func (a *softAuthenticator) create(t *testing.T, challenge string) string {
+	attestation, err := cbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": a.authData(t, true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := json.Marshal(map[string]any{
+		"id":    base64.RawURLEncoding.EncodeToString(a.credentialId),
+		"rawId": base64.RawURLEncoding.EncodeToString(a.credentialId),
+		"type":  "public-key",
+		"response": map[string]any{
+			"clientDataJSON":    base64.RawURLEncoding.EncodeToString(clientDataJSON("webauthn.create", challenge)),
+			"attestationObject": base64.RawURLEncoding.EncodeToString(attestation),
+		},
+	})
+
+	return string(body)
+}
+
+// get 模拟 navigator.credentials.get(), 返回提交给校验接口的请求体.
+func (a *softAuthenticator) get(t *testing.T, challenge string, userHandle []byte) string {
+	authData := a.authData(t, false)
+	clientData := clientDataJSON("webauthn.get", challenge)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := json.Marshal(map[string]any{
+		"id":    base64.RawURLEncoding.EncodeToString(a.credentialId),
+		"rawId": base64.RawURLEncoding.EncodeToString(a.credentialId),
+		"type":  "public-key",
+		"response": map[string]any{
+			"clientDataJSON":    base64.RawURLEncoding.EncodeToString(clientData),
+			"authenticatorData": base64.RawURLEncoding.EncodeToString(authData),
+			"signature":         base64.RawURLEncoding.EncodeToString(signature),
+			"userHandle":        base64.RawURLEncoding.EncodeToString(userHandle),
+		},
+	})
+
+	return string(body)
+}
+
+func newWebAuthnRouter() *gin.Engine {
+	router := newUserRouter()
+
+	router.POST("auth", Authorize)
+	router.POST("auth/webauthn/login/begin", BeginWebAuthnLogin)
+	router.POST("auth/webauthn/login/finish", FinishWebAuthnLogin)
+
+	authorized := router.Group("", authority.AuthorizeMiddleware(), ActiveUserMiddleware())
+	authorized.POST("auth/2fa/webauthn/validate/begin", BeginWebAuthnValidate2FA)
+	authorized.POST("auth/2fa/webauthn/validate/finish", FinishWebAuthnValidate2FA)
+
+	authorizedAnd2faValidated := authorized.Group("", authority.Authorize2FAMiddleware())
+	authorizedAnd2faValidated.POST("auth/2fa/webauthn/register/begin", BeginWebAuthnRegistration)
+	authorizedAnd2faValidated.POST("auth/2fa/webauthn/register/finish", FinishWebAuthnRegistration)
+	authorizedAnd2faValidated.GET("auth/2fa/webauthn/credentials", ListWebAuthnCredentials)
+	authorizedAnd2faValidated.DELETE("auth/2fa/webauthn/credentials/:id", DeleteWebAuthnCredential)
+	authorizedAnd2faValidated.GET("webauthn-test/me", func(c *gin.Context) {
+		c.JSON(http.StatusOK, c.MustGet(currentUserKey))
+	})
+
+	return router
+}
+
+// serveWebAuthn 以 webAuthnTestOrigin 发起请求, 返回响应和最新的 session Cookie.
+func serveWebAuthn(router *gin.Engine, method string, url string, body string, cookie *http.Cookie) (*httptest.ResponseRecorder, *http.Cookie) {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(method, webAuthnTestOrigin+url, strings.NewReader(body))
+	request.Header.Set("Origin", webAuthnTestOrigin)
+	if cookie != nil {
+		request.AddCookie(cookie)
+	}
+	router.ServeHTTP(recorder, request)
+
+	return recorder, latestSessionCookie(recorder, cookie)
+}
+
+// webAuthnChallenge 获取 navigator.credentials.create()/get() 参数中的 challenge.
+func webAuthnChallenge(t *testing.T, recorder *httptest.ResponseRecorder) string {
+	t.Helper()
+
+	var options struct {
+		PublicKey struct {
+			Challenge string `json:"challenge"`
+		} `json:"publicKey"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &options); recorder.Code != http.StatusOK || err != nil || len(options.PublicKey.Challenge) <= 0 {
+		t.Fatalf("begin webauthn ceremony responded %d, %s", recorder.Code, recorder.Body.String())
+	}
+
+	return options.PublicKey.Challenge
+}
+
+func TestWebAuthn(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := monitor_service.CreateUser(monitor_model.User{User: authority.User{Username: "webauthn-alice", Password: "alice"}, Role: monitor_model.RoleGuest}); err != nil {
+		t.Fatal(err)
+	}
+	defer monitor_db.GetDB().Unscoped().Where("username LIKE ?", "webauthn-%").Delete(&monitor_model.User{})
+	alice, _ := monitor_service.GetUserByName("webauthn-alice")
+	userHandle := []byte(strconv.FormatUint(uint64(alice.ID), 10))
+
+	router := newWebAuthnRouter()
+	authenticator := newSoftAuthenticator(t)
+
+	// 使用密码登录后注册凭据
+	recorder, cookie := serveWebAuthn(router, http.MethodPost, "/auth", `{"username":"webauthn-alice","password":"alice"}`, nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("password login responded %d, %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder, _ := serveWebAuthn(router, http.MethodPost, "/auth/2fa/webauthn/register/begin", `{"name":""}`, cookie); recorder.Code != http.StatusBadRequest {
+		t.Errorf("credential without name should be rejected, got %d", recorder.Code)
+	}
+	recorder, cookie = serveWebAuthn(router, http.MethodPost, "/auth/2fa/webauthn/register/begin", `{"name":"security key"}`, cookie)
+	challenge := webAuthnChallenge(t, recorder)
+	recorder, cookie = serveWebAuthn(router, http.MethodPost, "/auth/2fa/webauthn/register/finish", authenticator.create(t, challenge), cookie)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("finish webauthn registration responded %d, %s", recorder.Code, recorder.Body.String())
+	}
+
+	// 注册后开启 2FA, 当前会话不需要重新校验
+	if recorder, _ := serveWebAuthn(router, http.MethodGet, "/webauthn-test/me", "", cookie); recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `"enable2FA":true`) {
+		t.Errorf("registering session should stay validated with 2fa enabled, got %d, %s", recorder.Code, recorder.Body.String())
+	}
+	recorder, _ = serveWebAuthn(router, http.MethodGet, "/auth/2fa/webauthn/credentials", "", cookie)
+	var credentials []monitor_model.WebAuthnCredential
+	_ = json.Unmarshal(recorder.Body.Bytes(), &credentials)
+	if len(credentials) != 1 || credentials[0].Name != "security key" {
+		t.Fatalf("unexpected credentials %s", recorder.Body.String())
+	}
+
+	// 密码登录后需要通过 WebAuthn 校验 2FA
+	_, cookie = serveWebAuthn(router, http.MethodPost, "/auth", `{"username":"webauthn-alice","password":"alice"}`, nil)
+	if recorder, _ := serveWebAuthn(router, http.MethodGet, "/webauthn-test/me", "", cookie); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("2fa should be required after password login, got %d", recorder.Code)
+	}
+	recorder, cookie = serveWebAuthn(router, http.MethodPost, "/auth/2fa/webauthn/validate/begin", "", cookie)
+	challenge = webAuthnChallenge(t, recorder)
+	if recorder, _ := serveWebAuthn(router, http.MethodPost, "/auth/2fa/webauthn/validate/finish", authenticator.get(t, "forged", userHandle), cookie); recorder.Code != http.StatusBadRequest {
+		t.Errorf("assertion with forged challenge should be rejected, got %d", recorder.Code)
+	}
+	// 校验失败后 challenge 已失效, 需要重新开始
+	recorder, cookie = serveWebAuthn(router, http.MethodPost, "/auth/2fa/webauthn/validate/begin", "", cookie)
+	challenge = webAuthnChallenge(t, recorder)
+	recorder, cookie = serveWebAuthn(router, http.MethodPost, "/auth/2fa/webauthn/validate/finish", authenticator.get(t, challenge, userHandle), cookie)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("finish webauthn 2fa responded %d, %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder, _ := serveWebAuthn(router, http.MethodGet, "/webauthn-test/me", "", cookie); recorder.Code != http.StatusOK {
+		t.Errorf("2fa should be validated, got %d, %s", recorder.Code, recorder.Body.String())
+	}
+
+	// 通行密钥无密码登录
+	recorder, cookie = serveWebAuthn(router, http.MethodPost, "/auth/webauthn/login/begin", "", nil)
+	challenge = webAuthnChallenge(t, recorder)
+	assertion := authenticator.get(t, challenge, userHandle)
+	recorder, cookie = serveWebAuthn(router, http.MethodPost, "/auth/webauthn/login/finish", assertion, cookie)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("finish webauthn login responded %d, %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder, _ := serveWebAuthn(router, http.MethodGet, "/webauthn-test/me", "", cookie); recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "webauthn-alice") {
+		t.Errorf("passwordless login should be validated, got %d, %s", recorder.Code, recorder.Body.String())
+	}
+	stored, _ := monitor_service.ListWebAuthnCredentials(alice.ID)
+	if len(stored) != 1 || stored[0].LastUsedAt <= 0 || stored[0].Credential.Authenticator.SignCount != authenticator.counter {
+		t.Errorf("credential usage should be updated, got %+v", stored)
+	}
+
+	// 重放相同的断言(签名计数未增加)会被拒绝
+	recorder, replayCookie := serveWebAuthn(router, http.MethodPost, "/auth/webauthn/login/begin", "", nil)
+	authenticator.counter--
+	replay := authenticator.get(t, webAuthnChallenge(t, recorder), userHandle)
+	if recorder, _ := serveWebAuthn(router, http.MethodPost, "/auth/webauthn/login/finish", replay, replayCookie); recorder.Code != http.StatusBadRequest {
+		t.Errorf("assertion with stale sign count should be rejected, got %d", recorder.Code)
+	}
+	// 没有开始流程时直接提交断言会被拒绝
+	if recorder, _ := serveWebAuthn(router, http.MethodPost, "/auth/webauthn/login/finish", assertion, nil); recorder.Code != http.StatusBadRequest {
+		t.Errorf("assertion without ceremony should be rejected, got %d", recorder.Code)
+	}
+
+	// 被禁用的用户不能登录
+	monitor_db.GetDB().Model(&alice).Update("disabled", true)
+	recorder, disabledCookie := serveWebAuthn(router, http.MethodPost, "/auth/webauthn/login/begin", "", nil)
+	if recorder, _ := serveWebAuthn(router, http.MethodPost, "/auth/webauthn/login/finish", authenticator.get(t, webAuthnChallenge(t, recorder), userHandle), disabledCookie); recorder.Code != http.StatusBadRequest {
+		t.Errorf("disabled user should be rejected, got %d", recorder.Code)
+	}
+	monitor_db.GetDB().Model(&alice).Update("disabled", false)
+
+	// 删除最后一个凭据后关闭 2FA
+	recorder, _ = serveWebAuthn(router, http.MethodDelete, "/auth/2fa/webauthn/credentials/"+strconv.Itoa(int(credentials[0].ID)), "", cookie)
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `"enable2FA":false`) {
+		t.Errorf("delete credential responded %d, %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder, _ := serveWebAuthn(router, http.MethodDelete, "/auth/2fa/webauthn/credentials/"+strconv.Itoa(int(credentials[0].ID)), "", cookie); recorder.Code != http.StatusNotFound {
+		t.Errorf("deleted credential should not be found, got %d", recorder.Code)
+	}
+	if user, _ := monitor_service.GetUserByName("webauthn-alice"); user.Enable2FA {
+		t.Errorf("2fa should be disabled after deleting the last credential")
+	}
+}
@@ -13,20 +13,44 @@ var database *gorm.DB
 func Initial(db *gorm.DB) error {
 	database = db
 
-	return database.AutoMigrate(
+	if err := database.AutoMigrate(
 		&monitor_model.StoredSystemStat{},
 		&monitor_model.StoredSystemNetworkAdapterInfo{},
 		&monitor_model.StoredSystemDiskInfo{},
 		&monitor_model.StoredSystemCpuInfo{},
 		&monitor_model.User{},
+		&monitor_model.Role{},
 		&monitor_model.StoredConfiguration{},
 		&monitor_model.StoredNotification{},
 		&monitor_model.ShortcutSection{},
 		&monitor_model.ShortcutItem{},
 		&monitor_model.ShortcutIcon{},
 		&monitor_model.ShortcutSectionItemUsage{},
+		&monitor_model.ShortcutSectionItemDailyUsage{},
+		&monitor_model.ShortcutItemLinkCheck{},
+		&monitor_model.DiscoveredService{},
+		&monitor_model.DockerContainerShortcut{},
 		&monitor_model.UserAgent{},
-	)
+		&monitor_model.SearchProvider{},
+		&monitor_model.ApiToken{},
+		&monitor_model.WebAuthnCredential{},
+		&monitor_model.RecoveryCode{},
+		&monitor_model.LoginAttempt{},
+		&monitor_model.AuditLog{},
+		&monitor_model.NotificationChannel{},
+		&monitor_model.NotificationDelivery{},
+		&monitor_model.NotificationRoute{},
+		&monitor_model.NotificationQuietHours{},
+		&monitor_model.NotificationWebhook{},
+	); err != nil {
+		return err
+	}
+
+	// 审计日志只能追加, 在数据库层面禁止修改已有的记录
+	return database.Exec(`CREATE TRIGGER IF NOT EXISTS audit_logs_append_only BEFORE UPDATE ON audit_logs
+BEGIN
+	SELECT RAISE(ABORT, 'audit logs are append-only');
+END`).Error
 }
 
 func GetDB() *gorm.DB {
@@ -0,0 +1,18 @@
+package monitor_model
+
+// ApiToken 用户的个人 API 令牌, 用于脚本和自动化工具访问接口. 令牌明文只在创建时返回一次, 数据库中只存储哈希值.
+type ApiToken struct {
+	Model
+	UserId uint   `json:"userId" gorm:"index"`
+	Name   string `json:"name"`
+	// TokenHash 令牌的 sha256 哈希值.
+	TokenHash string `json:"-" gorm:"uniqueIndex"`
+	// Prefix 令牌的前几位字符, 用于在列表中区分令牌.
+	Prefix string `json:"prefix"`
+	// Scopes 令牌的权限范围, 见 authority.Scopes.
+	Scopes []string `json:"scopes" gorm:"serializer:json"`
+	// ExpiresAt 过期时间(毫秒时间戳), 为 0 时表示永不过期.
+	ExpiresAt int64 `json:"expiresAt"`
+	// LastUsedAt 最后一次使用的时间(毫秒时间戳).
+	LastUsedAt int64 `json:"lastUsedAt"`
+}
@@ -0,0 +1,36 @@
+package monitor_model
+
+// AuditLogOutcome 审计日志记录的操作结果.
+type AuditLogOutcome = string
+
+const (
+	AuditLogOutcomeSuccess AuditLogOutcome = "success"
+	AuditLogOutcomeFailure AuditLogOutcome = "failure"
+)
+
+// AuditLog 审计日志, 记录谁在什么时候做了什么. 只能追加, 不允许修改, 只会在超过保留时间后被删除.
+type AuditLog struct {
+	// Id 允许读和创建, 不可写
+	ID uint `gorm:"<-:create; primarykey" json:"id"`
+	// CreatedAt 创建时间(毫秒时间戳), 允许读和创建, 不可写
+	CreatedAt int64 `gorm:"<-:create; autoCreateTime:milli; index" json:"createdAt"`
+	// Actor 执行操作的用户名, 未登录时为空, 服务自身执行的操作为 system.
+	Actor     string `gorm:"<-:create; index" json:"actor"`
+	Ip        string `gorm:"<-:create" json:"ip"`
+	UserAgent string `gorm:"<-:create" json:"userAgent"`
+	Method    string `gorm:"<-:create" json:"method"`
+	// Route 匹配的路由, 如 /v1/web/shortcut/item/update/:id.
+	Route string `gorm:"<-:create; index" json:"route"`
+	// Action 操作名称, 如 auth.login, shortcut.item.update. 未指定时为请求方法和路由.
+	Action     string `gorm:"<-:create; index" json:"action"`
+	TargetType string `gorm:"<-:create; index" json:"targetType"`
+	TargetId   string `gorm:"<-:create" json:"targetId"`
+	// Before 和 After 分别为操作前后目标实体的 JSON 摘要, 敏感字段已被移除.
+	Before string `gorm:"<-:create" json:"before"`
+	After  string `gorm:"<-:create" json:"after"`
+	// Status 响应的 HTTP 状态码.
+	Status  int             `gorm:"<-:create" json:"status"`
+	Outcome AuditLogOutcome `gorm:"<-:create; index" json:"outcome"`
+	// Error 操作失败时的错误信息.
+	Error string `gorm:"<-:create" json:"error"`
+}
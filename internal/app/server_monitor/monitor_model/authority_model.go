@@ -5,17 +5,34 @@ import (
 	"github.com/siaikin/home-dashboard/internal/pkg/authority"
 )
 
+// UserRole 用户角色的 id, 对应 Role.ID.
 type UserRole = int
 
+// 内置角色的 id. 内置的管理员角色拥有所有权限.
 var (
 	RoleAdministrator UserRole = 1
 	RoleGuest                  = 2
 )
 
+// Role 角色, 即权限的集合.
+type Role struct {
+	Model
+	Name        string                 `json:"name" gorm:"uniqueIndex"`
+	Description string                 `json:"description"`
+	Permissions []authority.Permission `json:"permissions" gorm:"serializer:json"`
+	// BuiltIn 内置角色不能删除, 内置的管理员角色不能修改.
+	BuiltIn bool `json:"builtIn"`
+}
+
 type User struct {
 	Model `json:"model"`
 	authority.User
 	Role UserRole `json:"role"`
+	// Disabled 被禁用的用户无法登录, 已登录的会话也将失效.
+	Disabled bool `json:"disabled"`
+	// OidcIssuer 和 OidcSubject 用户第一次通过 OpenID Connect 登录时绑定的身份, 绑定后只允许该身份登录此用户.
+	OidcIssuer  string `json:"-"`
+	OidcSubject string `json:"-"`
 }
 
 func init() {
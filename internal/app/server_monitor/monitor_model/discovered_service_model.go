@@ -0,0 +1,36 @@
+package monitor_model
+
+import "github.com/siaikin/home-dashboard/internal/pkg/service_discovery"
+
+// DiscoveredServiceStatus 表示自动发现的服务在收件箱中的状态.
+type DiscoveredServiceStatus = string
+
+const (
+	// DiscoveredServiceStatusPending 等待处理.
+	DiscoveredServiceStatusPending DiscoveredServiceStatus = "pending"
+	// DiscoveredServiceStatusAccepted 已添加为快捷方式.
+	DiscoveredServiceStatusAccepted DiscoveredServiceStatus = "accepted"
+	// DiscoveredServiceStatusDismissed 已忽略, 再次发现时不会重新出现在收件箱中.
+	DiscoveredServiceStatusDismissed DiscoveredServiceStatus = "dismissed"
+)
+
+// DiscoveredService 自动发现的本机或局域网中的 web 服务.
+type DiscoveredService struct {
+	Model
+	// Url 服务的地址, 同一地址只保留一条记录.
+	Url    string                   `json:"url" gorm:"uniqueIndex"`
+	Host   string                   `json:"host"`
+	Port   uint16                   `json:"port"`
+	Source service_discovery.Source `json:"source"`
+	// Server 响应头中的 Server 字段.
+	Server          string                  `json:"server"`
+	Title           string                  `json:"title"`
+	Description     string                  `json:"description"`
+	IconUrl         string                  `json:"iconUrl"`
+	BackgroundColor string                  `json:"backgroundColor"`
+	Status          DiscoveredServiceStatus `json:"status"`
+	// ItemId 接受后创建的快捷方式.
+	ItemId uint `json:"itemId"`
+	// LastSeenAt 最后一次发现该服务的时间(毫秒时间戳).
+	LastSeenAt int64 `json:"lastSeenAt"`
+}
@@ -0,0 +1,15 @@
+package monitor_model
+
+// DockerContainerShortcut 记录根据 Docker 容器标签自动创建的快捷方式.
+type DockerContainerShortcut struct {
+	Model
+	// ContainerName 容器名称. 容器重建后 id 会改变, 因此以名称作为唯一标识.
+	ContainerName string `json:"containerName" gorm:"uniqueIndex"`
+	ContainerId   string `json:"containerId"`
+	ItemId        uint   `json:"itemId"`
+	SectionId     uint   `json:"sectionId"`
+	// SectionCreated 分组是否由 Docker 集成自动创建. 自动创建的分组在其中的快捷方式全部归档后也会被归档.
+	SectionCreated bool `json:"sectionCreated"`
+	// Archived 容器停止后快捷方式会被归档(软删除), 容器重新启动后恢复.
+	Archived bool `json:"archived"`
+}
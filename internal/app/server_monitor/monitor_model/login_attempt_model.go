@@ -0,0 +1,20 @@
+package monitor_model
+
+// LoginAttemptKind 登录尝试的类型.
+type LoginAttemptKind = string
+
+const (
+	// LoginAttemptKindPassword 用户名密码登录.
+	LoginAttemptKindPassword LoginAttemptKind = "password"
+	// LoginAttemptKind2FA 2FA 验证码或恢复码校验.
+	LoginAttemptKind2FA LoginAttemptKind = "2fa"
+)
+
+// LoginAttempt 登录尝试记录, 用于登录暴力破解防护, 管理员可以查看失败的登录尝试.
+type LoginAttempt struct {
+	Model
+	Username string           `json:"username" gorm:"index"`
+	Ip       string           `json:"ip" gorm:"index"`
+	Kind     LoginAttemptKind `json:"kind"`
+	Success  bool             `json:"success"`
+}
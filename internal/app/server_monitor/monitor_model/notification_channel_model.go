@@ -0,0 +1,44 @@
+package monitor_model
+
+import "github.com/siaikin/home-dashboard/internal/pkg/notification_channel"
+
+// NotificationChannel 通知的外部投递渠道. 新的未读用户通知会被转发到所有启用的渠道.
+type NotificationChannel struct {
+	Model
+	Name   string                      `gorm:"uniqueIndex" json:"name"`
+	Type   notification_channel.Type   `json:"type"`
+	Enable bool                        `json:"enable"`
+	Config notification_channel.Config `gorm:"serializer:json" json:"config"`
+	// UserId 渠道所属的用户, 投递时遵循该用户的免打扰时段. 为 0 时表示共享的渠道, 不受免打扰时段限制.
+	UserId uint `gorm:"index" json:"userId"`
+}
+
+// NotificationDeliveryStatus 通知投递的状态.
+type NotificationDeliveryStatus = string
+
+const (
+	// NotificationDeliveryStatusPending 投递中, 包括等待重试.
+	NotificationDeliveryStatusPending NotificationDeliveryStatus = "pending"
+	NotificationDeliveryStatusSuccess NotificationDeliveryStatus = "success"
+	// NotificationDeliveryStatusFailure 重试次数用尽后仍然失败.
+	NotificationDeliveryStatusFailure NotificationDeliveryStatus = "failure"
+	// NotificationDeliveryStatusSkipped 因免打扰时段, 频率限制或重复通知而未投递, 原因记录在 Error 中.
+	NotificationDeliveryStatusSkipped NotificationDeliveryStatus = "skipped"
+)
+
+// NotificationDelivery 通知投递记录, 每条通知在每个渠道上只会投递一次.
+type NotificationDelivery struct {
+	Model
+	ChannelId uint `gorm:"index" json:"channelId"`
+	// UniqueId 投递的通知的 notification.UserNotification.UniqueId.
+	UniqueId string `gorm:"index" json:"uniqueId"`
+	Title    string `json:"title"`
+	// Fingerprint 通知来源, 标题和描述的摘要, 用于识别反复出现的相同通知.
+	Fingerprint string `gorm:"index" json:"fingerprint"`
+	// Test 是否为手动触发的测试通知.
+	Test     bool                       `json:"test"`
+	Status   NotificationDeliveryStatus `gorm:"index" json:"status"`
+	Attempts int                        `json:"attempts"`
+	// Error 最近一次投递失败的错误信息, 或跳过投递的原因.
+	Error string `json:"error"`
+}
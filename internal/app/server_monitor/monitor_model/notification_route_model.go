@@ -0,0 +1,48 @@
+package monitor_model
+
+import "github.com/siaikin/home-dashboard/internal/pkg/notification"
+
+// NotificationRouteAction 通知路由规则匹配后执行的动作.
+type NotificationRouteAction = string
+
+const (
+	// NotificationRouteActionRoute 只投递到规则指定的渠道, 不再匹配后续的规则.
+	NotificationRouteActionRoute NotificationRouteAction = "route"
+	// NotificationRouteActionSuppress 不投递到任何渠道, 不再匹配后续的规则. 通知仍会显示在面板中.
+	NotificationRouteActionSuppress NotificationRouteAction = "suppress"
+	// NotificationRouteActionDowngrade 将通知的类型修改为 DowngradeTo, 然后继续匹配后续的规则.
+	NotificationRouteActionDowngrade NotificationRouteAction = "downgrade"
+)
+
+// NotificationRoute 通知的路由规则. 规则按 Priority 从小到大依次匹配, 没有规则决定投递目标时通知会投递到所有启用的渠道.
+// 匹配条件为空时表示匹配任意值, 所有条件都满足时规则才会匹配.
+type NotificationRoute struct {
+	Model
+	Name     string `json:"name"`
+	Enable   bool   `json:"enable"`
+	Priority int    `gorm:"index" json:"priority"`
+	// Origins 匹配的通知来源.
+	Origins []notification.UserNotificationOrigin `gorm:"serializer:json" json:"origins"`
+	// Kinds 匹配的通知类型.
+	Kinds []notification.UserNotificationKind `gorm:"serializer:json" json:"kinds"`
+	// TitlePattern 和 CaptionPattern 分别为匹配通知标题和描述的正则表达式.
+	TitlePattern   string                  `json:"titlePattern"`
+	CaptionPattern string                  `json:"captionPattern"`
+	Action         NotificationRouteAction `json:"action"`
+	// ChannelIds 动作为 route 时投递的目标渠道.
+	ChannelIds []uint `gorm:"serializer:json" json:"channelIds"`
+	// DowngradeTo 动作为 downgrade 时修改后的通知类型.
+	DowngradeTo notification.UserNotificationKind `json:"downgradeTo"`
+}
+
+// NotificationQuietHours 用户的免打扰时段. 免打扰时段内, 除了 error 类型的通知外, 不会投递到该用户的通知渠道.
+type NotificationQuietHours struct {
+	Model
+	UserId uint `gorm:"uniqueIndex" json:"userId"`
+	Enable bool `json:"enable"`
+	// Start 和 End 为一天中的时间, 格式为 15:04. Start 大于 End 时表示跨越午夜, 如 22:00 至 07:00.
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// Timezone IANA 时区名称, 如 Asia/Shanghai. 为空时使用服务器的时区.
+	Timezone string `json:"timezone"`
+}
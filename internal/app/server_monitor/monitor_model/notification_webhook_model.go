@@ -0,0 +1,18 @@
+package monitor_model
+
+import "github.com/siaikin/home-dashboard/internal/pkg/notification_webhook"
+
+// NotificationWebhook 入站 Webhook, 外部系统(CI, 定时任务, Alertmanager 等)通过它向面板发送通知.
+// 每个 Webhook 对应一个通知来源, 使用独立的令牌. 令牌明文只在创建和重新生成时返回一次, 数据库中只存储哈希值.
+type NotificationWebhook struct {
+	Model
+	Name   string                      `json:"name" gorm:"uniqueIndex"`
+	Enable bool                        `json:"enable"`
+	Format notification_webhook.Format `json:"format"`
+	// TokenHash 令牌的 sha256 哈希值.
+	TokenHash string `json:"-"`
+	// Prefix 令牌的前几位字符, 用于在列表中区分令牌.
+	Prefix string `json:"prefix"`
+	// LastReceivedAt 最后一次收到通知的时间(毫秒时间戳).
+	LastReceivedAt int64 `json:"lastReceivedAt"`
+}
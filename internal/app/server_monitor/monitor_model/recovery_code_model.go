@@ -0,0 +1,11 @@
+package monitor_model
+
+// RecoveryCode 2FA 的一次性恢复码, 用于丢失身份验证器时通过 2FA 校验. 数据库中只存储哈希值.
+type RecoveryCode struct {
+	Model
+	UserId uint `json:"userId" gorm:"index"`
+	// CodeHash 恢复码的 sha256 哈希值.
+	CodeHash string `json:"-" gorm:"index"`
+	// UsedAt 使用时间(毫秒时间戳), 为 0 时表示未使用.
+	UsedAt int64 `json:"usedAt"`
+}
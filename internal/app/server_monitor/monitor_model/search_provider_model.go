@@ -0,0 +1,21 @@
+package monitor_model
+
+// SearchProviderQueryPlaceholder 搜索链接模板中搜索内容的占位符.
+const SearchProviderQueryPlaceholder = "{query}"
+
+// SearchProvider 仪表盘搜索框使用的搜索引擎.
+type SearchProvider struct {
+	Model
+	Name string `json:"name"`
+	// Keyword 快捷关键字, 在搜索内容前输入 !keyword 即可使用该搜索引擎, 如 !gh.
+	Keyword string `json:"keyword" gorm:"index"`
+	// UrlTemplate 搜索结果页的链接模板, 使用 {query} 作为搜索内容的占位符.
+	UrlTemplate string `json:"urlTemplate"`
+	// SuggestionUrlTemplate 搜索建议接口的链接模板, 接口需要返回 OpenSearch 格式的 JSON 数据 [query, [suggestion, ...]].
+	// 为空时表示不支持搜索建议.
+	SuggestionUrlTemplate string `json:"suggestionUrlTemplate"`
+	// Icon 图标链接或 simple-icons 的 slug.
+	Icon string `json:"icon"`
+	// Default 未指定快捷关键字时使用的搜索引擎, 只能有一个.
+	Default bool `json:"default"`
+}
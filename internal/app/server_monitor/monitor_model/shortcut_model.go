@@ -1,13 +1,26 @@
 package monitor_model
 
+import "github.com/siaikin/home-dashboard/internal/pkg/link_checker"
+
 type ShortcutSection struct {
 	Model
 	Name    string         `json:"name"`
 	Icon    string         `json:"icon"`
 	Default bool           `json:"default"`
 	Items   []ShortcutItem `json:"items" gorm:"many2many:shortcut_section_link_shortcut_item;"`
+	// Virtual 虚拟分组的类型. 虚拟分组根据使用情况计算得出, 不会存储到数据库中. 为空时表示普通分组.
+	Virtual ShortcutSectionVirtualType `json:"virtual,omitempty" gorm:"-"`
 }
 
+type ShortcutSectionVirtualType = string
+
+const (
+	// ShortcutSectionVirtualTypeRecent 最近使用的快捷方式.
+	ShortcutSectionVirtualTypeRecent ShortcutSectionVirtualType = "recent"
+	// ShortcutSectionVirtualTypeFrequent 最常使用的快捷方式.
+	ShortcutSectionVirtualTypeFrequent ShortcutSectionVirtualType = "frequent"
+)
+
 type ShortcutItemTargetType int
 
 var (
@@ -57,4 +70,35 @@ type ShortcutSectionItemUsage struct {
 	ItemId    uint `json:"itemId" gorm:"primaryKey;autoIncrement:false"`
 	// 点击次数.
 	ClickCount int `json:"clickCount"`
+	// LastClickedAt 最后一次点击的时间(毫秒时间戳).
+	LastClickedAt int64 `json:"lastClickedAt"`
+}
+
+// ShortcutSectionItemDailyUsage 按天汇总的分组下的快捷方式使用情况.
+type ShortcutSectionItemDailyUsage struct {
+	SectionId uint `json:"sectionId" gorm:"primaryKey;autoIncrement:false"`
+	ItemId    uint `json:"itemId" gorm:"primaryKey;autoIncrement:false"`
+	// Day 当天零点(服务器本地时区)的毫秒时间戳.
+	Day int64 `json:"day" gorm:"primaryKey;autoIncrement:false"`
+	// 当天的点击次数.
+	ClickCount int `json:"clickCount"`
+	// LastClickedAt 当天最后一次点击的时间(毫秒时间戳).
+	LastClickedAt int64 `json:"lastClickedAt"`
+}
+
+// ShortcutItemLinkCheck 快捷方式链接的检查结果, 每个快捷方式仅保留最近一次的检查结果.
+type ShortcutItemLinkCheck struct {
+	ItemId uint          `json:"itemId" gorm:"primaryKey;autoIncrement:false"`
+	Item   *ShortcutItem `json:"item,omitempty" gorm:"foreignKey:ItemId"`
+	// Url 被检查的链接.
+	Url    string              `json:"url"`
+	Status link_checker.Status `json:"status"`
+	// StatusCode 最后一次请求的响应状态码, 请求失败时为 0.
+	StatusCode   int                `json:"statusCode"`
+	Error        string             `json:"error"`
+	Redirects    []link_checker.Hop `json:"redirects" gorm:"serializer:json"`
+	FinalUrl     string             `json:"finalUrl"`
+	SuggestedUrl string             `json:"suggestedUrl"`
+	// CheckedAt 检查的时间(毫秒时间戳).
+	CheckedAt int64 `json:"checkedAt"`
 }
@@ -6,4 +6,6 @@ import "github.com/siaikin/home-dashboard/internal/pkg/notification"
 type StoredNotification struct {
 	Model
 	notification.UserNotification
+	// Archived 通知是否已归档. 归档的通知仍然保留在历史记录中, 不影响已读状态.
+	Archived bool `gorm:"index" json:"archived"`
 }
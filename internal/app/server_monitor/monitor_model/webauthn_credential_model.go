@@ -0,0 +1,16 @@
+package monitor_model
+
+import "github.com/go-webauthn/webauthn/webauthn"
+
+// WebAuthnCredential 用户注册的 WebAuthn 凭据(通行密钥或安全密钥), 可用于 2FA 校验或无密码登录.
+type WebAuthnCredential struct {
+	Model
+	UserId uint   `json:"userId" gorm:"index"`
+	Name   string `json:"name"`
+	// CredentialId 凭据 id, 用于在登录时查找凭据.
+	CredentialId []byte `json:"-" gorm:"uniqueIndex"`
+	// Credential 凭据的公钥, 签名计数等信息.
+	Credential webauthn.Credential `json:"-" gorm:"serializer:json"`
+	// LastUsedAt 最后一次使用的时间(毫秒时间戳).
+	LastUsedAt int64 `json:"lastUsedAt"`
+}
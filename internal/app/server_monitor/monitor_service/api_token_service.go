@@ -0,0 +1,92 @@
+package monitor_service
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/pkg/authority"
+	"time"
+)
+
+var apiTokenModel = monitor_model.ApiToken{}
+
+// apiTokenTouchInterval 令牌最后使用时间的最小更新间隔, 避免每个请求都写入数据库.
+const apiTokenTouchInterval = time.Minute
+
+// apiTokenDisplayPrefixLength 列表中展示的令牌前缀长度.
+const apiTokenDisplayPrefixLength = len(authority.ApiTokenPrefix) + 8
+
+var ErrorInvalidApiToken = errors.New("invalid or expired api token")
+
+// CreateApiToken 为用户创建 API 令牌, 返回令牌明文. 令牌明文无法再次获取.
+func CreateApiToken(token monitor_model.ApiToken) (string, monitor_model.ApiToken, error) {
+	db := monitor_db.GetDB()
+
+	plaintext, err := authority.GenerateApiToken()
+	if err != nil {
+		return "", token, err
+	}
+	token.TokenHash = authority.HashApiToken(plaintext)
+	token.Prefix = plaintext[:apiTokenDisplayPrefixLength]
+
+	result := db.Create(&token)
+
+	return plaintext, token, result.Error
+}
+
+// ListApiTokens 获取用户的所有 API 令牌, 按创建时间倒序排列.
+func ListApiTokens(userId uint) ([]monitor_model.ApiToken, error) {
+	db := monitor_db.GetDB()
+
+	tokens := make([]monitor_model.ApiToken, 0)
+	result := db.Model(&apiTokenModel).Where("user_id = ?", userId).Order("created_at DESC").Find(&tokens)
+
+	return tokens, result.Error
+}
+
+// RevokeApiToken 撤销用户的 API 令牌, 令牌不存在时返回 ErrorNotFound.
+func RevokeApiToken(userId uint, id uint) error {
+	db := monitor_db.GetDB()
+
+	result := db.Where("user_id = ? AND id = ?", userId, id).Delete(&apiTokenModel)
+	if result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected <= 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+// VerifyApiToken 校验 API 令牌, 返回令牌所属的用户和令牌的权限范围. 用于 authority.SetTokenVerifier.
+func VerifyApiToken(plaintext string) (authority.User, []authority.Scope, error) {
+	db := monitor_db.GetDB()
+
+	tokens := make([]monitor_model.ApiToken, 0)
+	if result := db.Model(&apiTokenModel).Where("token_hash = ?", authority.HashApiToken(plaintext)).Limit(1).Find(&tokens); result.Error != nil {
+		return authority.User{}, nil, result.Error
+	} else if len(tokens) <= 0 {
+		return authority.User{}, nil, ErrorInvalidApiToken
+	}
+	token := tokens[0]
+
+	now := time.Now()
+	if token.ExpiresAt > 0 && now.UnixMilli() >= token.ExpiresAt {
+		return authority.User{}, nil, ErrorInvalidApiToken
+	}
+
+	user, err := GetUserById(token.UserId)
+	if errors.Is(err, ErrorNotFound) {
+		return authority.User{}, nil, ErrorInvalidApiToken
+	} else if err != nil {
+		return authority.User{}, nil, err
+	}
+
+	if now.Sub(time.UnixMilli(token.LastUsedAt)) >= apiTokenTouchInterval {
+		if result := db.Model(&token).UpdateColumn("last_used_at", now.UnixMilli()); result.Error != nil {
+			logger.Error("update api token last used time failed, %s\n", result.Error)
+		}
+	}
+
+	return authority.User{Username: user.Username}, token.Scopes, nil
+}
@@ -0,0 +1,98 @@
+package monitor_service
+
+import (
+	"encoding/json"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"time"
+)
+
+var auditLogModel = monitor_model.AuditLog{}
+
+// AuditActorSystem 服务自身执行的操作(如启动时检测到配置文件变更)记录的操作者.
+const AuditActorSystem = "system"
+
+// maxAuditSummaryLength 审计日志中操作前后摘要的最大长度, 超出部分被截断.
+const maxAuditSummaryLength = 4096
+
+// AuditLogFilter 查询审计日志的过滤条件, 为空的字段不参与过滤.
+type AuditLogFilter struct {
+	Actor      string
+	Action     string
+	Route      string
+	TargetType string
+	TargetId   string
+	Outcome    monitor_model.AuditLogOutcome
+	// From 和 To 为创建时间的范围(毫秒时间戳), 包含边界.
+	From int64
+	To   int64
+}
+
+// CreateAuditLog 追加一条审计日志.
+func CreateAuditLog(log monitor_model.AuditLog) error {
+	db := monitor_db.GetDB()
+
+	return db.Create(&log).Error
+}
+
+// AuditSummary 将操作前后的目标实体序列化为 JSON 摘要, 超过 maxAuditSummaryLength 时截断. v 为 nil 时返回空字符串.
+// 调用方需要自行移除敏感字段.
+func AuditSummary(v any) string {
+	if v == nil {
+		return ""
+	}
+
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	if len(bytes) > maxAuditSummaryLength {
+		return string(bytes[:maxAuditSummaryLength]) + "...(truncated)"
+	}
+
+	return string(bytes)
+}
+
+// ListAuditLogs 分页获取符合过滤条件的审计日志, 按时间倒序排列. page 从 1 开始, 同时返回符合条件的记录总数.
+func ListAuditLogs(filter AuditLogFilter, page int, pageSize int) ([]monitor_model.AuditLog, int64, error) {
+	db := monitor_db.GetDB()
+
+	query := db.Model(&auditLogModel)
+	for column, value := range map[string]string{
+		"actor":       filter.Actor,
+		"action":      filter.Action,
+		"route":       filter.Route,
+		"target_type": filter.TargetType,
+		"target_id":   filter.TargetId,
+		"outcome":     filter.Outcome,
+	} {
+		if len(value) > 0 {
+			query = query.Where(column+" = ?", value)
+		}
+	}
+	if filter.From > 0 {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if filter.To > 0 {
+		query = query.Where("created_at <= ?", filter.To)
+	}
+
+	total := int64(0)
+	if result := query.Count(&total); result.Error != nil {
+		return nil, 0, result.Error
+	}
+
+	logs := make([]monitor_model.AuditLog, 0)
+	result := query.Order("created_at DESC, id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&logs)
+
+	return logs, total, result.Error
+}
+
+// PurgeExpiredAuditLogs 删除超过保留时间的审计日志. 这是审计日志唯一的删除方式.
+func PurgeExpiredAuditLogs(retention time.Duration) (int64, error) {
+	db := monitor_db.GetDB()
+
+	result := db.Where("created_at < ?", time.Now().Add(-retention).UnixMilli()).Delete(&auditLogModel)
+
+	return result.RowsAffected, result.Error
+}
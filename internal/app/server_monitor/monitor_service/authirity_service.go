@@ -5,18 +5,41 @@ import (
 	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
 	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
 	"github.com/siaikin/home-dashboard/internal/pkg/authority"
+	"gorm.io/gorm"
 )
 
 var userModel = monitor_model.User{}
 
 var (
 	ErrorNotFound = errors.New("not found")
+	// ErrorLastAdministrator 操作会导致没有可用的管理员账号.
+	ErrorLastAdministrator = errors.New("at least one enabled administrator is required")
 )
 
 func GetUserByName(username string) (monitor_model.User, error) {
+	// 空的查询条件会被 gorm 忽略, 从而返回第一个用户
+	if len(username) <= 0 {
+		return monitor_model.User{}, ErrorNotFound
+	}
+
 	return GetUser(monitor_model.User{User: authority.User{Username: username}})
 }
 
+// GetUserById 通过 id 获取用户, 不存在时返回 ErrorNotFound.
+func GetUserById(id uint) (monitor_model.User, error) {
+	db := monitor_db.GetDB()
+
+	user := monitor_model.User{}
+	result := db.Model(&userModel).Where("id = ?", id).Limit(1).Find(&user)
+	if result.Error != nil {
+		return user, result.Error
+	} else if result.RowsAffected <= 0 {
+		return user, ErrorNotFound
+	}
+
+	return user, nil
+}
+
 func GetUser(query monitor_model.User) (monitor_model.User, error) {
 	db := monitor_db.GetDB()
 
@@ -31,14 +54,32 @@ func GetUser(query monitor_model.User) (monitor_model.User, error) {
 	}
 }
 
+// ListUsersByQuery 获取用户, 按创建时间排列.
+func ListUsersByQuery(query monitor_model.User) ([]monitor_model.User, error) {
+	db := monitor_db.GetDB()
+
+	users := make([]monitor_model.User, 0)
+	result := db.Model(&userModel).Where(&query).Order("id").Find(&users)
+
+	return users, result.Error
+}
+
+// CreateUser 创建用户, user.Password 为明文密码, 会被哈希后存储.
 func CreateUser(user monitor_model.User) error {
 	db := monitor_db.GetDB()
 
+	if len(user.Password) > 0 {
+		if err := SetUserPassword(&user, user.Password); err != nil {
+			return err
+		}
+	}
+
 	result := db.Create(&user)
 
 	return result.Error
 }
 
+// UpdateUser 更新用户. user.Password 会被原样存储, 修改密码需要先调用 SetUserPassword.
 func UpdateUser(user monitor_model.User) error {
 	db := monitor_db.GetDB()
 
@@ -47,12 +88,29 @@ func UpdateUser(user monitor_model.User) error {
 	return result.Error
 }
 
+// DeleteUser 删除用户及其所有 API 令牌, WebAuthn 凭据, 恢复码和免打扰时段. 用户的通知渠道变为共享的渠道.
 func DeleteUser(user monitor_model.User) error {
 	db := monitor_db.GetDB()
 
-	result := db.Delete(&user)
-
-	return result.Error
+	return db.Transaction(func(tx *gorm.DB) error {
+		if result := tx.Where("user_id = ?", user.ID).Delete(&apiTokenModel); result.Error != nil {
+			return result.Error
+		}
+		if result := tx.Where("user_id = ?", user.ID).Delete(&webAuthnCredentialModel); result.Error != nil {
+			return result.Error
+		}
+		if result := tx.Where("user_id = ?", user.ID).Delete(&recoveryCodeModel); result.Error != nil {
+			return result.Error
+		}
+		if result := tx.Where("user_id = ?", user.ID).Delete(&notificationQuietHoursModel); result.Error != nil {
+			return result.Error
+		}
+		if result := tx.Model(&notificationChannelModel).Where("user_id = ?", user.ID).Update("user_id", 0); result.Error != nil {
+			return result.Error
+		}
+
+		return tx.Delete(&user).Error
+	})
 }
 
 // CountUser 获取 User 记录的总条数
@@ -64,3 +122,92 @@ func CountUser(user monitor_model.User) (int64, error) {
 
 	return count, result.Error
 }
+
+// CountEnabledAdministrator 获取未被禁用的管理员账号数量, excludeId 对应的用户不计算在内.
+func CountEnabledAdministrator(excludeId uint) (int64, error) {
+	db := monitor_db.GetDB()
+
+	count := int64(0)
+	result := db.Model(&userModel).Where("role = ? AND disabled = ? AND id != ?", monitor_model.RoleAdministrator, false, excludeId).Count(&count)
+
+	return count, result.Error
+}
+
+// EnsureAdministratorRemains 检查对 user 的修改(降级, 禁用或删除)是否会导致没有可用的管理员账号, 是则返回 ErrorLastAdministrator.
+func EnsureAdministratorRemains(user monitor_model.User) error {
+	if user.Role != monitor_model.RoleAdministrator || user.Disabled {
+		return nil
+	}
+
+	if count, err := CountEnabledAdministrator(user.ID); err != nil {
+		return err
+	} else if count <= 0 {
+		return ErrorLastAdministrator
+	}
+
+	return nil
+}
+
+// VerifyUserPassword 校验用户的密码. 校验通过且存储的密码为明文或使用旧参数计算的哈希值时, 使用新的哈希值更新存储的密码.
+func VerifyUserPassword(user monitor_model.User, password string) (bool, error) {
+	ok, needsRehash := authority.VerifyPassword(user.Password, password)
+	if !ok || !needsRehash {
+		return ok, nil
+	}
+
+	hashed, err := authority.HashPassword(password)
+	if err != nil {
+		return false, err
+	}
+
+	db := monitor_db.GetDB()
+	if result := db.Model(&user).Update("password", hashed); result.Error != nil {
+		return false, result.Error
+	}
+
+	return true, nil
+}
+
+// MigratePlaintextPasswords 将旧版本存储的明文密码(包括已删除的用户)替换为哈希值.
+func MigratePlaintextPasswords() error {
+	db := monitor_db.GetDB()
+
+	users := make([]monitor_model.User, 0)
+	if result := db.Unscoped().Model(&userModel).Where("password != ''").Find(&users); result.Error != nil {
+		return result.Error
+	}
+
+	migrated := 0
+	for _, user := range users {
+		if authority.IsPasswordHash(user.Password) {
+			continue
+		}
+
+		hashed, err := authority.HashPassword(user.Password)
+		if err != nil {
+			return err
+		}
+		if result := db.Unscoped().Model(&user).Update("password", hashed); result.Error != nil {
+			return result.Error
+		}
+		migrated++
+	}
+
+	if migrated > 0 {
+		logger.Info("migrated %d plaintext passwords.\n", migrated)
+	}
+
+	return nil
+}
+
+// SetUserPassword 将 user 的密码设置为明文密码 plaintext 的哈希值.
+// plaintext 总是被当作明文处理, 即使它看起来像哈希值(如以 $argon2id$ 开头), 只有 MigratePlaintextPasswords 会跳过已经是哈希值的密码.
+func SetUserPassword(user *monitor_model.User, plaintext string) error {
+	hashed, err := authority.HashPassword(plaintext)
+	if err != nil {
+		return err
+	}
+	user.Password = hashed
+
+	return nil
+}
@@ -1,8 +1,10 @@
 package monitor_service
 
 import (
+	"encoding/json"
 	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
 	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/pkg/configuration"
 )
 
 var configurationModel = monitor_model.StoredConfiguration{}
@@ -34,10 +36,11 @@ func LatestNConfiguration(max int64) (*[]monitor_model.StoredConfiguration, erro
 	return &configs, result.Error
 }
 
-// CreateConfiguration 插入一条记录
+// CreateConfiguration 插入一条记录, 配置中的敏感信息不会被存储, 见 redactConfiguration.
 func CreateConfiguration(config monitor_model.StoredConfiguration) error {
 	db := monitor_db.GetDB()
 
+	redactConfiguration(&config.Configuration)
 	result := db.Create(&config)
 
 	return result.Error
@@ -61,3 +64,83 @@ func CountConfiguration() (*int64, error) {
 
 	return &count, result.Error
 }
+
+// AuditConfigurationChange 记录配置文件的变更, 只记录发生变化的 serverMonitor 配置项, 敏感信息不会被记录.
+func AuditConfigurationChange(previous configuration.Configuration, current configuration.Configuration) error {
+	redactConfiguration(&previous)
+	redactConfiguration(&current)
+
+	before, err := configurationSections(previous)
+	if err != nil {
+		return err
+	}
+	after, err := configurationSections(current)
+	if err != nil {
+		return err
+	}
+
+	changedBefore, changedAfter := map[string]json.RawMessage{}, map[string]json.RawMessage{}
+	for key, value := range after {
+		if string(before[key]) != string(value) {
+			changedBefore[key], changedAfter[key] = before[key], value
+		}
+	}
+	for key, value := range before {
+		if _, ok := after[key]; !ok {
+			changedBefore[key] = value
+		}
+	}
+	if len(changedBefore) <= 0 && len(changedAfter) <= 0 {
+		return nil
+	}
+
+	return CreateAuditLog(monitor_model.AuditLog{
+		Actor:      AuditActorSystem,
+		Action:     "configuration.update",
+		TargetType: "configuration",
+		Before:     AuditSummary(changedBefore),
+		After:      AuditSummary(changedAfter),
+		Outcome:    monitor_model.AuditLogOutcomeSuccess,
+	})
+}
+
+// configurationSections 将 serverMonitor 配置按配置项拆分为 JSON.
+func configurationSections(config configuration.Configuration) (map[string]json.RawMessage, error) {
+	bytes, err := json.Marshal(config.ServerMonitor)
+	if err != nil {
+		return nil, err
+	}
+
+	sections := map[string]json.RawMessage{}
+	err = json.Unmarshal(bytes, &sections)
+
+	return sections, err
+}
+
+// RedactStoredConfigurations 移除旧版本存储的配置记录中的敏感信息, 包括软删除的记录.
+func RedactStoredConfigurations() error {
+	db := monitor_db.GetDB()
+
+	configs := make([]monitor_model.StoredConfiguration, 0)
+	if result := db.Unscoped().Model(&configurationModel).Find(&configs); result.Error != nil {
+		return result.Error
+	}
+
+	for _, config := range configs {
+		if !redactConfiguration(&config.Configuration) {
+			continue
+		}
+
+		if result := db.Unscoped().Model(&config).Select("Configuration").Updates(&config); result.Error != nil {
+			return result.Error
+		}
+	}
+
+	return nil
+}
+
+// redactConfiguration 移除配置中的敏感信息, 即所有标记了 secret 标签的字段, 如管理员密码, 访问令牌和 OpenID Connect 的客户端密钥.
+// 返回是否有敏感信息被移除.
+func redactConfiguration(config *configuration.Configuration) bool {
+	return configuration.Redact(config)
+}
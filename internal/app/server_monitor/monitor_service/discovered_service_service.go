@@ -0,0 +1,162 @@
+package monitor_service
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/samber/lo"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"gorm.io/gorm"
+	"strings"
+)
+
+var discoveredServiceModel = monitor_model.DiscoveredService{}
+
+// ErrorAlreadyAccepted 自动发现的服务已被添加为快捷方式.
+var ErrorAlreadyAccepted = errors.New("discovered service already accepted")
+
+// SaveDiscoveredServices 保存自动发现的服务. 已存在的服务只更新服务信息和最后发现时间, 不会改变其状态.
+// 新发现的服务如果已有相同链接的快捷方式, 则直接标记为已接受, 否则进入收件箱等待处理. 返回新进入收件箱的服务.
+func SaveDiscoveredServices(services []monitor_model.DiscoveredService) ([]monitor_model.DiscoveredService, error) {
+	db := monitor_db.GetDB()
+
+	pending := make([]monitor_model.DiscoveredService, 0)
+	for _, service := range services {
+		existedList := make([]monitor_model.DiscoveredService, 0)
+		if result := db.Model(&discoveredServiceModel).Where(&monitor_model.DiscoveredService{Url: service.Url}).Limit(1).Find(&existedList); result.Error != nil {
+			return nil, result.Error
+		}
+
+		if len(existedList) > 0 {
+			existed := existedList[0]
+			service.ID = existed.ID
+			service.Status = existed.Status
+			service.ItemId = existed.ItemId
+			// 未重新获取网站信息时保留原有的信息.
+			if len(service.Title) <= 0 {
+				service.Title = existed.Title
+				service.Description = existed.Description
+				service.IconUrl = existed.IconUrl
+				service.BackgroundColor = existed.BackgroundColor
+			}
+
+			if result := db.Model(&existed).Select("Host", "Port", "Source", "Server", "Title", "Description", "IconUrl", "BackgroundColor", "LastSeenAt").Updates(&service); result.Error != nil {
+				return nil, result.Error
+			}
+			continue
+		}
+
+		service.Status = monitor_model.DiscoveredServiceStatusPending
+		if item, err := findShortcutItemByUrl(service.Url); err != nil {
+			return nil, err
+		} else if item != nil {
+			service.Status = monitor_model.DiscoveredServiceStatusAccepted
+			service.ItemId = item.ID
+		}
+
+		if result := db.Create(&service); result.Error != nil {
+			return nil, result.Error
+		}
+		if service.Status == monitor_model.DiscoveredServiceStatusPending {
+			pending = append(pending, service)
+		}
+	}
+
+	return pending, nil
+}
+
+// ListDiscoveredServicesByQuery 获取自动发现的服务, 按最后发现时间倒序排列.
+func ListDiscoveredServicesByQuery(query monitor_model.DiscoveredService) ([]monitor_model.DiscoveredService, error) {
+	db := monitor_db.GetDB()
+
+	services := make([]monitor_model.DiscoveredService, 0)
+	result := db.Model(&discoveredServiceModel).Where(&query).Order("last_seen_at desc").Find(&services)
+
+	return services, result.Error
+}
+
+// AcceptDiscoveredService 将自动发现的服务添加为快捷方式, 并加入 sectionIds 对应的分组. sectionIds 为空时加入默认分组.
+func AcceptDiscoveredService(id uint, sectionIds []uint) (monitor_model.ShortcutItem, error) {
+	db := monitor_db.GetDB()
+
+	service := monitor_model.DiscoveredService{}
+	if result := db.Model(&discoveredServiceModel).First(&service, id); errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return monitor_model.ShortcutItem{}, ErrorNotFound
+	} else if result.Error != nil {
+		return monitor_model.ShortcutItem{}, result.Error
+	} else if service.Status == monitor_model.DiscoveredServiceStatusAccepted {
+		return monitor_model.ShortcutItem{}, ErrorAlreadyAccepted
+	}
+
+	sections := make([]monitor_model.ShortcutSection, 0)
+	model := db.Model(&shortcutSectionModel)
+	if len(sectionIds) > 0 {
+		model = model.Where("id IN ?", lo.Uniq(sectionIds))
+	} else {
+		model = model.Where(&monitor_model.ShortcutSection{Default: true})
+	}
+	if result := model.Find(&sections); result.Error != nil {
+		return monitor_model.ShortcutItem{}, result.Error
+	} else if len(sectionIds) > 0 && len(sections) != len(lo.Uniq(sectionIds)) {
+		return monitor_model.ShortcutItem{}, ErrorNotFound
+	}
+
+	item := monitor_model.ShortcutItem{
+		Title:           lo.Ternary(len(service.Title) > 0, service.Title, service.Host),
+		Description:     service.Description,
+		URL:             service.Url,
+		IconType:        monitor_model.ShortcutItemIconTypeUrl,
+		IconUrl:         service.IconUrl,
+		Target:          monitor_model.ShortcutItemTargetTypeNewTab,
+		StatusCheck:     true,
+		StatusCheckUrl:  service.Url,
+		BackgroundColor: service.BackgroundColor,
+		Sections:        sections,
+	}
+	if len(item.IconUrl) <= 0 {
+		item.IconType = monitor_model.ShortcutItemIconTypeText
+		item.IconText = strings.ToUpper(string([]rune(item.Title)[:1]))
+	} else {
+		item.IconCachedUrl = GetCachedShortcutItemImageIconUrl(item)
+	}
+
+	created, err := CreateOrUpdateShortcutItems([]monitor_model.ShortcutItem{item})
+	if err != nil {
+		return monitor_model.ShortcutItem{}, err
+	}
+
+	if result := db.Model(&service).Select("Status", "ItemId").Updates(&monitor_model.DiscoveredService{Status: monitor_model.DiscoveredServiceStatusAccepted, ItemId: created[0].ID}); result.Error != nil {
+		return monitor_model.ShortcutItem{}, result.Error
+	}
+
+	return created[0], nil
+}
+
+// DismissDiscoveredService 忽略自动发现的服务, 再次发现该服务时不会重新出现在收件箱中.
+func DismissDiscoveredService(id uint) error {
+	db := monitor_db.GetDB()
+
+	result := db.Model(&discoveredServiceModel).Where("id = ?", id).Update("status", monitor_model.DiscoveredServiceStatusDismissed)
+	if result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected <= 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+// findShortcutItemByUrl 查找链接与 url 相同(忽略末尾的 /)的快捷方式, 不存在时返回 nil.
+func findShortcutItemByUrl(url string) (*monitor_model.ShortcutItem, error) {
+	db := monitor_db.GetDB()
+
+	trimmed := strings.TrimRight(url, "/")
+
+	items := make([]monitor_model.ShortcutItem, 0)
+	if result := db.Model(&shortcutItemModel).Where("url IN ?", []string{trimmed, trimmed + "/"}).Limit(1).Find(&items); result.Error != nil {
+		return nil, result.Error
+	} else if len(items) <= 0 {
+		return nil, nil
+	}
+
+	return &items[0], nil
+}
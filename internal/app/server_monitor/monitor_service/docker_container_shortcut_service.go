@@ -0,0 +1,191 @@
+package monitor_service
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var dockerContainerShortcutModel = monitor_model.DockerContainerShortcut{}
+
+// DockerContainerShortcutSpec 根据容器标签解析出的快捷方式信息.
+type DockerContainerShortcutSpec struct {
+	ContainerName string
+	ContainerId   string
+	// Item 快捷方式的信息, 只会使用 Title, Description, URL, Target 以及图标相关的字段.
+	Item monitor_model.ShortcutItem
+	// SectionName 快捷方式所在的分组名称, 分组不存在时自动创建. 为空时使用默认分组.
+	SectionName string
+}
+
+// ListDockerContainerShortcutsByQuery 获取根据 Docker 容器标签创建的快捷方式记录.
+func ListDockerContainerShortcutsByQuery(query monitor_model.DockerContainerShortcut) ([]monitor_model.DockerContainerShortcut, error) {
+	db := monitor_db.GetDB()
+
+	shortcuts := make([]monitor_model.DockerContainerShortcut, 0)
+	result := db.Model(&dockerContainerShortcutModel).Where(&query).Order("container_name").Find(&shortcuts)
+
+	return shortcuts, result.Error
+}
+
+// CreateOrUpdateDockerContainerShortcut 根据容器标签创建或更新快捷方式, 已归档的快捷方式和分组将被恢复.
+// 如果快捷方式的分组发生变化, 则将快捷方式从原分组移动到新分组.
+func CreateOrUpdateDockerContainerShortcut(spec DockerContainerShortcutSpec) (monitor_model.DockerContainerShortcut, error) {
+	db := monitor_db.GetDB()
+
+	// 缓存图标需要发起网络请求, 因此在事务之外进行. 图标地址未变化时复用已缓存的图标.
+	if spec.Item.IconType == monitor_model.ShortcutItemIconTypeUrl {
+		spec.Item.IconCachedUrl = cachedDockerShortcutIconUrl(spec)
+	}
+
+	shortcut := monitor_model.DockerContainerShortcut{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if result := tx.Model(&dockerContainerShortcutModel).Where(&monitor_model.DockerContainerShortcut{ContainerName: spec.ContainerName}).Limit(1).Find(&shortcut); result.Error != nil {
+			return result.Error
+		}
+
+		section, sectionCreated, err := findOrCreateDockerShortcutSection(tx, spec.SectionName)
+		if err != nil {
+			return err
+		}
+
+		item := monitor_model.ShortcutItem{}
+		if shortcut.ItemId != 0 {
+			if result := tx.Unscoped().Model(&shortcutItemModel).Limit(1).Find(&item, shortcut.ItemId); result.Error != nil {
+				return result.Error
+			}
+		}
+
+		item.Title = spec.Item.Title
+		item.Description = spec.Item.Description
+		item.URL = spec.Item.URL
+		item.Target = spec.Item.Target
+		item.StatusCheck = true
+		item.StatusCheckUrl = spec.Item.URL
+		item.IconType = spec.Item.IconType
+		item.IconText = spec.Item.IconText
+		item.IconID = spec.Item.IconID
+		item.IconUrl = spec.Item.IconUrl
+		item.IconCachedUrl = spec.Item.IconCachedUrl
+		item.DeletedAt = 0
+
+		if result := tx.Unscoped().Omit(clause.Associations).Save(&item); result.Error != nil {
+			return result.Error
+		}
+
+		if shortcut.SectionId != 0 && shortcut.SectionId != section.ID {
+			if err := tx.Model(&item).Association("Sections").Delete(&monitor_model.ShortcutSection{Model: monitor_model.Model{ID: shortcut.SectionId}}); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&item).Association("Sections").Append(&section); err != nil {
+			return err
+		}
+
+		shortcut.ContainerName = spec.ContainerName
+		shortcut.ContainerId = spec.ContainerId
+		shortcut.ItemId = item.ID
+		// 分组不变时保留原有的 SectionCreated, 以免恢复归档的分组后丢失该标记.
+		shortcut.SectionCreated = sectionCreated || (shortcut.SectionCreated && shortcut.SectionId == section.ID)
+		shortcut.SectionId = section.ID
+		shortcut.Archived = false
+
+		return tx.Save(&shortcut).Error
+	})
+
+	return shortcut, err
+}
+
+// ArchiveDockerContainerShortcut 归档(软删除)容器对应的快捷方式, 快捷方式与分组的关联关系会被保留, 以便容器重新启动后恢复.
+// 如果分组是自动创建的, 并且其中已没有未归档的快捷方式, 则一并归档该分组. 容器没有对应的快捷方式时返回 ErrorNotFound.
+func ArchiveDockerContainerShortcut(containerName string) error {
+	db := monitor_db.GetDB()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		shortcut := monitor_model.DockerContainerShortcut{}
+		if result := tx.Model(&dockerContainerShortcutModel).Where(&monitor_model.DockerContainerShortcut{ContainerName: containerName}).First(&shortcut); errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return ErrorNotFound
+		} else if result.Error != nil {
+			return result.Error
+		} else if shortcut.Archived {
+			return nil
+		}
+
+		if result := tx.Delete(&monitor_model.ShortcutItem{Model: monitor_model.Model{ID: shortcut.ItemId}}); result.Error != nil {
+			return result.Error
+		}
+
+		if result := tx.Model(&shortcut).Update("archived", true); result.Error != nil {
+			return result.Error
+		}
+
+		if !shortcut.SectionCreated {
+			return nil
+		}
+
+		remaining := tx.Model(&monitor_model.ShortcutSection{Model: monitor_model.Model{ID: shortcut.SectionId}}).Association("Items").Count()
+		if remaining > 0 {
+			return nil
+		}
+
+		return tx.Delete(&monitor_model.ShortcutSection{Model: monitor_model.Model{ID: shortcut.SectionId}}).Error
+	})
+}
+
+// cachedDockerShortcutIconUrl 获取容器快捷方式图标的缓存地址. 图标地址与已有的快捷方式相同时直接复用已缓存的图标.
+func cachedDockerShortcutIconUrl(spec DockerContainerShortcutSpec) string {
+	db := monitor_db.GetDB()
+
+	shortcuts := make([]monitor_model.DockerContainerShortcut, 0)
+	db.Model(&dockerContainerShortcutModel).Where(&monitor_model.DockerContainerShortcut{ContainerName: spec.ContainerName}).Limit(1).Find(&shortcuts)
+	if len(shortcuts) > 0 {
+		items := make([]monitor_model.ShortcutItem, 0)
+		db.Unscoped().Model(&shortcutItemModel).Limit(1).Find(&items, shortcuts[0].ItemId)
+		if len(items) > 0 && items[0].IconUrl == spec.Item.IconUrl && len(items[0].IconCachedUrl) > 0 {
+			return items[0].IconCachedUrl
+		}
+	}
+
+	return GetCachedShortcutItemImageIconUrl(spec.Item)
+}
+
+// findOrCreateDockerShortcutSection 根据名称查找分组, 已归档的分组将被恢复, 不存在时创建分组. name 为空时使用默认分组.
+// 第二个返回值表示分组是否为新创建的.
+func findOrCreateDockerShortcutSection(tx *gorm.DB, name string) (monitor_model.ShortcutSection, bool, error) {
+	sections := make([]monitor_model.ShortcutSection, 0)
+
+	if len(name) <= 0 {
+		if result := tx.Model(&shortcutSectionModel).Where(&monitor_model.ShortcutSection{Default: true}).Limit(1).Find(&sections); result.Error != nil {
+			return monitor_model.ShortcutSection{}, false, result.Error
+		} else if len(sections) <= 0 {
+			return monitor_model.ShortcutSection{}, false, errors.Errorf("default section not found")
+		}
+
+		return sections[0], false, nil
+	}
+
+	// 优先使用未删除的分组.
+	if result := tx.Unscoped().Model(&shortcutSectionModel).Where(&monitor_model.ShortcutSection{Name: name}).Order("deleted_at").Limit(1).Find(&sections); result.Error != nil {
+		return monitor_model.ShortcutSection{}, false, result.Error
+	}
+
+	if len(sections) > 0 {
+		section := sections[0]
+		if section.DeletedAt != 0 {
+			if result := tx.Unscoped().Model(&section).Update("deleted_at", 0); result.Error != nil {
+				return monitor_model.ShortcutSection{}, false, result.Error
+			}
+		}
+
+		return section, false, nil
+	}
+
+	section := monitor_model.ShortcutSection{Name: name}
+	if result := tx.Create(&section); result.Error != nil {
+		return monitor_model.ShortcutSection{}, false, result.Error
+	}
+
+	return section, true, nil
+}
@@ -0,0 +1,77 @@
+package monitor_service
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/samber/lo"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/pkg/authority"
+)
+
+var (
+	ErrorGroupNotAllowed      = errors.New("user is not in any allowed group")
+	ErrorExternalUserNotFound = errors.New("user not found and auto creation is disabled")
+	ErrorUserDisabled         = errors.New("user is disabled")
+)
+
+// ExternalUserPolicy 外部身份提供方认证的用户的登录和角色映射策略.
+type ExternalUserPolicy struct {
+	// AllowedGroups 允许登录的组, 为空时允许所有用户登录.
+	AllowedGroups []string
+	// AdministratorGroups 属于这些组的用户将成为管理员, 其他用户为访客. 为空时不修改已存在的用户的角色.
+	AdministratorGroups []string
+	// AutoCreateUser 用户不存在时是否自动创建.
+	AutoCreateUser bool
+}
+
+// ResolveExternalUser 获取由外部身份提供方(OpenID Connect, 反向代理)认证的用户, 用户不存在且开启了自动创建时创建用户.
+// 配置了管理员组时, 根据用户所属的组更新用户的角色. 自动创建的用户没有密码, 只能通过外部身份提供方登录.
+func ResolveExternalUser(policy ExternalUserPolicy, username string, groups []string) (monitor_model.User, error) {
+	if len(policy.AllowedGroups) > 0 && !lo.Some(groups, policy.AllowedGroups) {
+		return monitor_model.User{}, ErrorGroupNotAllowed
+	}
+
+	role := monitor_model.RoleGuest
+	if lo.Some(groups, policy.AdministratorGroups) {
+		role = monitor_model.RoleAdministrator
+	}
+
+	user, err := GetUserByName(username)
+	if errors.Is(err, ErrorNotFound) {
+		if !policy.AutoCreateUser {
+			return monitor_model.User{}, ErrorExternalUserNotFound
+		}
+
+		if err := CreateUser(monitor_model.User{User: authority.User{Username: username}, Role: role}); err != nil {
+			return monitor_model.User{}, err
+		}
+		logger.Info("user %s created by external login\n", username)
+
+		return GetUserByName(username)
+	} else if err != nil {
+		return monitor_model.User{}, err
+	}
+
+	if user.Disabled {
+		return monitor_model.User{}, ErrorUserDisabled
+	}
+
+	if len(policy.AdministratorGroups) <= 0 || user.Role == role {
+		return user, nil
+	}
+
+	// 降级最后一个管理员时保留其角色
+	if err := EnsureAdministratorRemains(user); errors.Is(err, ErrorLastAdministrator) {
+		logger.Warn("keep role of user %s, %s\n", user.Username, err)
+		return user, nil
+	} else if err != nil {
+		return monitor_model.User{}, err
+	}
+
+	updated := user
+	updated.Role = role
+	if err := UpdateUser(updated); err != nil {
+		return monitor_model.User{}, err
+	}
+
+	return updated, nil
+}
@@ -0,0 +1,260 @@
+package monitor_service
+
+import (
+	"fmt"
+	"github.com/go-errors/errors"
+	"github.com/samber/lo"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/pkg/configuration"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification"
+	"sync"
+	"time"
+)
+
+var loginAttemptModel = monitor_model.LoginAttempt{}
+
+// loginAttemptRetention 登录尝试记录的保留时间.
+const loginAttemptRetention = time.Hour * 24 * 30
+
+// loginAttemptMutex 保证检查失败次数和记录登录尝试之间没有其他请求, 防止并发请求绕过限制.
+var loginAttemptMutex sync.Mutex
+
+var (
+	ErrorLoginThrottled = errors.New("too many failed login attempts, try again later")
+	ErrorLoginLocked    = errors.New("login is temporarily locked due to too many failed attempts")
+)
+
+// LoginLockout 被暂时锁定的用户名或 IP.
+type LoginLockout struct {
+	Username string `json:"username,omitempty"`
+	Ip       string `json:"ip,omitempty"`
+	// Until 锁定的结束时间(毫秒时间戳).
+	Until int64 `json:"until"`
+}
+
+// loginProtectionPolicy 补全默认值后的登录暴力破解防护配置.
+type loginProtectionPolicy struct {
+	enable             bool
+	window             time.Duration
+	maxAttemptsPerUser int
+	maxAttemptsPerIp   int
+	backoffBase        time.Duration
+	backoffMax         time.Duration
+	lockoutDuration    time.Duration
+}
+
+func newLoginProtectionPolicy(config configuration.ServerMonitorLoginProtectionConfiguration) loginProtectionPolicy {
+	return loginProtectionPolicy{
+		enable:             config.Enable,
+		window:             lo.Ternary(config.Window > 0, config.Window*time.Second, 15*time.Minute),
+		maxAttemptsPerUser: lo.Ternary(config.MaxAttemptsPerUser > 0, config.MaxAttemptsPerUser, 5),
+		maxAttemptsPerIp:   lo.Ternary(config.MaxAttemptsPerIp > 0, config.MaxAttemptsPerIp, 20),
+		backoffBase:        lo.Ternary(config.BackoffBase > 0, config.BackoffBase*time.Second, time.Second),
+		backoffMax:         lo.Ternary(config.BackoffMax > 0, config.BackoffMax*time.Second, time.Minute),
+		lockoutDuration:    lo.Ternary(config.LockoutDuration > 0, config.LockoutDuration*time.Second, 15*time.Minute),
+	}
+}
+
+// retryAfter 根据最近的失败记录(按时间倒序)计算需要等待的时间, 以及是否处于锁定状态.
+func (p loginProtectionPolicy) retryAfter(failures []int64, maxAttempts int, now int64) (time.Duration, bool) {
+	if len(failures) <= 0 {
+		return 0, false
+	}
+
+	last := failures[0]
+	if len(failures) >= maxAttempts {
+		return time.Duration(last+p.lockoutDuration.Milliseconds()-now) * time.Millisecond, true
+	}
+
+	delay := p.backoffMax
+	if shift := len(failures) - 1; shift < 30 && p.backoffBase<<shift < p.backoffMax {
+		delay = p.backoffBase << shift
+	}
+
+	return time.Duration(last+delay.Milliseconds()-now) * time.Millisecond, false
+}
+
+// BeginLoginAttempt 在校验密码或 2FA 验证码之前调用. 用户名或 IP 需要等待或被锁定时返回需要等待的时间和
+// ErrorLoginThrottled 或 ErrorLoginLocked; 否则记录一次登录尝试, 校验完成后需要调用 FinishLoginAttempt.
+// 登录尝试在完成前视为失败, 因此并发的请求也会受到限制.
+func BeginLoginAttempt(config configuration.ServerMonitorLoginProtectionConfiguration, kind monitor_model.LoginAttemptKind, username string, ip string) (monitor_model.LoginAttempt, time.Duration, error) {
+	db := monitor_db.GetDB()
+	policy := newLoginProtectionPolicy(config)
+
+	loginAttemptMutex.Lock()
+	defer loginAttemptMutex.Unlock()
+
+	attempt := monitor_model.LoginAttempt{Username: username, Ip: ip, Kind: kind}
+
+	if policy.enable {
+		now := time.Now().UnixMilli()
+
+		retryAfter, locked := time.Duration(0), false
+		check := func(failures []int64, maxAttempts int) {
+			d, l := policy.retryAfter(failures, maxAttempts, now)
+			retryAfter = lo.Max([]time.Duration{retryAfter, d})
+			locked = locked || (l && d > 0)
+		}
+
+		// 用户名的失败次数按类型分别统计, 密码登录成功不会清除 2FA 校验的失败次数, 其中任一类型被锁定时都不允许登录
+		for _, k := range []monitor_model.LoginAttemptKind{monitor_model.LoginAttemptKindPassword, monitor_model.LoginAttemptKind2FA} {
+			failures, err := recentUserLoginFailures(policy, username, k, now)
+			if err != nil {
+				return attempt, 0, err
+			}
+			check(failures, policy.maxAttemptsPerUser)
+		}
+
+		failures, err := recentLoginFailures("ip = ?", []any{ip}, now-policy.window.Milliseconds(), policy.maxAttemptsPerIp)
+		if err != nil {
+			return attempt, 0, err
+		}
+		check(failures, policy.maxAttemptsPerIp)
+
+		if retryAfter > 0 {
+			return attempt, retryAfter, lo.Ternary(locked, ErrorLoginLocked, ErrorLoginThrottled)
+		}
+	}
+
+	if result := db.Where("created_at < ?", time.Now().Add(-loginAttemptRetention).UnixMilli()).Delete(&loginAttemptModel); result.Error != nil {
+		return attempt, 0, result.Error
+	}
+
+	result := db.Create(&attempt)
+
+	return attempt, 0, result.Error
+}
+
+// FinishLoginAttempt 记录登录尝试的结果. 用户名因本次失败被锁定时发送警告通知.
+func FinishLoginAttempt(config configuration.ServerMonitorLoginProtectionConfiguration, attempt monitor_model.LoginAttempt, success bool) error {
+	db := monitor_db.GetDB()
+	policy := newLoginProtectionPolicy(config)
+
+	if success {
+		return db.Model(&attempt).UpdateColumn("success", true).Error
+	}
+
+	if !policy.enable || len(attempt.Username) <= 0 {
+		return nil
+	}
+
+	failures, err := recentUserLoginFailures(policy, attempt.Username, attempt.Kind, time.Now().UnixMilli())
+	if err != nil {
+		return err
+	}
+	if len(failures) != policy.maxAttemptsPerUser {
+		return nil
+	}
+
+	logger.Warn("user %s is locked for %s after %d failed login attempts, last from %s\n", attempt.Username, policy.lockoutDuration, len(failures), attempt.Ip)
+	notification.SendUserNotifications([]notification.UserNotification{
+		{
+			UniqueId:       fmt.Sprintf("login-lockout-%s-%d", attempt.Username, attempt.CreatedAt),
+			Unread:         true,
+			Title:          fmt.Sprintf("Account %s is temporarily locked", attempt.Username),
+			Caption:        fmt.Sprintf("%d failed login attempts in a row, the last one from %s. Login is locked for %s.", len(failures), attempt.Ip, policy.lockoutDuration),
+			Kind:           notification.UserNotificationKindWarning,
+			Origin:         notification.UserNotificationOriginMain,
+			OriginCreateAt: attempt.CreatedAt,
+		},
+	})
+
+	return nil
+}
+
+// ListLoginAttempts 获取最近的 limit 条登录尝试记录, 按时间倒序排列.
+func ListLoginAttempts(failedOnly bool, limit int) ([]monitor_model.LoginAttempt, error) {
+	db := monitor_db.GetDB()
+
+	query := db.Model(&loginAttemptModel)
+	if failedOnly {
+		query = query.Where("success = ?", false)
+	}
+
+	attempts := make([]monitor_model.LoginAttempt, 0)
+	result := query.Order("created_at DESC").Limit(limit).Find(&attempts)
+
+	return attempts, result.Error
+}
+
+// ListLoginLockouts 获取当前被锁定的用户名和 IP.
+func ListLoginLockouts(config configuration.ServerMonitorLoginProtectionConfiguration) ([]LoginLockout, error) {
+	db := monitor_db.GetDB()
+	policy := newLoginProtectionPolicy(config)
+
+	lockouts := make([]LoginLockout, 0)
+	if !policy.enable {
+		return lockouts, nil
+	}
+
+	now := time.Now().UnixMilli()
+	since := now - policy.window.Milliseconds()
+
+	usernames := make([]string, 0)
+	if result := db.Model(&loginAttemptModel).Where("success = ? AND created_at > ? AND username != ''", false, since).Distinct().Pluck("username", &usernames); result.Error != nil {
+		return nil, result.Error
+	}
+	for _, username := range usernames {
+		until := int64(0)
+		for _, kind := range []monitor_model.LoginAttemptKind{monitor_model.LoginAttemptKindPassword, monitor_model.LoginAttemptKind2FA} {
+			failures, err := recentUserLoginFailures(policy, username, kind, now)
+			if err != nil {
+				return nil, err
+			}
+			if retryAfter, locked := policy.retryAfter(failures, policy.maxAttemptsPerUser, now); locked && retryAfter > 0 {
+				until = lo.Max([]int64{until, now + retryAfter.Milliseconds()})
+			}
+		}
+		if until > 0 {
+			lockouts = append(lockouts, LoginLockout{Username: username, Until: until})
+		}
+	}
+
+	ips := make([]string, 0)
+	if result := db.Model(&loginAttemptModel).Where("success = ? AND created_at > ?", false, since).Distinct().Pluck("ip", &ips); result.Error != nil {
+		return nil, result.Error
+	}
+	for _, ip := range ips {
+		failures, err := recentLoginFailures("ip = ?", []any{ip}, since, policy.maxAttemptsPerIp)
+		if err != nil {
+			return nil, err
+		}
+		if retryAfter, locked := policy.retryAfter(failures, policy.maxAttemptsPerIp, now); locked && retryAfter > 0 {
+			lockouts = append(lockouts, LoginLockout{Ip: ip, Until: now + retryAfter.Milliseconds()})
+		}
+	}
+
+	return lockouts, nil
+}
+
+// recentUserLoginFailures 获取用户名在时间窗口内且在同类型的最后一次成功之后的 kind 类型的失败记录.
+func recentUserLoginFailures(policy loginProtectionPolicy, username string, kind monitor_model.LoginAttemptKind, now int64) ([]int64, error) {
+	db := monitor_db.GetDB()
+
+	if len(username) <= 0 {
+		return nil, nil
+	}
+
+	lastSuccess := int64(0)
+	if result := db.Model(&loginAttemptModel).Where("username = ? AND kind = ? AND success = ?", username, kind, true).Select("COALESCE(MAX(created_at), 0)").Scan(&lastSuccess); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return recentLoginFailures("username = ? AND kind = ?", []any{username, kind}, lo.Max([]int64{now - policy.window.Milliseconds(), lastSuccess}), policy.maxAttemptsPerUser)
+}
+
+// recentLoginFailures 获取 since 之后匹配 condition 的最近 limit 次失败的时间(毫秒时间戳), 按时间倒序排列.
+func recentLoginFailures(condition string, values []any, since int64, limit int) ([]int64, error) {
+	db := monitor_db.GetDB()
+
+	failures := make([]int64, 0)
+	result := db.Model(&loginAttemptModel).
+		Where(condition, values...).
+		Where("success = ? AND created_at > ?", false, since).
+		Order("created_at DESC").
+		Limit(limit).
+		Pluck("created_at", &failures)
+
+	return failures, result.Error
+}
@@ -0,0 +1,196 @@
+package monitor_service
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification_channel"
+)
+
+var notificationChannelModel = monitor_model.NotificationChannel{}
+var notificationDeliveryModel = monitor_model.NotificationDelivery{}
+
+// ErrorNotificationChannelNameExists 通知渠道的名称已被使用.
+var ErrorNotificationChannelNameExists = errors.New("notification channel name already exists")
+
+// ListNotificationChannels 获取所有通知渠道, 按 id 排列. onlyEnabled 为 true 时只返回启用的渠道.
+func ListNotificationChannels(onlyEnabled bool) ([]monitor_model.NotificationChannel, error) {
+	db := monitor_db.GetDB()
+
+	channels := make([]monitor_model.NotificationChannel, 0)
+	query := db.Model(&notificationChannelModel).Order("id")
+	if onlyEnabled {
+		query = query.Where("enable = ?", true)
+	}
+
+	return channels, query.Find(&channels).Error
+}
+
+// GetNotificationChannel 获取通知渠道, 不存在时返回 ErrorNotFound.
+func GetNotificationChannel(id uint) (monitor_model.NotificationChannel, error) {
+	db := monitor_db.GetDB()
+
+	channel := monitor_model.NotificationChannel{}
+	result := db.Model(&notificationChannelModel).Where("id = ?", id).Limit(1).Find(&channel)
+	if result.Error != nil {
+		return channel, result.Error
+	} else if result.RowsAffected <= 0 {
+		return channel, ErrorNotFound
+	}
+
+	return channel, nil
+}
+
+// CreateNotificationChannel 创建通知渠道.
+func CreateNotificationChannel(channel monitor_model.NotificationChannel) (monitor_model.NotificationChannel, error) {
+	db := monitor_db.GetDB()
+
+	channel.ID = 0
+	if err := validateNotificationChannel(channel); err != nil {
+		return channel, err
+	}
+
+	result := db.Create(&channel)
+
+	return channel, result.Error
+}
+
+// UpdateNotificationChannel 修改通知渠道. 配置中为空的敏感字段(密码, 令牌, Webhook 请求头的值)保留原有的值.
+func UpdateNotificationChannel(channel monitor_model.NotificationChannel) (monitor_model.NotificationChannel, error) {
+	db := monitor_db.GetDB()
+
+	stored, err := GetNotificationChannel(channel.ID)
+	if err != nil {
+		return channel, err
+	}
+
+	channel.Config.KeepSecrets(stored.Config)
+	if err := validateNotificationChannel(channel); err != nil {
+		return channel, err
+	}
+
+	stored.Name = channel.Name
+	stored.Type = channel.Type
+	stored.Enable = channel.Enable
+	stored.Config = channel.Config
+	stored.UserId = channel.UserId
+	result := db.Select("Name", "Type", "Enable", "Config", "UserId").Updates(&stored)
+
+	return stored, result.Error
+}
+
+// DeleteNotificationChannel 删除通知渠道及其投递记录.
+func DeleteNotificationChannel(id uint) error {
+	db := monitor_db.GetDB()
+
+	channel, err := GetNotificationChannel(id)
+	if err != nil {
+		return err
+	}
+
+	if result := db.Unscoped().Where("channel_id = ?", id).Delete(&notificationDeliveryModel); result.Error != nil {
+		return result.Error
+	}
+
+	// 彻底删除以释放渠道名称
+	return db.Unscoped().Delete(&channel).Error
+}
+
+// WithoutNotificationChannelSecret 移除通知渠道配置中的敏感字段, 用于响应和审计日志.
+func WithoutNotificationChannelSecret(channel monitor_model.NotificationChannel) monitor_model.NotificationChannel {
+	channel.Config = channel.Config.WithoutSecrets()
+
+	return channel
+}
+
+// validateNotificationChannel 校验渠道名称不为空且未被其他渠道使用, 配置与渠道类型匹配, 所属的用户存在.
+func validateNotificationChannel(channel monitor_model.NotificationChannel) error {
+	db := monitor_db.GetDB()
+
+	if len(channel.Name) <= 0 {
+		return errors.Errorf("%w, notification channel name is required", notification_channel.ErrorInvalidConfig)
+	}
+	if err := notification_channel.Validate(channel.Type, channel.Config); err != nil {
+		return err
+	}
+
+	if channel.UserId > 0 {
+		if count, err := CountUser(monitor_model.User{Model: monitor_model.Model{ID: channel.UserId}}); err != nil {
+			return err
+		} else if count <= 0 {
+			return errors.Errorf("%w, user %d not found", notification_channel.ErrorInvalidConfig, channel.UserId)
+		}
+	}
+
+	count := int64(0)
+	if result := db.Model(&notificationChannelModel).Where("name = ? AND id != ?", channel.Name, channel.ID).Count(&count); result.Error != nil {
+		return result.Error
+	} else if count > 0 {
+		return ErrorNotificationChannelNameExists
+	}
+
+	return nil
+}
+
+// CreateNotificationDelivery 创建投递记录.
+func CreateNotificationDelivery(delivery monitor_model.NotificationDelivery) (monitor_model.NotificationDelivery, error) {
+	db := monitor_db.GetDB()
+
+	result := db.Create(&delivery)
+
+	return delivery, result.Error
+}
+
+// UpdateNotificationDelivery 更新投递记录的状态, 尝试次数和错误信息.
+func UpdateNotificationDelivery(delivery monitor_model.NotificationDelivery) error {
+	db := monitor_db.GetDB()
+
+	return db.Select("Status", "Attempts", "Error").Updates(&delivery).Error
+}
+
+// NotificationDelivered 通知是否已经投递(或正在投递)到渠道. 测试通知不计算在内.
+func NotificationDelivered(channelId uint, uniqueId string) (bool, error) {
+	db := monitor_db.GetDB()
+
+	count := int64(0)
+	result := db.Model(&notificationDeliveryModel).Where("channel_id = ? AND unique_id = ? AND test = ?", channelId, uniqueId, false).Count(&count)
+
+	return count > 0, result.Error
+}
+
+// CountRecentNotificationDeliveries 统计渠道在 since(毫秒时间戳)之后投递的通知数量, 不包括测试通知和被跳过的通知.
+// fingerprint 不为空时只统计相同通知的数量.
+func CountRecentNotificationDeliveries(channelId uint, since int64, fingerprint string) (int64, error) {
+	db := monitor_db.GetDB()
+
+	query := db.Model(&notificationDeliveryModel).
+		Where("channel_id = ? AND test = ? AND status != ? AND created_at >= ?", channelId, false, monitor_model.NotificationDeliveryStatusSkipped, since)
+	if len(fingerprint) > 0 {
+		query = query.Where("fingerprint = ?", fingerprint)
+	}
+
+	count := int64(0)
+	result := query.Count(&count)
+
+	return count, result.Error
+}
+
+// ListNotificationDeliveries 分页获取投递记录, 按时间倒序排列. channelId 为 0 时返回所有渠道的记录. page 从 1 开始.
+func ListNotificationDeliveries(channelId uint, page int, pageSize int) ([]monitor_model.NotificationDelivery, int64, error) {
+	db := monitor_db.GetDB()
+
+	query := db.Model(&notificationDeliveryModel)
+	if channelId > 0 {
+		query = query.Where("channel_id = ?", channelId)
+	}
+
+	total := int64(0)
+	if result := query.Count(&total); result.Error != nil {
+		return nil, 0, result.Error
+	}
+
+	deliveries := make([]monitor_model.NotificationDelivery, 0)
+	result := query.Order("id desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&deliveries)
+
+	return deliveries, total, result.Error
+}
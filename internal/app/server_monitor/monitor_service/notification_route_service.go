@@ -0,0 +1,235 @@
+package monitor_service
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/samber/lo"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification"
+	"regexp"
+	"time"
+)
+
+var notificationRouteModel = monitor_model.NotificationRoute{}
+var notificationQuietHoursModel = monitor_model.NotificationQuietHours{}
+
+// ErrorInvalidNotificationRoute 通知路由规则或免打扰时段无效.
+var ErrorInvalidNotificationRoute = errors.New("invalid notification route")
+
+// userNotificationKinds 所有的通知类型.
+var userNotificationKinds = []notification.UserNotificationKind{
+	notification.UserNotificationKindError,
+	notification.UserNotificationKindWarning,
+	notification.UserNotificationKindInfo,
+	notification.UserNotificationKindSuccess,
+}
+
+// quietHoursLayout 免打扰时段的时间格式.
+const quietHoursLayout = "15:04"
+
+// ListNotificationRoutes 获取所有通知路由规则, 按匹配顺序排列. onlyEnabled 为 true 时只返回启用的规则.
+func ListNotificationRoutes(onlyEnabled bool) ([]monitor_model.NotificationRoute, error) {
+	db := monitor_db.GetDB()
+
+	routes := make([]monitor_model.NotificationRoute, 0)
+	query := db.Model(&notificationRouteModel).Order("priority").Order("id")
+	if onlyEnabled {
+		query = query.Where("enable = ?", true)
+	}
+
+	return routes, query.Find(&routes).Error
+}
+
+// GetNotificationRoute 获取通知路由规则, 不存在时返回 ErrorNotFound.
+func GetNotificationRoute(id uint) (monitor_model.NotificationRoute, error) {
+	db := monitor_db.GetDB()
+
+	route := monitor_model.NotificationRoute{}
+	result := db.Model(&notificationRouteModel).Where("id = ?", id).Limit(1).Find(&route)
+	if result.Error != nil {
+		return route, result.Error
+	} else if result.RowsAffected <= 0 {
+		return route, ErrorNotFound
+	}
+
+	return route, nil
+}
+
+// CreateNotificationRoute 创建通知路由规则.
+func CreateNotificationRoute(route monitor_model.NotificationRoute) (monitor_model.NotificationRoute, error) {
+	db := monitor_db.GetDB()
+
+	route.ID = 0
+	if err := validateNotificationRoute(route); err != nil {
+		return route, err
+	}
+
+	result := db.Create(&route)
+
+	return route, result.Error
+}
+
+// UpdateNotificationRoute 修改通知路由规则.
+func UpdateNotificationRoute(route monitor_model.NotificationRoute) (monitor_model.NotificationRoute, error) {
+	db := monitor_db.GetDB()
+
+	if _, err := GetNotificationRoute(route.ID); err != nil {
+		return route, err
+	}
+	if err := validateNotificationRoute(route); err != nil {
+		return route, err
+	}
+
+	result := db.Select("Name", "Enable", "Priority", "Origins", "Kinds", "TitlePattern", "CaptionPattern", "Action", "ChannelIds", "DowngradeTo").Updates(&route)
+	if result.Error != nil {
+		return route, result.Error
+	}
+
+	return GetNotificationRoute(route.ID)
+}
+
+// DeleteNotificationRoute 删除通知路由规则.
+func DeleteNotificationRoute(id uint) error {
+	db := monitor_db.GetDB()
+
+	route, err := GetNotificationRoute(id)
+	if err != nil {
+		return err
+	}
+
+	return db.Delete(&route).Error
+}
+
+// validateNotificationRoute 校验规则的匹配条件和动作. 路由到的渠道必须存在.
+func validateNotificationRoute(route monitor_model.NotificationRoute) error {
+	for _, kind := range route.Kinds {
+		if !lo.Contains(userNotificationKinds, kind) {
+			return errors.Errorf("%w, unknown notification kind %s", ErrorInvalidNotificationRoute, kind)
+		}
+	}
+	if _, err := regexp.Compile(route.TitlePattern); err != nil {
+		return errors.Errorf("%w, invalid title pattern. %w", ErrorInvalidNotificationRoute, err)
+	}
+	if _, err := regexp.Compile(route.CaptionPattern); err != nil {
+		return errors.Errorf("%w, invalid caption pattern. %w", ErrorInvalidNotificationRoute, err)
+	}
+
+	switch route.Action {
+	case monitor_model.NotificationRouteActionRoute:
+		if len(route.ChannelIds) <= 0 {
+			return errors.Errorf("%w, at least one channel is required", ErrorInvalidNotificationRoute)
+		}
+		for _, channelId := range route.ChannelIds {
+			if _, err := GetNotificationChannel(channelId); errors.Is(err, ErrorNotFound) {
+				return errors.Errorf("%w, notification channel %d not found", ErrorInvalidNotificationRoute, channelId)
+			} else if err != nil {
+				return err
+			}
+		}
+	case monitor_model.NotificationRouteActionDowngrade:
+		if !lo.Contains(userNotificationKinds, route.DowngradeTo) {
+			return errors.Errorf("%w, unknown notification kind %s", ErrorInvalidNotificationRoute, route.DowngradeTo)
+		}
+	case monitor_model.NotificationRouteActionSuppress:
+	default:
+		return errors.Errorf("%w, unknown action %s", ErrorInvalidNotificationRoute, route.Action)
+	}
+
+	return nil
+}
+
+// MatchNotificationRoute 通知是否满足规则的所有匹配条件. 正则表达式无效时视为不匹配.
+func MatchNotificationRoute(route monitor_model.NotificationRoute, userNotification notification.UserNotification) bool {
+	if len(route.Origins) > 0 && !lo.Contains(route.Origins, userNotification.Origin) {
+		return false
+	}
+	if len(route.Kinds) > 0 && !lo.Contains(route.Kinds, userNotification.Kind) {
+		return false
+	}
+
+	for _, pair := range [][2]string{{route.TitlePattern, userNotification.Title}, {route.CaptionPattern, userNotification.Caption}} {
+		if len(pair[0]) <= 0 {
+			continue
+		}
+		if matched, err := regexp.MatchString(pair[0], pair[1]); err != nil || !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// GetNotificationQuietHours 获取用户的免打扰时段, 未设置时返回未启用的免打扰时段.
+func GetNotificationQuietHours(userId uint) (monitor_model.NotificationQuietHours, error) {
+	db := monitor_db.GetDB()
+
+	quietHours := monitor_model.NotificationQuietHours{UserId: userId}
+	result := db.Model(&notificationQuietHoursModel).Where("user_id = ?", userId).Limit(1).Find(&quietHours)
+
+	return quietHours, result.Error
+}
+
+// SaveNotificationQuietHours 创建或修改用户的免打扰时段.
+func SaveNotificationQuietHours(quietHours monitor_model.NotificationQuietHours) (monitor_model.NotificationQuietHours, error) {
+	db := monitor_db.GetDB()
+
+	if _, err := time.Parse(quietHoursLayout, quietHours.Start); err != nil {
+		return quietHours, errors.Errorf("%w, start should be formatted as HH:MM", ErrorInvalidNotificationRoute)
+	}
+	if _, err := time.Parse(quietHoursLayout, quietHours.End); err != nil {
+		return quietHours, errors.Errorf("%w, end should be formatted as HH:MM", ErrorInvalidNotificationRoute)
+	}
+	if _, err := quietHoursLocation(quietHours); err != nil {
+		return quietHours, errors.Errorf("%w, unknown timezone %s", ErrorInvalidNotificationRoute, quietHours.Timezone)
+	}
+
+	stored, err := GetNotificationQuietHours(quietHours.UserId)
+	if err != nil {
+		return quietHours, err
+	}
+
+	stored.Enable = quietHours.Enable
+	stored.Start = quietHours.Start
+	stored.End = quietHours.End
+	stored.Timezone = quietHours.Timezone
+	if stored.ID <= 0 {
+		return stored, db.Create(&stored).Error
+	}
+
+	return stored, db.Select("Enable", "Start", "End", "Timezone").Updates(&stored).Error
+}
+
+// InNotificationQuietHours now 是否在免打扰时段内. 免打扰时段包括开始时间, 不包括结束时间.
+func InNotificationQuietHours(quietHours monitor_model.NotificationQuietHours, now time.Time) bool {
+	if !quietHours.Enable {
+		return false
+	}
+
+	start, startErr := time.Parse(quietHoursLayout, quietHours.Start)
+	end, endErr := time.Parse(quietHoursLayout, quietHours.End)
+	location, locationErr := quietHoursLocation(quietHours)
+	if startErr != nil || endErr != nil || locationErr != nil {
+		return false
+	}
+
+	now = now.In(location)
+	minutes := now.Hour()*60 + now.Minute()
+	startMinutes := start.Hour()*60 + start.Minute()
+	endMinutes := end.Hour()*60 + end.Minute()
+
+	if startMinutes <= endMinutes {
+		return minutes >= startMinutes && minutes < endMinutes
+	}
+
+	// 跨越午夜, 如 22:00 至 07:00
+	return minutes >= startMinutes || minutes < endMinutes
+}
+
+// quietHoursLocation 获取免打扰时段的时区, 未设置时使用服务器的时区.
+func quietHoursLocation(quietHours monitor_model.NotificationQuietHours) (*time.Location, error) {
+	if len(quietHours.Timezone) <= 0 {
+		return time.Local, nil
+	}
+
+	return time.LoadLocation(quietHours.Timezone)
+}
@@ -0,0 +1,183 @@
+package monitor_service
+
+import (
+	"crypto/subtle"
+	"github.com/go-errors/errors"
+	"github.com/samber/lo"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification_webhook"
+	"strings"
+	"time"
+)
+
+var notificationWebhookModel = monitor_model.NotificationWebhook{}
+
+// notificationWebhookDisplayPrefixLength 列表中展示的令牌前缀长度.
+const notificationWebhookDisplayPrefixLength = len(notification_webhook.TokenPrefix) + 8
+
+// ErrorInvalidNotificationWebhook 入站 Webhook 的名称或格式无效.
+var ErrorInvalidNotificationWebhook = errors.New("invalid notification webhook")
+
+// ErrorNotificationWebhookNameExists 入站 Webhook 的名称已被使用.
+var ErrorNotificationWebhookNameExists = errors.New("notification webhook name already exists")
+
+// ErrorInvalidNotificationWebhookToken 入站 Webhook 不存在, 未启用或令牌错误.
+var ErrorInvalidNotificationWebhookToken = errors.New("invalid notification webhook token")
+
+// ListNotificationWebhooks 获取所有入站 Webhook, 按 id 排列.
+func ListNotificationWebhooks() ([]monitor_model.NotificationWebhook, error) {
+	db := monitor_db.GetDB()
+
+	webhooks := make([]monitor_model.NotificationWebhook, 0)
+	result := db.Model(&notificationWebhookModel).Order("id").Find(&webhooks)
+
+	return webhooks, result.Error
+}
+
+// GetNotificationWebhook 获取入站 Webhook, 不存在时返回 ErrorNotFound.
+func GetNotificationWebhook(id uint) (monitor_model.NotificationWebhook, error) {
+	db := monitor_db.GetDB()
+
+	webhook := monitor_model.NotificationWebhook{}
+	result := db.Model(&notificationWebhookModel).Where("id = ?", id).Limit(1).Find(&webhook)
+	if result.Error != nil {
+		return webhook, result.Error
+	} else if result.RowsAffected <= 0 {
+		return webhook, ErrorNotFound
+	}
+
+	return webhook, nil
+}
+
+// CreateNotificationWebhook 创建入站 Webhook, 返回令牌明文. 令牌明文无法再次获取.
+func CreateNotificationWebhook(webhook monitor_model.NotificationWebhook) (string, monitor_model.NotificationWebhook, error) {
+	db := monitor_db.GetDB()
+
+	webhook.ID = 0
+	if err := validateNotificationWebhook(webhook); err != nil {
+		return "", webhook, err
+	}
+
+	plaintext, err := notification_webhook.GenerateToken()
+	if err != nil {
+		return "", webhook, err
+	}
+	webhook.TokenHash = notification_webhook.HashToken(plaintext)
+	webhook.Prefix = plaintext[:notificationWebhookDisplayPrefixLength]
+
+	result := db.Create(&webhook)
+
+	return plaintext, webhook, result.Error
+}
+
+// UpdateNotificationWebhook 修改入站 Webhook 的名称, 启用状态和格式. 令牌不变.
+func UpdateNotificationWebhook(webhook monitor_model.NotificationWebhook) (monitor_model.NotificationWebhook, error) {
+	db := monitor_db.GetDB()
+
+	stored, err := GetNotificationWebhook(webhook.ID)
+	if err != nil {
+		return webhook, err
+	}
+	if err := validateNotificationWebhook(webhook); err != nil {
+		return webhook, err
+	}
+
+	stored.Name = webhook.Name
+	stored.Enable = webhook.Enable
+	stored.Format = webhook.Format
+	result := db.Select("Name", "Enable", "Format").Updates(&stored)
+
+	return stored, result.Error
+}
+
+// RegenerateNotificationWebhookToken 重新生成入站 Webhook 的令牌, 原有的令牌立即失效. 返回新的令牌明文.
+func RegenerateNotificationWebhookToken(id uint) (string, monitor_model.NotificationWebhook, error) {
+	db := monitor_db.GetDB()
+
+	webhook, err := GetNotificationWebhook(id)
+	if err != nil {
+		return "", webhook, err
+	}
+
+	plaintext, err := notification_webhook.GenerateToken()
+	if err != nil {
+		return "", webhook, err
+	}
+	webhook.TokenHash = notification_webhook.HashToken(plaintext)
+	webhook.Prefix = plaintext[:notificationWebhookDisplayPrefixLength]
+
+	result := db.Select("TokenHash", "Prefix").Updates(&webhook)
+
+	return plaintext, webhook, result.Error
+}
+
+// DeleteNotificationWebhook 删除入站 Webhook. 已经收到的通知不会被删除.
+func DeleteNotificationWebhook(id uint) error {
+	db := monitor_db.GetDB()
+
+	webhook, err := GetNotificationWebhook(id)
+	if err != nil {
+		return err
+	}
+
+	// 彻底删除以释放名称
+	return db.Unscoped().Delete(&webhook).Error
+}
+
+// VerifyNotificationWebhookToken 校验入站 Webhook 的令牌, 并记录最后一次收到通知的时间.
+// Webhook 不存在, 未启用或令牌错误时都返回 ErrorInvalidNotificationWebhookToken, 避免泄露 Webhook 是否存在.
+func VerifyNotificationWebhookToken(id uint, token string) (monitor_model.NotificationWebhook, error) {
+	db := monitor_db.GetDB()
+
+	webhook, err := GetNotificationWebhook(id)
+	if errors.Is(err, ErrorNotFound) {
+		return webhook, ErrorInvalidNotificationWebhookToken
+	} else if err != nil {
+		return webhook, err
+	}
+
+	if !webhook.Enable || subtle.ConstantTimeCompare([]byte(webhook.TokenHash), []byte(notification_webhook.HashToken(token))) != 1 {
+		return webhook, ErrorInvalidNotificationWebhookToken
+	}
+
+	webhook.LastReceivedAt = time.Now().UnixMilli()
+	result := db.Select("LastReceivedAt").Updates(&webhook)
+
+	return webhook, result.Error
+}
+
+// notificationWebhookOriginPrefix 入站 Webhook 收到的通知的来源前缀.
+const notificationWebhookOriginPrefix = "webhook:"
+
+// NotificationWebhookOrigin 入站 Webhook 收到的通知的来源.
+func NotificationWebhookOrigin(webhook monitor_model.NotificationWebhook) notification.UserNotificationOrigin {
+	return notificationWebhookOriginPrefix + webhook.Name
+}
+
+// isNotificationWebhookOrigin 判断通知是否来自入站 Webhook.
+func isNotificationWebhookOrigin(origin notification.UserNotificationOrigin) bool {
+	return strings.HasPrefix(origin, notificationWebhookOriginPrefix)
+}
+
+// validateNotificationWebhook 校验名称不为空且未被其他 Webhook 使用, 格式受支持.
+func validateNotificationWebhook(webhook monitor_model.NotificationWebhook) error {
+	db := monitor_db.GetDB()
+
+	if len(strings.TrimSpace(webhook.Name)) <= 0 {
+		return errors.Errorf("%w, name is required", ErrorInvalidNotificationWebhook)
+	}
+	if !lo.Contains(notification_webhook.Formats, webhook.Format) {
+		return errors.Errorf("%w, unknown format %s", ErrorInvalidNotificationWebhook, webhook.Format)
+	}
+
+	count := int64(0)
+	if result := db.Model(&notificationWebhookModel).Where("name = ? AND id != ?", webhook.Name, webhook.ID).Count(&count); result.Error != nil {
+		return result.Error
+	} else if count > 0 {
+		return ErrorNotificationWebhookNameExists
+	}
+
+	return nil
+}
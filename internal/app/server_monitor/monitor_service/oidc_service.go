@@ -0,0 +1,91 @@
+package monitor_service
+
+import (
+	"context"
+	"github.com/go-errors/errors"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/pkg/authority"
+	"github.com/siaikin/home-dashboard/internal/pkg/configuration"
+	"reflect"
+	"sync"
+)
+
+// ErrorOidcIdentityMismatch 用户已绑定其他的 OpenID Connect 身份.
+var ErrorOidcIdentityMismatch = errors.New("oidc identity mismatch")
+
+var oidcClientMutex sync.Mutex
+
+var oidcClient *authority.OidcClient
+
+// GetOidcClient 获取 OpenID Connect 客户端. 客户端在第一次使用时创建, 配置修改后重新创建.
+func GetOidcClient(ctx context.Context, config configuration.ServerMonitorOidcConfiguration) (*authority.OidcClient, error) {
+	oidcClientMutex.Lock()
+	defer oidcClientMutex.Unlock()
+
+	if oidcClient != nil && reflect.DeepEqual(oidcClient.Config(), config) {
+		return oidcClient, nil
+	}
+
+	client, err := authority.NewOidcClient(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	oidcClient = client
+
+	return oidcClient, nil
+}
+
+// ResolveOidcUser 获取 OpenID Connect 登录的用户, 见 ResolveExternalUser.
+// 用户第一次通过 OpenID Connect 登录时绑定 identity 的 issuer 和 subject, 之后只允许该身份登录, 避免其他身份通过相同的用户名登录此用户.
+func ResolveOidcUser(config configuration.ServerMonitorOidcConfiguration, identity authority.OidcIdentity) (monitor_model.User, error) {
+	if len(identity.Issuer) <= 0 || len(identity.Subject) <= 0 {
+		return monitor_model.User{}, errors.Errorf("%w, issuer and subject are required", ErrorOidcIdentityMismatch)
+	}
+
+	// 在更新用户的角色前校验绑定的身份
+	if stored, err := GetUserByName(identity.Username); err == nil {
+		if err := ensureOidcIdentity(stored, identity); err != nil {
+			return monitor_model.User{}, err
+		}
+	} else if !errors.Is(err, ErrorNotFound) {
+		return monitor_model.User{}, err
+	}
+
+	user, err := ResolveExternalUser(ExternalUserPolicy{
+		AllowedGroups:       config.AllowedGroups,
+		AdministratorGroups: config.AdministratorGroups,
+		AutoCreateUser:      config.AutoCreateUser,
+	}, identity.Username, identity.Groups)
+	if err != nil {
+		return monitor_model.User{}, err
+	} else if len(user.OidcSubject) > 0 {
+		return user, ensureOidcIdentity(user, identity)
+	}
+
+	// 只在未绑定时绑定, 避免覆盖同时登录的其他身份
+	db := monitor_db.GetDB()
+	result := db.Model(&userModel).
+		Where("id = ? AND oidc_subject = ?", user.ID, "").
+		Updates(map[string]any{"oidc_issuer": identity.Issuer, "oidc_subject": identity.Subject})
+	if result.Error != nil {
+		return monitor_model.User{}, result.Error
+	} else if result.RowsAffected <= 0 {
+		return monitor_model.User{}, errors.Errorf("%w, user %s has been bound to another identity", ErrorOidcIdentityMismatch, user.Username)
+	}
+	logger.Info("user %s bound to oidc subject %s of %s\n", user.Username, identity.Subject, identity.Issuer)
+
+	user.OidcIssuer = identity.Issuer
+	user.OidcSubject = identity.Subject
+
+	return user, nil
+}
+
+// ensureOidcIdentity 校验用户未绑定身份或绑定的身份与 identity 相同.
+func ensureOidcIdentity(user monitor_model.User, identity authority.OidcIdentity) error {
+	if len(user.OidcSubject) <= 0 || (user.OidcIssuer == identity.Issuer && user.OidcSubject == identity.Subject) {
+		return nil
+	}
+
+	return errors.Errorf("%w, user %s has been bound to another identity", ErrorOidcIdentityMismatch, user.Username)
+}
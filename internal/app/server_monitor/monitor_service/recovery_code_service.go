@@ -0,0 +1,88 @@
+package monitor_service
+
+import (
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/pkg/authority"
+	"gorm.io/gorm"
+	"time"
+)
+
+var recoveryCodeModel = monitor_model.RecoveryCode{}
+
+// RegenerateRecoveryCodes 为用户生成新的恢复码, 旧的恢复码全部失效. 返回恢复码明文, 明文无法再次获取.
+func RegenerateRecoveryCodes(userId uint) ([]string, error) {
+	db := monitor_db.GetDB()
+
+	codes, err := authority.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if result := tx.Where("user_id = ?", userId).Delete(&recoveryCodeModel); result.Error != nil {
+			return result.Error
+		}
+
+		for _, code := range codes {
+			if result := tx.Create(&monitor_model.RecoveryCode{UserId: userId, CodeHash: authority.HashRecoveryCode(code)}); result.Error != nil {
+				return result.Error
+			}
+		}
+
+		return nil
+	})
+
+	return codes, err
+}
+
+// UseRecoveryCode 使用用户的恢复码, 每个恢复码只能使用一次. 返回恢复码是否有效.
+func UseRecoveryCode(userId uint, code string) (bool, error) {
+	db := monitor_db.GetDB()
+
+	// 通过 used_at = 0 条件更新, 并发使用同一个恢复码时只有一个请求成功
+	result := db.Model(&recoveryCodeModel).
+		Where("user_id = ? AND code_hash = ? AND used_at = 0", userId, authority.HashRecoveryCode(code)).
+		UpdateColumn("used_at", time.Now().UnixMilli())
+
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteRecoveryCodes 删除用户的所有恢复码, 用于关闭 2FA 时.
+func DeleteRecoveryCodes(userId uint) error {
+	db := monitor_db.GetDB()
+
+	return db.Where("user_id = ?", userId).Delete(&recoveryCodeModel).Error
+}
+
+// CountUnusedRecoveryCodes 获取用户剩余未使用的恢复码数量.
+func CountUnusedRecoveryCodes(userId uint) (int64, error) {
+	db := monitor_db.GetDB()
+
+	count := int64(0)
+	result := db.Model(&recoveryCodeModel).Where("user_id = ? AND used_at = 0", userId).Count(&count)
+
+	return count, result.Error
+}
+
+// Reset2FA 重置用户的 2FA: 解绑身份验证器应用, 删除所有 WebAuthn 凭据和恢复码.
+// 用于管理员丢失身份验证器时通过命令行恢复访问, 用户不存在时返回 ErrorNotFound.
+func Reset2FA(username string) error {
+	db := monitor_db.GetDB()
+
+	user, err := GetUserByName(username)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if result := tx.Where("user_id = ?", user.ID).Delete(&webAuthnCredentialModel); result.Error != nil {
+			return result.Error
+		}
+		if result := tx.Where("user_id = ?", user.ID).Delete(&recoveryCodeModel); result.Error != nil {
+			return result.Error
+		}
+
+		return tx.Model(&user).UpdateColumns(map[string]any{"enable2_fa": false, "secret2_fa": ""}).Error
+	})
+}
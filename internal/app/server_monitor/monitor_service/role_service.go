@@ -0,0 +1,214 @@
+package monitor_service
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/samber/lo"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/pkg/authority"
+	"strings"
+)
+
+var roleModel = monitor_model.Role{}
+
+var (
+	// ErrorBuiltInRole 内置角色不能删除, 内置的管理员角色不能修改.
+	ErrorBuiltInRole = errors.New("built-in role cannot be changed")
+	// ErrorRoleInUse 角色仍被用户使用, 不能删除.
+	ErrorRoleInUse = errors.New("role is assigned to users")
+	// ErrorRoleNameExists 角色名已被使用.
+	ErrorRoleNameExists = errors.New("role name already exists")
+	// ErrorInvalidRole 角色名为空或包含未知的权限.
+	ErrorInvalidRole = errors.New("invalid role")
+	// ErrorPermissionExceeded 分配的角色或设置的权限超出了操作者自己拥有的权限.
+	ErrorPermissionExceeded = errors.New("permissions exceed the operator's own permissions")
+)
+
+// defaultGuestPermissions 内置访客角色的默认权限, 只允许查看. 需要更多权限时由管理员修改访客角色或分配其他角色.
+var defaultGuestPermissions = []authority.Permission{
+	authority.PermissionStatsView,
+	authority.PermissionProcessView,
+	authority.PermissionShortcutsView,
+	authority.PermissionNotificationsView,
+}
+
+// GenerateBuiltInRoles 创建不存在的内置角色. 内置角色的 id 与引入角色之前的 monitor_model.UserRole 取值一致.
+func GenerateBuiltInRoles() error {
+	db := monitor_db.GetDB()
+
+	roles := []monitor_model.Role{
+		{Model: monitor_model.Model{ID: uint(monitor_model.RoleAdministrator)}, Name: "administrator", Description: "Has all permissions", BuiltIn: true},
+		{Model: monitor_model.Model{ID: uint(monitor_model.RoleGuest)}, Name: "guest", Description: "Default role of new users", Permissions: defaultGuestPermissions, BuiltIn: true},
+	}
+	for _, role := range roles {
+		count := int64(0)
+		if result := db.Unscoped().Model(&roleModel).Where("id = ?", role.ID).Count(&count); result.Error != nil {
+			return result.Error
+		} else if count > 0 {
+			continue
+		}
+
+		if result := db.Create(&role); result.Error != nil {
+			return result.Error
+		}
+	}
+
+	return nil
+}
+
+// ListRoles 获取所有角色, 按 id 排列.
+func ListRoles() ([]monitor_model.Role, error) {
+	db := monitor_db.GetDB()
+
+	roles := make([]monitor_model.Role, 0)
+	if result := db.Model(&roleModel).Order("id").Find(&roles); result.Error != nil {
+		return nil, result.Error
+	}
+
+	for i := range roles {
+		withAdministratorPermissions(&roles[i])
+	}
+
+	return roles, nil
+}
+
+// GetRole 获取角色, 不存在时返回 ErrorNotFound.
+func GetRole(id uint) (monitor_model.Role, error) {
+	db := monitor_db.GetDB()
+
+	role := monitor_model.Role{}
+	result := db.Model(&roleModel).Where("id = ?", id).Limit(1).Find(&role)
+	if result.Error != nil {
+		return role, result.Error
+	} else if result.RowsAffected <= 0 {
+		return role, ErrorNotFound
+	}
+
+	withAdministratorPermissions(&role)
+
+	return role, nil
+}
+
+// CreateRole 创建角色. 角色名已被使用时返回 ErrorRoleNameExists.
+func CreateRole(role monitor_model.Role) (monitor_model.Role, error) {
+	db := monitor_db.GetDB()
+
+	role.ID = 0
+	role.BuiltIn = false
+	role.Permissions = lo.Uniq(role.Permissions)
+	if err := validateRole(role); err != nil {
+		return role, err
+	}
+
+	result := db.Create(&role)
+
+	return role, result.Error
+}
+
+// UpdateRole 修改角色的名称, 描述和权限. 内置的管理员角色不能修改, 内置角色不能改名.
+func UpdateRole(role monitor_model.Role) (monitor_model.Role, error) {
+	db := monitor_db.GetDB()
+
+	stored, err := GetRole(role.ID)
+	if err != nil {
+		return role, err
+	}
+	if stored.ID == uint(monitor_model.RoleAdministrator) || (stored.BuiltIn && role.Name != stored.Name) {
+		return role, ErrorBuiltInRole
+	}
+	if err := validateRole(role); err != nil {
+		return role, err
+	}
+
+	stored.Name = role.Name
+	stored.Description = role.Description
+	stored.Permissions = lo.Uniq(role.Permissions)
+	result := db.Select("Name", "Description", "Permissions").Updates(&stored)
+
+	return stored, result.Error
+}
+
+// DeleteRole 删除角色. 内置角色和仍被用户使用的角色不能删除.
+func DeleteRole(id uint) error {
+	db := monitor_db.GetDB()
+
+	role, err := GetRole(id)
+	if err != nil {
+		return err
+	} else if role.BuiltIn {
+		return ErrorBuiltInRole
+	}
+
+	if count, err := CountUser(monitor_model.User{Role: monitor_model.UserRole(id)}); err != nil {
+		return err
+	} else if count > 0 {
+		return ErrorRoleInUse
+	}
+
+	// 彻底删除以释放角色名
+	return db.Unscoped().Delete(&role).Error
+}
+
+// RoleHasPermission 检查角色是否拥有 permission 权限. 角色不存在时视为没有任何权限.
+func RoleHasPermission(roleId monitor_model.UserRole, permission authority.Permission) (bool, error) {
+	if roleId == monitor_model.RoleAdministrator {
+		return true, nil
+	}
+
+	role, err := GetRole(uint(roleId))
+	if errors.Is(err, ErrorNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return lo.Contains(role.Permissions, permission), nil
+}
+
+// EnsurePermissionsGranted 校验角色 operator 拥有 permissions 中的所有权限, 否则返回 ErrorPermissionExceeded.
+// 用于避免用户通过分配角色或修改角色的权限获得自己没有的权限. 角色不存在时视为没有任何权限.
+func EnsurePermissionsGranted(operator monitor_model.UserRole, permissions []authority.Permission) error {
+	granted := make([]authority.Permission, 0)
+	if role, err := GetRole(uint(operator)); err == nil {
+		granted = role.Permissions
+	} else if !errors.Is(err, ErrorNotFound) {
+		return err
+	}
+
+	// 未知的权限由 validateRole 校验
+	if missing := lo.Without(lo.Intersect(authority.Permissions, permissions), granted...); len(missing) > 0 {
+		return errors.Errorf("%w, missing %s", ErrorPermissionExceeded, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// validateRole 校验角色名不为空且未被其他角色使用, 权限均为 authority.Permissions 中的权限.
+func validateRole(role monitor_model.Role) error {
+	db := monitor_db.GetDB()
+
+	if len(role.Name) <= 0 {
+		return errors.Errorf("%w, role name is required", ErrorInvalidRole)
+	}
+	if unknown, ok := lo.Find(role.Permissions, func(permission authority.Permission) bool {
+		return !lo.Contains(authority.Permissions, permission)
+	}); ok {
+		return errors.Errorf("%w, unknown permission %s", ErrorInvalidRole, unknown)
+	}
+
+	count := int64(0)
+	if result := db.Unscoped().Model(&roleModel).Where("name = ? AND id != ?", role.Name, role.ID).Count(&count); result.Error != nil {
+		return result.Error
+	} else if count > 0 {
+		return ErrorRoleNameExists
+	}
+
+	return nil
+}
+
+// withAdministratorPermissions 内置的管理员角色始终拥有所有权限, 包括新版本增加的权限.
+func withAdministratorPermissions(role *monitor_model.Role) {
+	if role.ID == uint(monitor_model.RoleAdministrator) {
+		role.Permissions = authority.Permissions
+	}
+}
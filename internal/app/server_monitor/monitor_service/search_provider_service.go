@@ -0,0 +1,257 @@
+package monitor_service
+
+import (
+	"encoding/json"
+	"github.com/go-errors/errors"
+	"github.com/samber/lo"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/pkg/comfy_http_client"
+	"gorm.io/gorm"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var searchProviderModel = monitor_model.SearchProvider{}
+
+// suggestionHttpClient 请求搜索建议使用的 http client. 搜索建议需要及时响应, 超时时间较短.
+var suggestionHttpClient, _ = comfy_http_client.New("", http.Header{}, time.Second*5)
+
+// SearchQuery 统一搜索的结果.
+type SearchQuery struct {
+	// Provider 本次搜索使用的搜索引擎, 没有可用的搜索引擎时为 nil.
+	Provider *monitor_model.SearchProvider `json:"provider"`
+	// Query 去除快捷关键字后的搜索内容.
+	Query string `json:"query"`
+	// SearchUrl 搜索结果页的链接.
+	SearchUrl   string   `json:"searchUrl"`
+	Suggestions []string `json:"suggestions"`
+	// Items 匹配的快捷方式, 按使用次数倒序排列.
+	Items []monitor_model.ShortcutItem `json:"items"`
+}
+
+// CreateOrUpdateSearchProviders 创建或更新 monitor_model.SearchProvider. 设置为默认的搜索引擎时, 其他搜索引擎将不再是默认的.
+func CreateOrUpdateSearchProviders(providers []monitor_model.SearchProvider) ([]monitor_model.SearchProvider, error) {
+	db := monitor_db.GetDB()
+
+	affected := make([]monitor_model.SearchProvider, len(providers))
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for i, provider := range providers {
+			if result := tx.Save(&provider); result.Error != nil {
+				return result.Error
+			}
+
+			if provider.Default {
+				if result := tx.Model(&searchProviderModel).Where("id != ?", provider.ID).Update("default", false); result.Error != nil {
+					return result.Error
+				}
+			}
+			affected[i] = provider
+		}
+
+		return nil
+	})
+
+	return affected, err
+}
+
+// ListSearchProvidersByQuery 获取 monitor_model.SearchProvider, 默认的搜索引擎排在最前面.
+func ListSearchProvidersByQuery(query monitor_model.SearchProvider) ([]monitor_model.SearchProvider, error) {
+	db := monitor_db.GetDB()
+
+	providers := make([]monitor_model.SearchProvider, 0)
+	result := db.Model(&searchProviderModel).Where(&query).Order("`default` DESC, id").Find(&providers)
+
+	return providers, result.Error
+}
+
+func CountSearchProvider(query monitor_model.SearchProvider) (int64, error) {
+	db := monitor_db.GetDB()
+
+	count := int64(0)
+	result := db.Model(&searchProviderModel).Where(&query).Count(&count)
+
+	return count, result.Error
+}
+
+func GetSearchProvider(id uint) (monitor_model.SearchProvider, error) {
+	db := monitor_db.GetDB()
+
+	providers := make([]monitor_model.SearchProvider, 0)
+	if result := db.Model(&searchProviderModel).Where("id = ?", id).Limit(1).Find(&providers); result.Error != nil {
+		return monitor_model.SearchProvider{}, result.Error
+	} else if len(providers) <= 0 {
+		return monitor_model.SearchProvider{}, ErrorNotFound
+	}
+
+	return providers[0], nil
+}
+
+func DeleteSearchProviders(ids []uint) error {
+	db := monitor_db.GetDB()
+
+	return db.Delete(&searchProviderModel, ids).Error
+}
+
+// ValidateSearchProvider 校验搜索引擎的链接模板和快捷关键字.
+func ValidateSearchProvider(provider monitor_model.SearchProvider) error {
+	if len(strings.TrimSpace(provider.Name)) <= 0 {
+		return errors.New("name is required")
+	}
+	if strings.ContainsAny(provider.Keyword, " \t!") {
+		return errors.Errorf("keyword %s should not contain whitespace or !", provider.Keyword)
+	}
+
+	templates := []string{provider.UrlTemplate}
+	if len(provider.SuggestionUrlTemplate) > 0 {
+		templates = append(templates, provider.SuggestionUrlTemplate)
+	}
+	for _, template := range templates {
+		if !strings.Contains(template, monitor_model.SearchProviderQueryPlaceholder) {
+			return errors.Errorf("url template %s should contain %s", template, monitor_model.SearchProviderQueryPlaceholder)
+		}
+
+		u, err := url.Parse(strings.ReplaceAll(template, monitor_model.SearchProviderQueryPlaceholder, ""))
+		if err != nil {
+			return err
+		} else if u.Scheme != "http" && u.Scheme != "https" {
+			return errors.Errorf("url template %s should be a http(s) url", template)
+		}
+	}
+
+	return nil
+}
+
+// BuildSearchUrl 使用搜索内容替换链接模板中的占位符.
+func BuildSearchUrl(template string, query string) string {
+	return strings.ReplaceAll(template, monitor_model.SearchProviderQueryPlaceholder, url.QueryEscape(query))
+}
+
+// FetchSearchSuggestions 通过搜索引擎的搜索建议接口获取搜索建议. 搜索引擎不支持搜索建议或搜索内容为空时返回空列表.
+func FetchSearchSuggestions(provider monitor_model.SearchProvider, query string) ([]string, error) {
+	if len(provider.SuggestionUrlTemplate) <= 0 || len(strings.TrimSpace(query)) <= 0 {
+		return []string{}, nil
+	}
+
+	req, err := suggestionHttpClient.Get(BuildSearchUrl(provider.SuggestionUrlTemplate, query))
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := suggestionHttpClient.Send(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("fetch suggestions from %s failed, status code %d", provider.Name, res.StatusCode)
+	}
+
+	// OpenSearch 格式: [query, [suggestion, ...], ...]
+	var body []json.RawMessage
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, err
+	} else if len(body) < 2 {
+		return nil, errors.Errorf("invalid suggestions response from %s", provider.Name)
+	}
+
+	suggestions := make([]string, 0)
+	if err := json.Unmarshal(body[1], &suggestions); err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
+
+// ParseSearchQuery 解析搜索内容中的快捷关键字(如 "!gh home-dashboard"), 返回对应的搜索引擎和去除快捷关键字后的搜索内容.
+// 没有快捷关键字或快捷关键字不存在时使用默认的搜索引擎, 没有默认的搜索引擎时使用第一个搜索引擎. 没有任何搜索引擎时返回 nil.
+func ParseSearchQuery(raw string) (*monitor_model.SearchProvider, string, error) {
+	providers, err := ListSearchProvidersByQuery(monitor_model.SearchProvider{})
+	if err != nil {
+		return nil, "", err
+	}
+
+	query := strings.TrimSpace(raw)
+	if strings.HasPrefix(query, "!") {
+		keyword, rest, _ := strings.Cut(query[1:], " ")
+		for i := range providers {
+			if len(keyword) > 0 && strings.EqualFold(providers[i].Keyword, keyword) {
+				return &providers[i], strings.TrimSpace(rest), nil
+			}
+		}
+	}
+
+	if len(providers) <= 0 {
+		return nil, query, nil
+	}
+
+	return &providers[0], query, nil
+}
+
+// Search 统一搜索: 解析快捷关键字, 获取搜索引擎的搜索建议, 并匹配标题, 链接或标签包含搜索内容的快捷方式.
+// 获取搜索建议失败时只记录日志, 不影响快捷方式的匹配结果.
+func Search(raw string, max int) (SearchQuery, error) {
+	provider, query, err := ParseSearchQuery(raw)
+	if err != nil {
+		return SearchQuery{}, err
+	}
+
+	result := SearchQuery{Provider: provider, Query: query, Suggestions: []string{}}
+
+	items, err := SearchShortcutItems(query, max)
+	if err != nil {
+		return SearchQuery{}, err
+	}
+	result.Items = items
+
+	if provider != nil {
+		result.SearchUrl = BuildSearchUrl(provider.UrlTemplate, query)
+
+		if suggestions, err := FetchSearchSuggestions(*provider, query); err != nil {
+			logger.Warn("fetch search suggestions failed, %s\n", err)
+		} else {
+			result.Suggestions = suggestions
+		}
+	}
+
+	return result, nil
+}
+
+// SearchShortcutItems 匹配标题, 链接或标签包含 keyword 的快捷方式, 按使用次数倒序排列, 使用次数相同时按标题排列.
+func SearchShortcutItems(keyword string, max int) ([]monitor_model.ShortcutItem, error) {
+	keyword = strings.TrimSpace(keyword)
+	if len(keyword) <= 0 {
+		return []monitor_model.ShortcutItem{}, nil
+	}
+
+	db := monitor_db.GetDB()
+
+	// 转义 LIKE 中的通配符
+	like := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(keyword) + "%"
+
+	var matched []struct {
+		ID         uint
+		ClickCount int
+	}
+	result := db.Model(&shortcutItemModel).
+		Select("shortcut_items.id AS id, COALESCE(SUM(shortcut_section_item_usages.click_count), 0) AS click_count").
+		Joins("LEFT JOIN shortcut_section_item_usages ON shortcut_section_item_usages.item_id = shortcut_items.id").
+		Where(`shortcut_items.title LIKE ? ESCAPE '\' OR shortcut_items.url LIKE ? ESCAPE '\' OR shortcut_items.tags LIKE ? ESCAPE '\'`, like, like, like).
+		Group("shortcut_items.id").
+		Order("click_count DESC, shortcut_items.title").
+		Limit(lo.Ternary(max > 0, max, -1)).
+		Scan(&matched)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	ids := make([]uint, len(matched))
+	for i, m := range matched {
+		ids[i] = m.ID
+	}
+
+	return listShortcutItemsByIdsInOrder(ids)
+}
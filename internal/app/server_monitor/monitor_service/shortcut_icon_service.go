@@ -1,13 +1,12 @@
 package monitor_service
 
 import (
-	"github.com/PuerkitoBio/goquery"
+	"github.com/samber/lo"
 	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
 	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
 	"github.com/siaikin/home-dashboard/internal/pkg/comfy_http_client"
+	"github.com/siaikin/home-dashboard/internal/pkg/simple_icons"
 	"net/http"
-	"path/filepath"
-	"strings"
 	"time"
 )
 
@@ -43,28 +42,45 @@ func listShortcutIcons(max int64, query monitor_model.ShortcutIcon) (*[]monitor_
 	return &icons, result.Error
 }
 
-// RefreshShortcutIcons 从 https://github.com/simple-icons/simple-icons 拉取图标列表, 并更新到数据库.
+// httpClient 包内共用的 http 客户端, 请求时需要使用完整的 url.
+var httpClient *comfy_http_client.Client
+
+func init() {
+	httpClient, _ = comfy_http_client.New("", http.Header{}, time.Minute*5)
+}
+
+// RefreshShortcutIcons 使用打包的 simple-icons 数据集更新数据库中的图标列表, 不需要访问网络.
 func RefreshShortcutIcons() error {
 	db := monitor_db.GetDB()
 
-	storedIcons, err := listShortcutIcons(0, monitor_model.ShortcutIcon{})
-	storedIconSlugMap := make(map[string]*monitor_model.ShortcutIcon, len(*storedIcons))
-	for _, icon := range *storedIcons {
-		storedIconSlugMap[icon.Slug] = &icon
+	dataset, err := simple_icons.Load()
+	if err != nil {
+		return err
+	} else if len(dataset.Icons) <= 0 {
+		logger.Warn("simple-icons dataset is empty, run go generate ./internal/pkg/simple_icons to bundle it.\n")
+		return nil
 	}
 
-	icons, err := fetchShortcutIconFromSimpleIcons(false)
+	storedIcons, err := listShortcutIcons(0, monitor_model.ShortcutIcon{})
 	if err != nil {
 		return err
-	} else if icons == nil {
-		return nil
+	}
+	storedIconSlugMap := make(map[string]monitor_model.ShortcutIcon, len(*storedIcons))
+	for _, icon := range *storedIcons {
+		storedIconSlugMap[icon.Slug] = icon
 	}
 
 	// 需要被创建的图标
 	createdIcons := make([]monitor_model.ShortcutIcon, 0)
 	// 需要被更新的图标
 	updatedIcons := make([]monitor_model.ShortcutIcon, 0)
-	for _, icon := range *icons {
+	for _, datasetIcon := range dataset.Icons {
+		icon := monitor_model.ShortcutIcon{
+			Brand: datasetIcon.Title,
+			Slug:  datasetIcon.Slug,
+			Color: "#" + datasetIcon.Hex,
+		}
+
 		if storedIcon, ok := storedIconSlugMap[icon.Slug]; !ok {
 			createdIcons = append(createdIcons, icon)
 		} else {
@@ -73,90 +89,69 @@ func RefreshShortcutIcons() error {
 				continue
 			}
 
-			icon.ID = storedIcon.ID
+			icon.Model = storedIcon.Model
 			updatedIcons = append(updatedIcons, icon)
 		}
 	}
 
-	if result := db.Model(&shortcutIconModel).Create(&createdIcons); result.Error != nil {
-		return result.Error
-	} else {
-		logger.Info("Created %d shortcut icons.", len(createdIcons))
+	if len(createdIcons) > 0 {
+		if result := db.Model(&shortcutIconModel).CreateInBatches(&createdIcons, 500); result.Error != nil {
+			return result.Error
+		}
 	}
+	logger.Info("Created %d shortcut icons from simple-icons %s.\n", len(createdIcons), dataset.Version)
 
 	for _, icon := range updatedIcons {
 		if result := db.Model(&shortcutIconModel).Save(&icon); result.Error != nil {
 			return result.Error
 		}
 	}
-	logger.Info("Updated %d shortcut icons.", len(updatedIcons))
+	logger.Info("Updated %d shortcut icons from simple-icons %s.\n", len(updatedIcons), dataset.Version)
 
 	return nil
 }
 
-var httpClient *comfy_http_client.Client
-var eTag string
-
-func init() {
-	httpClient, _ = comfy_http_client.New("https://simpleicons.org", http.Header{}, time.Minute*5)
-}
+// SearchShortcutIcons 在品牌名称和别名中模糊搜索图标, 按匹配程度排序. limit 小于等于 0 时返回所有匹配的图标.
+func SearchShortcutIcons(keyword string, limit int) ([]monitor_model.ShortcutIcon, error) {
+	db := monitor_db.GetDB()
 
-// fetchShortcutIconFromSimpleIcons 从 https://github.com/simple-icons/simple-icons 获取图标列表.
-// 如果 force 为 true, 则强制从远程获取. 否则仅在 ETag 不匹配时拉取图标列表.
-func fetchShortcutIconFromSimpleIcons(force bool) (*[]monitor_model.ShortcutIcon, error) {
-	req, err := httpClient.Get("")
+	matched, err := simple_icons.Search(keyword, limit)
 	if err != nil {
 		return nil, err
+	} else if len(matched) <= 0 {
+		return []monitor_model.ShortcutIcon{}, nil
 	}
 
-	if !force {
-		req.Header.Set("If-None-Match", eTag)
-	}
-
-	res, err := httpClient.Send(req)
-	if err != nil {
-		return nil, err
-	}
-	defer func() {
-		_ = res.Body.Close()
-	}()
+	slugs := lo.Map(matched, func(icon simple_icons.Icon, _ int) string { return icon.Slug })
 
-	if res.StatusCode == http.StatusNotModified {
-		return nil, nil
+	icons := make([]monitor_model.ShortcutIcon, 0, len(slugs))
+	if result := db.Model(&shortcutIconModel).Where("slug IN ?", slugs).Find(&icons); result.Error != nil {
+		return nil, result.Error
 	}
 
-	if res.StatusCode != http.StatusOK {
-		return nil, err
+	// 按搜索结果的顺序排序.
+	iconSlugMap := lo.KeyBy(icons, func(icon monitor_model.ShortcutIcon) string { return icon.Slug })
+	sorted := make([]monitor_model.ShortcutIcon, 0, len(icons))
+	for _, slug := range slugs {
+		if icon, ok := iconSlugMap[slug]; ok {
+			sorted = append(sorted, icon)
+		}
 	}
 
-	eTag = res.Header.Get("ETag")
+	return sorted, nil
+}
 
-	doc, err := goquery.NewDocumentFromReader(res.Body)
+// GetShortcutIconSvg 获取图标的 SVG 文件内容以及数据集的版本, 图标不存在时返回 ErrorNotFound.
+func GetShortcutIconSvg(slug string) (string, string, error) {
+	dataset, err := simple_icons.Load()
 	if err != nil {
-		return nil, err
+		return "", "", err
 	}
 
-	icons := make([]monitor_model.ShortcutIcon, 0)
-	doc.Find("li.grid-item").Each(func(i int, s *goquery.Selection) {
-		// 忽略已废弃的图标
-		if s.Find(".deprecated").Size() > 0 {
-			return
-		}
-
-		iconUrl := s.Find("img.icon-preview").AttrOr("d-src", ".svg")
-		slug := strings.Replace(filepath.Base(iconUrl), ".svg", "", 1)
-		if len(slug) <= 0 {
-			return
-		}
-
-		icon := monitor_model.ShortcutIcon{
-			Brand: s.Find(".grid-item__title").Text(),
-			Slug:  slug,
-			Color: s.Find(".grid-item__color").Text(),
-		}
-
-		icons = append(icons, icon)
-	})
+	icon, ok := dataset.Get(slug)
+	if !ok {
+		return "", "", ErrorNotFound
+	}
 
-	return &icons, nil
+	return icon.Svg(), dataset.Version, nil
 }
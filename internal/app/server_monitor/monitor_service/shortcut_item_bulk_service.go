@@ -0,0 +1,216 @@
+package monitor_service
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/samber/lo"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ShortcutItemBulkAction 批量操作的类型.
+type ShortcutItemBulkAction = string
+
+const (
+	// ShortcutItemBulkActionMove 将快捷方式从 FromSectionId 移动到 SectionIds. FromSectionId 为 0 时从所有分组中移出.
+	ShortcutItemBulkActionMove ShortcutItemBulkAction = "move"
+	// ShortcutItemBulkActionCopy 复制快捷方式(创建新的快捷方式)并添加到 SectionIds.
+	ShortcutItemBulkActionCopy ShortcutItemBulkAction = "copy"
+	// ShortcutItemBulkActionAssign 将快捷方式添加到 SectionIds, 不影响快捷方式所在的其他分组.
+	ShortcutItemBulkActionAssign ShortcutItemBulkAction = "assign"
+	// ShortcutItemBulkActionUnassign 将快捷方式从 SectionIds 中移出.
+	ShortcutItemBulkActionUnassign ShortcutItemBulkAction = "unassign"
+	// ShortcutItemBulkActionUpdate 批量修改快捷方式的字段, 仅修改 Fields 中不为 nil 的字段.
+	ShortcutItemBulkActionUpdate ShortcutItemBulkAction = "update"
+)
+
+// ShortcutItemBulkFields 批量修改的字段, 为 nil 的字段不会被修改.
+type ShortcutItemBulkFields struct {
+	Target          *monitor_model.ShortcutItemTargetType `json:"target"`
+	StatusCheck     *bool                                 `json:"statusCheck"`
+	BackgroundColor *string                               `json:"backgroundColor"`
+	Tags            *string                               `json:"tags"`
+}
+
+// ShortcutItemBulkOperation 一次批量操作.
+type ShortcutItemBulkOperation struct {
+	Action  ShortcutItemBulkAction `json:"action" binding:"required"`
+	ItemIds []uint                 `json:"itemIds" binding:"required"`
+	// FromSectionId 仅用于 ShortcutItemBulkActionMove.
+	FromSectionId uint `json:"fromSectionId"`
+	// SectionIds 目标分组, 用于 move, copy, assign 和 unassign.
+	SectionIds []uint `json:"sectionIds"`
+	// Fields 仅用于 ShortcutItemBulkActionUpdate.
+	Fields ShortcutItemBulkFields `json:"fields"`
+}
+
+// ShortcutItemBulkItemResult 单个快捷方式的操作结果.
+type ShortcutItemBulkItemResult struct {
+	ItemId uint `json:"itemId"`
+	// CreatedItemId 复制操作创建的快捷方式 id.
+	CreatedItemId uint   `json:"createdItemId,omitempty"`
+	Success       bool   `json:"success"`
+	Error         string `json:"error,omitempty"`
+}
+
+// ShortcutItemBulkOperationResult 一次批量操作的结果.
+type ShortcutItemBulkOperationResult struct {
+	Action ShortcutItemBulkAction       `json:"action"`
+	Items  []ShortcutItemBulkItemResult `json:"items"`
+}
+
+var errShortcutItemBulkFailed = errors.New("some shortcut item bulk operations failed")
+
+// BulkOperateShortcutItems 在同一个事务中依次执行 operations. 只要有一个快捷方式操作失败, 所有操作都将回滚.
+// 返回每个快捷方式的操作结果以及事务是否已提交.
+func BulkOperateShortcutItems(operations []ShortcutItemBulkOperation) ([]ShortcutItemBulkOperationResult, bool, error) {
+	db := monitor_db.GetDB()
+
+	results := make([]ShortcutItemBulkOperationResult, len(operations))
+	err := db.Transaction(func(tx *gorm.DB) error {
+		failed := false
+
+		for i, operation := range operations {
+			results[i] = bulkOperateShortcutItems(tx, operation)
+
+			failed = failed || lo.SomeBy(results[i].Items, func(result ShortcutItemBulkItemResult) bool {
+				return !result.Success
+			})
+		}
+
+		if failed {
+			return errShortcutItemBulkFailed
+		}
+
+		return nil
+	})
+
+	if errors.Is(err, errShortcutItemBulkFailed) {
+		return results, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	return results, true, nil
+}
+
+func bulkOperateShortcutItems(tx *gorm.DB, operation ShortcutItemBulkOperation) ShortcutItemBulkOperationResult {
+	itemIds := lo.Uniq(operation.ItemIds)
+	result := ShortcutItemBulkOperationResult{
+		Action: operation.Action,
+		Items:  make([]ShortcutItemBulkItemResult, len(itemIds)),
+	}
+
+	// 分组校验失败时, 该操作涉及的所有快捷方式都视为失败.
+	sections, sectionErr := findBulkTargetSections(tx, operation)
+
+	for i, itemId := range itemIds {
+		itemResult := ShortcutItemBulkItemResult{ItemId: itemId}
+
+		var err error
+		if sectionErr != nil {
+			err = sectionErr
+		} else {
+			itemResult.CreatedItemId, err = bulkOperateShortcutItem(tx, operation, itemId, sections)
+		}
+
+		if err != nil {
+			itemResult.Error = err.Error()
+		} else {
+			itemResult.Success = true
+		}
+		result.Items[i] = itemResult
+	}
+
+	return result
+}
+
+// findBulkTargetSections 查询操作的目标分组, 任意一个分组不存在都将返回错误.
+func findBulkTargetSections(tx *gorm.DB, operation ShortcutItemBulkOperation) ([]monitor_model.ShortcutSection, error) {
+	switch operation.Action {
+	case ShortcutItemBulkActionMove, ShortcutItemBulkActionCopy, ShortcutItemBulkActionAssign, ShortcutItemBulkActionUnassign:
+	case ShortcutItemBulkActionUpdate:
+		return nil, nil
+	default:
+		return nil, errors.Errorf("unknown bulk action %s", operation.Action)
+	}
+
+	sectionIds := lo.Uniq(operation.SectionIds)
+	if len(sectionIds) <= 0 {
+		return nil, errors.Errorf("sectionIds is required for %s", operation.Action)
+	}
+
+	sections := make([]monitor_model.ShortcutSection, 0, len(sectionIds))
+	if result := tx.Model(&shortcutSectionModel).Where("id IN ?", sectionIds).Find(&sections); result.Error != nil {
+		return nil, result.Error
+	}
+	if len(sections) != len(sectionIds) {
+		missing, _ := lo.Difference(sectionIds, lo.Map(sections, func(section monitor_model.ShortcutSection, _ int) uint {
+			return section.ID
+		}))
+		return nil, errors.Errorf("sections %v not found", missing)
+	}
+
+	return sections, nil
+}
+
+// bulkOperateShortcutItem 对单个快捷方式执行操作, 复制操作会返回新建的快捷方式 id.
+func bulkOperateShortcutItem(tx *gorm.DB, operation ShortcutItemBulkOperation, itemId uint, sections []monitor_model.ShortcutSection) (uint, error) {
+	item := monitor_model.ShortcutItem{}
+	if result := tx.Model(&shortcutItemModel).First(&item, itemId); errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return 0, errors.Errorf("item %d not found", itemId)
+	} else if result.Error != nil {
+		return 0, result.Error
+	}
+
+	switch operation.Action {
+	case ShortcutItemBulkActionMove:
+		if operation.FromSectionId != 0 {
+			if err := tx.Model(&item).Association("Sections").Delete(&monitor_model.ShortcutSection{Model: monitor_model.Model{ID: operation.FromSectionId}}); err != nil {
+				return 0, err
+			}
+		} else if err := tx.Model(&item).Association("Sections").Clear(); err != nil {
+			return 0, err
+		}
+
+		return 0, tx.Model(&item).Association("Sections").Append(&sections)
+	case ShortcutItemBulkActionCopy:
+		copied := item
+		copied.Model = monitor_model.Model{}
+		copied.Icon = monitor_model.ShortcutIcon{}
+		copied.Sections = nil
+		copied.Usages = nil
+
+		if result := tx.Omit(clause.Associations).Create(&copied); result.Error != nil {
+			return 0, result.Error
+		}
+
+		return copied.ID, tx.Model(&copied).Association("Sections").Append(&sections)
+	case ShortcutItemBulkActionAssign:
+		return 0, tx.Model(&item).Association("Sections").Append(&sections)
+	case ShortcutItemBulkActionUnassign:
+		return 0, tx.Model(&item).Association("Sections").Delete(&sections)
+	case ShortcutItemBulkActionUpdate:
+		fields := map[string]any{}
+		if operation.Fields.Target != nil {
+			fields["target"] = *operation.Fields.Target
+		}
+		if operation.Fields.StatusCheck != nil {
+			fields["status_check"] = *operation.Fields.StatusCheck
+		}
+		if operation.Fields.BackgroundColor != nil {
+			fields["background_color"] = *operation.Fields.BackgroundColor
+		}
+		if operation.Fields.Tags != nil {
+			fields["tags"] = *operation.Fields.Tags
+		}
+		if len(fields) <= 0 {
+			return 0, errors.Errorf("no fields to update")
+		}
+
+		return 0, tx.Model(&item).Updates(fields).Error
+	default:
+		return 0, errors.Errorf("unknown bulk action %s", operation.Action)
+	}
+}
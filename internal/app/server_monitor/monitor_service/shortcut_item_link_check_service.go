@@ -0,0 +1,132 @@
+package monitor_service
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/samber/lo"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/pkg/link_checker"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	url2 "net/url"
+)
+
+var shortcutItemLinkCheckModel = monitor_model.ShortcutItemLinkCheck{}
+
+// CreateOrUpdateShortcutItemLinkChecks 保存快捷方式链接的检查结果, 已存在的检查结果将被覆盖.
+func CreateOrUpdateShortcutItemLinkChecks(checks []monitor_model.ShortcutItemLinkCheck) error {
+	if len(checks) <= 0 {
+		return nil
+	}
+
+	db := monitor_db.GetDB()
+
+	result := db.Model(&shortcutItemLinkCheckModel).Omit(clause.Associations).Clauses(clause.OnConflict{UpdateAll: true}).Create(&checks)
+
+	return result.Error
+}
+
+// ListShortcutItemLinkChecks 获取快捷方式链接的检查结果, 已删除的快捷方式的检查结果将被忽略.
+// 如果 onlyProblems 为 true, 则只返回链接失效或被永久重定向的检查结果.
+func ListShortcutItemLinkChecks(onlyProblems bool) ([]monitor_model.ShortcutItemLinkCheck, error) {
+	db := monitor_db.GetDB()
+
+	model := db.Model(&shortcutItemLinkCheckModel).
+		Joins("JOIN shortcut_items ON shortcut_items.id = shortcut_item_link_checks.item_id AND shortcut_items.deleted_at = 0").
+		Preload("Item")
+	if onlyProblems {
+		model = model.Where("shortcut_item_link_checks.status <> ?", link_checker.StatusOk)
+	}
+
+	checks := make([]monitor_model.ShortcutItemLinkCheck, 0)
+	result := model.Order("shortcut_item_link_checks.checked_at desc").Find(&checks)
+
+	return checks, result.Error
+}
+
+// DeleteShortcutItemLinkChecks 删除快捷方式链接的检查结果.
+func DeleteShortcutItemLinkChecks(itemIds []uint) error {
+	if len(itemIds) <= 0 {
+		return nil
+	}
+
+	db := monitor_db.GetDB()
+
+	result := db.Where("item_id IN ?", itemIds).Delete(&shortcutItemLinkCheckModel)
+
+	return result.Error
+}
+
+// AcceptShortcutItemLinkCheck 将快捷方式的链接更新为 url, url 为空时使用检查结果中的建议地址.
+// 如果状态检查地址与原链接相同, 则一并更新. 如果图标与原链接同源, 则将图标地址迁移到新地址并刷新缓存的图标.
+// 更新成功后删除该快捷方式的检查结果.
+func AcceptShortcutItemLinkCheck(itemId uint, url string) (monitor_model.ShortcutItem, error) {
+	db := monitor_db.GetDB()
+
+	check := monitor_model.ShortcutItemLinkCheck{}
+	if result := db.Model(&shortcutItemLinkCheckModel).Where("item_id = ?", itemId).First(&check); errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return monitor_model.ShortcutItem{}, ErrorNotFound
+	} else if result.Error != nil {
+		return monitor_model.ShortcutItem{}, result.Error
+	}
+
+	item := monitor_model.ShortcutItem{}
+	if result := db.Model(&shortcutItemModel).First(&item, itemId); errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return monitor_model.ShortcutItem{}, ErrorNotFound
+	} else if result.Error != nil {
+		return monitor_model.ShortcutItem{}, result.Error
+	}
+
+	newUrl := lo.Ternary(len(url) > 0, url, check.SuggestedUrl)
+	if len(newUrl) <= 0 {
+		return monitor_model.ShortcutItem{}, errors.Errorf("item %d has no suggested url", itemId)
+	}
+	parsedNewUrl, err := url2.Parse(newUrl)
+	if err != nil || !parsedNewUrl.IsAbs() {
+		return monitor_model.ShortcutItem{}, errors.Errorf("invalid url %s", newUrl)
+	}
+
+	oldUrl := item.URL
+	item.URL = newUrl
+	if item.StatusCheckUrl == oldUrl {
+		item.StatusCheckUrl = newUrl
+	}
+	if iconUrl, ok := rebaseUrl(item.IconUrl, oldUrl, parsedNewUrl); ok {
+		item.IconUrl = iconUrl
+	}
+	if cachedUrl := GetCachedShortcutItemImageIconUrl(item); len(cachedUrl) > 0 {
+		item.IconCachedUrl = cachedUrl
+	}
+
+	updated, err := CreateOrUpdateShortcutItems([]monitor_model.ShortcutItem{item})
+	if err != nil {
+		return monitor_model.ShortcutItem{}, err
+	}
+
+	if err := DeleteShortcutItemLinkChecks([]uint{itemId}); err != nil {
+		return monitor_model.ShortcutItem{}, err
+	}
+
+	return updated[0], nil
+}
+
+// rebaseUrl 如果 url 与 oldBase 同源, 则将 url 的协议和主机替换为 newBase 的协议和主机.
+func rebaseUrl(url string, oldBase string, newBase *url2.URL) (string, bool) {
+	parsedUrl, err := url2.Parse(url)
+	if err != nil {
+		return "", false
+	}
+	parsedOldBase, err := url2.Parse(oldBase)
+	if err != nil {
+		return "", false
+	}
+
+	if parsedUrl.Scheme != parsedOldBase.Scheme || parsedUrl.Host != parsedOldBase.Host || len(parsedUrl.Host) <= 0 {
+		return "", false
+	}
+
+	parsedUrl.Scheme = newBase.Scheme
+	parsedUrl.Host = newBase.Host
+
+	return parsedUrl.String(), true
+}
@@ -41,8 +41,8 @@ func CreateOrUpdateShortcutItems(items []monitor_model.ShortcutItem) ([]monitor_
 	return affected, nil
 }
 
-// DeleteShortcutItems 删除 monitor_model.ShortcutItem.
-// 如果快捷方式已被 monitor_model.ShortcutSection 引用, 则也会删除 monitor_model.ShortcutItem 与 monitor_model.ShortcutSection 的关联关系.
+// DeleteShortcutItems 删除 monitor_model.ShortcutItem, 被删除的快捷方式将被移入回收站.
+// monitor_model.ShortcutItem 与 monitor_model.ShortcutSection 的关联关系会被保留, 以便从回收站恢复, 见 RestoreShortcutItems.
 func DeleteShortcutItems(ids []uint) error {
 	db := monitor_db.GetDB()
 
@@ -50,7 +50,7 @@ func DeleteShortcutItems(ids []uint) error {
 	for i, id := range ids {
 		items[i] = monitor_model.ShortcutItem{Model: monitor_model.Model{ID: id}}
 	}
-	if result := db.Select("Sections").Delete(&items); result.Error != nil {
+	if result := db.Delete(&items); result.Error != nil {
 		return result.Error
 	}
 
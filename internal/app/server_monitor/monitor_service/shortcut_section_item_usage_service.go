@@ -1,9 +1,11 @@
 package monitor_service
 
 import (
+	"github.com/jinzhu/now"
 	"github.com/samber/lo"
 	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
 	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"time"
 )
 
 var shortcutSectionItemUsageModel = monitor_model.ShortcutSectionItemUsage{}
@@ -15,12 +17,17 @@ func CreateOrUpdateShortcutSectionItemUsages(usages []monitor_model.ShortcutSect
 		return usage.SectionId > 0 && usage.ItemId > 0
 	})
 
-	// 如果 usages 中有记录在数据库中不存在, 先创建这些记录.
-	createdUsages := lo.Filter(usages, func(usage monitor_model.ShortcutSectionItemUsage, index int) bool {
+	// 如果 usages 中有记录在数据库中不存在, 先创建这些记录. 同一批中可能包含同一快捷方式的多次点击, 只需创建一次.
+	createdUsages := lo.UniqBy(lo.Filter(usages, func(usage monitor_model.ShortcutSectionItemUsage, index int) bool {
 		count, err := CountShortcutSectionItemUsage(monitor_model.ShortcutSectionItemUsage{SectionId: usage.SectionId, ItemId: usage.ItemId})
 		return err == nil && count <= 0
+	}), func(usage monitor_model.ShortcutSectionItemUsage) [2]uint {
+		return [2]uint{usage.SectionId, usage.ItemId}
 	})
 	for _, usage := range createdUsages {
+		// 新建的记录只作为占位, 点击次数在下方统一累加, 避免重复计数.
+		usage.ClickCount = 0
+		usage.LastClickedAt = 0
 		if result := db.Create(&usage); result.Error != nil {
 			return nil, result.Error
 		}
@@ -43,9 +50,20 @@ func CreateOrUpdateShortcutSectionItemUsages(usages []monitor_model.ShortcutSect
 			return nil, result.Error
 		}
 
+		// 未指定点击时间时, 以收到使用记录的时间作为点击时间.
+		if usage.LastClickedAt <= 0 {
+			usage.LastClickedAt = time.Now().UnixMilli()
+		}
+
+		// 在累加总点击次数前记录当天的使用情况, 此时 usage.ClickCount 为本次新增的点击次数.
+		if err := increaseShortcutSectionItemDailyUsage(usage); err != nil {
+			return nil, err
+		}
+
 		usage.ClickCount += storedUsage.ClickCount
+		usage.LastClickedAt = lo.Max([]int64{usage.LastClickedAt, storedUsage.LastClickedAt})
 
-		if result := db.Model(usage).Update("ClickCount", usage.ClickCount); result.Error != nil {
+		if result := db.Model(usage).Select("ClickCount", "LastClickedAt").Updates(usage); result.Error != nil {
 			return nil, result.Error
 		}
 		affected[i] = usage
@@ -54,6 +72,25 @@ func CreateOrUpdateShortcutSectionItemUsages(usages []monitor_model.ShortcutSect
 	return affected, nil
 }
 
+// increaseShortcutSectionItemDailyUsage 将 usage 中的点击次数累加到点击时间所在当天的使用记录中.
+func increaseShortcutSectionItemDailyUsage(usage monitor_model.ShortcutSectionItemUsage) error {
+	db := monitor_db.GetDB()
+
+	dailyUsage := monitor_model.ShortcutSectionItemDailyUsage{
+		SectionId: usage.SectionId,
+		ItemId:    usage.ItemId,
+		Day:       now.With(time.UnixMilli(usage.LastClickedAt)).BeginningOfDay().UnixMilli(),
+	}
+	if result := db.Where(&dailyUsage).FirstOrCreate(&dailyUsage); result.Error != nil {
+		return result.Error
+	}
+
+	dailyUsage.ClickCount += usage.ClickCount
+	dailyUsage.LastClickedAt = lo.Max([]int64{usage.LastClickedAt, dailyUsage.LastClickedAt})
+
+	return db.Model(&dailyUsage).Select("ClickCount", "LastClickedAt").Updates(&dailyUsage).Error
+}
+
 func DeleteShortcutSectionItemUsages(ids []uint) error {
 	db := monitor_db.GetDB()
 
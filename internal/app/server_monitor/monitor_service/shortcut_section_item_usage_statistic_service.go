@@ -0,0 +1,162 @@
+package monitor_service
+
+import (
+	"github.com/jinzhu/now"
+	"github.com/samber/lo"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"math"
+	"sort"
+	"time"
+)
+
+var shortcutSectionItemDailyUsageModel = monitor_model.ShortcutSectionItemDailyUsage{}
+
+var (
+	// FrequentlyUsedShortcutItemDays "最常使用" 虚拟分组统计的天数.
+	FrequentlyUsedShortcutItemDays = 30
+	// FrequentlyUsedShortcutItemHalfLife "最常使用" 虚拟分组中点击次数的衰减半衰期. 越早的点击对评分的贡献越小.
+	FrequentlyUsedShortcutItemHalfLife = time.Hour * 24 * 7
+)
+
+// ShortcutSectionItemUsageStatistic 分组下的快捷方式在一段时间内的使用情况.
+type ShortcutSectionItemUsageStatistic struct {
+	SectionId uint `json:"sectionId"`
+	ItemId    uint `json:"itemId"`
+	// Day 按天统计时为当天零点的毫秒时间戳, 否则为 0.
+	Day           int64 `json:"day,omitempty"`
+	ClickCount    int   `json:"clickCount"`
+	LastClickedAt int64 `json:"lastClickedAt"`
+}
+
+// ListShortcutSectionItemUsageStatistics 统计 [start, end] 时间范围内(毫秒时间戳, 为 0 时不限制)的快捷方式使用情况, 按点击次数倒序排列.
+// query 中的 SectionId 和 ItemId 用于过滤. 如果 groupByDay 为 true, 则按天分别统计.
+func ListShortcutSectionItemUsageStatistics(query monitor_model.ShortcutSectionItemDailyUsage, start int64, end int64, groupByDay bool) ([]ShortcutSectionItemUsageStatistic, error) {
+	db := monitor_db.GetDB()
+
+	model := db.Model(&shortcutSectionItemDailyUsageModel).Where(&monitor_model.ShortcutSectionItemDailyUsage{SectionId: query.SectionId, ItemId: query.ItemId})
+	if start > 0 {
+		// 每天的记录以当天零点为准, 因此需要包含 start 所在的当天.
+		model = model.Where("day >= ?", now.With(time.UnixMilli(start)).BeginningOfDay().UnixMilli())
+	}
+	if end > 0 {
+		model = model.Where("day <= ?", end)
+	}
+
+	columns := "section_id, item_id, SUM(click_count) AS click_count, MAX(last_clicked_at) AS last_clicked_at"
+	groups := "section_id, item_id"
+	if groupByDay {
+		columns += ", day"
+		groups += ", day"
+	}
+
+	statistics := make([]ShortcutSectionItemUsageStatistic, 0)
+	result := model.Select(columns).Group(groups).Order("click_count desc").Scan(&statistics)
+
+	return statistics, result.Error
+}
+
+// ListRecentlyUsedShortcutItems 获取最近使用的 max 个快捷方式, 按最后一次点击时间倒序排列. 同一快捷方式在多个分组中的使用情况会合并计算.
+func ListRecentlyUsedShortcutItems(max int) ([]monitor_model.ShortcutItem, error) {
+	db := monitor_db.GetDB()
+
+	itemIds := make([]uint, 0)
+	result := db.Model(&shortcutSectionItemUsageModel).
+		Joins("JOIN shortcut_items ON shortcut_items.id = item_id AND shortcut_items.deleted_at = 0").
+		Where("last_clicked_at > 0").
+		Group("item_id").
+		Order("MAX(last_clicked_at) desc").
+		Limit(lo.Ternary(max > 0, max, -1)).
+		Pluck("item_id", &itemIds)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return listShortcutItemsByIdsInOrder(itemIds)
+}
+
+// ListFrequentlyUsedShortcutItems 获取最近 FrequentlyUsedShortcutItemDays 天内最常使用的 max 个快捷方式.
+// 每次点击的权重随时间按 FrequentlyUsedShortcutItemHalfLife 指数衰减, 近期频繁使用的快捷方式排名更靠前.
+func ListFrequentlyUsedShortcutItems(max int) ([]monitor_model.ShortcutItem, error) {
+	db := monitor_db.GetDB()
+
+	current := time.Now()
+	since := now.With(current.AddDate(0, 0, 1-FrequentlyUsedShortcutItemDays)).BeginningOfDay()
+
+	dailyUsages := make([]monitor_model.ShortcutSectionItemDailyUsage, 0)
+	result := db.Model(&shortcutSectionItemDailyUsageModel).
+		Joins("JOIN shortcut_items ON shortcut_items.id = item_id AND shortcut_items.deleted_at = 0").
+		Where("day >= ?", since.UnixMilli()).
+		Find(&dailyUsages)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	scores := make(map[uint]float64)
+	for _, usage := range dailyUsages {
+		age := current.Sub(time.UnixMilli(usage.LastClickedAt))
+		if age < 0 {
+			age = 0
+		}
+
+		scores[usage.ItemId] += float64(usage.ClickCount) * math.Pow(0.5, float64(age)/float64(FrequentlyUsedShortcutItemHalfLife))
+	}
+
+	itemIds := lo.Keys(scores)
+	sort.SliceStable(itemIds, func(i, j int) bool {
+		if scores[itemIds[i]] == scores[itemIds[j]] {
+			return itemIds[i] < itemIds[j]
+		}
+		return scores[itemIds[i]] > scores[itemIds[j]]
+	})
+	if max > 0 && len(itemIds) > max {
+		itemIds = itemIds[:max]
+	}
+
+	return listShortcutItemsByIdsInOrder(itemIds)
+}
+
+// ListVirtualShortcutSections 获取根据使用情况计算得出的虚拟分组, 包括 "最近使用" 和 "最常使用". 每个分组最多包含 max 个快捷方式.
+func ListVirtualShortcutSections(max int) ([]monitor_model.ShortcutSection, error) {
+	recent, err := ListRecentlyUsedShortcutItems(max)
+	if err != nil {
+		return nil, err
+	}
+
+	frequent, err := ListFrequentlyUsedShortcutItems(max)
+	if err != nil {
+		return nil, err
+	}
+
+	return []monitor_model.ShortcutSection{
+		{Name: "Recently used", Items: recent, Virtual: monitor_model.ShortcutSectionVirtualTypeRecent},
+		{Name: "Most used (last 30 days)", Items: frequent, Virtual: monitor_model.ShortcutSectionVirtualTypeFrequent},
+	}, nil
+}
+
+// listShortcutItemsByIdsInOrder 根据 ids 获取快捷方式, 返回结果与 ids 的顺序一致. 不存在的快捷方式将被忽略.
+func listShortcutItemsByIdsInOrder(ids []uint) ([]monitor_model.ShortcutItem, error) {
+	if len(ids) <= 0 {
+		return []monitor_model.ShortcutItem{}, nil
+	}
+
+	db := monitor_db.GetDB()
+
+	items := make([]monitor_model.ShortcutItem, 0, len(ids))
+	if result := db.Model(&shortcutItemModel).Preload("Icon").Where("id IN ?", ids).Find(&items); result.Error != nil {
+		return nil, result.Error
+	}
+
+	itemMap := lo.KeyBy(items, func(item monitor_model.ShortcutItem) uint {
+		return item.ID
+	})
+
+	ordered := make([]monitor_model.ShortcutItem, 0, len(items))
+	for _, id := range ids {
+		if item, ok := itemMap[id]; ok {
+			ordered = append(ordered, item)
+		}
+	}
+
+	return ordered, nil
+}
@@ -33,6 +33,8 @@ func CreateOrUpdateShortcutSections(sections []monitor_model.ShortcutSection) ([
 	return affected, nil
 }
 
+// DeleteShortcutSections 删除 monitor_model.ShortcutSection, 被删除的分组将被移入回收站.
+// 分组与快捷方式的关联关系会被保留, 以便从回收站恢复, 见 RestoreShortcutSections.
 func DeleteShortcutSections(ids []uint) error {
 	db := monitor_db.GetDB()
 
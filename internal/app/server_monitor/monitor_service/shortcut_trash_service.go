@@ -0,0 +1,241 @@
+package monitor_service
+
+import (
+	"github.com/samber/lo"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"gorm.io/gorm"
+	"math"
+	"time"
+)
+
+// 回收站即被软删除的 monitor_model.ShortcutSection 和 monitor_model.ShortcutItem.
+// 软删除时保留分组与快捷方式的关联关系以及使用记录, 以便恢复. 彻底删除时才会删除这些数据.
+
+// ListTrashedShortcutSections 获取回收站中的分组, 按删除时间倒序排列. 分组中只包含未删除的快捷方式.
+func ListTrashedShortcutSections() ([]monitor_model.ShortcutSection, error) {
+	db := monitor_db.GetDB()
+
+	sections := make([]monitor_model.ShortcutSection, 0)
+	result := db.Unscoped().Model(&shortcutSectionModel).
+		Preload("Items", "deleted_at = 0").
+		Where("deleted_at != 0").
+		Order("deleted_at DESC").
+		Find(&sections)
+
+	return sections, result.Error
+}
+
+// ListTrashedShortcutItems 获取回收站中的快捷方式, 按删除时间倒序排列. 快捷方式中包含删除前所属的分组(包括已删除的分组).
+func ListTrashedShortcutItems() ([]monitor_model.ShortcutItem, error) {
+	db := monitor_db.GetDB()
+
+	items := make([]monitor_model.ShortcutItem, 0)
+	result := db.Unscoped().Model(&shortcutItemModel).
+		Preload("Sections").
+		Preload("Icon").
+		Where("deleted_at != 0").
+		Order("deleted_at DESC").
+		Find(&items)
+
+	return items, result.Error
+}
+
+// RestoreShortcutSections 恢复回收站中的分组, 分组中的快捷方式随之恢复显示.
+func RestoreShortcutSections(ids []uint) error {
+	db := monitor_db.GetDB()
+
+	return db.Unscoped().Model(&shortcutSectionModel).Where("id IN ? AND deleted_at != 0", ids).Update("deleted_at", 0).Error
+}
+
+// RestoreShortcutItems 恢复回收站中的快捷方式.
+// 快捷方式与分组的关联关系在删除时被保留, 恢复后仍然属于原来的分组. 如果快捷方式不属于任何未删除的分组
+// (如分组已被删除, 或快捷方式由旧版本删除而丢失了关联关系), 则根据使用记录恢复关联关系, 仍然没有时添加到默认分组.
+func RestoreShortcutItems(ids []uint) error {
+	db := monitor_db.GetDB()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		items := make([]monitor_model.ShortcutItem, 0)
+		if result := tx.Unscoped().Model(&shortcutItemModel).Where("id IN ? AND deleted_at != 0", ids).Find(&items); result.Error != nil {
+			return result.Error
+		}
+
+		for _, item := range items {
+			if result := tx.Unscoped().Model(&item).Update("deleted_at", 0); result.Error != nil {
+				return result.Error
+			}
+
+			if count := tx.Model(&item).Association("Sections").Count(); count > 0 {
+				continue
+			}
+
+			sections, err := restorableShortcutSections(tx, item.ID)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&item).Association("Sections").Append(&sections); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// restorableShortcutSections 获取快捷方式恢复后应该加入的分组: 有使用记录的未删除分组, 没有时为默认分组.
+func restorableShortcutSections(tx *gorm.DB, itemId uint) ([]monitor_model.ShortcutSection, error) {
+	sections := make([]monitor_model.ShortcutSection, 0)
+
+	usedSectionIds := tx.Model(&shortcutSectionItemUsageModel).Select("section_id").Where("item_id = ?", itemId)
+	if result := tx.Model(&shortcutSectionModel).Where("id IN (?)", usedSectionIds).Find(&sections); result.Error != nil {
+		return nil, result.Error
+	} else if len(sections) > 0 {
+		return sections, nil
+	}
+
+	if result := tx.Model(&shortcutSectionModel).Where(&monitor_model.ShortcutSection{Default: true}).Limit(1).Find(&sections); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return sections, nil
+}
+
+// PurgeShortcutSections 彻底删除回收站中的分组以及分组的关联关系和使用记录, 未删除的分组不受影响.
+// 删除后不属于任何未删除分组的快捷方式将被移入回收站.
+func PurgeShortcutSections(ids []uint) error {
+	db := monitor_db.GetDB()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		return purgeShortcutSections(tx, ids)
+	})
+}
+
+func purgeShortcutSections(tx *gorm.DB, ids []uint) error {
+	sectionIds := make([]uint, 0)
+	if result := tx.Unscoped().Model(&shortcutSectionModel).Where("id IN ? AND deleted_at != 0", ids).Pluck("id", &sectionIds); result.Error != nil {
+		return result.Error
+	} else if len(sectionIds) <= 0 {
+		return nil
+	}
+
+	// 分组中的快捷方式
+	itemIds := make([]uint, 0)
+	if result := tx.Table("shortcut_section_link_shortcut_item").Where("shortcut_section_id IN ?", sectionIds).Distinct().Pluck("shortcut_item_id", &itemIds); result.Error != nil {
+		return result.Error
+	}
+
+	if result := tx.Table("shortcut_section_item_link_shortcut_usage").Where("shortcut_section_item_usage_section_id IN ?", sectionIds).Delete(nil); result.Error != nil {
+		return result.Error
+	}
+	if result := tx.Where("section_id IN ?", sectionIds).Delete(&monitor_model.ShortcutSectionItemUsage{}); result.Error != nil {
+		return result.Error
+	}
+	if result := tx.Where("section_id IN ?", sectionIds).Delete(&monitor_model.ShortcutSectionItemDailyUsage{}); result.Error != nil {
+		return result.Error
+	}
+	if result := tx.Model(&dockerContainerShortcutModel).Where("section_id IN ?", sectionIds).Updates(map[string]any{"section_id": 0, "section_created": false}); result.Error != nil {
+		return result.Error
+	}
+
+	sections := lo.Map(sectionIds, func(id uint, _ int) monitor_model.ShortcutSection {
+		return monitor_model.ShortcutSection{Model: monitor_model.Model{ID: id}}
+	})
+	if result := tx.Unscoped().Select("Items").Delete(&sections); result.Error != nil {
+		return result.Error
+	}
+
+	if len(itemIds) <= 0 {
+		return nil
+	}
+
+	// 将不再属于任何未删除分组的快捷方式移入回收站
+	liveItemIds := tx.Table("shortcut_section_link_shortcut_item").
+		Select("shortcut_item_id").
+		Joins("JOIN shortcut_sections ON shortcut_sections.id = shortcut_section_id AND shortcut_sections.deleted_at = 0")
+	return tx.Model(&shortcutItemModel).Where("id IN ? AND id NOT IN (?)", itemIds, liveItemIds).Update("deleted_at", time.Now().UnixMilli()).Error
+}
+
+// PurgeShortcutItems 彻底删除回收站中的快捷方式以及快捷方式的关联关系, 使用记录和链接检查结果, 未删除的快捷方式不受影响.
+func PurgeShortcutItems(ids []uint) error {
+	db := monitor_db.GetDB()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		return purgeShortcutItems(tx, ids)
+	})
+}
+
+func purgeShortcutItems(tx *gorm.DB, ids []uint) error {
+	itemIds := make([]uint, 0)
+	if result := tx.Unscoped().Model(&shortcutItemModel).Where("id IN ? AND deleted_at != 0", ids).Pluck("id", &itemIds); result.Error != nil {
+		return result.Error
+	} else if len(itemIds) <= 0 {
+		return nil
+	}
+
+	if result := tx.Table("shortcut_section_item_link_shortcut_usage").Where("shortcut_section_item_usage_item_id IN ?", itemIds).Delete(nil); result.Error != nil {
+		return result.Error
+	}
+	if result := tx.Where("item_id IN ?", itemIds).Delete(&monitor_model.ShortcutSectionItemUsage{}); result.Error != nil {
+		return result.Error
+	}
+	if result := tx.Where("item_id IN ?", itemIds).Delete(&monitor_model.ShortcutSectionItemDailyUsage{}); result.Error != nil {
+		return result.Error
+	}
+	if result := tx.Where("item_id IN ?", itemIds).Delete(&monitor_model.ShortcutItemLinkCheck{}); result.Error != nil {
+		return result.Error
+	}
+	// 容器重新启动时会重新创建快捷方式.
+	if result := tx.Unscoped().Where("item_id IN ?", itemIds).Delete(&monitor_model.DockerContainerShortcut{}); result.Error != nil {
+		return result.Error
+	}
+	// 用户已经删除了该快捷方式, 不再推荐对应的服务.
+	if result := tx.Model(&discoveredServiceModel).Where("item_id IN ?", itemIds).Updates(map[string]any{"item_id": 0, "status": monitor_model.DiscoveredServiceStatusDismissed}); result.Error != nil {
+		return result.Error
+	}
+
+	items := lo.Map(itemIds, func(id uint, _ int) monitor_model.ShortcutItem {
+		return monitor_model.ShortcutItem{Model: monitor_model.Model{ID: id}}
+	})
+
+	return tx.Unscoped().Select("Sections").Delete(&items).Error
+}
+
+// PurgeExpiredShortcutTrash 彻底删除在回收站中超过 retention 的分组和快捷方式.
+func PurgeExpiredShortcutTrash(retention time.Duration) error {
+	return purgeShortcutTrashDeletedBefore(time.Now().Add(-retention).UnixMilli())
+}
+
+// EmptyShortcutTrash 清空回收站, 包括彻底删除分组时移入回收站的快捷方式.
+func EmptyShortcutTrash() error {
+	return purgeShortcutTrashDeletedBefore(math.MaxInt64)
+}
+
+// purgeShortcutTrashDeletedBefore 彻底删除在 before(毫秒时间戳)之前被删除的分组和快捷方式.
+// 先删除分组, 以便同时删除因此移入回收站的快捷方式(如果满足时间条件).
+func purgeShortcutTrashDeletedBefore(before int64) error {
+	db := monitor_db.GetDB()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		sectionIds := make([]uint, 0)
+		if result := tx.Unscoped().Model(&shortcutSectionModel).Where("deleted_at != 0 AND deleted_at < ?", before).Pluck("id", &sectionIds); result.Error != nil {
+			return result.Error
+		}
+		if err := purgeShortcutSections(tx, sectionIds); err != nil {
+			return err
+		}
+
+		itemIds := make([]uint, 0)
+		if result := tx.Unscoped().Model(&shortcutItemModel).Where("deleted_at != 0 AND deleted_at < ?", before).Pluck("id", &itemIds); result.Error != nil {
+			return result.Error
+		}
+		if err := purgeShortcutItems(tx, itemIds); err != nil {
+			return err
+		}
+
+		if len(sectionIds) > 0 || len(itemIds) > 0 {
+			logger.Info("purged %d shortcut sections and %d shortcut items from trash.\n", len(sectionIds), len(itemIds))
+		}
+
+		return nil
+	})
+}
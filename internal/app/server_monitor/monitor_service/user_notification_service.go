@@ -4,10 +4,26 @@ import (
 	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
 	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
 	"github.com/siaikin/home-dashboard/internal/pkg/notification"
+	"strings"
+	"time"
 )
 
 var notificationModel = monitor_model.StoredNotification{}
 
+// NotificationFilter 查询通知历史的过滤条件, 为空的字段不参与过滤.
+type NotificationFilter struct {
+	Origins []notification.UserNotificationOrigin
+	Kinds   []notification.UserNotificationKind
+	// Unread 和 Archived 为 nil 时不按已读状态和归档状态过滤.
+	Unread   *bool
+	Archived *bool
+	// From 和 To 为通知在来源处创建时间的范围(毫秒时间戳), 包含边界.
+	From int64
+	To   int64
+	// Search 按空白字符分隔的关键词, 通知的标题或描述需要包含每一个关键词.
+	Search string
+}
+
 // LatestNNotification 获取前 max 个通知, 根据插入顺序排序. 当记录条数小于 max 时, 返回的结果数组长度为实际记录条数的长度.
 // 如果 onlyUnread 为 true, 则只返回未读的通知. 否则返回所有通知.
 func LatestNNotification(max int64, onlyUnread bool) (*[]monitor_model.StoredNotification, error) {
@@ -67,21 +83,39 @@ func CreateOrUpdateNotification(_notification monitor_model.StoredNotification)
 	return CreateOrUpdateNotifications([]monitor_model.StoredNotification{_notification})
 }
 
-// CreateOrUpdateNotifications 插入或更新多条通知记录.
+// CreateOrUpdateNotifications 插入或更新多条通知记录. 根据 UniqueId 去重.
+// 只有入站 Webhook 的通知会更新已存在的记录, 如告警恢复时更新为已读, 再次触发时更新为未读. 只更新通知的内容和已读状态,
+// 保留归档状态和通知在来源处的创建时间. GitHub 等模块每次拉取都会重新发送仍未读的通知, 已存在的记录不更新, 避免本地已读的通知变为未读.
 func CreateOrUpdateNotifications(notifications []monitor_model.StoredNotification) error {
 	db := monitor_db.GetDB()
 
 	for _, _notification := range notifications {
-		result := db.Model(&notificationModel).Where(monitor_model.StoredNotification{UserNotification: notification.UserNotification{UniqueId: _notification.UniqueId}}).Attrs(_notification).FirstOrCreate(&_notification)
+		stored := monitor_model.StoredNotification{}
+		result := db.Model(&notificationModel).Where("unique_id = ?", _notification.UniqueId).Limit(1).Find(&stored)
 		if result.Error != nil {
 			return result.Error
 		}
-		// 受影响的行数大于 0 说明记录已存在, 不需要更新.
-		if result.RowsAffected > 0 {
+
+		if result.RowsAffected <= 0 {
+			if result = db.Create(&_notification); result.Error != nil {
+				return result.Error
+			}
+			continue
+		}
+
+		// 内容和状态都没有变化时不更新, 避免刷新 UpdatedAt 使已读的通知无法按保留时间清理
+		if !isNotificationWebhookOrigin(stored.Origin) ||
+			(stored.Unread == _notification.Unread && stored.Title == _notification.Title && stored.Caption == _notification.Caption &&
+				stored.Link == _notification.Link && stored.Kind == _notification.Kind) {
 			continue
 		}
 
-		if result = db.Save(&_notification); result.Error != nil {
+		stored.Unread = _notification.Unread
+		stored.Title = _notification.Title
+		stored.Caption = _notification.Caption
+		stored.Link = _notification.Link
+		stored.Kind = _notification.Kind
+		if result = db.Select("Unread", "Title", "Caption", "Link", "Kind").Updates(&stored); result.Error != nil {
 			return result.Error
 		}
 	}
@@ -141,11 +175,81 @@ func SyncNotificationsReadStateByUniqueIds(unreadUniqueIds []string, origin stri
 func MarkAllNotificationAsRead() error {
 	db := monitor_db.GetDB()
 
-	result := db.Model(&notificationModel).Where(map[string]any{"unread": true}).Update("unread", true)
+	result := db.Model(&notificationModel).Where(map[string]any{"unread": true}).Update("unread", false)
 
 	return result.Error
 }
 
+// ListNotifications 分页获取符合过滤条件的通知, 按通知在来源处的创建时间倒序排列. page 从 1 开始, 同时返回符合条件的记录总数.
+func ListNotifications(filter NotificationFilter, page int, pageSize int) ([]monitor_model.StoredNotification, int64, error) {
+	db := monitor_db.GetDB()
+
+	query := db.Model(&notificationModel)
+	if len(filter.Origins) > 0 {
+		query = query.Where("origin IN ?", filter.Origins)
+	}
+	if len(filter.Kinds) > 0 {
+		query = query.Where("kind IN ?", filter.Kinds)
+	}
+	if filter.Unread != nil {
+		query = query.Where("unread = ?", *filter.Unread)
+	}
+	if filter.Archived != nil {
+		query = query.Where("archived = ?", *filter.Archived)
+	}
+	if filter.From > 0 {
+		query = query.Where("origin_create_at >= ?", filter.From)
+	}
+	if filter.To > 0 {
+		query = query.Where("origin_create_at <= ?", filter.To)
+	}
+
+	// 转义 LIKE 中的通配符
+	escaper := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	for _, keyword := range strings.Fields(filter.Search) {
+		like := "%" + escaper.Replace(keyword) + "%"
+		query = query.Where(`(title LIKE ? ESCAPE '\' OR caption LIKE ? ESCAPE '\')`, like, like)
+	}
+
+	total := int64(0)
+	if result := query.Count(&total); result.Error != nil {
+		return nil, 0, result.Error
+	}
+
+	notifications := make([]monitor_model.StoredNotification, 0)
+	result := query.Order("origin_create_at desc").Order("id desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&notifications)
+
+	return notifications, total, result.Error
+}
+
+// ArchiveNotifications 归档或取消归档多条通知, 返回受影响的记录数.
+func ArchiveNotifications(ids []uint, archived bool) (int64, error) {
+	db := monitor_db.GetDB()
+
+	result := db.Model(&notificationModel).Where("id IN ?", ids).Update("archived", archived)
+
+	return result.RowsAffected, result.Error
+}
+
+// DeleteNotifications 删除多条通知记录, 返回受影响的记录数.
+// 来源仍然未读的通知(如 Github 的未读通知)会在来源下次同步时重新出现, 只想隐藏时应该使用归档.
+func DeleteNotifications(ids []uint) (int64, error) {
+	db := monitor_db.GetDB()
+
+	result := db.Where("id IN ?", ids).Delete(&notificationModel)
+
+	return result.RowsAffected, result.Error
+}
+
+// PurgeReadNotifications 彻底删除超过保留时间未更新(如标记为已读后)的已读通知, 包括已删除的通知, 返回删除的记录数. 未读通知不会被删除.
+func PurgeReadNotifications(retention time.Duration) (int64, error) {
+	db := monitor_db.GetDB()
+
+	result := db.Unscoped().Where("unread = ? AND updated_at < ?", false, time.Now().Add(-retention).UnixMilli()).Delete(&notificationModel)
+
+	return result.RowsAffected, result.Error
+}
+
 // DeleteNotification 删除一条通知记录
 func DeleteNotification(id uint) error {
 	db := monitor_db.GetDB()
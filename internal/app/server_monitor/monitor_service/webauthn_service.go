@@ -0,0 +1,152 @@
+package monitor_service
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/samber/lo"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"gorm.io/gorm"
+	"strconv"
+	"time"
+)
+
+var webAuthnCredentialModel = monitor_model.WebAuthnCredential{}
+
+// ErrorWebAuthnCloned 凭据的签名计数异常, 认证器可能被复制.
+var ErrorWebAuthnCloned = errors.New("webauthn authenticator may be cloned")
+
+// WebAuthnUser 实现 webauthn.User, 包含用户已注册的凭据.
+type WebAuthnUser struct {
+	monitor_model.User
+	Credentials []monitor_model.WebAuthnCredential
+}
+
+// WebAuthnID 用户的 user handle, 为用户 id 的十进制字符串.
+func (u WebAuthnUser) WebAuthnID() []byte {
+	return []byte(strconv.FormatUint(uint64(u.ID), 10))
+}
+
+func (u WebAuthnUser) WebAuthnName() string {
+	return u.Username
+}
+
+func (u WebAuthnUser) WebAuthnDisplayName() string {
+	return u.Username
+}
+
+func (u WebAuthnUser) WebAuthnIcon() string {
+	return ""
+}
+
+func (u WebAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return lo.Map(u.Credentials, func(credential monitor_model.WebAuthnCredential, _ int) webauthn.Credential {
+		return credential.Credential
+	})
+}
+
+// GetWebAuthnUser 获取用户及其已注册的凭据.
+func GetWebAuthnUser(user monitor_model.User) (WebAuthnUser, error) {
+	credentials, err := ListWebAuthnCredentials(user.ID)
+
+	return WebAuthnUser{User: user, Credentials: credentials}, err
+}
+
+// GetWebAuthnUserByHandle 通过 user handle 获取用户及其已注册的凭据, 用于无密码登录.
+func GetWebAuthnUserByHandle(handle []byte) (WebAuthnUser, error) {
+	id, err := strconv.ParseUint(string(handle), 10, 0)
+	if err != nil || id == 0 {
+		return WebAuthnUser{}, ErrorNotFound
+	}
+
+	user, err := GetUserById(uint(id))
+	if err != nil {
+		return WebAuthnUser{}, err
+	}
+
+	return GetWebAuthnUser(user)
+}
+
+// ListWebAuthnCredentials 获取用户已注册的凭据, 按创建时间排列.
+func ListWebAuthnCredentials(userId uint) ([]monitor_model.WebAuthnCredential, error) {
+	db := monitor_db.GetDB()
+
+	credentials := make([]monitor_model.WebAuthnCredential, 0)
+	result := db.Model(&webAuthnCredentialModel).Where("user_id = ?", userId).Order("id").Find(&credentials)
+
+	return credentials, result.Error
+}
+
+// CreateWebAuthnCredential 保存用户注册的凭据并开启 2FA.
+func CreateWebAuthnCredential(user monitor_model.User, name string, credential webauthn.Credential) (monitor_model.WebAuthnCredential, error) {
+	db := monitor_db.GetDB()
+
+	created := monitor_model.WebAuthnCredential{
+		UserId:       user.ID,
+		Name:         name,
+		CredentialId: credential.ID,
+		Credential:   credential,
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if result := tx.Create(&created); result.Error != nil {
+			return result.Error
+		}
+
+		return tx.Model(&user).UpdateColumn("enable2_fa", true).Error
+	})
+
+	return created, err
+}
+
+// DeleteWebAuthnCredential 删除用户的凭据. 删除最后一个凭据且没有绑定身份验证器应用时关闭 2FA 并删除恢复码.
+// 返回删除后用户是否仍开启 2FA, 凭据不存在时返回 ErrorNotFound.
+func DeleteWebAuthnCredential(user monitor_model.User, id uint) (bool, error) {
+	db := monitor_db.GetDB()
+
+	enable2FA := user.Enable2FA
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ? AND id = ?", user.ID, id).Delete(&webAuthnCredentialModel)
+		if result.Error != nil {
+			return result.Error
+		} else if result.RowsAffected <= 0 {
+			return ErrorNotFound
+		}
+
+		count := int64(0)
+		if result := tx.Model(&webAuthnCredentialModel).Where("user_id = ?", user.ID).Count(&count); result.Error != nil {
+			return result.Error
+		}
+
+		enable2FA = count > 0 || len(user.Secret2FA) > 0
+		if !enable2FA {
+			if result := tx.Where("user_id = ?", user.ID).Delete(&recoveryCodeModel); result.Error != nil {
+				return result.Error
+			}
+		}
+
+		return tx.Model(&user).UpdateColumn("enable2_fa", enable2FA).Error
+	})
+
+	return enable2FA, err
+}
+
+// UpdateWebAuthnCredentialUsage 登录成功后更新凭据的签名计数和最后使用时间. 签名计数异常时返回 ErrorWebAuthnCloned.
+func UpdateWebAuthnCredentialUsage(user WebAuthnUser, credential webauthn.Credential) error {
+	db := monitor_db.GetDB()
+
+	if credential.Authenticator.CloneWarning {
+		return ErrorWebAuthnCloned
+	}
+
+	stored, ok := lo.Find(user.Credentials, func(item monitor_model.WebAuthnCredential) bool {
+		return string(item.CredentialId) == string(credential.ID)
+	})
+	if !ok {
+		return ErrorNotFound
+	}
+
+	stored.Credential = credential
+	stored.LastUsedAt = time.Now().UnixMilli()
+
+	return db.Model(&stored).Select("Credential", "LastUsedAt").Updates(&stored).Error
+}
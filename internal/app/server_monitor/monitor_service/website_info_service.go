@@ -0,0 +1,54 @@
+package monitor_service
+
+import (
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/pkg/website_metadata"
+	"net/http"
+	url2 "net/url"
+)
+
+// WebsiteInfo 从网站中提取到的快捷方式信息.
+type WebsiteInfo struct {
+	// Item 根据网站信息生成的快捷方式, 图标使用评分最高的候选图标, 背景色使用网站的主题色.
+	Item monitor_model.ShortcutItem `json:"item"`
+	// IconCandidates 按评分从高到低排序的候选图标, 供用户选择.
+	IconCandidates []website_metadata.IconCandidate `json:"iconCandidates"`
+	// ThemeColor 网站声明的主题色.
+	ThemeColor string `json:"themeColor"`
+}
+
+// ExtractWebsiteInfoFromUrl 从 url 对应的网站中提取标题, 描述, 图标和主题色, 并以 monitor_model.ShortcutItem 的形式返回.
+// header 中只有 User-Agent 和 Accept-Language 会被发送, 见 website_metadata.Fetch.
+func ExtractWebsiteInfoFromUrl(url *url2.URL, header http.Header) (*WebsiteInfo, error) {
+	metadata, err := FetchWebsiteMetadata(url, header)
+	if err != nil {
+		return nil, err
+	}
+
+	item := monitor_model.ShortcutItem{
+		Title:           metadata.Title,
+		Description:     metadata.Description,
+		URL:             url.String(),
+		IconType:        monitor_model.ShortcutItemIconTypeUrl,
+		Tags:            "",
+		Target:          monitor_model.ShortcutItemTargetTypeNewTab,
+		StatusCheck:     true,
+		StatusCheckUrl:  url.String(),
+		BackgroundColor: metadata.ThemeColor,
+	}
+	if icon, ok := metadata.BestIcon(); ok {
+		item.IconUrl = icon.Url
+	}
+	item.IconCachedUrl = GetCachedShortcutItemImageIconUrl(item)
+
+	return &WebsiteInfo{
+		Item:           item,
+		IconCandidates: metadata.Icons,
+		ThemeColor:     metadata.ThemeColor,
+	}, nil
+}
+
+// FetchWebsiteMetadata 获取 url 对应网站的元数据. 与 ExtractWebsiteInfoFromUrl 不同, 不会缓存图标.
+func FetchWebsiteMetadata(url *url2.URL, header http.Header) (*website_metadata.Metadata, error) {
+	return website_metadata.Fetch(httpClient, url, header)
+}
@@ -0,0 +1,43 @@
+package notification_cleaner
+
+import (
+	"context"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/comfy_log"
+	"github.com/siaikin/home-dashboard/internal/pkg/configuration"
+	"time"
+)
+
+var logger = comfy_log.New("[notification_cleaner]")
+
+// 检查通知历史的时间间隔.
+const checkInterval = time.Hour
+
+// Loop 定期删除超过保留时间的已读通知.
+func Loop(ctx context.Context) {
+	retention := configuration.Get().ServerMonitor.NotificationHistory.Retention * time.Second
+	if retention <= 0 {
+		retention = time.Hour * 24 * 30
+	}
+
+	go func() {
+		defer logger.Info("stop notification clean loop\n")
+
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		for {
+			if purged, err := monitor_service.PurgeReadNotifications(retention); err != nil {
+				logger.Error("purge read notifications failed, %w\n", err)
+			} else if purged > 0 {
+				logger.Info("purged %d read notifications\n", purged)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -0,0 +1,279 @@
+package notification_delivery
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/samber/lo"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/comfy_log"
+	"github.com/siaikin/home-dashboard/internal/pkg/configuration"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification_channel"
+	"strings"
+	"time"
+)
+
+var logger = comfy_log.New("[notification_delivery]")
+
+// 两次重试之间最多等待的时间.
+const maxBackoff = time.Hour
+
+// StartListen 订阅用户通知, 按照路由规则将新的未读通知投递到启用的通知渠道.
+func StartListen(ctx context.Context) {
+	go func() {
+		defer logger.Info("stop listen user notification for delivery\n")
+
+		listener := notification.GetListener()
+		listenerCh := listener.Ch()
+		defer listener.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-listenerCh:
+				if !ok {
+					return
+				}
+				if message.Type != notification.UserNotificationReceivedMessageType {
+					continue
+				}
+
+				userNotifications, ok := message.Data["notifications"].([]notification.UserNotification)
+				if !ok {
+					logger.Error("invalid user notifications\n")
+					continue
+				}
+
+				Dispatch(ctx, userNotifications)
+			}
+		}
+	}()
+}
+
+// Dispatch 在后台将未读通知投递到启用的通知渠道. 通知先经过路由规则决定投递的渠道, 或被抑制, 降级;
+// 然后根据渠道所属用户的免打扰时段, 频率限制和去重时间窗口决定是否跳过. 第三方模块会重复发送相同的通知,
+// 因此已经投递过的通知(根据 UniqueId 判断)不会再次投递.
+func Dispatch(ctx context.Context, userNotifications []notification.UserNotification) {
+	channels, err := monitor_service.ListNotificationChannels(true)
+	if err != nil {
+		logger.Error("list notification channels failed, %w\n", err)
+		return
+	}
+	routes, err := monitor_service.ListNotificationRoutes(true)
+	if err != nil {
+		logger.Error("list notification routes failed, %w\n", err)
+		return
+	}
+
+	config := configuration.Get().ServerMonitor.NotificationDelivery
+	maxAttempts := config.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 5
+	}
+	backoff := config.Backoff * time.Second
+	if backoff <= 0 {
+		backoff = time.Second * 10
+	}
+
+	for _, userNotification := range userNotifications {
+		if !userNotification.Unread {
+			continue
+		}
+
+		routed, channelIds, suppressed := route(routes, userNotification)
+		if suppressed {
+			logger.Info("notification %s is suppressed by route\n", userNotification.UniqueId)
+			continue
+		}
+
+		for _, channel := range channels {
+			if channelIds != nil && !lo.Contains(channelIds, channel.ID) {
+				continue
+			}
+
+			if delivered, err := monitor_service.NotificationDelivered(channel.ID, routed.UniqueId); err != nil {
+				logger.Error("check notification delivery failed, %w\n", err)
+				continue
+			} else if delivered {
+				continue
+			}
+
+			delivery := monitor_model.NotificationDelivery{
+				ChannelId:   channel.ID,
+				UniqueId:    routed.UniqueId,
+				Title:       routed.Title,
+				Fingerprint: fingerprint(routed),
+				Status:      monitor_model.NotificationDeliveryStatusPending,
+			}
+			if reason, err := skipReason(channel, routed, delivery.Fingerprint); err != nil {
+				logger.Error("check notification delivery limits failed, %w\n", err)
+				continue
+			} else if len(reason) > 0 {
+				delivery.Status = monitor_model.NotificationDeliveryStatusSkipped
+				delivery.Error = reason
+			}
+
+			// 先创建投递记录, 避免投递完成前收到的相同通知被重复投递
+			delivery, err := monitor_service.CreateNotificationDelivery(delivery)
+			if err != nil {
+				logger.Error("create notification delivery failed, %w\n", err)
+				continue
+			}
+
+			if delivery.Status == monitor_model.NotificationDeliveryStatusPending {
+				go deliver(ctx, channel, routed, delivery, maxAttempts, backoff)
+			}
+		}
+	}
+}
+
+// route 依次匹配路由规则. 返回经过降级后的通知, 以及规则指定的渠道(为 nil 时投递到所有渠道)和通知是否被抑制.
+func route(routes []monitor_model.NotificationRoute, userNotification notification.UserNotification) (notification.UserNotification, []uint, bool) {
+	for _, notificationRoute := range routes {
+		if !monitor_service.MatchNotificationRoute(notificationRoute, userNotification) {
+			continue
+		}
+
+		switch notificationRoute.Action {
+		case monitor_model.NotificationRouteActionDowngrade:
+			userNotification.Kind = notificationRoute.DowngradeTo
+		case monitor_model.NotificationRouteActionRoute:
+			return userNotification, notificationRoute.ChannelIds, false
+		case monitor_model.NotificationRouteActionSuppress:
+			return userNotification, nil, true
+		}
+	}
+
+	return userNotification, nil, false
+}
+
+// skipReason 检查渠道所属用户的免打扰时段(error 类型的通知除外), 去重时间窗口和频率限制, 返回跳过投递的原因. 不需要跳过时返回空字符串.
+func skipReason(channel monitor_model.NotificationChannel, userNotification notification.UserNotification, fingerprint string) (string, error) {
+	config := configuration.Get().ServerMonitor.NotificationDelivery
+	now := time.Now()
+
+	if channel.UserId > 0 && userNotification.Kind != notification.UserNotificationKindError {
+		quietHours, err := monitor_service.GetNotificationQuietHours(channel.UserId)
+		if err != nil {
+			return "", err
+		} else if monitor_service.InNotificationQuietHours(quietHours, now) {
+			return "quiet hours", nil
+		}
+	}
+
+	if dedupWindow := config.DedupWindow * time.Second; dedupWindow > 0 {
+		if count, err := monitor_service.CountRecentNotificationDeliveries(channel.ID, now.Add(-dedupWindow).UnixMilli(), fingerprint); err != nil {
+			return "", err
+		} else if count > 0 {
+			return "duplicate notification within dedup window", nil
+		}
+	}
+
+	if config.RateLimit > 0 {
+		window := config.RateLimitWindow * time.Second
+		if window <= 0 {
+			window = time.Hour
+		}
+
+		if count, err := monitor_service.CountRecentNotificationDeliveries(channel.ID, now.Add(-window).UnixMilli(), ""); err != nil {
+			return "", err
+		} else if count >= int64(config.RateLimit) {
+			return "rate limit exceeded", nil
+		}
+	}
+
+	return "", nil
+}
+
+// fingerprint 根据通知的来源, 标题和描述生成摘要, 用于识别 UniqueId 不同但内容相同的通知.
+func fingerprint(userNotification notification.UserNotification) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{userNotification.Origin, userNotification.Title, userNotification.Caption}, "\x00")))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// SendTest 立即通过渠道发送一条测试通知, 只尝试一次. 返回投递记录和发送失败的原因.
+func SendTest(ctx context.Context, channel monitor_model.NotificationChannel) (monitor_model.NotificationDelivery, error) {
+	now := time.Now().UnixMilli()
+	testNotification := notification.UserNotification{
+		UniqueId:       fmt.Sprintf("notification-channel-test-%d", now),
+		Unread:         true,
+		Title:          "Test notification",
+		Caption:        fmt.Sprintf("This is a test notification from Home Dashboard to channel %s.", channel.Name),
+		Kind:           notification.UserNotificationKindInfo,
+		Origin:         notification.UserNotificationOriginMain,
+		OriginCreateAt: now,
+	}
+
+	delivery, err := monitor_service.CreateNotificationDelivery(monitor_model.NotificationDelivery{
+		ChannelId: channel.ID,
+		UniqueId:  testNotification.UniqueId,
+		Title:     testNotification.Title,
+		Test:      true,
+		Status:    monitor_model.NotificationDeliveryStatusPending,
+	})
+	if err != nil {
+		return delivery, err
+	}
+
+	return deliver(ctx, channel, testNotification, delivery, 1, 0)
+}
+
+// deliver 投递通知, 失败时按指数退避重试, 最多尝试 maxAttempts 次. 每次尝试后更新投递记录.
+func deliver(ctx context.Context, channel monitor_model.NotificationChannel, userNotification notification.UserNotification, delivery monitor_model.NotificationDelivery, maxAttempts int, backoff time.Duration) (monitor_model.NotificationDelivery, error) {
+	for {
+		delivery.Attempts++
+		err := send(ctx, channel, userNotification)
+		switch {
+		case err == nil:
+			delivery.Status = monitor_model.NotificationDeliveryStatusSuccess
+			delivery.Error = ""
+		case delivery.Attempts >= maxAttempts:
+			delivery.Status = monitor_model.NotificationDeliveryStatusFailure
+			delivery.Error = err.Error()
+			logger.Warn("deliver notification %s to channel %s failed after %d attempts, %s\n", userNotification.UniqueId, channel.Name, delivery.Attempts, err)
+		default:
+			delivery.Error = err.Error()
+		}
+
+		if updateErr := monitor_service.UpdateNotificationDelivery(delivery); updateErr != nil {
+			logger.Error("update notification delivery failed, %w\n", updateErr)
+		}
+		if delivery.Status != monitor_model.NotificationDeliveryStatusPending {
+			return delivery, err
+		}
+
+		wait := backoff << (delivery.Attempts - 1)
+		if wait > maxBackoff || wait <= 0 {
+			wait = maxBackoff
+		}
+
+		select {
+		case <-ctx.Done():
+			// 服务停止时放弃重试, 避免投递记录一直处于投递中
+			delivery.Status = monitor_model.NotificationDeliveryStatusFailure
+			delivery.Error = fmt.Sprintf("%s, retry canceled: %s", delivery.Error, ctx.Err())
+			if updateErr := monitor_service.UpdateNotificationDelivery(delivery); updateErr != nil {
+				logger.Error("update notification delivery failed, %w\n", updateErr)
+			}
+			return delivery, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// send 通过渠道发送通知, 超时时间为配置的 Timeout.
+func send(ctx context.Context, channel monitor_model.NotificationChannel, userNotification notification.UserNotification) error {
+	timeout := configuration.Get().ServerMonitor.NotificationDelivery.Timeout * time.Second
+	if timeout <= 0 {
+		timeout = time.Second * 10
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return notification_channel.Send(sendCtx, nil, channel.Type, channel.Config, userNotification)
+}
@@ -0,0 +1,284 @@
+package notification_delivery
+
+import (
+	"context"
+	"github.com/go-errors/errors"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/authority"
+	"github.com/siaikin/home-dashboard/internal/pkg/configuration"
+	"github.com/siaikin/home-dashboard/internal/pkg/database"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification_channel"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newFlakyServer 创建前 failures 次请求响应 503, 之后响应 200 的 HTTP 服务.
+func newFlakyServer(t *testing.T, failures int32) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	requests := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, requests
+}
+
+func createWebhookChannel(t *testing.T, name string, url string) monitor_model.NotificationChannel {
+	t.Helper()
+
+	channel, err := monitor_service.CreateNotificationChannel(monitor_model.NotificationChannel{
+		Name:   name,
+		Type:   notification_channel.TypeWebhook,
+		Enable: true,
+		Config: notification_channel.Config{Webhook: notification_channel.WebhookConfig{Url: url}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = monitor_service.DeleteNotificationChannel(channel.ID) })
+
+	return channel
+}
+
+// waitDelivery 等待渠道上的投递完成.
+func waitDelivery(t *testing.T, channelId uint) monitor_model.NotificationDelivery {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		deliveries, _, err := monitor_service.ListNotificationDeliveries(channelId, 1, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deliveries) > 0 && deliveries[0].Status != monitor_model.NotificationDeliveryStatusPending {
+			return deliveries[0]
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	t.Fatalf("delivery of channel %d is not finished", channelId)
+	return monitor_model.NotificationDelivery{}
+}
+
+func TestDeliver(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+
+	userNotification := notification.UserNotification{UniqueId: "delivery-1", Unread: true, Title: "disk full", Kind: notification.UserNotificationKindError}
+
+	// 失败后按退避时间重试, 直到成功
+	server, requests := newFlakyServer(t, 2)
+	channel := createWebhookChannel(t, "delivery-flaky", server.URL)
+	delivery, _ := monitor_service.CreateNotificationDelivery(monitor_model.NotificationDelivery{ChannelId: channel.ID, UniqueId: userNotification.UniqueId, Status: monitor_model.NotificationDeliveryStatusPending})
+	delivery, err := deliver(context.Background(), channel, userNotification, delivery, 5, time.Millisecond)
+	if err != nil || delivery.Status != monitor_model.NotificationDeliveryStatusSuccess || delivery.Attempts != 3 || requests.Load() != 3 {
+		t.Errorf("delivery should succeed after retries, got %+v, %v", delivery, err)
+	}
+
+	// 重试次数用尽后记录失败原因
+	server, requests = newFlakyServer(t, 10)
+	channel = createWebhookChannel(t, "delivery-down", server.URL)
+	delivery, _ = monitor_service.CreateNotificationDelivery(monitor_model.NotificationDelivery{ChannelId: channel.ID, UniqueId: userNotification.UniqueId, Status: monitor_model.NotificationDeliveryStatusPending})
+	if _, err := deliver(context.Background(), channel, userNotification, delivery, 3, time.Millisecond); err == nil {
+		t.Errorf("delivery to unavailable server should fail")
+	}
+	if stored, _, _ := monitor_service.ListNotificationDeliveries(channel.ID, 1, 1); len(stored) != 1 ||
+		stored[0].Status != monitor_model.NotificationDeliveryStatusFailure || stored[0].Attempts != 3 || len(stored[0].Error) <= 0 || requests.Load() != 3 {
+		t.Errorf("unexpected failed delivery %+v", stored)
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+
+	server, requests := newFlakyServer(t, 0)
+	channel := createWebhookChannel(t, "dispatch-webhook", server.URL)
+	disabled := createWebhookChannel(t, "dispatch-disabled", server.URL)
+	disabled.Enable = false
+	if _, err := monitor_service.UpdateNotificationChannel(disabled); err != nil {
+		t.Fatal(err)
+	}
+
+	// 已读的通知和已经投递过的通知不会被投递, 禁用的渠道不会收到通知
+	userNotification := notification.UserNotification{UniqueId: "dispatch-1", Unread: true, Title: "backup finished"}
+	Dispatch(context.Background(), []notification.UserNotification{userNotification, {UniqueId: "dispatch-read", Title: "read"}})
+	if delivery := waitDelivery(t, channel.ID); delivery.Status != monitor_model.NotificationDeliveryStatusSuccess || delivery.UniqueId != "dispatch-1" {
+		t.Errorf("unexpected delivery %+v", delivery)
+	}
+
+	Dispatch(context.Background(), []notification.UserNotification{userNotification})
+	time.Sleep(50 * time.Millisecond)
+	if _, total, _ := monitor_service.ListNotificationDeliveries(channel.ID, 1, 10); total != 1 || requests.Load() != 1 {
+		t.Errorf("notification should be delivered once, got %d deliveries and %d requests", total, requests.Load())
+	}
+	if _, total, _ := monitor_service.ListNotificationDeliveries(disabled.ID, 1, 10); total != 0 {
+		t.Errorf("disabled channel should not receive notifications, got %d", total)
+	}
+
+	// 测试通知不影响去重, 也不会重试
+	if delivery, err := SendTest(context.Background(), channel); err != nil || !delivery.Test || delivery.Attempts != 1 {
+		t.Errorf("unexpected test delivery %+v, %v", delivery, err)
+	}
+}
+
+// waitDeliveries 等待渠道上的所有投递完成, 返回以 UniqueId 为键的投递记录.
+func waitDeliveries(t *testing.T, channelId uint) map[string]monitor_model.NotificationDelivery {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		deliveries, _, err := monitor_service.ListNotificationDeliveries(channelId, 1, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result := make(map[string]monitor_model.NotificationDelivery)
+		pending := false
+		for _, delivery := range deliveries {
+			result[delivery.UniqueId] = delivery
+			pending = pending || delivery.Status == monitor_model.NotificationDeliveryStatusPending
+		}
+		if !pending {
+			return result
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	t.Fatalf("deliveries of channel %d are not finished", channelId)
+	return nil
+}
+
+func TestRouting(t *testing.T) {
+	// 使用默认的内存数据库
+	if err := monitor_db.Initial(database.GetDB()); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &configuration.Get().ServerMonitor.NotificationDelivery
+	original := *config
+	defer func() { *config = original }()
+	config.DedupWindow = 600
+	config.RateLimit = 2
+	config.RateLimitWindow = 3600
+
+	if err := monitor_service.CreateUser(monitor_model.User{User: authority.User{Username: "routing-owner"}, Role: monitor_model.RoleGuest}); err != nil {
+		t.Fatal(err)
+	}
+	defer monitor_db.GetDB().Unscoped().Where("username LIKE ?", "routing-%").Delete(&monitor_model.User{})
+	owner, _ := monitor_service.GetUserByName("routing-owner")
+
+	// 渠道所属的用户当前处于免打扰时段
+	now := time.Now().UTC()
+	if _, err := monitor_service.SaveNotificationQuietHours(monitor_model.NotificationQuietHours{
+		UserId:   owner.ID,
+		Enable:   true,
+		Start:    now.Add(-time.Hour).Format("15:04"),
+		End:      now.Add(time.Hour).Format("15:04"),
+		Timezone: "UTC",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer monitor_db.GetDB().Unscoped().Where("user_id = ?", owner.ID).Delete(&monitor_model.NotificationQuietHours{})
+
+	server, _ := newFlakyServer(t, 0)
+	shared := createWebhookChannel(t, "routing-shared", server.URL)
+	personal := createWebhookChannel(t, "routing-personal", server.URL)
+	personal.UserId = owner.ID
+	if _, err := monitor_service.UpdateNotificationChannel(personal); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, route := range []monitor_model.NotificationRoute{
+		{Name: "downgrade flaky probes", Enable: true, Priority: 1, TitlePattern: "^flaky", Action: monitor_model.NotificationRouteActionDowngrade, DowngradeTo: notification.UserNotificationKindWarning},
+		{Name: "mute github", Enable: true, Priority: 2, Origins: []string{notification.UserNotificationOriginGithub}, Action: monitor_model.NotificationRouteActionSuppress},
+		{Name: "errors to phone", Enable: true, Priority: 3, Kinds: []string{notification.UserNotificationKindError}, Action: monitor_model.NotificationRouteActionRoute, ChannelIds: []uint{personal.ID}},
+		{Name: "disabled", Priority: 0, Action: monitor_model.NotificationRouteActionSuppress},
+	} {
+		created, err := monitor_service.CreateNotificationRoute(route)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = monitor_service.DeleteNotificationRoute(created.ID) }()
+	}
+	if _, err := monitor_service.CreateNotificationRoute(monitor_model.NotificationRoute{Name: "invalid", TitlePattern: "(", Action: monitor_model.NotificationRouteActionSuppress}); !errors.Is(err, monitor_service.ErrorInvalidNotificationRoute) {
+		t.Errorf("invalid pattern should be rejected, got %v", err)
+	}
+
+	Dispatch(context.Background(), []notification.UserNotification{
+		{UniqueId: "routing-error", Unread: true, Title: "disk failing", Kind: notification.UserNotificationKindError, Origin: notification.UserNotificationOriginMain},
+		{UniqueId: "routing-github", Unread: true, Title: "new issue", Kind: notification.UserNotificationKindInfo, Origin: notification.UserNotificationOriginGithub},
+		{UniqueId: "routing-flaky-1", Unread: true, Title: "flaky probe", Kind: notification.UserNotificationKindError, Origin: notification.UserNotificationOriginMain},
+		{UniqueId: "routing-flaky-2", Unread: true, Title: "flaky probe", Kind: notification.UserNotificationKindError, Origin: notification.UserNotificationOriginMain},
+		{UniqueId: "routing-info-1", Unread: true, Title: "backup finished", Kind: notification.UserNotificationKindInfo, Origin: notification.UserNotificationOriginMain},
+		{UniqueId: "routing-info-2", Unread: true, Title: "backup started", Kind: notification.UserNotificationKindInfo, Origin: notification.UserNotificationOriginMain},
+	})
+
+	statuses := func(deliveries map[string]monitor_model.NotificationDelivery) map[string]string {
+		result := make(map[string]string)
+		for uniqueId, delivery := range deliveries {
+			result[uniqueId] = delivery.Status + " " + delivery.Error
+		}
+		return result
+	}
+
+	// error 通知只路由到个人渠道, 不受免打扰时段限制; 被降级的通知受免打扰时段限制
+	personalDeliveries := statuses(waitDeliveries(t, personal.ID))
+	if len(personalDeliveries) != 5 || personalDeliveries["routing-error"] != "success " ||
+		personalDeliveries["routing-flaky-1"] != "skipped quiet hours" || personalDeliveries["routing-info-1"] != "skipped quiet hours" {
+		t.Errorf("unexpected deliveries of personal channel %v", personalDeliveries)
+	}
+
+	// 被抑制的通知不投递; 内容相同的通知在去重时间窗口内只投递一次; 超出频率限制的通知不投递
+	sharedDeliveries := statuses(waitDeliveries(t, shared.ID))
+	if len(sharedDeliveries) != 4 || sharedDeliveries["routing-flaky-1"] != "success " ||
+		sharedDeliveries["routing-flaky-2"] != "skipped duplicate notification within dedup window" ||
+		sharedDeliveries["routing-info-1"] != "success " || sharedDeliveries["routing-info-2"] != "skipped rate limit exceeded" {
+		t.Errorf("unexpected deliveries of shared channel %v", sharedDeliveries)
+	}
+}
+
+func TestInNotificationQuietHours(t *testing.T) {
+	at := func(clock string) time.Time {
+		parsed, _ := time.Parse("2006-01-02 15:04", "2024-01-01 "+clock)
+		return parsed
+	}
+
+	overnight := monitor_model.NotificationQuietHours{Enable: true, Start: "22:00", End: "07:00", Timezone: "UTC"}
+	daytime := monitor_model.NotificationQuietHours{Enable: true, Start: "09:00", End: "17:30", Timezone: "UTC"}
+	shanghai := monitor_model.NotificationQuietHours{Enable: true, Start: "22:00", End: "07:00", Timezone: "Asia/Shanghai"}
+
+	for _, testCase := range []struct {
+		quietHours monitor_model.NotificationQuietHours
+		now        time.Time
+		expected   bool
+	}{
+		{overnight, at("23:30"), true},
+		{overnight, at("06:59"), true},
+		{overnight, at("07:00"), false},
+		{overnight, at("12:00"), false},
+		{daytime, at("09:00"), true},
+		{daytime, at("17:30"), false},
+		{shanghai, at("15:00"), true},
+		{shanghai, at("00:00"), false},
+		{monitor_model.NotificationQuietHours{Start: "00:00", End: "23:59"}, at("12:00"), false},
+	} {
+		if actual := monitor_service.InNotificationQuietHours(testCase.quietHours, testCase.now); actual != testCase.expected {
+			t.Errorf("quiet hours %s-%s %s at %s should be %v", testCase.quietHours.Start, testCase.quietHours.End, testCase.quietHours.Timezone, testCase.now.Format("15:04"), testCase.expected)
+		}
+	}
+}
@@ -4,15 +4,23 @@ import (
 	"context"
 	"github.com/go-errors/errors"
 	"github.com/jinzhu/copier"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/audit_log_cleaner"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/docker_discoverer"
 	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_db"
 	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
 	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_process_realtime"
 	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_realtime"
 	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/notification_cleaner"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/notification_delivery"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/service_discoverer"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/shortcut_link_checker"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/shortcut_trash_cleaner"
 	"github.com/siaikin/home-dashboard/internal/app/server_monitor/user_notification"
 	"github.com/siaikin/home-dashboard/internal/pkg/authority"
 	"github.com/siaikin/home-dashboard/internal/pkg/comfy_log"
 	"github.com/siaikin/home-dashboard/internal/pkg/configuration"
+	"github.com/siaikin/home-dashboard/internal/pkg/sessions"
 	"gorm.io/gorm"
 	"net"
 	"net/http"
@@ -31,11 +39,26 @@ func Initial(db *gorm.DB) error {
 		return err
 	}
 
+	logger.Info("generate built-in roles...\n")
+	if err := monitor_service.GenerateBuiltInRoles(); err != nil {
+		return err
+	}
+
 	logger.Info("generate administrator user...\n")
 	if err := generateAdministratorUser(); err != nil {
 		return err
 	}
 
+	logger.Info("migrate plaintext passwords...\n")
+	if err := monitor_service.MigratePlaintextPasswords(); err != nil {
+		return err
+	}
+
+	logger.Info("redact stored configurations...\n")
+	if err := monitor_service.RedactStoredConfigurations(); err != nil {
+		return err
+	}
+
 	logger.Info("store latest configuration...\n")
 	if err := storeLatestConfiguration(); err != nil {
 		return err
@@ -46,6 +69,16 @@ func Initial(db *gorm.DB) error {
 		return err
 	}
 
+	logger.Info("generate default search providers...\n")
+	if err := generateDefaultSearchProviders(); err != nil {
+		return err
+	}
+
+	logger.Info("seed shortcut icons...\n")
+	if err := monitor_service.RefreshShortcutIcons(); err != nil {
+		return err
+	}
+
 	logger.Info("fetch user agent list...\n")
 	if err := fetchUserAgent(); err != nil {
 		return err
@@ -58,6 +91,14 @@ func Start(ctx context.Context, listener *net.Listener) {
 	monitor_realtime.Loop(ctx, time.Second)
 	monitor_process_realtime.Loop(ctx, time.Second)
 	user_notification.StartListenUserNotificationNotify(ctx)
+	notification_delivery.StartListen(ctx)
+	shortcut_link_checker.Loop(ctx)
+	service_discoverer.Loop(ctx)
+	docker_discoverer.Loop(ctx)
+	shortcut_trash_cleaner.Loop(ctx)
+	audit_log_cleaner.Loop(ctx)
+	notification_cleaner.Loop(ctx)
+	sessions.Loop(ctx)
 
 	go func() {
 		if err := startServer(listener, configuration.Get().ServerMonitor.Development.Enable); err != nil {
@@ -115,29 +156,25 @@ func initialDeviceTable() error {
 	return nil
 }
 
-// 从配置文件中的管理员配置中生成管理员账号.
-// 如果数据库中已存在管理员账号, 则检查账号密码是否一致, 不一致则删除账号并重新创建管理员账号.
+// 从配置文件中的管理员配置中生成第一个管理员账号.
+// 数据库中已存在管理员账号时不做任何处理, 之后的用户均通过用户管理接口维护, 配置文件中的管理员配置不会覆盖数据库中的用户.
 func generateAdministratorUser() error {
+	if count, err := monitor_service.CountUser(monitor_model.User{Role: monitor_model.RoleAdministrator}); err != nil {
+		return err
+	} else if count > 0 {
+		return nil
+	}
+
 	administrator := configuration.Get().ServerMonitor.Administrator
 
 	if len(administrator.Password) <= 0 || len(administrator.Username) <= 0 {
 		return errors.Errorf("administrator username or password is empty. please check config file")
 	}
 
-	user, err := monitor_service.GetUser(monitor_model.User{Role: monitor_model.RoleAdministrator})
-	if err != nil && !errors.Is(err, monitor_service.ErrorNotFound) {
+	if count, err := monitor_service.CountUser(monitor_model.User{User: authority.User{Username: administrator.Username}}); err != nil {
 		return err
-	}
-
-	if user.Username == administrator.Username && user.Password == administrator.Password {
-		return nil
-	}
-
-	if !errors.Is(err, monitor_service.ErrorNotFound) {
-		// 管理员账号密码不一致则删除账号并重新创建管理员账号
-		if err := monitor_service.DeleteUser(user); err != nil {
-			return errors.New(err)
-		}
+	} else if count > 0 {
+		return errors.Errorf("user %s already exists but is not an administrator. please change administrator username in config file", administrator.Username)
 	}
 
 	adminUser := monitor_model.User{
@@ -173,6 +210,13 @@ func storeLatestConfiguration() error {
 		return err
 	}
 
+	// 首次启动时没有之前的配置, 不记录变更
+	if config.ID > 0 {
+		if err := monitor_service.AuditConfigurationChange(config.Configuration, *currentConfig); err != nil {
+			logger.Warn("audit configuration change failed, %s\n", err)
+		}
+	}
+
 	return nil
 }
 
@@ -197,6 +241,48 @@ func generateDefaultShortcutSection() error {
 	return nil
 }
 
+// 没有任何搜索引擎时创建常用的 monitor_model.SearchProvider 数据
+func generateDefaultSearchProviders() error {
+	if count, err := monitor_service.CountSearchProvider(monitor_model.SearchProvider{}); err != nil {
+		return err
+	} else if count > 0 {
+		return nil
+	}
+
+	_, err := monitor_service.CreateOrUpdateSearchProviders([]monitor_model.SearchProvider{
+		{
+			Name:                  "DuckDuckGo",
+			Keyword:               "ddg",
+			UrlTemplate:           "https://duckduckgo.com/?q={query}",
+			SuggestionUrlTemplate: "https://duckduckgo.com/ac/?q={query}&type=list",
+			Icon:                  "duckduckgo",
+			Default:               true,
+		},
+		{
+			Name:                  "Google",
+			Keyword:               "g",
+			UrlTemplate:           "https://www.google.com/search?q={query}",
+			SuggestionUrlTemplate: "https://www.google.com/complete/search?client=firefox&ie=utf-8&oe=utf-8&q={query}",
+			Icon:                  "google",
+		},
+		{
+			Name:                  "Bing",
+			Keyword:               "b",
+			UrlTemplate:           "https://www.bing.com/search?q={query}",
+			SuggestionUrlTemplate: "https://api.bing.com/osjson.aspx?query={query}",
+			Icon:                  "microsoftbing",
+		},
+		{
+			Name:        "GitHub",
+			Keyword:     "gh",
+			UrlTemplate: "https://github.com/search?q={query}",
+			Icon:        "github",
+		},
+	})
+
+	return err
+}
+
 // 第一次启动时拉取 userAgent 列表, 并存储到数据库中
 func fetchUserAgent() error {
 	count, err := monitor_service.CountUserAgent(monitor_model.UserAgent{})
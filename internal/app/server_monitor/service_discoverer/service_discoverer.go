@@ -0,0 +1,242 @@
+package service_discoverer
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-errors/errors"
+	"github.com/samber/lo"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/comfy_log"
+	"github.com/siaikin/home-dashboard/internal/pkg/configuration"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification"
+	"github.com/siaikin/home-dashboard/internal/pkg/service_discovery"
+	"net"
+	url2 "net/url"
+	"sync/atomic"
+	"time"
+)
+
+var logger = comfy_log.New("[service_discoverer]")
+
+// ErrorRunning 已有正在进行的扫描.
+var ErrorRunning = errors.New("service discovery is running")
+
+// 单个网段最多扫描的主机数量.
+const maxCidrHosts = 1024
+
+var running atomic.Bool
+var lastRunAt atomic.Int64
+
+// 手动触发的扫描在后台运行, 不能使用请求的 context, 因此使用 Loop 传入的 context.
+var loopCtx = context.Background()
+
+// Running 是否有正在进行的扫描.
+func Running() bool {
+	return running.Load()
+}
+
+// LastRunAt 最近一次扫描完成的时间(毫秒时间戳), 从未扫描过时为 0.
+func LastRunAt() int64 {
+	return lastRunAt.Load()
+}
+
+// Loop 按照配置的时间间隔定期扫描本机及局域网中的服务. 未启用定期扫描时直接返回.
+func Loop(ctx context.Context) {
+	loopCtx = ctx
+
+	config := configuration.Get().ServerMonitor.ServiceDiscovery
+	if !config.Enable {
+		return
+	}
+
+	interval := config.Interval * time.Second
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer logger.Info("stop service discovery loop\n")
+
+		for {
+			select {
+			case <-ctx.Done():
+				ticker.Stop()
+				return
+			case <-ticker.C:
+				if err := Run(ctx); err != nil && !errors.Is(err, ErrorRunning) {
+					logger.Error("discover services failed, %w\n", err)
+				}
+			}
+		}
+	}()
+}
+
+// Start 在后台扫描本机及局域网中的服务, 用于手动触发扫描. 如果已有正在进行的扫描, 则返回 ErrorRunning.
+func Start() error {
+	if running.Load() {
+		return ErrorRunning
+	}
+
+	go func() {
+		if err := Run(loopCtx); err != nil && !errors.Is(err, ErrorRunning) {
+			logger.Error("discover services failed, %w\n", err)
+		}
+	}()
+
+	return nil
+}
+
+// Run 扫描本机正在监听的端口和配置的局域网网段, 提取探测到的服务的网站信息并保存到收件箱中. 有新服务进入收件箱时发送用户通知.
+func Run(ctx context.Context) error {
+	if !running.CompareAndSwap(false, true) {
+		return ErrorRunning
+	}
+	defer running.Store(false)
+
+	config := configuration.Get().ServerMonitor.ServiceDiscovery
+	timeout := config.Timeout * time.Second
+	if timeout <= 0 {
+		timeout = time.Second * 3
+	}
+	concurrency := config.Concurrency
+	if concurrency <= 0 {
+		concurrency = 32
+	}
+
+	targets, err := collectTargets(ctx, config)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("start probing %d addresses\n", len(targets))
+	endpoints := service_discovery.NewProber(timeout).Scan(ctx, targets, concurrency)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	existed, err := monitor_service.ListDiscoveredServicesByQuery(monitor_model.DiscoveredService{})
+	if err != nil {
+		return err
+	}
+	existedMap := lo.KeyBy(existed, func(service monitor_model.DiscoveredService) string {
+		return service.Url
+	})
+
+	now := time.Now().UnixMilli()
+	services := make([]monitor_model.DiscoveredService, len(endpoints))
+	for i, endpoint := range endpoints {
+		services[i] = monitor_model.DiscoveredService{
+			Url:        endpoint.Url,
+			Host:       endpoint.Host,
+			Port:       endpoint.Port,
+			Source:     endpoint.Source,
+			Server:     endpoint.Server,
+			LastSeenAt: now,
+		}
+
+		// 已接受或已忽略的服务不再重新提取网站信息.
+		if service, ok := existedMap[endpoint.Url]; ok && service.Status != monitor_model.DiscoveredServiceStatusPending {
+			continue
+		}
+		fillWebsiteInfo(&services[i])
+	}
+
+	pending, err := monitor_service.SaveDiscoveredServices(services)
+	if err != nil {
+		return err
+	}
+	lastRunAt.Store(now)
+
+	logger.Info("discovered %d services, %d new\n", len(services), len(pending))
+
+	if len(pending) > 0 {
+		notification.SendUserNotifications([]notification.UserNotification{
+			{
+				UniqueId:       fmt.Sprintf("service-discovery-%d", now),
+				Unread:         true,
+				Title:          fmt.Sprintf("%d new services discovered", len(pending)),
+				Caption:        "Check the discovered services inbox to add them as shortcuts.",
+				Kind:           notification.UserNotificationKindInfo,
+				Origin:         notification.UserNotificationOriginMain,
+				OriginCreateAt: now,
+			},
+		})
+	}
+
+	return nil
+}
+
+// collectTargets 收集待探测的地址. 本服务自身监听的端口将被排除.
+func collectTargets(ctx context.Context, config configuration.ServerMonitorServiceDiscoveryConfiguration) ([]service_discovery.Target, error) {
+	serverMonitor := configuration.Get().ServerMonitor
+	ownPorts := []uint16{uint16(serverMonitor.Port), uint16(serverMonitor.Update.Port)}
+
+	advertiseHost := config.AdvertiseHost
+	if len(advertiseHost) <= 0 {
+		advertiseHost = firstNonLoopbackIPv4()
+	}
+
+	targets, err := service_discovery.LocalTargets(ctx, advertiseHost)
+	if err != nil {
+		// 部分平台上可能没有权限获取监听的端口, 此时仍然可以扫描局域网.
+		logger.Warn("list local listening ports failed, %w\n", err)
+		targets = make([]service_discovery.Target, 0)
+	}
+	targets = lo.Filter(targets, func(target service_discovery.Target, _ int) bool {
+		return !lo.Contains(ownPorts, target.Port)
+	})
+
+	for _, cidr := range config.Cidrs {
+		cidrTargets, err := service_discovery.CidrTargets(cidr, config.Ports, maxCidrHosts)
+		if err != nil {
+			logger.Warn("skip cidr %s, %w\n", cidr, err)
+			continue
+		}
+
+		targets = append(targets, cidrTargets...)
+	}
+
+	return targets, nil
+}
+
+// fillWebsiteInfo 提取服务的网站信息. 提取失败时使用 主机:端口 作为标题.
+func fillWebsiteInfo(service *monitor_model.DiscoveredService) {
+	service.Title = net.JoinHostPort(service.Host, fmt.Sprint(service.Port))
+
+	url, err := url2.Parse(service.Url)
+	if err != nil {
+		return
+	}
+
+	metadata, err := monitor_service.FetchWebsiteMetadata(url, nil)
+	if err != nil {
+		logger.Info("fetch website metadata from %s failed, %s\n", service.Url, err)
+		return
+	}
+
+	if len(metadata.Title) > 0 {
+		service.Title = metadata.Title
+	}
+	service.Description = metadata.Description
+	service.BackgroundColor = metadata.ThemeColor
+	if icon, ok := metadata.BestIcon(); ok {
+		service.IconUrl = icon.Url
+	}
+}
+
+func firstNonLoopbackIPv4() string {
+	addresses, err := net.InterfaceAddrs()
+	if err != nil {
+		return ""
+	}
+
+	for _, address := range addresses {
+		if ipNet, ok := address.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+			return ipNet.IP.String()
+		}
+	}
+
+	return ""
+}
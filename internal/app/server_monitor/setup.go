@@ -23,13 +23,17 @@ import (
 
 func setupEngine(mock bool) *gin.Engine {
 	r := gin.Default()
-	//if err := r.SetTrustedProxies(nil); err != nil {
-	//	logger.Info("server set proxy failed, %s\n", err)
-	//	return nil
-	//}
+	// 只信任配置的反向代理传递的 X-Forwarded-For 等请求头, 未配置时不信任任何代理
+	if err := r.SetTrustedProxies(configuration.Get().ServerMonitor.ProxyAuth.TrustedProxies); err != nil {
+		logger.Error("server set trusted proxies failed, %s\n", err)
+		_ = r.SetTrustedProxies(nil)
+	}
 
-	r.Use(gzip.Gzip(gzip.DefaultCompression))
+	// 反向代理的响应可能已被目标服务压缩, 因此不再压缩.
+	r.Use(gzip.Gzip(gzip.DefaultCompression, gzip.WithExcludedPaths([]string{monitor_controller.ProxyShortcutItemPrefix + "/"})))
 	r.Use(sessions.GetSessionMiddleware())
+	// 记录修改数据的请求和登录等安全相关的操作. 嵌入快捷方式的反向代理请求属于目标服务, 不记录. 入站 Webhook 只记录令牌校验失败的请求
+	r.Use(monitor_controller.AuditMiddleware(monitor_controller.ProxyShortcutItemPrefix+"/", monitor_controller.ReceiveNotificationWebhookPrefix+"/"))
 
 	r.Use(func(c *gin.Context) {
 		c.Next()
@@ -105,6 +109,7 @@ func setupRouter(router *gin.RouterGroup, mock bool) {
 					return
 				}
 
+				user.Password = ""
 				session.Set(authority.InfoKey, user.User)
 
 				context.Next()
@@ -133,60 +138,189 @@ func setupRouter(router *gin.RouterGroup, mock bool) {
 
 	router.POST("auth", monitor_controller.Authorize)
 	router.POST("unauth", monitor_controller.Unauthorize)
+	// OpenID Connect 单点登录
+	router.GET("auth/oidc", monitor_controller.GetOidcInfo)
+	router.GET("auth/oidc/login", monitor_controller.OidcLogin)
+	router.GET("auth/oidc/callback", monitor_controller.OidcCallback)
+	// 使用受信任的反向代理传递的用户名请求头登录, 用户名请求头只在该接口中使用
+	router.GET("auth/proxy", monitor_controller.TrustedHeaderLogin)
+	// 使用通行密钥无密码登录
+	router.POST("auth/webauthn/login/begin", monitor_controller.BeginWebAuthnLogin)
+	router.POST("auth/webauthn/login/finish", monitor_controller.FinishWebAuthnLogin)
 	// 获取当前登录用户信息
 	router.GET("user/current", monitor_controller.GetCurrentUser)
+	// 外部系统通过入站 Webhook 发送通知, 使用 Webhook 的令牌认证
+	router.POST("notification/webhook/receive/:id", monitor_controller.ReceiveNotificationWebhook)
+
+	// 使用 API 令牌访问时通过数据库校验令牌
+	authority.SetTokenVerifier(monitor_service.VerifyApiToken)
 
 	// 该路由组下的接口需要登录
-	authorized := router.Group("", authority.AuthorizeMiddleware())
+	authorized := router.Group("", authority.AuthorizeMiddleware(), monitor_controller.ActiveUserMiddleware())
 	// 2FA 校验相关接口
-	authorized.GET("auth/2fa/qrcode", monitor_controller.Generate2FABindingQRCode)
-	authorized.POST("auth/2fa/bind/app", monitor_controller.Binding2FAByAuthenticatorApp)
 	authorized.POST("auth/2fa/validate", monitor_controller.Validate2FA)
-	authorized.POST("auth/2fa/disable", monitor_controller.Disable2FA)
+	authorized.POST("auth/2fa/webauthn/validate/begin", monitor_controller.BeginWebAuthnValidate2FA)
+	authorized.POST("auth/2fa/webauthn/validate/finish", monitor_controller.FinishWebAuthnValidate2FA)
 
 	// 该路由组下的接口需要登录且 2FA 校验通过(如果 2FA 开启)
 	authorizedAnd2faValidated := authorized.Group("", authority.Authorize2FAMiddleware())
+	// 绑定或解绑身份验证器应用, 已开启 2FA 时需要先通过 2FA 校验
+	authorizedAnd2faValidated.GET("auth/2fa/qrcode", monitor_controller.Generate2FABindingQRCode)
+	authorizedAnd2faValidated.POST("auth/2fa/bind/app", monitor_controller.Binding2FAByAuthenticatorApp)
+	authorizedAnd2faValidated.POST("auth/2fa/disable", monitor_controller.Disable2FA)
+	// WebAuthn 凭据管理接口, 注册新凭据前需要先通过已有的 2FA 校验
+	authorizedAnd2faValidated.POST("auth/2fa/webauthn/register/begin", monitor_controller.BeginWebAuthnRegistration)
+	authorizedAnd2faValidated.POST("auth/2fa/webauthn/register/finish", monitor_controller.FinishWebAuthnRegistration)
+	authorizedAnd2faValidated.GET("auth/2fa/webauthn/credentials", monitor_controller.ListWebAuthnCredentials)
+	authorizedAnd2faValidated.DELETE("auth/2fa/webauthn/credentials/:id", monitor_controller.DeleteWebAuthnCredential)
+	// 恢复码接口
+	authorizedAnd2faValidated.GET("auth/2fa/recovery-codes", monitor_controller.GetRecoveryCodesStatus)
+	authorizedAnd2faValidated.POST("auth/2fa/recovery-codes/regenerate", monitor_controller.RegenerateRecoveryCodes)
+
+	// 角色和权限列表, 用于显示当前用户可以执行的操作和分配角色
+	authorizedAnd2faValidated.GET("role/permissions", monitor_controller.ListPermissions)
+	authorizedAnd2faValidated.GET("role/list", monitor_controller.ListRoles)
+
+	// 根据当前用户的角色检查权限
+	authority.SetPermissionChecker(monitor_controller.CheckPermission)
+	// permitted 返回只允许拥有 permission 权限的用户访问的路由组
+	permitted := func(permission authority.Permission) *gin.RouterGroup {
+		return authorizedAnd2faValidated.Group("", authority.PermissionMiddleware(permission))
+	}
 
-	authorizedAnd2faValidated.GET("notification", monitor_controller.Notification)
-	authorizedAnd2faValidated.POST("notification/collect", monitor_controller.ModifyCollectStat)
-	authorizedAnd2faValidated.GET("notification/collect", monitor_controller.GetCollectStat)
-	authorizedAnd2faValidated.GET("info/device", monitor_controller.DeviceInfo)
+	// 以下路由组下的接口允许使用拥有对应权限范围的 API 令牌访问, 其他接口只能通过登录会话访问. API 令牌同样受令牌所属用户的权限限制
+	statsRead := authority.Scoped(permitted(authority.PermissionStatsView), authority.ScopeStatsRead)
+	shortcutsRead := authority.Scoped(permitted(authority.PermissionShortcutsView), authority.ScopeShortcutsRead)
+	shortcutsWrite := authority.Scoped(permitted(authority.PermissionShortcutsEdit), authority.ScopeShortcutsWrite)
+	notificationsRead := authority.Scoped(permitted(authority.PermissionNotificationsView), authority.ScopeNotificationsRead)
+	notificationsWrite := authority.Scoped(permitted(authority.PermissionNotificationsManage), authority.ScopeNotificationsWrite)
+	upgrade := authority.Scoped(permitted(authority.PermissionSystemUpgrade), authority.ScopeUpgrade)
+
+	statsRead.GET("notification", monitor_controller.Notification)
+	statsRead.POST("notification/collect", monitor_controller.ModifyCollectStat)
+	statsRead.GET("notification/collect", monitor_controller.GetCollectStat)
+	statsRead.GET("info/device", monitor_controller.DeviceInfo)
 
 	// 通知消息相关的接口
-	authorizedAnd2faValidated.GET("notification/list/unread", monitor_controller.ListUnreadNotifications)
-	authorizedAnd2faValidated.PATCH("notification/read/:id", monitor_controller.MarkNotificationAsRead)
-	authorizedAnd2faValidated.PATCH("notification/read/all", monitor_controller.MarkAllNotificationAsRead)
+	notificationsRead.GET("notification/list/unread", monitor_controller.ListUnreadNotifications)
+	notificationsRead.GET("notification/list", monitor_controller.ListNotifications)
+	notificationsWrite.PATCH("notification/read/:id", monitor_controller.MarkNotificationAsRead)
+	notificationsWrite.PATCH("notification/read/all", monitor_controller.MarkAllNotificationAsRead)
+	notificationsWrite.PATCH("notification/archive", monitor_controller.ArchiveNotifications)
+	notificationsWrite.POST("notification/delete", monitor_controller.DeleteNotifications)
+	// -> 通知的外部投递渠道
+	notificationChannelsManage := permitted(authority.PermissionNotificationChannelsManage)
+	notificationChannelsManage.GET("notification/channel/list", monitor_controller.ListNotificationChannels)
+	notificationChannelsManage.POST("notification/channel/create", monitor_controller.CreateNotificationChannel)
+	notificationChannelsManage.PUT("notification/channel/update/:id", monitor_controller.UpdateNotificationChannel)
+	notificationChannelsManage.DELETE("notification/channel/delete/:id", monitor_controller.DeleteNotificationChannel)
+	notificationChannelsManage.POST("notification/channel/test/:id", monitor_controller.SendTestNotification)
+	notificationChannelsManage.GET("notification/channel/deliveries", monitor_controller.ListNotificationDeliveries)
+	// -> 通知的路由规则
+	notificationChannelsManage.GET("notification/route/list", monitor_controller.ListNotificationRoutes)
+	notificationChannelsManage.POST("notification/route/create", monitor_controller.CreateNotificationRoute)
+	notificationChannelsManage.PUT("notification/route/update/:id", monitor_controller.UpdateNotificationRoute)
+	notificationChannelsManage.DELETE("notification/route/delete/:id", monitor_controller.DeleteNotificationRoute)
+	// -> 接收外部系统通知的入站 Webhook
+	notificationChannelsManage.GET("notification/webhook/list", monitor_controller.ListNotificationWebhooks)
+	notificationChannelsManage.POST("notification/webhook/create", monitor_controller.CreateNotificationWebhook)
+	notificationChannelsManage.PUT("notification/webhook/update/:id", monitor_controller.UpdateNotificationWebhook)
+	notificationChannelsManage.POST("notification/webhook/token/:id", monitor_controller.RegenerateNotificationWebhookToken)
+	notificationChannelsManage.DELETE("notification/webhook/delete/:id", monitor_controller.DeleteNotificationWebhook)
 
 	// 获取配置的更新信息
-	authorizedAnd2faValidated.GET("configuration/updates", monitor_controller.GetChangedConfiguration)
+	permitted(authority.PermissionSystemConfiguration).GET("configuration/updates", monitor_controller.GetChangedConfiguration)
 
 	// 服务升级接口
-	authorizedAnd2faValidated.POST("upgrade", monitor_controller.Upgrade)
+	upgrade.POST("upgrade", monitor_controller.Upgrade)
 
 	// 获取系统信息
-	authorizedAnd2faValidated.GET("system/info", monitor_controller.SystemInfo)
+	statsRead.GET("system/info", monitor_controller.SystemInfo)
 
 	// 获取版本信息
-	authorizedAnd2faValidated.GET("version", monitor_controller.Version)
+	statsRead.GET("version", monitor_controller.Version)
+
+	// 修改当前登录用户的密码
+	authorizedAnd2faValidated.PUT("user/password", monitor_controller.ChangePassword)
+	// 当前登录用户的会话管理
+	authorizedAnd2faValidated.GET("user/session/list", monitor_controller.ListSessions)
+	authorizedAnd2faValidated.DELETE("user/session/revoke/:id", monitor_controller.RevokeSession)
+	authorizedAnd2faValidated.DELETE("user/session/revoke-others", monitor_controller.RevokeOtherSessions)
+	// 当前登录用户的 API 令牌管理
+	authorizedAnd2faValidated.POST("user/token/create", monitor_controller.CreateApiToken)
+	authorizedAnd2faValidated.GET("user/token/list", monitor_controller.ListApiTokens)
+	authorizedAnd2faValidated.DELETE("user/token/revoke/:id", monitor_controller.RevokeApiToken)
+	// 当前登录用户的通知免打扰时段
+	authorizedAnd2faValidated.GET("user/quiet-hours", monitor_controller.GetNotificationQuietHours)
+	authorizedAnd2faValidated.PUT("user/quiet-hours", monitor_controller.UpdateNotificationQuietHours)
+
+	// 用户管理接口
+	usersManage := permitted(authority.PermissionUsersManage)
+	usersManage.POST("user/create", monitor_controller.CreateUser)
+	usersManage.GET("user/list", monitor_controller.ListUsers)
+	usersManage.PUT("user/update/:id", monitor_controller.UpdateUser)
+	usersManage.PUT("user/disable/:id", monitor_controller.DisableUser)
+	usersManage.PUT("user/enable/:id", monitor_controller.EnableUser)
+	usersManage.DELETE("user/delete/:id", monitor_controller.DeleteUser)
+	// 登录尝试记录和被锁定的用户名, IP
+	usersManage.GET("user/login-attempts", monitor_controller.ListLoginAttempts)
+	// 轮换 session 签名密钥
+	usersManage.POST("session/secret/rotate", monitor_controller.RotateSessionSecret)
+	// 角色管理接口
+	rolesManage := permitted(authority.PermissionRolesManage)
+	rolesManage.POST("role/create", monitor_controller.CreateRole)
+	rolesManage.PUT("role/update/:id", monitor_controller.UpdateRole)
+	rolesManage.DELETE("role/delete/:id", monitor_controller.DeleteRole)
+	// 审计日志
+	permitted(authority.PermissionAuditView).GET("audit/logs", monitor_controller.ListAuditLogs)
 
 	// 书签相关接口
 	// -> 书签文件夹接口
-	authorizedAnd2faValidated.POST("shortcut/section/create", monitor_controller.CreateShortcutSection)
-	authorizedAnd2faValidated.GET("shortcut/section/list", monitor_controller.ListShortcutSections)
-	authorizedAnd2faValidated.PUT("shortcut/section/update/:id", monitor_controller.UpdateShortcutSection)
-	authorizedAnd2faValidated.DELETE("shortcut/section/delete/:id", monitor_controller.DeleteShortcutSection)
-	authorizedAnd2faValidated.DELETE("shortcut/section/delete/:id/items", monitor_controller.DeleteShortcutSectionItems)
+	shortcutsWrite.POST("shortcut/section/create", monitor_controller.CreateShortcutSection)
+	shortcutsRead.GET("shortcut/section/list", monitor_controller.ListShortcutSections)
+	shortcutsRead.GET("shortcut/section/virtual/list", monitor_controller.ListVirtualShortcutSections)
+	shortcutsWrite.PUT("shortcut/section/update/:id", monitor_controller.UpdateShortcutSection)
+	shortcutsWrite.DELETE("shortcut/section/delete/:id", monitor_controller.DeleteShortcutSection)
+	shortcutsWrite.DELETE("shortcut/section/delete/:id/items", monitor_controller.DeleteShortcutSectionItems)
 	// -> 书签接口
-	authorizedAnd2faValidated.GET("shortcut/item/extract-from-url", monitor_controller.ExtractShortcutItemInfoFromURL)
-	authorizedAnd2faValidated.POST("shortcut/item/create", monitor_controller.CreateShortcutItem)
-	authorizedAnd2faValidated.GET("shortcut/item/list", monitor_controller.ListShortcutItems)
-	authorizedAnd2faValidated.PUT("shortcut/item/update/:id", monitor_controller.UpdateShortcutItem)
-	authorizedAnd2faValidated.DELETE("shortcut/item/delete", monitor_controller.DeleteShortcutItem)
-	authorizedAnd2faValidated.PUT("shortcut/item/refresh-image-icon-cache/:sectionId", monitor_controller.RefreshCachedShortcutItemImageIcon)
+	shortcutsRead.GET("shortcut/item/extract-from-url", monitor_controller.ExtractShortcutItemInfoFromURL)
+	shortcutsWrite.POST("shortcut/item/create", monitor_controller.CreateShortcutItem)
+	shortcutsRead.GET("shortcut/item/list", monitor_controller.ListShortcutItems)
+	shortcutsWrite.PUT("shortcut/item/update/:id", monitor_controller.UpdateShortcutItem)
+	shortcutsWrite.DELETE("shortcut/item/delete", monitor_controller.DeleteShortcutItem)
+	shortcutsWrite.POST("shortcut/item/bulk", monitor_controller.BulkOperateShortcutItems)
+	shortcutsRead.GET("shortcut/item/link-check/report", monitor_controller.ListShortcutItemLinkChecks)
+	shortcutsWrite.POST("shortcut/item/link-check/run", monitor_controller.RunShortcutItemLinkCheck)
+	shortcutsWrite.PUT("shortcut/item/link-check/accept/:itemId", monitor_controller.AcceptShortcutItemLinkCheck)
+	shortcutsRead.GET("shortcut/discovery/list", monitor_controller.ListDiscoveredServices)
+	shortcutsWrite.POST("shortcut/discovery/run", monitor_controller.RunServiceDiscovery)
+	shortcutsWrite.PUT("shortcut/discovery/accept/:id", monitor_controller.AcceptDiscoveredService)
+	shortcutsWrite.PUT("shortcut/discovery/dismiss/:id", monitor_controller.DismissDiscoveredService)
+	shortcutsRead.GET("shortcut/docker/list", monitor_controller.ListDockerContainerShortcuts)
+	shortcutsWrite.POST("shortcut/docker/sync", monitor_controller.SyncDockerContainerShortcuts)
+	shortcutsWrite.PUT("shortcut/item/refresh-image-icon-cache/:sectionId", monitor_controller.RefreshCachedShortcutItemImageIcon)
+	// -> 回收站接口
+	shortcutsRead.GET("shortcut/trash/list", monitor_controller.ListShortcutTrash)
+	shortcutsWrite.PUT("shortcut/trash/restore", monitor_controller.RestoreShortcutTrash)
+	shortcutsWrite.DELETE("shortcut/trash/purge", monitor_controller.PurgeShortcutTrash)
+	shortcutsWrite.DELETE("shortcut/trash/empty", monitor_controller.EmptyShortcutTrash)
 	// -> 书签图标接口
-	authorizedAnd2faValidated.PUT("shortcut/icon/refresh", monitor_controller.RefreshShortcutIcons)
+	shortcutsWrite.PUT("shortcut/icon/refresh", monitor_controller.RefreshShortcutIcons)
+	shortcutsRead.GET("shortcut/icon/search", monitor_controller.SearchShortcutIcons)
+	shortcutsRead.GET("shortcut/icon/svg/:slug", monitor_controller.GetShortcutIconSvg)
 	// -> 收集书签使用情况
-	authorizedAnd2faValidated.POST("shortcut/usage/collect", monitor_controller.CollectShortcutSectionItemUsages)
+	shortcutsWrite.POST("shortcut/usage/collect", monitor_controller.CollectShortcutSectionItemUsages)
+	shortcutsRead.GET("shortcut/usage/statistics", monitor_controller.ListShortcutSectionItemUsageStatistics)
+
+	// 搜索相关接口
+	// -> 搜索引擎接口
+	shortcutsWrite.POST("search/provider/create", monitor_controller.CreateSearchProvider)
+	shortcutsRead.GET("search/provider/list", monitor_controller.ListSearchProviders)
+	shortcutsWrite.PUT("search/provider/update/:id", monitor_controller.UpdateSearchProvider)
+	shortcutsWrite.DELETE("search/provider/delete/:id", monitor_controller.DeleteSearchProvider)
+	// -> 搜索建议与统一搜索接口
+	shortcutsRead.GET("search/suggestion", monitor_controller.ListSearchSuggestions)
+	shortcutsRead.GET("search/query", monitor_controller.Search)
 
 	// 启用第三方服务
 	if err := third_party.Load(authorizedAnd2faValidated); err != nil {
@@ -206,6 +340,13 @@ func startServer(listener *net.Listener, mock bool) error {
 		return errors.Errorf("file service start failed, %w\n", err)
 	}
 
+	// 供 nginx auth_request 和 Traefik ForwardAuth 使用的转发认证接口
+	engine.Group("/v1/auth").Match([]string{http.MethodGet, http.MethodHead}, "verify", monitor_controller.ForwardAuthVerify)
+
+	// 嵌入快捷方式的反向代理, 需要登录, 2FA 校验通过(如果 2FA 开启)且拥有查看快捷方式的权限
+	proxyRouter := engine.Group(monitor_controller.ProxyShortcutItemPrefix, authority.AuthorizeMiddleware(), monitor_controller.ActiveUserMiddleware(), authority.Authorize2FAMiddleware(), authority.PermissionMiddleware(authority.PermissionShortcutsView))
+	proxyRouter.Any("/:itemId/*path", monitor_controller.ProxyShortcutItem)
+
 	// 嵌入 home-dashboard-web-ui 静态资源
 	if err := web_submodules.EmbedHomeDashboardWebUI(engine); err != nil {
 		return errors.Errorf("embed home-dashboard-web-ui failed, %w\n", err)
@@ -0,0 +1,201 @@
+package shortcut_link_checker
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-errors/errors"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_model"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/comfy_log"
+	"github.com/siaikin/home-dashboard/internal/pkg/configuration"
+	"github.com/siaikin/home-dashboard/internal/pkg/link_checker"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var logger = comfy_log.New("[shortcut_link_checker]")
+
+// ErrorRunning 已有正在进行的检查.
+var ErrorRunning = errors.New("shortcut link check is running")
+
+var running atomic.Bool
+var lastRunAt atomic.Int64
+
+// 手动触发的检查在后台运行, 不能使用请求的 context, 因此使用 Loop 传入的 context.
+var loopCtx = context.Background()
+
+// Running 是否有正在进行的检查.
+func Running() bool {
+	return running.Load()
+}
+
+// LastRunAt 最近一次检查完成的时间(毫秒时间戳), 从未检查过时为 0.
+func LastRunAt() int64 {
+	return lastRunAt.Load()
+}
+
+// Loop 按照配置的时间间隔定期检查所有快捷方式的链接. 未启用定期检查时直接返回.
+func Loop(ctx context.Context) {
+	loopCtx = ctx
+
+	config := configuration.Get().ServerMonitor.LinkChecker
+	if !config.Enable {
+		return
+	}
+
+	interval := config.Interval * time.Second
+	if interval <= 0 {
+		interval = time.Hour * 24
+	}
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer logger.Info("stop shortcut link check loop\n")
+
+		for {
+			select {
+			case <-ctx.Done():
+				ticker.Stop()
+				return
+			case <-ticker.C:
+				if err := Run(ctx); err != nil && !errors.Is(err, ErrorRunning) {
+					logger.Error("check shortcut links failed, %w\n", err)
+				}
+			}
+		}
+	}()
+}
+
+// Start 在后台检查所有快捷方式的链接, 用于手动触发检查. 如果已有正在进行的检查, 则返回 ErrorRunning.
+func Start() error {
+	if running.Load() {
+		return ErrorRunning
+	}
+
+	go func() {
+		if err := Run(loopCtx); err != nil && !errors.Is(err, ErrorRunning) {
+			logger.Error("check shortcut links failed, %w\n", err)
+		}
+	}()
+
+	return nil
+}
+
+// Run 检查所有快捷方式的链接并保存检查结果. 发现失效或被永久重定向的链接时发送用户通知.
+func Run(ctx context.Context) error {
+	if !running.CompareAndSwap(false, true) {
+		return ErrorRunning
+	}
+	defer running.Store(false)
+
+	items, err := monitor_service.ListShortcutItemsByQuery(monitor_model.ShortcutItem{}, nil)
+	if err != nil {
+		return err
+	}
+
+	config := configuration.Get().ServerMonitor.LinkChecker
+	timeout := config.Timeout * time.Second
+	if timeout <= 0 {
+		timeout = time.Second * 10
+	}
+	concurrency := config.Concurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+
+	checker := link_checker.New(timeout, nil)
+	if ua, err := monitor_service.RandomUserAgent(); err == nil {
+		checker.Header.Set("User-Agent", ua.UserAgent)
+	}
+
+	logger.Info("start checking %d shortcut links\n", len(*items))
+
+	checks := checkItems(ctx, checker, *items, concurrency)
+	// 检查被中断时, 未完成的检查结果不可信, 不做保存.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := monitor_service.CreateOrUpdateShortcutItemLinkChecks(checks); err != nil {
+		return err
+	}
+
+	now := time.Now().UnixMilli()
+	lastRunAt.Store(now)
+
+	problems := 0
+	for _, check := range checks {
+		if check.Status != link_checker.StatusOk {
+			problems++
+		}
+	}
+	logger.Info("checked %d shortcut links, %d problems found\n", len(checks), problems)
+
+	if problems > 0 {
+		notification.SendUserNotifications([]notification.UserNotification{
+			{
+				UniqueId:       fmt.Sprintf("shortcut-link-check-%d", now),
+				Unread:         true,
+				Title:          fmt.Sprintf("%d shortcut links need attention", problems),
+				Caption:        "Some shortcuts are unreachable or permanently redirected, check the link report for suggested fixes.",
+				Kind:           notification.UserNotificationKindWarning,
+				Origin:         notification.UserNotificationOriginMain,
+				OriginCreateAt: now,
+			},
+		})
+	}
+
+	return nil
+}
+
+// checkItems 使用 concurrency 个 goroutine 并发检查快捷方式的链接, 链接为空的快捷方式将被忽略.
+func checkItems(ctx context.Context, checker *link_checker.Checker, items []monitor_model.ShortcutItem, concurrency int) []monitor_model.ShortcutItemLinkCheck {
+	queue := make(chan monitor_model.ShortcutItem)
+	checks := make([]monitor_model.ShortcutItemLinkCheck, 0, len(items))
+	mutex := sync.Mutex{}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for item := range queue {
+				result := checker.Check(ctx, item.URL)
+
+				mutex.Lock()
+				checks = append(checks, monitor_model.ShortcutItemLinkCheck{
+					ItemId:       item.ID,
+					Url:          result.Url,
+					Status:       result.Status,
+					StatusCode:   result.StatusCode,
+					Error:        result.Error,
+					Redirects:    result.Redirects,
+					FinalUrl:     result.FinalUrl,
+					SuggestedUrl: result.SuggestedUrl,
+					CheckedAt:    time.Now().UnixMilli(),
+				})
+				mutex.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, item := range items {
+		if len(strings.TrimSpace(item.URL)) <= 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			break feed
+		case queue <- item:
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	return checks
+}
@@ -0,0 +1,46 @@
+package shortcut_trash_cleaner
+
+import (
+	"context"
+	"github.com/siaikin/home-dashboard/internal/app/server_monitor/monitor_service"
+	"github.com/siaikin/home-dashboard/internal/pkg/comfy_log"
+	"github.com/siaikin/home-dashboard/internal/pkg/configuration"
+	"time"
+)
+
+var logger = comfy_log.New("[shortcut_trash_cleaner]")
+
+// 检查回收站的时间间隔.
+const checkInterval = time.Hour
+
+// Loop 定期彻底删除在回收站中超过保留时间的快捷方式和分组. 未启用自动清理时直接返回.
+func Loop(ctx context.Context) {
+	config := configuration.Get().ServerMonitor.Trash
+	if !config.AutoPurge {
+		return
+	}
+
+	retention := config.Retention * time.Second
+	if retention <= 0 {
+		retention = time.Hour * 24 * 30
+	}
+
+	go func() {
+		defer logger.Info("stop shortcut trash clean loop\n")
+
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := monitor_service.PurgeExpiredShortcutTrash(retention); err != nil {
+				logger.Error("purge expired shortcut trash failed, %w\n", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
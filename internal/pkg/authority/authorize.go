@@ -18,9 +18,24 @@ type User struct {
 // InfoKey session 中存储用户信息的 key. 用户信息类型为 User
 var InfoKey = "authorityInfo"
 
-// AuthorizeMiddleware 权限验证中间件
+// AuthorizeMiddleware 权限验证中间件. 请求携带 `Authorization: Bearer <token>` 请求头时使用 API 令牌校验,
+// 只允许访问通过 [Scoped] 注册且令牌拥有对应权限范围的路由; 否则使用登录会话校验.
 func AuthorizeMiddleware() gin.HandlerFunc {
 	return func(context *gin.Context) {
+		if token, ok := bearerToken(context); ok {
+			if code, err := authorizeToken(context, token); err != nil {
+				errorCode := comfy_errors.LoginRequestError
+				if code == http.StatusForbidden {
+					errorCode = comfy_errors.PermissionDeniedError
+				}
+				_ = context.AbortWithError(code, comfy_errors.NewResponseError(errorCode, err.Error()))
+				return
+			}
+
+			context.Next()
+			return
+		}
+
 		session := sessions.Default(context)
 
 		info := session.Get(InfoKey)
@@ -33,9 +48,14 @@ func AuthorizeMiddleware() gin.HandlerFunc {
 }
 
 // Authorize2FAMiddleware 2FA 权限验证中间件. 当用户开启了 2FA 时, 该中间件会验证用户是否已经通过 2FA.
-// 该中间件应该在 [AuthorizeMiddleware] 之后使用.
+// 该中间件应该在 [AuthorizeMiddleware] 之后使用. API 令牌只能在通过 2FA 校验后创建, 因此使用 API 令牌的请求不需要再次校验.
 func Authorize2FAMiddleware() gin.HandlerFunc {
 	return func(context *gin.Context) {
+		if IsTokenRequest(context) {
+			context.Next()
+			return
+		}
+
 		session := sessions.Default(context)
 
 		info := session.Get(InfoKey).(User)
@@ -0,0 +1,166 @@
+package authority
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/go-errors/errors"
+	"github.com/samber/lo"
+	"github.com/siaikin/home-dashboard/internal/pkg/configuration"
+	"golang.org/x/oauth2"
+)
+
+var defaultOidcScopes = []string{oidc.ScopeOpenID, "profile", "email", "groups"}
+
+const (
+	defaultOidcUsernameClaim = "preferred_username"
+	defaultOidcGroupsClaim   = "groups"
+)
+
+// OidcIdentity 从 ID Token 中解析出的用户身份.
+type OidcIdentity struct {
+	// Issuer 签发 ID Token 的提供方.
+	Issuer string
+	// Subject 用户在提供方中的唯一标识.
+	Subject  string
+	Username string
+	Groups   []string
+}
+
+// OidcAuthRequest 一次授权请求的参数, 需要保存到 session 中, 在回调时校验.
+type OidcAuthRequest struct {
+	State string
+	Nonce string
+	// CodeVerifier PKCE 的 code_verifier, 授权地址中只包含其 S256 摘要.
+	CodeVerifier string
+}
+
+// OidcClient OpenID Connect 客户端, 使用授权码模式(PKCE)登录.
+type OidcClient struct {
+	config   configuration.ServerMonitorOidcConfiguration
+	verifier *oidc.IDTokenVerifier
+	oauth2   oauth2.Config
+}
+
+// NewOidcClient 通过 <issuer>/.well-known/openid-configuration 获取提供方的配置并创建客户端.
+func NewOidcClient(ctx context.Context, config configuration.ServerMonitorOidcConfiguration) (*OidcClient, error) {
+	if len(config.Issuer) <= 0 || len(config.ClientId) <= 0 || len(config.RedirectUrl) <= 0 {
+		return nil, errors.New("oidc issuer, clientId and redirectUrl are required")
+	}
+
+	provider, err := oidc.NewProvider(ctx, config.Issuer)
+	if err != nil {
+		return nil, errors.Errorf("discover oidc provider %s failed, %w", config.Issuer, err)
+	}
+
+	scopes := lo.Ternary(len(config.Scopes) > 0, config.Scopes, defaultOidcScopes)
+	if !lo.Contains(scopes, oidc.ScopeOpenID) {
+		scopes = append([]string{oidc.ScopeOpenID}, scopes...)
+	}
+
+	return &OidcClient{
+		config:   config,
+		verifier: provider.Verifier(&oidc.Config{ClientID: config.ClientId}),
+		oauth2: oauth2.Config{
+			ClientID:     config.ClientId,
+			ClientSecret: config.ClientSecret,
+			Endpoint:     provider.Endpoint(),
+			RedirectURL:  config.RedirectUrl,
+			Scopes:       scopes,
+		},
+	}, nil
+}
+
+// Config 创建客户端时使用的配置.
+func (o *OidcClient) Config() configuration.ServerMonitorOidcConfiguration {
+	return o.config
+}
+
+// NewAuthRequest 生成新的授权请求参数.
+func (o *OidcClient) NewAuthRequest() (OidcAuthRequest, error) {
+	values := make([]string, 3)
+	for i := range values {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return OidcAuthRequest{}, errors.New(err)
+		}
+		values[i] = base64.RawURLEncoding.EncodeToString(buf)
+	}
+
+	return OidcAuthRequest{State: values[0], Nonce: values[1], CodeVerifier: values[2]}, nil
+}
+
+// AuthCodeURL 获取提供方的授权地址.
+func (o *OidcClient) AuthCodeURL(request OidcAuthRequest) string {
+	return o.oauth2.AuthCodeURL(
+		request.State,
+		oidc.Nonce(request.Nonce),
+		oauth2.SetAuthURLParam("code_challenge", pkceChallenge(request.CodeVerifier)),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	)
+}
+
+// Exchange 使用授权码换取并校验 ID Token, 返回 ID Token 中的用户身份.
+func (o *OidcClient) Exchange(ctx context.Context, code string, request OidcAuthRequest) (OidcIdentity, error) {
+	token, err := o.oauth2.Exchange(ctx, code, oauth2.SetAuthURLParam("code_verifier", request.CodeVerifier))
+	if err != nil {
+		return OidcIdentity{}, errors.Errorf("exchange oidc code failed, %w", err)
+	}
+
+	rawIdToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return OidcIdentity{}, errors.New("oidc token response has no id_token")
+	}
+
+	idToken, err := o.verifier.Verify(ctx, rawIdToken)
+	if err != nil {
+		return OidcIdentity{}, errors.Errorf("verify oidc id_token failed, %w", err)
+	}
+	if idToken.Nonce != request.Nonce {
+		return OidcIdentity{}, errors.New("oidc id_token nonce mismatch")
+	}
+
+	claims := map[string]any{}
+	if err := idToken.Claims(&claims); err != nil {
+		return OidcIdentity{}, errors.New(err)
+	}
+
+	usernameClaim := lo.Ternary(len(o.config.UsernameClaim) > 0, o.config.UsernameClaim, defaultOidcUsernameClaim)
+	username, _ := claims[usernameClaim].(string)
+	if len(username) <= 0 {
+		return OidcIdentity{}, errors.Errorf("oidc id_token has no %s claim", usernameClaim)
+	}
+
+	groupsClaim := lo.Ternary(len(o.config.GroupsClaim) > 0, o.config.GroupsClaim, defaultOidcGroupsClaim)
+
+	return OidcIdentity{
+		Issuer:   idToken.Issuer,
+		Subject:  idToken.Subject,
+		Username: username,
+		Groups:   stringsClaim(claims[groupsClaim]),
+	}, nil
+}
+
+// stringsClaim 将字符串或字符串数组类型的 claim 转换为 []string.
+func stringsClaim(claim any) []string {
+	switch value := claim.(type) {
+	case string:
+		return []string{value}
+	case []any:
+		return lo.FilterMap(value, func(item any, _ int) (string, bool) {
+			s, ok := item.(string)
+			return s, ok
+		})
+	default:
+		return []string{}
+	}
+}
+
+// pkceChallenge 计算 PKCE 的 S256 code_challenge.
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
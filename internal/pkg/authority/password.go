@@ -0,0 +1,108 @@
+package authority
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"github.com/go-errors/errors"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+// argon2id 的参数, 参考 OWASP 的推荐配置. 修改参数后, 使用旧参数计算的哈希值会在校验通过时被重新计算.
+const (
+	argon2Memory   uint32 = 19 * 1024
+	argon2Time     uint32 = 2
+	argon2Threads  uint8  = 1
+	argon2SaltLen         = 16
+	argon2KeyLen   uint32 = 32
+	argon2IdPrefix        = "$argon2id$"
+)
+
+var bcryptPrefixes = []string{"$2a$", "$2b$", "$2y$"}
+
+var errorInvalidPasswordHash = errors.New("invalid password hash")
+
+// HashPassword 使用 argon2id 计算密码的哈希值, 结果为 PHC 字符串格式:
+//
+//	$argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", errors.New(err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2IdPrefix,
+		argon2.Version,
+		argon2Memory, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// IsPasswordHash 判断 s 是否为 HashPassword 或 bcrypt 计算的哈希值. 不是时认为 s 为明文密码.
+func IsPasswordHash(s string) bool {
+	return strings.HasPrefix(s, argon2IdPrefix) || isBcryptHash(s)
+}
+
+// VerifyPassword 以常量时间校验密码是否与 hashed 匹配. hashed 可以是 argon2id 或 bcrypt 哈希值, 也可以是旧版本存储的明文密码.
+// needsRehash 为 true 时表示 hashed 为明文, bcrypt 哈希值或使用旧参数计算的哈希值, 校验通过后应使用 HashPassword 重新计算.
+func VerifyPassword(hashed string, password string) (ok bool, needsRehash bool) {
+	switch {
+	case strings.HasPrefix(hashed, argon2IdPrefix):
+		memory, time, threads, salt, key, err := decodeArgon2IdHash(hashed)
+		if err != nil {
+			return false, false
+		}
+
+		actual := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(key)))
+		ok = subtle.ConstantTimeCompare(actual, key) == 1
+		needsRehash = memory != argon2Memory || time != argon2Time || threads != argon2Threads || uint32(len(key)) != argon2KeyLen
+		return ok, ok && needsRehash
+	case isBcryptHash(hashed):
+		return bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password)) == nil, true
+	default:
+		// 旧版本存储的明文密码
+		return len(hashed) > 0 && subtle.ConstantTimeCompare([]byte(hashed), []byte(password)) == 1, true
+	}
+}
+
+func isBcryptHash(s string) bool {
+	for _, prefix := range bcryptPrefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func decodeArgon2IdHash(hashed string) (memory uint32, time uint32, threads uint8, salt []byte, key []byte, err error) {
+	// "", "argon2id", "v=19", "m=19456,t=2,p=1", salt, key
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 6 {
+		return 0, 0, 0, nil, nil, errorInvalidPasswordHash
+	}
+
+	version := 0
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return 0, 0, 0, nil, nil, errorInvalidPasswordHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return 0, 0, 0, nil, nil, errorInvalidPasswordHash
+	}
+
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return 0, 0, 0, nil, nil, errorInvalidPasswordHash
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(key) <= 0 {
+		return 0, 0, 0, nil, nil, errorInvalidPasswordHash
+	}
+
+	return memory, time, threads, salt, key, nil
+}
@@ -0,0 +1,65 @@
+package authority
+
+import (
+	"encoding/base64"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"testing"
+)
+
+func TestHashPassword(t *testing.T) {
+	hashed, err := HashPassword("123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(hashed, "$argon2id$v=19$m=19456,t=2,p=1$") || !IsPasswordHash(hashed) {
+		t.Errorf("unexpected hash %s", hashed)
+	}
+	if another, _ := HashPassword("123456"); another == hashed {
+		t.Errorf("hash should be salted")
+	}
+
+	if ok, needsRehash := VerifyPassword(hashed, "123456"); !ok || needsRehash {
+		t.Errorf("password should be verified without rehash, got %v %v", ok, needsRehash)
+	}
+	if ok, _ := VerifyPassword(hashed, "1234567"); ok {
+		t.Errorf("wrong password should not be verified")
+	}
+}
+
+func TestVerifyPassword(t *testing.T) {
+	t.Run("plaintext", func(t *testing.T) {
+		if ok, needsRehash := VerifyPassword("123456", "123456"); !ok || !needsRehash {
+			t.Errorf("plaintext password should be verified and rehashed, got %v %v", ok, needsRehash)
+		}
+		if ok, _ := VerifyPassword("", ""); ok {
+			t.Errorf("empty password should not be verified")
+		}
+	})
+
+	t.Run("bcrypt", func(t *testing.T) {
+		hashed, _ := bcrypt.GenerateFromPassword([]byte("123456"), bcrypt.MinCost)
+		if ok, needsRehash := VerifyPassword(string(hashed), "123456"); !ok || !needsRehash {
+			t.Errorf("bcrypt password should be verified and rehashed, got %v %v", ok, needsRehash)
+		}
+	})
+
+	t.Run("outdated parameters", func(t *testing.T) {
+		salt := []byte("saltsaltsaltsalt")
+		key := argon2.IDKey([]byte("123456"), salt, 1, 8*1024, 1, 32)
+		hashed := fmt.Sprintf("$argon2id$v=19$m=8192,t=1,p=1$%s$%s", base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+
+		if ok, needsRehash := VerifyPassword(hashed, "123456"); !ok || !needsRehash {
+			t.Errorf("password hashed with outdated parameters should be verified and rehashed, got %v %v", ok, needsRehash)
+		}
+	})
+
+	t.Run("invalid hash", func(t *testing.T) {
+		if ok, _ := VerifyPassword("$argon2id$v=19$m=8192,t=1,p=1$c2FsdA$", ""); ok {
+			t.Errorf("invalid hash should not be verified")
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package authority
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/siaikin/home-dashboard/internal/pkg/comfy_errors"
+	"net/http"
+)
+
+// Permission 用户的权限. 角色是权限的集合, 每个路由通过 PermissionMiddleware 声明所需的权限.
+type Permission = string
+
+const (
+	// PermissionStatsView 查看系统实时统计信息, 设备信息和版本信息.
+	PermissionStatsView Permission = "stats.view"
+	// PermissionProcessView 查看进程的实时统计信息.
+	PermissionProcessView Permission = "process.view"
+	// PermissionProcessControl 控制进程, 如结束进程.
+	PermissionProcessControl Permission = "process.control"
+	// PermissionShortcutsView 查看快捷方式, 搜索引擎和使用统计.
+	PermissionShortcutsView Permission = "shortcuts.view"
+	// PermissionShortcutsEdit 创建, 修改和删除快捷方式和搜索引擎.
+	PermissionShortcutsEdit Permission = "shortcuts.edit"
+	// PermissionNotificationsView 查看通知消息.
+	PermissionNotificationsView Permission = "notifications.view"
+	// PermissionNotificationsManage 修改通知消息的状态.
+	PermissionNotificationsManage Permission = "notifications.manage"
+	// PermissionNotificationChannelsManage 管理通知的外部投递渠道(Webhook, 邮件等), 路由规则和入站 Webhook, 查看投递记录.
+	PermissionNotificationChannelsManage Permission = "notifications.channels"
+	// PermissionSystemUpgrade 升级服务.
+	PermissionSystemUpgrade Permission = "system.upgrade"
+	// PermissionSystemConfiguration 查看配置文件的变更.
+	PermissionSystemConfiguration Permission = "system.configuration"
+	// PermissionUsersManage 管理用户, 查看登录尝试记录和轮换 session 签名密钥.
+	PermissionUsersManage Permission = "users.manage"
+	// PermissionRolesManage 管理角色及其权限.
+	PermissionRolesManage Permission = "roles.manage"
+	// PermissionAuditView 查看审计日志.
+	PermissionAuditView Permission = "audit.view"
+	// PermissionThirdPartyGitHub 访问第三方服务 GitHub 的接口.
+	PermissionThirdPartyGitHub Permission = "thirdparty.github"
+	// PermissionThirdPartyWakapi 访问第三方服务 Wakapi 的接口.
+	PermissionThirdPartyWakapi Permission = "thirdparty.wakapi"
+)
+
+// Permissions 所有可用的权限.
+var Permissions = []Permission{
+	PermissionStatsView,
+	PermissionProcessView,
+	PermissionProcessControl,
+	PermissionShortcutsView,
+	PermissionShortcutsEdit,
+	PermissionNotificationsView,
+	PermissionNotificationsManage,
+	PermissionNotificationChannelsManage,
+	PermissionSystemUpgrade,
+	PermissionSystemConfiguration,
+	PermissionUsersManage,
+	PermissionRolesManage,
+	PermissionAuditView,
+	PermissionThirdPartyGitHub,
+	PermissionThirdPartyWakapi,
+}
+
+// PermissionChecker 检查发起请求的用户是否拥有 permission 权限.
+type PermissionChecker func(c *gin.Context, permission Permission) (bool, error)
+
+var permissionChecker PermissionChecker
+
+// SetPermissionChecker 设置权限的检查函数. 未设置时 PermissionMiddleware 拒绝所有请求.
+func SetPermissionChecker(checker PermissionChecker) {
+	permissionChecker = checker
+}
+
+// HasPermission 检查发起请求的用户是否拥有 permission 权限.
+func HasPermission(c *gin.Context, permission Permission) (bool, error) {
+	if permissionChecker == nil {
+		return false, nil
+	}
+
+	return permissionChecker(c, permission)
+}
+
+// PermissionMiddleware 只允许拥有 permission 权限的用户访问. 该中间件应该在 AuthorizeMiddleware 之后使用.
+func PermissionMiddleware(permission Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if ok, err := HasPermission(c, permission); err != nil {
+			_ = c.AbortWithError(http.StatusInternalServerError, comfy_errors.NewResponseError(comfy_errors.UnknownError, "check permission %s failed. %w", permission, err))
+			return
+		} else if !ok {
+			_ = c.AbortWithError(http.StatusForbidden, comfy_errors.NewResponseError(comfy_errors.PermissionDeniedError, "permission %s required", permission))
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,51 @@
+package authority
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"github.com/go-errors/errors"
+	"strings"
+)
+
+// RecoveryCodeCount 每次生成的恢复码数量.
+const RecoveryCodeCount = 10
+
+// recoveryCodeLength 恢复码的随机字节数, 编码后为 16 个字符.
+const recoveryCodeLength = 10
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateRecoveryCodes 生成 RecoveryCodeCount 个一次性恢复码, 格式为 xxxx-xxxx-xxxx-xxxx.
+// 恢复码明文只应返回给用户一次, 数据库中只存储 HashRecoveryCode 计算的哈希值.
+func GenerateRecoveryCodes() ([]string, error) {
+	codes := make([]string, RecoveryCodeCount)
+
+	for i := range codes {
+		buf := make([]byte, recoveryCodeLength)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, errors.New(err)
+		}
+
+		encoded := strings.ToLower(recoveryCodeEncoding.EncodeToString(buf))
+		codes[i] = encoded[0:4] + "-" + encoded[4:8] + "-" + encoded[8:12] + "-" + encoded[12:16]
+	}
+
+	return codes, nil
+}
+
+// HashRecoveryCode 计算恢复码的哈希值. 忽略大小写, 空白和连字符, 方便用户手动输入.
+// 恢复码与 API 令牌一样为高熵的随机值, 使用 sha256 即可.
+func HashRecoveryCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' || r == '\t' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(code)))
+
+	sum := sha256.Sum256([]byte(normalized))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,181 @@
+package authority
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/go-errors/errors"
+	"github.com/samber/lo"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Scope API 令牌的权限范围.
+type Scope = string
+
+const (
+	// ScopeStatsRead 读取系统实时统计信息, 设备信息和版本信息.
+	ScopeStatsRead Scope = "stats:read"
+	// ScopeShortcutsRead 读取快捷方式.
+	ScopeShortcutsRead Scope = "shortcuts:read"
+	// ScopeShortcutsWrite 创建, 修改和删除快捷方式.
+	ScopeShortcutsWrite Scope = "shortcuts:write"
+	// ScopeNotificationsRead 读取通知消息.
+	ScopeNotificationsRead Scope = "notifications:read"
+	// ScopeNotificationsWrite 修改通知消息的状态.
+	ScopeNotificationsWrite Scope = "notifications:write"
+	// ScopeUpgrade 升级服务.
+	ScopeUpgrade Scope = "upgrade"
+)
+
+// Scopes 所有可用的权限范围.
+var Scopes = []Scope{
+	ScopeStatsRead,
+	ScopeShortcutsRead,
+	ScopeShortcutsWrite,
+	ScopeNotificationsRead,
+	ScopeNotificationsWrite,
+	ScopeUpgrade,
+}
+
+// ApiTokenPrefix API 令牌的前缀, 便于识别和扫描泄露的令牌.
+const ApiTokenPrefix = "hdp_"
+
+// apiTokenLength API 令牌随机部分的字节数.
+const apiTokenLength = 32
+
+// bearerPrefix Authorization 请求头中 API 令牌的前缀.
+const bearerPrefix = "Bearer "
+
+// gin.Context 中存储 API 令牌请求的用户信息和权限范围的 key.
+const (
+	tokenUserKey   = "authorityTokenUser"
+	tokenScopesKey = "authorityTokenScopes"
+)
+
+// TokenVerifier 校验 API 令牌, 返回令牌所属的用户和令牌的权限范围. 令牌无效或已过期时返回 error.
+type TokenVerifier func(token string) (User, []Scope, error)
+
+var tokenVerifier TokenVerifier
+
+// SetTokenVerifier 设置 API 令牌的校验函数. 未设置时不允许使用 API 令牌访问.
+func SetTokenVerifier(verifier TokenVerifier) {
+	tokenVerifier = verifier
+}
+
+// GenerateApiToken 生成新的 API 令牌. 令牌明文只应返回给用户一次, 数据库中只存储 HashApiToken 计算的哈希值.
+func GenerateApiToken() (string, error) {
+	buf := make([]byte, apiTokenLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.New(err)
+	}
+
+	return ApiTokenPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashApiToken 计算 API 令牌的哈希值. 令牌本身为高熵的随机值, 使用 sha256 即可, 不需要使用 HashPassword.
+func HashApiToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// routeScopes 允许使用 API 令牌访问的路由及所需的权限范围, key 为 "<method> <full path>".
+var routeScopes sync.Map
+
+// ScopedGroup 通过 ScopedGroup 注册的路由允许使用拥有 scope 权限的 API 令牌访问, 其他路由只能通过登录会话访问.
+type ScopedGroup struct {
+	group *gin.RouterGroup
+	scope Scope
+}
+
+// Scoped 返回注册路由到 group 的 ScopedGroup.
+func Scoped(group *gin.RouterGroup, scope Scope) *ScopedGroup {
+	return &ScopedGroup{group: group, scope: scope}
+}
+
+func (s *ScopedGroup) Handle(method string, relativePath string, handlers ...gin.HandlerFunc) {
+	fullPath := path.Join(s.group.BasePath(), relativePath)
+	if strings.HasSuffix(relativePath, "/") && !strings.HasSuffix(fullPath, "/") {
+		fullPath += "/"
+	}
+	routeScopes.Store(method+" "+fullPath, s.scope)
+
+	s.group.Handle(method, relativePath, handlers...)
+}
+
+func (s *ScopedGroup) GET(relativePath string, handlers ...gin.HandlerFunc) {
+	s.Handle(http.MethodGet, relativePath, handlers...)
+}
+
+func (s *ScopedGroup) POST(relativePath string, handlers ...gin.HandlerFunc) {
+	s.Handle(http.MethodPost, relativePath, handlers...)
+}
+
+func (s *ScopedGroup) PUT(relativePath string, handlers ...gin.HandlerFunc) {
+	s.Handle(http.MethodPut, relativePath, handlers...)
+}
+
+func (s *ScopedGroup) PATCH(relativePath string, handlers ...gin.HandlerFunc) {
+	s.Handle(http.MethodPatch, relativePath, handlers...)
+}
+
+func (s *ScopedGroup) DELETE(relativePath string, handlers ...gin.HandlerFunc) {
+	s.Handle(http.MethodDelete, relativePath, handlers...)
+}
+
+// GetUser 获取发起请求的用户信息. 使用 API 令牌的请求从 gin.Context 中获取, 否则从 session 中获取.
+func GetUser(c *gin.Context) (User, bool) {
+	if user, ok := c.Get(tokenUserKey); ok {
+		return user.(User), true
+	}
+
+	user, ok := sessions.Default(c).Get(InfoKey).(User)
+	return user, ok
+}
+
+// IsTokenRequest 判断请求是否使用 API 令牌访问.
+func IsTokenRequest(c *gin.Context) bool {
+	_, ok := c.Get(tokenUserKey)
+	return ok
+}
+
+// bearerToken 获取 Authorization 请求头中的 API 令牌, 没有时返回 false.
+func bearerToken(c *gin.Context) (string, bool) {
+	header := c.GetHeader("Authorization")
+	if len(header) < len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return "", false
+	}
+
+	return strings.TrimSpace(header[len(bearerPrefix):]), true
+}
+
+// authorizeToken 校验 API 令牌及其是否拥有访问当前路由的权限. 校验通过后将用户信息和权限范围存储到 gin.Context 中.
+func authorizeToken(c *gin.Context, token string) (int, error) {
+	if tokenVerifier == nil {
+		return http.StatusUnauthorized, errors.New("api token is not supported")
+	}
+
+	user, scopes, err := tokenVerifier(token)
+	if err != nil {
+		return http.StatusUnauthorized, err
+	}
+
+	scope, ok := routeScopes.Load(c.Request.Method + " " + c.FullPath())
+	if !ok {
+		return http.StatusForbidden, errors.New("this route is not accessible with api token")
+	}
+	if !lo.Contains(scopes, scope.(Scope)) {
+		return http.StatusForbidden, errors.Errorf("api token is missing scope %s", scope)
+	}
+
+	c.Set(tokenUserKey, user)
+	c.Set(tokenScopesKey, scopes)
+
+	return http.StatusOK, nil
+}
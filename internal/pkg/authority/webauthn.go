@@ -0,0 +1,56 @@
+package authority
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/samber/lo"
+	"github.com/siaikin/home-dashboard/internal/pkg/configuration"
+	"net"
+	"net/url"
+	"strings"
+)
+
+const defaultWebAuthnRPDisplayName = "Home Dashboard"
+
+// NewWebAuthn 创建 WebAuthn relying party. host 和 origin 为请求的 Host 和 Origin 请求头,
+// 未配置 RPID 时使用 host 作为 RPID, 未配置 RPOrigins 时只允许域名与 RPID 相同或为其子域名的 origin.
+func NewWebAuthn(config configuration.ServerMonitorWebAuthnConfiguration, host string, origin string) (*webauthn.WebAuthn, error) {
+	rpId := config.RPID
+	if len(rpId) <= 0 {
+		if hostname, _, err := net.SplitHostPort(host); err == nil {
+			host = hostname
+		}
+		rpId = host
+	}
+
+	origins := config.RPOrigins
+	if len(origins) <= 0 {
+		parsed, err := url.Parse(origin)
+		if err != nil || len(parsed.Hostname()) <= 0 {
+			return nil, errors.Errorf("invalid origin %s", origin)
+		}
+		if hostname := parsed.Hostname(); hostname != rpId && !strings.HasSuffix(hostname, "."+rpId) {
+			return nil, errors.Errorf("origin %s does not match rp id %s", origin, rpId)
+		}
+		origins = []string{origin}
+	}
+
+	requireResidentKey := false
+	instance, err := webauthn.New(&webauthn.Config{
+		RPDisplayName: lo.Ternary(len(config.RPDisplayName) > 0, config.RPDisplayName, defaultWebAuthnRPDisplayName),
+		RPID:          rpId,
+		RPOrigins:     origins,
+		// 优先创建可发现凭据(通行密钥), 以便用于无密码登录
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			RequireResidentKey: &requireResidentKey,
+			ResidentKey:        protocol.ResidentKeyRequirementPreferred,
+			UserVerification:   protocol.VerificationPreferred,
+		},
+	})
+	if err != nil {
+		return nil, errors.New(err)
+	}
+
+	return instance, nil
+}
@@ -9,4 +9,6 @@ const (
 	EntityNotFoundError
 	EntityAlreadyExistsError
 	EntityValidationError
+	PermissionDeniedError
+	TooManyRequestsError
 )
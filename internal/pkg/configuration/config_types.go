@@ -16,13 +16,38 @@ type ServerMonitorConfiguration struct {
 	ThirdParty ServerMonitorThirdPartyConfiguration `json:"thirdParty" toml:"thirdParty"`
 	// 用于检查服务更新的配置
 	Update ServerMonitorUpdateConfiguration `json:"update" toml:"update"`
+	// 快捷方式链接检查的配置
+	LinkChecker ServerMonitorLinkCheckerConfiguration `json:"linkChecker" toml:"linkChecker"`
+	// 本机及局域网服务自动发现的配置
+	ServiceDiscovery ServerMonitorServiceDiscoveryConfiguration `json:"serviceDiscovery" toml:"serviceDiscovery"`
+	// 根据 Docker 容器标签自动创建快捷方式的配置
+	Docker ServerMonitorDockerConfiguration `json:"docker" toml:"docker"`
+	// 回收站的配置
+	Trash ServerMonitorTrashConfiguration `json:"trash" toml:"trash"`
+	// 登录会话的配置
+	Session ServerMonitorSessionConfiguration `json:"session" toml:"session"`
+	// OpenID Connect 单点登录的配置
+	Oidc ServerMonitorOidcConfiguration `json:"oidc" toml:"oidc"`
+	// 反向代理认证和转发认证的配置
+	ProxyAuth ServerMonitorProxyAuthConfiguration `json:"proxyAuth" toml:"proxyAuth"`
+	// WebAuthn(通行密钥)的配置
+	WebAuthn ServerMonitorWebAuthnConfiguration `json:"webAuthn" toml:"webAuthn"`
+	// 登录暴力破解防护的配置
+	LoginProtection ServerMonitorLoginProtectionConfiguration `json:"loginProtection" toml:"loginProtection"`
+	// 审计日志的配置
+	Audit ServerMonitorAuditConfiguration `json:"audit" toml:"audit"`
+	// 将用户通知投递到外部通知渠道的配置
+	NotificationDelivery ServerMonitorNotificationDeliveryConfiguration `json:"notificationDelivery" toml:"notificationDelivery"`
+	// 通知历史的配置
+	NotificationHistory ServerMonitorNotificationHistoryConfiguration `json:"notificationHistory" toml:"notificationHistory"`
 }
 
+// ServerMonitorAdministratorConfiguration 第一个管理员账号的配置, 仅在数据库中没有管理员账号时用于创建管理员账号.
 type ServerMonitorAdministratorConfiguration struct {
 	// 管理员用户名, 默认为 administrator
 	Username string `json:"username" toml:"username"`
 	// 管理员密码, 默认为 123456
-	Password string `json:"password" toml:"password"`
+	Password string `json:"password" toml:"password" secret:"true"`
 }
 
 type ServerMonitorDevelopmentConfiguration struct {
@@ -51,7 +76,7 @@ type ServerMonitorThirdPartyWakapiConfiguration struct {
 	// 默认为 false
 	Enable bool `json:"enable" toml:"enable"`
 	// Wakapi 用户的 api_key
-	ApiKey string `json:"apiKey" toml:"apiKey"`
+	ApiKey string `json:"apiKey" toml:"apiKey" secret:"true"`
 	// Wakapi 服务的地址
 	ApiUrl string `json:"apiUrl" toml:"apiUrl"`
 }
@@ -63,7 +88,7 @@ type ServerMonitorThirdPartyGitHubConfiguration struct {
 	Enable bool `json:"enable" toml:"enable"`
 	// GitHub 访问令牌, 用于访问 GitHub API
 	// See https://docs.github.com/zh/rest/overview/authenticating-to-the-rest-api?apiVersion=2022-11-28#%E4%BD%BF%E7%94%A8-personal-access-token-%E8%BF%9B%E8%A1%8C%E8%BA%AB%E4%BB%BD%E9%AA%8C%E8%AF%81
-	PersonalAccessToken string `json:"personalAccessToken" toml:"personalAccessToken"`
+	PersonalAccessToken string `json:"personalAccessToken" toml:"personalAccessToken" secret:"true"`
 }
 
 // ServerMonitorUpdateConfiguration 用于检查服务更新的配置.
@@ -94,7 +119,7 @@ type ServerMonitorUpdateFetchersConfiguration struct {
 // See [github.com/siaikin/home-dashboard/internal/pkg/overseer/fetcher.GitHubFetcher]
 type ServerMonitorUpdateFetcherGitHubConfiguration struct {
 	// GitHub 访问令牌, 用于访问 GitHub API. 未配置时, 将会使用 [ServerMonitorThirdPartyGitHubConfiguration.PersonalAccessToken] 的值.
-	PersonalAccessToken string `json:"personalAccessToken" toml:"personalAccessToken"`
+	PersonalAccessToken string `json:"personalAccessToken" toml:"personalAccessToken" secret:"true"`
 	// GitHub 仓库的拥有者
 	Owner string `json:"owner" toml:"owner"`
 	// GitHub 仓库的名称
@@ -103,6 +128,207 @@ type ServerMonitorUpdateFetcherGitHubConfiguration struct {
 	GHProxy bool `json:"ghproxy" toml:"ghproxy"`
 }
 
+// ServerMonitorLinkCheckerConfiguration 快捷方式链接检查的配置.
+// 定期检查所有快捷方式的链接, 发现失效(404/410, 域名解析失败, 证书错误等)或被永久重定向的链接.
+type ServerMonitorLinkCheckerConfiguration struct {
+	// 是否定期检查快捷方式的链接. 未启用时仍可以手动触发检查.
+	// 默认为 false
+	Enable bool `json:"enable" toml:"enable"`
+	// 检查的时间间隔, 单位为秒
+	// 默认为 1 天(86400 秒)
+	Interval time.Duration `json:"interval" toml:"interval"`
+	// 单个链接的请求超时时间, 单位为秒
+	// 默认为 10 秒
+	Timeout time.Duration `json:"timeout" toml:"timeout"`
+	// 同时检查的链接数量
+	// 默认为 4
+	Concurrency int `json:"concurrency" toml:"concurrency"`
+}
+
+// ServerMonitorServiceDiscoveryConfiguration 本机及局域网服务自动发现的配置.
+// 定期扫描本机正在监听的 TCP 端口以及配置的局域网网段, 将探测到的 HTTP(S) 服务作为快捷方式的候选.
+type ServerMonitorServiceDiscoveryConfiguration struct {
+	// 是否定期扫描. 未启用时仍可以手动触发扫描.
+	// 默认为 false
+	Enable bool `json:"enable" toml:"enable"`
+	// 扫描的时间间隔, 单位为秒
+	// 默认为 1 小时(3600 秒)
+	Interval time.Duration `json:"interval" toml:"interval"`
+	// 探测单个端口的超时时间, 单位为秒
+	// 默认为 3 秒
+	Timeout time.Duration `json:"timeout" toml:"timeout"`
+	// 同时探测的端口数量
+	// 默认为 32
+	Concurrency int `json:"concurrency" toml:"concurrency"`
+	// 本机服务的主机地址, 用于生成快捷方式的链接. 为空时使用本机第一个非回环的 IPv4 地址.
+	AdvertiseHost string `json:"advertiseHost" toml:"advertiseHost"`
+	// 需要扫描的局域网网段, 如 ["192.168.1.0/24"]. 默认不扫描局域网.
+	Cidrs []string `json:"cidrs" toml:"cidrs"`
+	// 扫描局域网网段时探测的端口
+	Ports []uint16 `json:"ports" toml:"ports"`
+}
+
+// ServerMonitorDockerConfiguration 根据 Docker 容器标签自动创建快捷方式的配置.
+// 监听容器的启动和停止事件, 读取容器的 home-dashboard.title, home-dashboard.url, home-dashboard.icon, home-dashboard.section 等标签,
+// 自动创建, 更新或归档对应的快捷方式和分组.
+type ServerMonitorDockerConfiguration struct {
+	// 是否启用 Docker 集成
+	// 默认为 false
+	Enable bool `json:"enable" toml:"enable"`
+	// Docker Engine API 的地址, 格式与 DOCKER_HOST 环境变量相同, 如 unix:///var/run/docker.sock 或 tcp://127.0.0.1:2375
+	// 默认为 unix:///var/run/docker.sock
+	Host string `json:"host" toml:"host"`
+	// 标签的前缀
+	// 默认为 home-dashboard
+	LabelPrefix string `json:"labelPrefix" toml:"labelPrefix"`
+}
+
+// ServerMonitorTrashConfiguration 回收站的配置. 被删除的快捷方式和分组会先移入回收站, 可以恢复或彻底删除.
+type ServerMonitorTrashConfiguration struct {
+	// 是否自动彻底删除在回收站中超过保留时间的快捷方式和分组. 未启用时仍可以手动清理回收站.
+	// 默认为 false
+	AutoPurge bool `json:"autoPurge" toml:"autoPurge"`
+	// 在回收站中的保留时间, 单位为秒
+	// 默认为 30 天(2592000 秒)
+	Retention time.Duration `json:"retention" toml:"retention"`
+}
+
+// ServerMonitorSessionConfiguration 登录会话的配置.
+type ServerMonitorSessionConfiguration struct {
+	// 自动轮换 session 签名密钥的时间间隔, 单位为秒. 为 0 时不自动轮换, 默认为 0
+	SecretRotationInterval time.Duration `json:"secretRotationInterval" toml:"secretRotationInterval"`
+	// 被替换的签名密钥仍可用于校验的宽限期, 单位为秒. 应大于登录会话的有效期(24 小时), 默认为 604800 (7 天)
+	SecretGracePeriod time.Duration `json:"secretGracePeriod" toml:"secretGracePeriod"`
+	// session Cookie 的 Domain, 如 example.com. 设置后 Cookie 对该域名及其子域名有效, 转发认证才能保护同一父域名下其他子域名的应用.
+	// 默认为空, 即 Cookie 只对访问仪表盘使用的域名有效, 转发认证只能保护与仪表盘域名相同的应用
+	CookieDomain string `json:"cookieDomain" toml:"cookieDomain"`
+}
+
+// ServerMonitorOidcConfiguration OpenID Connect 单点登录的配置. 使用授权码模式(PKCE)登录, 可以与用户名密码登录同时使用.
+type ServerMonitorOidcConfiguration struct {
+	// 是否启用 OpenID Connect 登录
+	// 默认为 false
+	Enable bool `json:"enable" toml:"enable"`
+	// 登录页面按钮上显示的名称
+	// 默认为 OpenID Connect
+	Name string `json:"name" toml:"name"`
+	// Issuer 地址, 将从 <issuer>/.well-known/openid-configuration 获取提供方的配置
+	Issuer       string `json:"issuer" toml:"issuer"`
+	ClientId     string `json:"clientId" toml:"clientId"`
+	ClientSecret string `json:"clientSecret" toml:"clientSecret" secret:"true"`
+	// 回调地址, 需要与在提供方中注册的地址一致, 如 https://dashboard.example.com/v1/web/auth/oidc/callback
+	RedirectUrl string `json:"redirectUrl" toml:"redirectUrl"`
+	// 请求的 scope, 默认为 ["openid", "profile", "email", "groups"]
+	Scopes []string `json:"scopes" toml:"scopes"`
+	// 作为用户名的 claim, 默认为 preferred_username
+	UsernameClaim string `json:"usernameClaim" toml:"usernameClaim"`
+	// 包含用户所属组的 claim, 默认为 groups
+	GroupsClaim string `json:"groupsClaim" toml:"groupsClaim"`
+	// 允许登录的组, 为空时允许所有用户登录
+	AllowedGroups []string `json:"allowedGroups" toml:"allowedGroups"`
+	// 属于这些组的用户将成为管理员, 其他用户为访客. 为空时不修改已存在的用户的角色, 新创建的用户为访客
+	AdministratorGroups []string `json:"administratorGroups" toml:"administratorGroups"`
+	// 用户不存在时是否自动创建. 未启用时只有已存在的同名用户可以登录
+	// 默认为 false
+	AutoCreateUser bool `json:"autoCreateUser" toml:"autoCreateUser"`
+}
+
+// ServerMonitorProxyAuthConfiguration 反向代理认证和转发认证(/v1/auth/verify)的配置.
+type ServerMonitorProxyAuthConfiguration struct {
+	// 受信任的反向代理的 IP 或 CIDR, 如 ["127.0.0.1", "172.16.0.0/12"]. 只有来自这些地址的请求才会使用 X-Forwarded-For 等请求头获取客户端 IP,
+	// 以及使用 UserHeader 登录. 默认为空, 即不信任任何代理
+	TrustedProxies []string `json:"trustedProxies" toml:"trustedProxies"`
+	// 是否信任反向代理传递的用户名请求头并自动登录. 用于在 Authelia, Authentik 等认证代理之后部署的场景
+	// 默认为 false
+	Enable bool `json:"enable" toml:"enable"`
+	// 包含用户名的请求头, 默认为 Remote-User
+	UserHeader string `json:"userHeader" toml:"userHeader"`
+	// 包含用户所属组的请求头, 多个组以逗号分隔, 默认为 Remote-Groups
+	GroupsHeader string `json:"groupsHeader" toml:"groupsHeader"`
+	// 允许登录的组, 为空时允许所有用户登录
+	AllowedGroups []string `json:"allowedGroups" toml:"allowedGroups"`
+	// 属于这些组的用户将成为管理员, 其他用户为访客. 为空时不修改已存在的用户的角色, 新创建的用户为访客
+	AdministratorGroups []string `json:"administratorGroups" toml:"administratorGroups"`
+	// 用户不存在时是否自动创建. 未启用时只有已存在的同名用户可以登录
+	// 默认为 false
+	AutoCreateUser bool `json:"autoCreateUser" toml:"autoCreateUser"`
+	// 转发认证接口在未登录且请求参数 redirect=true 时跳转的登录页面地址, 原始请求地址将作为 redirect 参数附加到该地址上.
+	// 为空时未登录的请求总是响应 401
+	LoginUrl string `json:"loginUrl" toml:"loginUrl"`
+}
+
+// ServerMonitorWebAuthnConfiguration WebAuthn(通行密钥)的配置. 通行密钥可以作为 2FA 的第二因素, 也可以用于无密码登录.
+type ServerMonitorWebAuthnConfiguration struct {
+	// 认证器中显示的名称, 默认为 Home Dashboard
+	RPDisplayName string `json:"rpDisplayName" toml:"rpDisplayName"`
+	// Relying Party ID, 通常为访问仪表盘使用的域名, 如 dashboard.example.com. 注册后修改将导致已注册的凭据失效.
+	// 为空时使用请求的 Host
+	RPID string `json:"rpId" toml:"rpId"`
+	// 允许的来源, 如 ["https://dashboard.example.com"]. 为空时允许域名与 RPID 相同或为其子域名的来源
+	RPOrigins []string `json:"rpOrigins" toml:"rpOrigins"`
+}
+
+// ServerMonitorLoginProtectionConfiguration 登录暴力破解防护的配置. 对用户名密码登录和 2FA 校验按用户名和 IP 分别记录失败次数,
+// 每次失败后需要等待的时间按指数增长, 失败次数达到上限后暂时锁定.
+type ServerMonitorLoginProtectionConfiguration struct {
+	// 是否启用登录暴力破解防护. 未启用时仍会记录登录尝试
+	// 默认为 true
+	Enable bool `json:"enable" toml:"enable"`
+	// 统计失败次数的时间窗口, 单位为秒. 用户名在登录成功后重新开始统计
+	// 默认为 900 (15 分钟)
+	Window time.Duration `json:"window" toml:"window"`
+	// 同一用户名在时间窗口内允许的最大失败次数, 达到后锁定该用户名
+	// 默认为 5
+	MaxAttemptsPerUser int `json:"maxAttemptsPerUser" toml:"maxAttemptsPerUser"`
+	// 同一 IP 在时间窗口内允许的最大失败次数, 达到后锁定该 IP
+	// 默认为 20
+	MaxAttemptsPerIp int `json:"maxAttemptsPerIp" toml:"maxAttemptsPerIp"`
+	// 第一次失败后需要等待的时间, 单位为秒. 之后每次失败翻倍
+	// 默认为 1
+	BackoffBase time.Duration `json:"backoffBase" toml:"backoffBase"`
+	// 每次失败后需要等待的最长时间, 单位为秒
+	// 默认为 60
+	BackoffMax time.Duration `json:"backoffMax" toml:"backoffMax"`
+	// 锁定时长, 单位为秒
+	// 默认为 900 (15 分钟)
+	LockoutDuration time.Duration `json:"lockoutDuration" toml:"lockoutDuration"`
+}
+
+// ServerMonitorAuditConfiguration 审计日志的配置. 登录, 2FA 变更以及所有修改数据的请求都会记录到只能追加的审计日志中.
+type ServerMonitorAuditConfiguration struct {
+	// 审计日志的保留时间, 单位为秒. 超过保留时间的记录会被定期删除
+	// 默认为 90 天(7776000 秒)
+	Retention time.Duration `json:"retention" toml:"retention"`
+}
+
+// ServerMonitorNotificationDeliveryConfiguration 将用户通知投递到外部通知渠道(Webhook, 邮件, ntfy, Gotify)的配置.
+// 渠道本身通过接口管理, 每条通知在每个渠道上独立重试.
+type ServerMonitorNotificationDeliveryConfiguration struct {
+	// 每次投递最多尝试的次数
+	// 默认为 5
+	MaxAttempts int `json:"maxAttempts" toml:"maxAttempts"`
+	// 第一次重试前等待的时间, 单位为秒. 之后每次重试的等待时间翻倍, 最多等待 1 小时
+	// 默认为 10 秒
+	Backoff time.Duration `json:"backoff" toml:"backoff"`
+	// 单次投递的超时时间, 单位为秒
+	// 默认为 10 秒
+	Timeout time.Duration `json:"timeout" toml:"timeout"`
+	// 每个渠道在 RateLimitWindow 内最多投递的通知数量, 超出的通知不会投递. 为 0 时不限制
+	RateLimit int `json:"rateLimit" toml:"rateLimit"`
+	// 频率限制的统计时间窗口, 单位为秒
+	// 默认为 1 小时(3600 秒)
+	RateLimitWindow time.Duration `json:"rateLimitWindow" toml:"rateLimitWindow"`
+	// 去重的时间窗口, 单位为秒. 来源, 标题和描述都相同的通知在时间窗口内只会投递到同一渠道一次, 用于避免反复出现的告警刷屏. 为 0 时不去重
+	DedupWindow time.Duration `json:"dedupWindow" toml:"dedupWindow"`
+}
+
+// ServerMonitorNotificationHistoryConfiguration 通知历史的配置.
+type ServerMonitorNotificationHistoryConfiguration struct {
+	// 已读通知的保留时间, 单位为秒. 超过保留时间的已读通知会被定期删除, 未读通知不会被删除
+	// 默认为 30 天(2592000 秒)
+	Retention time.Duration `json:"retention" toml:"retention"`
+}
+
 type Configuration struct {
 	ServerMonitor ServerMonitorConfiguration `json:"serverMonitor" toml:"serverMonitor"`
 	// 配置文件的修改时间, 值来自 [time.Time.UnixNano]
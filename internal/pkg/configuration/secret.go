@@ -0,0 +1,45 @@
+package configuration
+
+import "reflect"
+
+// secretTag 标记敏感信息字段的结构体标签, 如 `secret:"true"`. 这些字段不会被存储到数据库或记录到审计日志中.
+const secretTag = "secret"
+
+// Redact 清空配置中所有标记了 secret 标签的字段, 返回是否有字段被清空.
+func Redact(config *Configuration) bool {
+	return redact(reflect.ValueOf(config).Elem())
+}
+
+func redact(value reflect.Value) bool {
+	redacted := false
+
+	switch value.Kind() {
+	case reflect.Pointer:
+		if !value.IsNil() {
+			redacted = redact(value.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			redacted = redact(value.Index(i)) || redacted
+		}
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Field(i)
+			if !field.CanSet() {
+				continue
+			}
+
+			if value.Type().Field(i).Tag.Get(secretTag) == "true" {
+				if !field.IsZero() {
+					field.Set(reflect.Zero(field.Type()))
+					redacted = true
+				}
+				continue
+			}
+
+			redacted = redact(field) || redacted
+		}
+	}
+
+	return redacted
+}
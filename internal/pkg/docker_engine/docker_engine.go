@@ -0,0 +1,198 @@
+package docker_engine
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"github.com/go-errors/errors"
+	"io"
+	"net"
+	"net/http"
+	url2 "net/url"
+	"strings"
+	"time"
+)
+
+// DefaultHost Docker Engine 在 Linux 上默认监听的 unix socket.
+const DefaultHost = "unix:///var/run/docker.sock"
+
+// Container 容器的基本信息, 对应 GET /containers/json 的响应.
+type Container struct {
+	Id     string            `json:"Id"`
+	Names  []string          `json:"Names"`
+	Image  string            `json:"Image"`
+	Labels map[string]string `json:"Labels"`
+	State  string            `json:"State"`
+	Status string            `json:"Status"`
+}
+
+// Name 容器的名称, 去掉了 Docker 添加的前缀 "/".
+func (c Container) Name() string {
+	if len(c.Names) <= 0 {
+		return ""
+	}
+
+	return strings.TrimPrefix(c.Names[0], "/")
+}
+
+// Event Docker Engine 的事件, 对应 GET /events 的响应.
+type Event struct {
+	Type   string     `json:"Type"`
+	Action string     `json:"Action"`
+	Actor  EventActor `json:"Actor"`
+	// Time 事件发生的时间(秒时间戳).
+	Time int64 `json:"time"`
+}
+
+// EventActor 产生事件的对象. 容器事件的 Attributes 包含容器的名称(name), 镜像(image)以及所有标签.
+type EventActor struct {
+	ID         string            `json:"ID"`
+	Attributes map[string]string `json:"Attributes"`
+}
+
+// Client Docker Engine API 的客户端, 支持 unix socket 和 TCP 两种连接方式.
+type Client struct {
+	baseUrl string
+	client  *http.Client
+}
+
+// New 创建 Client. host 的格式与 DOCKER_HOST 环境变量相同, 如 unix:///var/run/docker.sock 或 tcp://127.0.0.1:2375.
+// 也可以直接使用 http(s):// 开头的地址.
+func New(host string) (*Client, error) {
+	if len(host) <= 0 {
+		host = DefaultHost
+	}
+
+	parsed, err := url2.Parse(host)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	client := &Client{client: &http.Client{Transport: transport}}
+
+	switch parsed.Scheme {
+	case "unix":
+		socketPath := parsed.Path
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			dialer := net.Dialer{Timeout: time.Second * 10}
+			return dialer.DialContext(ctx, "unix", socketPath)
+		}
+		// unix socket 不需要主机名, 使用任意合法的主机名即可.
+		client.baseUrl = "http://docker"
+	case "tcp":
+		client.baseUrl = "http://" + parsed.Host
+	case "http", "https":
+		client.baseUrl = strings.TrimRight(parsed.String(), "/")
+	default:
+		return nil, errors.Errorf("unsupported docker host %s", host)
+	}
+
+	return client, nil
+}
+
+// ListContainers 获取容器列表. all 为 false 时只返回正在运行的容器. labels 不为空时只返回包含这些标签的容器, 格式为 "key" 或 "key=value".
+func (c *Client) ListContainers(ctx context.Context, all bool, labels []string) ([]Container, error) {
+	query := url2.Values{}
+	if all {
+		query.Set("all", "1")
+	}
+	if len(labels) > 0 {
+		filters, err := json.Marshal(map[string][]string{"label": labels})
+		if err != nil {
+			return nil, err
+		}
+		query.Set("filters", string(filters))
+	}
+
+	res, err := c.get(ctx, "/containers/json", query)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	containers := make([]Container, 0)
+	if err := json.NewDecoder(res.Body).Decode(&containers); err != nil {
+		return nil, err
+	}
+
+	return containers, nil
+}
+
+// Events 订阅容器事件, actions 为关注的事件类型, 如 start, stop, die, destroy. 为空时订阅所有容器事件.
+// 事件依次写入返回的 channel, 连接断开或 ctx 取消时关闭 channel, 并将错误(如果有)写入 error channel.
+func (c *Client) Events(ctx context.Context, actions []string) (<-chan Event, <-chan error) {
+	events := make(chan Event)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(events)
+		defer close(errs)
+
+		filters := map[string][]string{"type": {"container"}}
+		if len(actions) > 0 {
+			filters["event"] = actions
+		}
+		filtersJson, err := json.Marshal(filters)
+		if err != nil {
+			errs <- err
+			return
+		}
+
+		res, err := c.get(ctx, "/events", url2.Values{"filters": {string(filtersJson)}})
+		if err != nil {
+			errs <- err
+			return
+		}
+		defer res.Body.Close()
+
+		decoder := json.NewDecoder(bufio.NewReader(res.Body))
+		for {
+			event := Event{}
+			if err := decoder.Decode(&event); err != nil {
+				if ctx.Err() == nil && !errors.Is(err, io.EOF) {
+					errs <- err
+				}
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case events <- event:
+			}
+		}
+	}()
+
+	return events, errs
+}
+
+func (c *Client) get(ctx context.Context, path string, query url2.Values) (*http.Response, error) {
+	url := c.baseUrl + path
+	if len(query) > 0 {
+		url += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		defer res.Body.Close()
+
+		var body struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(io.LimitReader(res.Body, 4096)).Decode(&body)
+
+		return nil, errors.Errorf("docker engine responded %d on %s: %s", res.StatusCode, path, body.Message)
+	}
+
+	return res, nil
+}
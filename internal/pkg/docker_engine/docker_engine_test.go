@@ -0,0 +1,112 @@
+package docker_engine
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newFakeEngineHandler(t *testing.T) http.Handler {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("filters"); got != `{"label":["home-dashboard.url"]}` {
+			t.Errorf("unexpected filters %s", got)
+		}
+
+		_ = json.NewEncoder(w).Encode([]Container{
+			{Id: "abc", Names: []string{"/grafana"}, Labels: map[string]string{"home-dashboard.url": "http://grafana"}, State: "running"},
+		})
+	})
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		flusher := w.(http.Flusher)
+		encoder := json.NewEncoder(w)
+
+		for _, action := range []string{"start", "stop"} {
+			_ = encoder.Encode(Event{Type: "container", Action: action, Actor: EventActor{ID: "abc", Attributes: map[string]string{"name": "grafana"}}})
+			flusher.Flush()
+		}
+	})
+	mux.HandleFunc("/error", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	return mux
+}
+
+func TestClientOverTcp(t *testing.T) {
+	server := httptest.NewServer(newFakeEngineHandler(t))
+	t.Cleanup(server.Close)
+
+	client, err := New("tcp://" + server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testClient(t, client)
+}
+
+func TestClientOverUnixSocket(t *testing.T) {
+	listener, err := net.Listen("unix", filepath.Join(t.TempDir(), "docker.sock"))
+	if err != nil {
+		t.Skipf("unix socket is not supported, %s", err)
+	}
+
+	server := httptest.NewUnstartedServer(newFakeEngineHandler(t))
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+
+	client, err := New("unix://" + listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testClient(t, client)
+}
+
+func testClient(t *testing.T, client *Client) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	containers, err := client.ListContainers(ctx, false, []string{"home-dashboard.url"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(containers) != 1 || containers[0].Name() != "grafana" {
+		t.Errorf("unexpected containers %+v", containers)
+	}
+
+	events, errs := client.Events(ctx, []string{"start", "stop"})
+	actions := make([]string, 0)
+	for event := range events {
+		actions = append(actions, event.Action)
+	}
+	if err := <-errs; err != nil {
+		t.Error(err)
+	}
+	if len(actions) != 2 || actions[0] != "start" || actions[1] != "stop" {
+		t.Errorf("unexpected events %v", actions)
+	}
+
+	if _, err := client.get(ctx, "/error", nil); err == nil {
+		t.Error("non 2xx response should be an error")
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New("ssh://example.com"); err == nil {
+		t.Error("unsupported scheme should be an error")
+	}
+	if client, err := New(""); err != nil || client.baseUrl != "http://docker" {
+		t.Errorf("empty host should fall back to the default unix socket, got %+v %v", client, err)
+	}
+}
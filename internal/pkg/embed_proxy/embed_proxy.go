@@ -0,0 +1,193 @@
+package embed_proxy
+
+import (
+	"net/http"
+	"net/http/httputil"
+	url2 "net/url"
+	"strings"
+)
+
+// 阻止页面被嵌入 iframe 的响应头.
+var framingHeaders = []string{"X-Frame-Options"}
+
+// 可能包含 frame-ancestors 指令的响应头.
+var cspHeaders = []string{"Content-Security-Policy", "Content-Security-Policy-Report-Only"}
+
+// sandboxPolicy 限制目标页面导航顶层窗口等行为. 包含 allow-same-origin, 使目标页面可以正常使用 Cookie 和 Storage,
+// dashboard 的 session Cookie 由 hiddenCookies 和 HttpOnly 保护.
+const sandboxPolicy = "sandbox allow-same-origin allow-scripts allow-forms allow-popups allow-popups-to-escape-sandbox allow-modals allow-downloads"
+
+// New 创建反向代理, 将 prefix 下的请求转发到 target, 如 prefix 为 /v1/proxy/1 时, /v1/proxy/1/a 将被转发到 target/a.
+//
+// 为了使目标页面可以在 iframe 中显示, 代理会:
+//   - 移除 X-Frame-Options 响应头和 Content-Security-Policy 中的 frame-ancestors 指令.
+//   - 将指向目标服务的 Location 响应头改写为代理地址.
+//   - 添加 Content-Security-Policy: sandbox, 禁止目标页面导航顶层窗口.
+//   - 将 Set-Cookie 的 Path 改写到 prefix 下, 并移除 Domain.
+//
+// hiddenCookies 中的 Cookie 不会转发给目标服务, 目标服务设置的同名 Cookie 也会被丢弃, 用于避免泄露或覆盖 dashboard 自身的 session. transport 为 nil 时使用 http.DefaultTransport.
+// WebSocket 等协议升级请求由 httputil.ReverseProxy 直接支持.
+func New(target *url2.URL, prefix string, hiddenCookies []string, transport http.RoundTripper) *httputil.ReverseProxy {
+	prefix = strings.TrimRight(prefix, "/")
+	targetPath := strings.TrimRight(target.Path, "/")
+
+	return &httputil.ReverseProxy{
+		Transport: transport,
+		Director: func(req *http.Request) {
+			req.URL.Scheme = target.Scheme
+			req.URL.Host = target.Host
+			req.URL.Path = targetPath + ensureLeadingSlash(strings.TrimPrefix(req.URL.Path, prefix))
+			if len(req.URL.RawPath) > 0 {
+				req.URL.RawPath = strings.TrimRight(target.EscapedPath(), "/") + ensureLeadingSlash(strings.TrimPrefix(req.URL.RawPath, prefix))
+			}
+			if len(target.RawQuery) > 0 {
+				req.URL.RawQuery = strings.Trim(target.RawQuery+"&"+req.URL.RawQuery, "&")
+			}
+
+			req.Header.Set("X-Forwarded-Host", req.Host)
+			req.Header.Set("X-Forwarded-Prefix", prefix)
+			if req.TLS != nil {
+				req.Header.Set("X-Forwarded-Proto", "https")
+			} else {
+				req.Header.Set("X-Forwarded-Proto", "http")
+			}
+			req.Host = target.Host
+
+			// 部分服务(如 WebSocket 服务)会校验 Origin, 因此将其改写为目标服务的地址.
+			if len(req.Header.Get("Origin")) > 0 {
+				req.Header.Set("Origin", target.Scheme+"://"+target.Host)
+			}
+			req.Header.Del("Referer")
+
+			removeCookies(req, hiddenCookies)
+		},
+		ModifyResponse: func(res *http.Response) error {
+			for _, header := range framingHeaders {
+				res.Header.Del(header)
+			}
+			for _, header := range cspHeaders {
+				removeFrameAncestors(res.Header, header)
+			}
+
+			if location := res.Header.Get("Location"); len(location) > 0 {
+				res.Header.Set("Location", rewriteLocation(location, target, prefix))
+			}
+
+			rewriteCookies(res, targetPath, prefix, hiddenCookies)
+			res.Header.Add("Content-Security-Policy", sandboxPolicy)
+
+			return nil
+		},
+	}
+}
+
+// removeFrameAncestors 移除 CSP 中的 frame-ancestors 指令, 移除后为空时删除该响应头.
+func removeFrameAncestors(header http.Header, key string) {
+	values := header.Values(key)
+	if len(values) <= 0 {
+		return
+	}
+
+	header.Del(key)
+	for _, value := range values {
+		directives := make([]string, 0)
+		for _, directive := range strings.Split(value, ";") {
+			directive = strings.TrimSpace(directive)
+			if len(directive) <= 0 || strings.HasPrefix(strings.ToLower(directive), "frame-ancestors") {
+				continue
+			}
+			directives = append(directives, directive)
+		}
+
+		if len(directives) > 0 {
+			header.Add(key, strings.Join(directives, "; "))
+		}
+	}
+}
+
+// rewriteLocation 将指向目标服务的跳转地址改写为代理地址, 指向其他服务的地址保持不变.
+func rewriteLocation(location string, target *url2.URL, prefix string) string {
+	parsed, err := url2.Parse(location)
+	if err != nil {
+		return location
+	}
+
+	if parsed.IsAbs() || len(parsed.Host) > 0 {
+		if !strings.EqualFold(parsed.Host, target.Host) {
+			return location
+		}
+	} else if !strings.HasPrefix(parsed.Path, "/") {
+		// 相对路径无需改写.
+		return location
+	}
+
+	parsed.Scheme = ""
+	parsed.Host = ""
+	parsed.User = nil
+	parsed.Path = rewritePath(parsed.Path, strings.TrimRight(target.Path, "/"), prefix)
+	parsed.RawPath = ""
+
+	return parsed.String()
+}
+
+// rewriteCookies 将 Set-Cookie 的 Path 改写到 prefix 下并移除 Domain, 使浏览器只在访问代理地址时携带该 Cookie.
+// 与 hiddenCookies 同名的 Cookie 会被丢弃, 避免覆盖 dashboard 自身的 Cookie.
+func rewriteCookies(res *http.Response, targetPath string, prefix string, hiddenCookies []string) {
+	cookies := res.Cookies()
+	if len(cookies) <= 0 {
+		return
+	}
+
+	res.Header.Del("Set-Cookie")
+	for _, cookie := range cookies {
+		if containsCookie(hiddenCookies, cookie.Name) {
+			continue
+		}
+
+		cookie.Path = rewritePath(cookie.Path, targetPath, prefix)
+		cookie.Domain = ""
+		res.Header.Add("Set-Cookie", cookie.String())
+	}
+}
+
+// rewritePath 将目标服务的路径改写为代理路径, 如 targetPath 为 /app, prefix 为 /v1/proxy/1 时, /app/a 将被改写为 /v1/proxy/1/a.
+func rewritePath(path string, targetPath string, prefix string) string {
+	if len(targetPath) > 0 && (path == targetPath || strings.HasPrefix(path, targetPath+"/")) {
+		path = strings.TrimPrefix(path, targetPath)
+	}
+
+	return prefix + ensureLeadingSlash(path)
+}
+
+// removeCookies 从请求中移除指定名称的 Cookie.
+func removeCookies(req *http.Request, names []string) {
+	if len(names) <= 0 {
+		return
+	}
+
+	cookies := req.Cookies()
+	req.Header.Del("Cookie")
+	for _, cookie := range cookies {
+		if !containsCookie(names, cookie.Name) {
+			req.AddCookie(cookie)
+		}
+	}
+}
+
+func containsCookie(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func ensureLeadingSlash(path string) string {
+	if strings.HasPrefix(path, "/") {
+		return path
+	}
+
+	return "/" + path
+}
@@ -0,0 +1,136 @@
+package embed_proxy
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	url2 "net/url"
+	"strings"
+	"testing"
+)
+
+func newTestServers(t *testing.T) (*httptest.Server, *httptest.Server) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/app/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Frame-Options", "DENY")
+		w.Header().Set("Content-Security-Policy", "default-src 'self'; frame-ancestors 'none'")
+		http.SetCookie(w, &http.Cookie{Name: "upstream", Value: "1", Path: "/app", Domain: "upstream.lan"})
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "shadow", Path: "/"})
+		_, _ = io.WriteString(w, r.URL.Path+"?"+r.URL.RawQuery+" cookie="+r.Header.Get("Cookie")+" host="+r.Host)
+	})
+	mux.HandleFunc("/app/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://"+r.Host+"/app/page?from=redirect", http.StatusFound)
+	})
+	mux.HandleFunc("/app/ws", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "websocket" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		_, _ = io.WriteString(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		line, _ := buf.ReadString('\n')
+		_, _ = io.WriteString(conn, "echo "+line)
+	})
+	upstream := httptest.NewServer(mux)
+	t.Cleanup(upstream.Close)
+
+	target, _ := url2.Parse(upstream.URL + "/app")
+	proxy := httptest.NewServer(New(target, "/v1/proxy/1", []string{"session"}, nil))
+	t.Cleanup(proxy.Close)
+
+	return upstream, proxy
+}
+
+func TestProxy(t *testing.T) {
+	upstream, proxy := newTestServers(t)
+
+	req, _ := http.NewRequest(http.MethodGet, proxy.URL+"/v1/proxy/1/page?a=1", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: "secret"})
+	req.AddCookie(&http.Cookie{Name: "upstream", Value: "1"})
+	res, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(res.Body)
+	if want := "/app/page?a=1 cookie=upstream=1 host=" + strings.TrimPrefix(upstream.URL, "http://"); string(body) != want {
+		t.Errorf("unexpected upstream request, got %q, want %q", body, want)
+	}
+	if len(res.Header.Get("X-Frame-Options")) > 0 {
+		t.Error("X-Frame-Options should be removed")
+	}
+	if csp := res.Header.Values("Content-Security-Policy"); len(csp) != 2 || csp[0] != "default-src 'self'" || csp[1] != sandboxPolicy {
+		t.Errorf("frame-ancestors should be removed from csp and sandbox should be added, got %q", csp)
+	}
+	if !strings.Contains(sandboxPolicy, "allow-same-origin") {
+		t.Error("sandbox should allow same origin so that the app can use cookies and storage")
+	}
+	// Cookie 的 Path 改写到代理地址下, 与 dashboard session 同名的 Cookie 被丢弃
+	if cookies := res.Header.Values("Set-Cookie"); len(cookies) != 1 || !strings.HasPrefix(cookies[0], "upstream=1") ||
+		!strings.Contains(cookies[0], "Path=/v1/proxy/1/") || strings.Contains(cookies[0], "Domain") {
+		t.Errorf("cookie path should be rewritten and session cookie should be dropped, got %q", cookies)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, proxy.URL+"/v1/proxy/1/redirect", nil)
+	res, err = http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if location := res.Header.Get("Location"); location != "/v1/proxy/1/page?from=redirect" {
+		t.Errorf("location should be rewritten, got %q", location)
+	}
+}
+
+func TestProxyWebSocket(t *testing.T) {
+	_, proxy := newTestServers(t)
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(proxy.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	_, _ = io.WriteString(conn, "GET /v1/proxy/1/ws HTTP/1.1\r\nHost: dashboard\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+	reader := bufio.NewReader(conn)
+	res, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expect 101, got %d", res.StatusCode)
+	}
+
+	_, _ = io.WriteString(conn, "hello\n")
+	if line, _ := reader.ReadString('\n'); line != "echo hello\n" {
+		t.Errorf("unexpected message %q", line)
+	}
+}
+
+func TestRewriteLocation(t *testing.T) {
+	target, _ := url2.Parse("http://upstream.lan/app")
+
+	cases := map[string]string{
+		"http://upstream.lan/app/login": "/v1/proxy/1/login",
+		"/app":                          "/v1/proxy/1/",
+		"/other":                        "/v1/proxy/1/other",
+		"relative":                      "relative",
+		"https://example.com/app":       "https://example.com/app",
+	}
+	for location, want := range cases {
+		if got := rewriteLocation(location, target, "/v1/proxy/1"); got != want {
+			t.Errorf("rewriteLocation(%q) = %q, want %q", location, got, want)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package link_checker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/go-errors/errors"
+	"io"
+	"net"
+	"net/http"
+	url2 "net/url"
+	"time"
+)
+
+// Status 链接的检查结果.
+type Status = string
+
+const (
+	// StatusOk 链接可以正常访问. 401, 403 等需要认证的响应也视为可以访问.
+	StatusOk Status = "ok"
+	// StatusRedirected 链接被永久重定向(301/308)到了新的地址, 建议更新为 Result.SuggestedUrl.
+	StatusRedirected Status = "redirected"
+	// StatusNotFound 链接返回 404 或 410.
+	StatusNotFound Status = "not_found"
+	// StatusHttpError 链接返回 5xx 或重定向次数过多.
+	StatusHttpError Status = "http_error"
+	// StatusDnsError 域名解析失败.
+	StatusDnsError Status = "dns_error"
+	// StatusTlsError TLS 握手或证书校验失败.
+	StatusTlsError Status = "tls_error"
+	// StatusConnectionError 连接被拒绝, 超时等其他网络错误.
+	StatusConnectionError Status = "connection_error"
+	// StatusInvalidUrl 链接不是合法的地址.
+	StatusInvalidUrl Status = "invalid_url"
+)
+
+// DefaultMaxRedirects 默认最多跟随的重定向次数.
+const DefaultMaxRedirects = 10
+
+// Hop 重定向链中的一次跳转.
+type Hop struct {
+	Url        string `json:"url"`
+	StatusCode int    `json:"statusCode"`
+	// Location 跳转的目标地址(已解析为绝对地址).
+	Location string `json:"location"`
+}
+
+// Result 链接的检查结果.
+type Result struct {
+	Url    string `json:"url"`
+	Status Status `json:"status"`
+	// StatusCode 最后一次请求的响应状态码, 请求失败时为 0.
+	StatusCode int    `json:"statusCode"`
+	Error      string `json:"error,omitempty"`
+	// Redirects 依次经过的重定向.
+	Redirects []Hop `json:"redirects"`
+	// FinalUrl 最后一次请求的地址.
+	FinalUrl string `json:"finalUrl"`
+	// SuggestedUrl 建议替换的新地址. 仅当链接开头的连续重定向均为永久重定向(301/308)时给出, 值为最后一个永久重定向的目标地址.
+	SuggestedUrl string `json:"suggestedUrl,omitempty"`
+}
+
+// Checker 检查链接是否失效.
+type Checker struct {
+	client       *http.Client
+	MaxRedirects int
+	// Header 每次请求都会携带的请求头.
+	Header http.Header
+}
+
+// New 创建 Checker. transport 为 nil 时使用 http.DefaultTransport.
+func New(timeout time.Duration, transport http.RoundTripper) *Checker {
+	return &Checker{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			// 手动跟随重定向, 以便记录每一次跳转.
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		MaxRedirects: DefaultMaxRedirects,
+		Header:       http.Header{},
+	}
+}
+
+// Check 检查 url 是否可以访问, 并记录重定向链.
+func (c *Checker) Check(ctx context.Context, url string) Result {
+	result := Result{Url: url, FinalUrl: url, Redirects: []Hop{}}
+
+	// 是否仍处于开头的连续永久重定向中.
+	permanent := true
+
+	current := url
+	for {
+		res, err := c.get(ctx, current)
+		if err != nil {
+			result.Status = classifyError(err)
+			result.Error = err.Error()
+			break
+		}
+		result.StatusCode = res.StatusCode
+
+		location, isRedirect := redirectLocation(res)
+		if !isRedirect {
+			result.Status = classifyStatusCode(res.StatusCode)
+			break
+		}
+
+		result.Redirects = append(result.Redirects, Hop{Url: current, StatusCode: res.StatusCode, Location: location})
+		if permanent && (res.StatusCode == http.StatusMovedPermanently || res.StatusCode == http.StatusPermanentRedirect) {
+			result.SuggestedUrl = location
+		} else {
+			permanent = false
+		}
+
+		if len(result.Redirects) > c.MaxRedirects {
+			result.Status = StatusHttpError
+			result.Error = "too many redirects"
+			break
+		}
+
+		current = location
+		result.FinalUrl = current
+	}
+
+	if result.SuggestedUrl == url {
+		result.SuggestedUrl = ""
+	}
+	if result.Status == StatusOk && len(result.SuggestedUrl) > 0 {
+		result.Status = StatusRedirected
+	}
+
+	return result
+}
+
+func (c *Checker) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range c.Header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// 只关心状态码, 丢弃少量响应体以便复用连接.
+	_, _ = io.CopyN(io.Discard, res.Body, 4096)
+	_ = res.Body.Close()
+
+	return res, nil
+}
+
+// redirectLocation 返回重定向的目标地址. 非 3xx 或没有 Location 的响应不视为重定向.
+func redirectLocation(res *http.Response) (string, bool) {
+	switch res.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return "", false
+	}
+
+	location, err := res.Location()
+	if err != nil {
+		return "", false
+	}
+
+	return location.String(), true
+}
+
+func classifyStatusCode(code int) Status {
+	switch {
+	case code == http.StatusNotFound || code == http.StatusGone:
+		return StatusNotFound
+	case code >= 500:
+		return StatusHttpError
+	default:
+		return StatusOk
+	}
+}
+
+func classifyError(err error) Status {
+	var dnsError *net.DNSError
+	var unknownAuthorityError x509.UnknownAuthorityError
+	var hostnameError x509.HostnameError
+	var certificateInvalidError x509.CertificateInvalidError
+	var certificateVerificationError *tls.CertificateVerificationError
+	var recordHeaderError tls.RecordHeaderError
+	var alertError tls.AlertError
+	var urlError *url2.Error
+
+	switch {
+	case errors.As(err, &dnsError):
+		return StatusDnsError
+	case errors.As(err, &unknownAuthorityError),
+		errors.As(err, &hostnameError),
+		errors.As(err, &certificateInvalidError),
+		errors.As(err, &certificateVerificationError),
+		errors.As(err, &recordHeaderError),
+		errors.As(err, &alertError):
+		return StatusTlsError
+	case errors.As(err, &urlError) && urlError.Op == "parse":
+		return StatusInvalidUrl
+	default:
+		return StatusConnectionError
+	}
+}
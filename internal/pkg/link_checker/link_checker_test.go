@@ -0,0 +1,119 @@
+package link_checker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/auth", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	})
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/older", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/older", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusPermanentRedirect)
+	})
+	mux.HandleFunc("/login-required", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/moved-then-login", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/moved-then-login", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok?login", http.StatusFound)
+	})
+	mux.HandleFunc("/temporary", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusFound)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestCheck(t *testing.T) {
+	server := newTestServer(t)
+	checker := New(time.Second*5, nil)
+
+	tests := []struct {
+		path          string
+		wantStatus    Status
+		wantSuggested string
+		wantRedirects int
+	}{
+		{"/ok", StatusOk, "", 0},
+		{"/auth", StatusOk, "", 0},
+		{"/gone", StatusNotFound, "", 0},
+		{"/missing", StatusNotFound, "", 0},
+		{"/old", StatusRedirected, "/ok", 2},
+		{"/login-required", StatusRedirected, "/moved-then-login", 2},
+		{"/temporary", StatusOk, "", 1},
+		{"/loop", StatusHttpError, "", DefaultMaxRedirects + 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			result := checker.Check(context.Background(), server.URL+tt.path)
+
+			if result.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s, result %+v", result.Status, tt.wantStatus, result)
+			}
+
+			wantSuggested := ""
+			if len(tt.wantSuggested) > 0 {
+				wantSuggested = server.URL + tt.wantSuggested
+			}
+			if result.SuggestedUrl != wantSuggested {
+				t.Errorf("suggested url = %q, want %q", result.SuggestedUrl, wantSuggested)
+			}
+
+			if len(result.Redirects) != tt.wantRedirects {
+				t.Errorf("redirects = %d, want %d", len(result.Redirects), tt.wantRedirects)
+			}
+		})
+	}
+}
+
+func TestCheckErrors(t *testing.T) {
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(tlsServer.Close)
+
+	// 获取一个未被监听的端口.
+	closedServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closedServer.Close()
+
+	checker := New(time.Second*5, nil)
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus Status
+	}{
+		{"self-signed certificate", tlsServer.URL, StatusTlsError},
+		{"connection refused", closedServer.URL, StatusConnectionError},
+		{"invalid url", "http://[::1", StatusInvalidUrl},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := checker.Check(context.Background(), tt.url); result.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s, result %+v", result.Status, tt.wantStatus, result)
+			}
+		})
+	}
+}
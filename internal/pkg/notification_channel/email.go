@@ -0,0 +1,159 @@
+package notification_channel
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/go-errors/errors"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EmailConfig 通过 SMTP 发送邮件的配置.
+type EmailConfig struct {
+	Host string `json:"host"`
+	// Port 默认为 587, 启用 Tls 时默认为 465.
+	Port     int      `json:"port"`
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	// Tls 为 true 时使用隐式 TLS 连接(SMTPS), 否则在服务器支持时使用 STARTTLS.
+	Tls bool `json:"tls"`
+}
+
+func (c EmailConfig) validate() error {
+	if len(c.Host) <= 0 {
+		return errors.Errorf("%w, smtp host is required", ErrorInvalidConfig)
+	}
+	if c.Port < 0 || c.Port > 65535 {
+		return errors.Errorf("%w, smtp port should be between 1 and 65535", ErrorInvalidConfig)
+	}
+	if _, err := mail.ParseAddress(c.From); err != nil {
+		return errors.Errorf("%w, invalid sender address %s", ErrorInvalidConfig, c.From)
+	}
+	if len(c.To) <= 0 {
+		return errors.Errorf("%w, at least one recipient is required", ErrorInvalidConfig)
+	}
+	for _, to := range c.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return errors.Errorf("%w, invalid recipient address %s", ErrorInvalidConfig, to)
+		}
+	}
+
+	return nil
+}
+
+func (c EmailConfig) address() string {
+	port := c.Port
+	if port <= 0 {
+		port = 587
+		if c.Tls {
+			port = 465
+		}
+	}
+
+	return net.JoinHostPort(c.Host, strconv.Itoa(port))
+}
+
+// buildEmailMessage 生成纯文本邮件, 标题和正文使用 UTF-8 编码.
+func buildEmailMessage(config EmailConfig, userNotification notification.UserNotification) ([]byte, error) {
+	subject := userNotification.Title
+	if len(userNotification.Kind) > 0 {
+		subject = fmt.Sprintf("[%s] %s", userNotification.Kind, subject)
+	}
+
+	message := bytes.Buffer{}
+	message.WriteString("From: " + config.From + "\r\n")
+	message.WriteString("To: " + strings.Join(config.To, ", ") + "\r\n")
+	message.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	message.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	message.WriteString("Content-Transfer-Encoding: quoted-printable\r\n")
+	message.WriteString("\r\n")
+
+	writer := quotedprintable.NewWriter(&message)
+	body := userNotification.Caption
+	if len(userNotification.Link) > 0 {
+		body += "\r\n\r\n" + userNotification.Link
+	}
+	if _, err := writer.Write([]byte(body)); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return message.Bytes(), nil
+}
+
+func sendEmail(ctx context.Context, config EmailConfig, userNotification notification.UserNotification) error {
+	message, err := buildEmailMessage(config, userNotification)
+	if err != nil {
+		return err
+	}
+
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", config.address())
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if config.Tls {
+		conn = tls.Client(conn, &tls.Config{ServerName: config.Host})
+	}
+
+	client, err := smtp.NewClient(conn, config.Host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && !config.Tls {
+		if err := client.StartTLS(&tls.Config{ServerName: config.Host}); err != nil {
+			return err
+		}
+	}
+	if len(config.Username) > 0 {
+		// smtp.PlainAuth 只允许在 TLS 连接或本机地址上发送密码
+		if err := client.Auth(smtp.PlainAuth("", config.Username, config.Password, config.Host)); err != nil {
+			return err
+		}
+	}
+
+	from, _ := mail.ParseAddress(config.From)
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, to := range config.To {
+		recipient, _ := mail.ParseAddress(to)
+		if err := client.Rcpt(recipient.Address); err != nil {
+			return err
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(message); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
@@ -0,0 +1,141 @@
+package notification_channel
+
+import (
+	"context"
+	"github.com/go-errors/errors"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification"
+	"net/http"
+	url2 "net/url"
+)
+
+// Type 通知渠道的类型.
+type Type = string
+
+const (
+	// TypeWebhook 通用 Webhook, 使用 JSON 模板生成请求体.
+	TypeWebhook Type = "webhook"
+	// TypeEmail 通过 SMTP 发送邮件.
+	TypeEmail Type = "email"
+	// TypeNtfy 推送到 ntfy 服务.
+	TypeNtfy Type = "ntfy"
+	// TypeGotify 推送到 Gotify 服务.
+	TypeGotify Type = "gotify"
+)
+
+// Types 所有支持的通知渠道类型.
+var Types = []Type{TypeWebhook, TypeEmail, TypeNtfy, TypeGotify}
+
+// ErrorInvalidConfig 通知渠道的配置无效.
+var ErrorInvalidConfig = errors.New("invalid notification channel config")
+
+// Config 通知渠道的配置, 只有与渠道类型对应的配置会被使用.
+type Config struct {
+	Webhook WebhookConfig `json:"webhook"`
+	Email   EmailConfig   `json:"email"`
+	Ntfy    NtfyConfig    `json:"ntfy"`
+	Gotify  GotifyConfig  `json:"gotify"`
+}
+
+// secrets 返回配置中的敏感字段. Webhook 的请求头可能包含认证信息, 其值同样视为敏感字段, 由 WithoutSecrets 和 KeepSecrets 单独处理.
+func (c *Config) secrets() []*string {
+	return []*string{&c.Email.Password, &c.Ntfy.Token, &c.Gotify.Token}
+}
+
+// WithoutSecrets 返回移除敏感字段后的配置, 用于响应和审计日志. Webhook 的请求头只保留名称.
+func (c Config) WithoutSecrets() Config {
+	for _, secret := range c.secrets() {
+		*secret = ""
+	}
+
+	// 复制请求头, 避免修改原配置
+	if c.Webhook.Headers != nil {
+		headers := make(map[string]string, len(c.Webhook.Headers))
+		for key := range c.Webhook.Headers {
+			headers[key] = ""
+		}
+		c.Webhook.Headers = headers
+	}
+
+	return c
+}
+
+// KeepSecrets 将配置中为空的敏感字段替换为 stored 中的值, 用于更新时保留原有的密码, 令牌和请求头.
+func (c *Config) KeepSecrets(stored Config) {
+	storedSecrets := stored.secrets()
+	for i, secret := range c.secrets() {
+		if len(*secret) <= 0 {
+			*secret = *storedSecrets[i]
+		}
+	}
+
+	for key, value := range c.Webhook.Headers {
+		if storedValue, ok := stored.Webhook.Headers[key]; ok && len(value) <= 0 {
+			c.Webhook.Headers[key] = storedValue
+		}
+	}
+}
+
+// Validate 校验 channelType 类型的渠道配置是否完整.
+func Validate(channelType Type, config Config) error {
+	switch channelType {
+	case TypeWebhook:
+		return config.Webhook.validate()
+	case TypeEmail:
+		return config.Email.validate()
+	case TypeNtfy:
+		return config.Ntfy.validate()
+	case TypeGotify:
+		return config.Gotify.validate()
+	default:
+		return errors.Errorf("%w, unknown channel type %s", ErrorInvalidConfig, channelType)
+	}
+}
+
+// Send 通过 channelType 类型的渠道发送一条通知. client 用于 HTTP 请求, 为 nil 时使用 http.DefaultClient.
+// SMTP 连接的超时时间由 ctx 控制.
+func Send(ctx context.Context, client *http.Client, channelType Type, config Config, userNotification notification.UserNotification) error {
+	if err := Validate(channelType, config); err != nil {
+		return err
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	switch channelType {
+	case TypeWebhook:
+		return sendWebhook(ctx, client, config.Webhook, userNotification)
+	case TypeEmail:
+		return sendEmail(ctx, config.Email, userNotification)
+	case TypeNtfy:
+		return sendNtfy(ctx, client, config.Ntfy, userNotification)
+	case TypeGotify:
+		return sendGotify(ctx, client, config.Gotify, userNotification)
+	default:
+		return errors.Errorf("%w, unknown channel type %s", ErrorInvalidConfig, channelType)
+	}
+}
+
+// validateHttpUrl 校验 rawUrl 是 http 或 https 地址.
+func validateHttpUrl(name string, rawUrl string) error {
+	parsed, err := url2.Parse(rawUrl)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || len(parsed.Host) <= 0 {
+		return errors.Errorf("%w, %s should be http or https url", ErrorInvalidConfig, name)
+	}
+
+	return nil
+}
+
+// doRequest 发送请求, 响应状态码不是 2xx 时返回错误.
+func doRequest(client *http.Client, request *http.Request) error {
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return errors.Errorf("%s %s responded %s", request.Method, request.URL.Redacted(), response.Status)
+	}
+
+	return nil
+}
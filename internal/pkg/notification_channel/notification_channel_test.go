@@ -0,0 +1,257 @@
+package notification_channel
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"github.com/go-errors/errors"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testNotification = notification.UserNotification{
+	UniqueId: "test-1",
+	Unread:   true,
+	Title:    "Backup \"nightly\" failed",
+	Caption:  "disk is full, 备份失败",
+	Link:     "https://example.com/backup",
+	Kind:     notification.UserNotificationKindError,
+	Origin:   notification.UserNotificationOriginMain,
+}
+
+type capturedRequest struct {
+	method string
+	path   string
+	header http.Header
+	body   []byte
+}
+
+// newCaptureServer 创建记录收到的请求的 HTTP 服务, 响应 status 状态码.
+func newCaptureServer(t *testing.T, status int) (*httptest.Server, chan capturedRequest) {
+	t.Helper()
+
+	requests := make(chan capturedRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- capturedRequest{method: r.Method, path: r.URL.Path, header: r.Header, body: body}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, requests
+}
+
+// smtpSession 本地 SMTP 服务收到的一封邮件.
+type smtpSession struct {
+	auth string
+	from string
+	to   []string
+	data string
+}
+
+// newSmtpServer 在本机启动一个只支持 AUTH PLAIN 的简易 SMTP 服务.
+func newSmtpServer(t *testing.T) (string, int, chan smtpSession) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	sessions := make(chan smtpSession, 1)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func(conn net.Conn) {
+				defer conn.Close()
+
+				reader := bufio.NewReader(conn)
+				reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+				session := smtpSession{}
+
+				reply("220 localhost ESMTP")
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					line = strings.TrimRight(line, "\r\n")
+					command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+					switch command {
+					case "EHLO", "HELO":
+						reply("250-localhost")
+						reply("250 AUTH PLAIN")
+					case "AUTH":
+						session.auth = line
+						reply("235 authenticated")
+					case "MAIL":
+						session.from = line
+						reply("250 ok")
+					case "RCPT":
+						session.to = append(session.to, line)
+						reply("250 ok")
+					case "DATA":
+						reply("354 go ahead")
+						data := strings.Builder{}
+						for {
+							dataLine, err := reader.ReadString('\n')
+							if err != nil {
+								return
+							}
+							if dataLine == ".\r\n" {
+								break
+							}
+							data.WriteString(dataLine)
+						}
+						session.data = data.String()
+						reply("250 queued")
+					case "QUIT":
+						reply("221 bye")
+						sessions <- session
+						return
+					default:
+						reply("250 ok")
+					}
+				}
+			}(conn)
+		}
+	}()
+
+	address := listener.Addr().(*net.TCPAddr)
+
+	return address.IP.String(), address.Port, sessions
+}
+
+func TestWebhook(t *testing.T) {
+	server, requests := newCaptureServer(t, http.StatusNoContent)
+
+	config := Config{Webhook: WebhookConfig{
+		Url:      server.URL + "/hook",
+		Headers:  map[string]string{"X-Token": "secret"},
+		Template: `{"text": {{json .Title}}, "kind": "{{.Kind}}"}`,
+	}}
+	if err := Send(context.Background(), nil, TypeWebhook, config, testNotification); err != nil {
+		t.Fatal(err)
+	}
+
+	request := <-requests
+	var body map[string]string
+	if err := json.Unmarshal(request.body, &body); err != nil {
+		t.Fatalf("webhook body should be json, got %s", request.body)
+	}
+	if request.method != http.MethodPost || request.path != "/hook" || request.header.Get("X-Token") != "secret" ||
+		body["text"] != testNotification.Title || body["kind"] != "error" {
+		t.Errorf("unexpected webhook request %s %s %v %s", request.method, request.path, request.header, request.body)
+	}
+
+	// 模板为空时发送通知本身
+	config.Webhook.Template = ""
+	if err := Send(context.Background(), nil, TypeWebhook, config, testNotification); err != nil {
+		t.Fatal(err)
+	}
+	var sent notification.UserNotification
+	if request := <-requests; json.Unmarshal(request.body, &sent) != nil || sent != testNotification {
+		t.Errorf("default webhook body should be the notification, got %s", request.body)
+	}
+
+	// 渲染结果不是合法的 JSON
+	config.Webhook.Template = `{"text": {{.Title}}}`
+	if err := Send(context.Background(), nil, TypeWebhook, config, testNotification); err == nil {
+		t.Errorf("invalid json body should be rejected")
+	}
+	config.Webhook.Template = `{{.Title`
+	if err := Send(context.Background(), nil, TypeWebhook, config, testNotification); !errors.Is(err, ErrorInvalidConfig) {
+		t.Errorf("invalid template should be rejected, got %v", err)
+	}
+}
+
+func TestWebhookErrorStatus(t *testing.T) {
+	server, _ := newCaptureServer(t, http.StatusBadGateway)
+
+	err := Send(context.Background(), nil, TypeWebhook, Config{Webhook: WebhookConfig{Url: server.URL}}, testNotification)
+	if err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("non 2xx response should be an error, got %v", err)
+	}
+}
+
+func TestEmail(t *testing.T) {
+	host, port, sessions := newSmtpServer(t)
+
+	config := Config{Email: EmailConfig{
+		Host:     host,
+		Port:     port,
+		Username: "dashboard",
+		Password: "smtp-secret",
+		From:     "Home Dashboard <dashboard@example.com>",
+		To:       []string{"alice@example.com", "Bob <bob@example.com>"},
+	}}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := Send(ctx, nil, TypeEmail, config, testNotification); err != nil {
+		t.Fatal(err)
+	}
+
+	session := <-sessions
+	if !strings.HasPrefix(session.auth, "AUTH PLAIN") || session.from != "MAIL FROM:<dashboard@example.com>" ||
+		len(session.to) != 2 || session.to[1] != "RCPT TO:<bob@example.com>" {
+		t.Errorf("unexpected smtp session %+v", session)
+	}
+	if !strings.Contains(session.data, "Subject: [error] Backup \"nightly\" failed") || !strings.Contains(session.data, "https://example.com/backup") {
+		t.Errorf("unexpected email %s", session.data)
+	}
+
+	if err := Validate(TypeEmail, Config{Email: EmailConfig{Host: host, From: "dashboard@example.com"}}); !errors.Is(err, ErrorInvalidConfig) {
+		t.Errorf("email without recipient should be rejected, got %v", err)
+	}
+}
+
+func TestNtfy(t *testing.T) {
+	server, requests := newCaptureServer(t, http.StatusOK)
+
+	config := Config{Ntfy: NtfyConfig{Server: server.URL, Topic: "alerts", Token: "tk_secret"}}
+	if err := Send(context.Background(), nil, TypeNtfy, config, testNotification); err != nil {
+		t.Fatal(err)
+	}
+
+	request := <-requests
+	var body map[string]any
+	_ = json.Unmarshal(request.body, &body)
+	if request.path != "/" || request.header.Get("Authorization") != "Bearer tk_secret" ||
+		body["topic"] != "alerts" || body["title"] != testNotification.Title || body["priority"] != float64(5) || body["click"] != testNotification.Link {
+		t.Errorf("unexpected ntfy request %s %v %s", request.path, request.header, request.body)
+	}
+}
+
+func TestGotify(t *testing.T) {
+	server, requests := newCaptureServer(t, http.StatusOK)
+
+	config := Config{Gotify: GotifyConfig{Server: server.URL + "/", Token: "app-token"}}
+	if err := Send(context.Background(), nil, TypeGotify, config, testNotification); err != nil {
+		t.Fatal(err)
+	}
+
+	request := <-requests
+	var body map[string]any
+	_ = json.Unmarshal(request.body, &body)
+	if request.path != "/message" || request.header.Get("X-Gotify-Key") != "app-token" ||
+		body["message"] != testNotification.Caption || body["priority"] != float64(8) {
+		t.Errorf("unexpected gotify request %s %v %s", request.path, request.header, request.body)
+	}
+
+	if err := Validate(TypeGotify, Config{Gotify: GotifyConfig{Server: "ftp://" + strconv.Itoa(1)}}); !errors.Is(err, ErrorInvalidConfig) {
+		t.Errorf("invalid gotify server should be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package notification_channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/go-errors/errors"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification"
+	"net/http"
+	"strings"
+)
+
+// NtfyConfig 推送到 ntfy 的配置.
+type NtfyConfig struct {
+	// Server ntfy 服务的地址, 默认为 https://ntfy.sh.
+	Server string `json:"server"`
+	Topic  string `json:"topic"`
+	// Token 访问令牌, 为空时匿名发布.
+	Token string `json:"token"`
+}
+
+func (c NtfyConfig) validate() error {
+	if len(c.Topic) <= 0 {
+		return errors.Errorf("%w, ntfy topic is required", ErrorInvalidConfig)
+	}
+	if len(c.Server) > 0 {
+		return validateHttpUrl("ntfy server", c.Server)
+	}
+
+	return nil
+}
+
+// GotifyConfig 推送到 Gotify 的配置.
+type GotifyConfig struct {
+	Server string `json:"server"`
+	// Token Gotify 应用的令牌.
+	Token string `json:"token"`
+}
+
+func (c GotifyConfig) validate() error {
+	if err := validateHttpUrl("gotify server", c.Server); err != nil {
+		return err
+	}
+	if len(c.Token) <= 0 {
+		return errors.Errorf("%w, gotify application token is required", ErrorInvalidConfig)
+	}
+
+	return nil
+}
+
+// ntfyPriorities 通知类型对应的 ntfy 优先级(1-5), 未列出的类型使用默认优先级 3.
+var ntfyPriorities = map[notification.UserNotificationKind]int{
+	notification.UserNotificationKindError:   5,
+	notification.UserNotificationKindWarning: 4,
+}
+
+// gotifyPriorities 通知类型对应的 Gotify 优先级(0-10), 未列出的类型使用 2.
+var gotifyPriorities = map[notification.UserNotificationKind]int{
+	notification.UserNotificationKindError:   8,
+	notification.UserNotificationKindWarning: 5,
+}
+
+func sendNtfy(ctx context.Context, client *http.Client, config NtfyConfig, userNotification notification.UserNotification) error {
+	server := config.Server
+	if len(server) <= 0 {
+		server = "https://ntfy.sh"
+	}
+
+	priority, ok := ntfyPriorities[userNotification.Kind]
+	if !ok {
+		priority = 3
+	}
+
+	// 使用 JSON 发布消息, 避免标题中的非 ASCII 字符在请求头中被破坏
+	body, err := json.Marshal(map[string]any{
+		"topic":    config.Topic,
+		"title":    userNotification.Title,
+		"message":  userNotification.Caption,
+		"priority": priority,
+		"tags":     []string{userNotification.Kind, userNotification.Origin},
+		"click":    userNotification.Link,
+	})
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(server, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if len(config.Token) > 0 {
+		request.Header.Set("Authorization", "Bearer "+config.Token)
+	}
+
+	return doRequest(client, request)
+}
+
+func sendGotify(ctx context.Context, client *http.Client, config GotifyConfig, userNotification notification.UserNotification) error {
+	priority, ok := gotifyPriorities[userNotification.Kind]
+	if !ok {
+		priority = 2
+	}
+
+	message := map[string]any{
+		"title":    userNotification.Title,
+		"message":  userNotification.Caption,
+		"priority": priority,
+	}
+	if len(userNotification.Link) > 0 {
+		message["extras"] = map[string]any{
+			"client::notification": map[string]any{"click": map[string]any{"url": userNotification.Link}},
+		}
+	}
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(config.Server, "/")+"/message", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Gotify-Key", config.Token)
+
+	return doRequest(client, request)
+}
@@ -0,0 +1,88 @@
+package notification_channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/go-errors/errors"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification"
+	"net/http"
+	"strings"
+	"text/template"
+)
+
+// WebhookConfig 通用 Webhook 的配置.
+type WebhookConfig struct {
+	Url string `json:"url"`
+	// Method 请求方法, 默认为 POST.
+	Method  string            `json:"method"`
+	Headers map[string]string `json:"headers"`
+	// Template 请求体的 text/template 模板, 渲染结果必须是合法的 JSON. 模板的数据为 notification.UserNotification,
+	// 可以使用 json 函数将值转义为 JSON, 如 {"text": {{json .Title}}}. 为空时请求体为通知本身的 JSON.
+	Template string `json:"template"`
+}
+
+func (c WebhookConfig) validate() error {
+	if err := validateHttpUrl("webhook url", c.Url); err != nil {
+		return err
+	}
+	if _, err := c.parseTemplate(); err != nil {
+		return errors.Errorf("%w, parse webhook template failed. %w", ErrorInvalidConfig, err)
+	}
+
+	return nil
+}
+
+func (c WebhookConfig) parseTemplate() (*template.Template, error) {
+	return template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			marshaled, err := json.Marshal(v)
+			return string(marshaled), err
+		},
+	}).Parse(c.Template)
+}
+
+// renderWebhookBody 使用模板渲染请求体.
+func renderWebhookBody(config WebhookConfig, userNotification notification.UserNotification) ([]byte, error) {
+	if len(strings.TrimSpace(config.Template)) <= 0 {
+		return json.Marshal(userNotification)
+	}
+
+	tmpl, err := config.parseTemplate()
+	if err != nil {
+		return nil, err
+	}
+
+	body := bytes.Buffer{}
+	if err := tmpl.Execute(&body, userNotification); err != nil {
+		return nil, errors.Errorf("render webhook template failed. %w", err)
+	}
+	if !json.Valid(body.Bytes()) {
+		return nil, errors.Errorf("rendered webhook body is not valid json")
+	}
+
+	return body.Bytes(), nil
+}
+
+func sendWebhook(ctx context.Context, client *http.Client, config WebhookConfig, userNotification notification.UserNotification) error {
+	body, err := renderWebhookBody(config, userNotification)
+	if err != nil {
+		return err
+	}
+
+	method := strings.ToUpper(config.Method)
+	if len(method) <= 0 {
+		method = http.MethodPost
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, config.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for key, value := range config.Headers {
+		request.Header.Set(key, value)
+	}
+
+	return doRequest(client, request)
+}
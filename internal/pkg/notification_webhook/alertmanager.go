@@ -0,0 +1,97 @@
+package notification_webhook
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/go-errors/errors"
+	"github.com/samber/lo"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AlertmanagerPayload Alertmanager Webhook 的请求体, 见 https://prometheus.io/docs/alerting/latest/configuration/#webhook_config.
+// Grafana 告警的请求体在此基础上增加了一些字段.
+type AlertmanagerPayload struct {
+	Status      string              `json:"status"`
+	ExternalURL string              `json:"externalURL"`
+	Alerts      []AlertmanagerAlert `json:"alerts"`
+}
+
+// AlertmanagerAlert Alertmanager Webhook 请求体中的一条告警.
+type AlertmanagerAlert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+
+	// 以下字段只存在于 Grafana 告警的请求体中.
+	DashboardURL string `json:"dashboardURL"`
+	PanelURL     string `json:"panelURL"`
+	ValueString  string `json:"valueString"`
+}
+
+func parseAlertmanager(body []byte) ([]notification.UserNotification, error) {
+	payload := AlertmanagerPayload{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, errors.Errorf("%w, %w", ErrorInvalidPayload, err)
+	}
+	if len(payload.Alerts) <= 0 {
+		return nil, errors.Errorf("%w, alerts is required", ErrorInvalidPayload)
+	}
+
+	return lo.Map(payload.Alerts, func(alert AlertmanagerAlert, _ int) notification.UserNotification {
+		return alertNotification(payload, alert)
+	}), nil
+}
+
+// alertNotification 将一条告警转换为通知. 同一条告警(相同的 fingerprint 和开始时间)触发和恢复时的 UniqueId 相同,
+// 因此恢复时会更新触发时创建的通知. 告警再次触发时开始时间不同, 会创建新的通知.
+func alertNotification(payload AlertmanagerPayload, alert AlertmanagerAlert) notification.UserNotification {
+	fingerprint := alert.Fingerprint
+	if len(fingerprint) <= 0 {
+		fingerprint = labelsFingerprint(alert.Labels)
+	}
+
+	title := firstNonEmpty(alert.Labels["alertname"], "Alert")
+	if instance := alert.Labels["instance"]; len(instance) > 0 {
+		title = fmt.Sprintf("%s on %s", title, instance)
+	}
+
+	userNotification := notification.UserNotification{
+		UniqueId:       fmt.Sprintf("%s-%d", fingerprint, alert.StartsAt.UnixMilli()),
+		Title:          title,
+		Caption:        firstNonEmpty(alert.Annotations["summary"], alert.Annotations["description"], alert.Annotations["message"], alert.ValueString),
+		Link:           firstNonEmpty(alert.PanelURL, alert.DashboardURL, alert.GeneratorURL, payload.ExternalURL),
+		OriginCreateAt: lo.Ternary(alert.StartsAt.IsZero(), time.Now().UnixMilli(), alert.StartsAt.UnixMilli()),
+	}
+
+	return stateNotification(userNotification, firstNonEmpty(alert.Status, payload.Status), severityKind(alert.Labels["severity"]))
+}
+
+// labelsFingerprint 根据告警的标签生成摘要, 用于缺少 fingerprint 字段的旧版本 Alertmanager.
+func labelsFingerprint(labels map[string]string) string {
+	keys := lo.Keys(labels)
+	sort.Strings(keys)
+
+	pairs := lo.Map(keys, func(key string, _ int) string {
+		return key + "=" + labels[key]
+	})
+	sum := sha256.Sum256([]byte(strings.Join(pairs, "\x00")))
+
+	return hex.EncodeToString(sum[:8])
+}
+
+// firstNonEmpty 返回第一个不为空的字符串.
+func firstNonEmpty(values ...string) string {
+	value, _ := lo.Find(values, func(value string) bool {
+		return len(value) > 0
+	})
+
+	return value
+}
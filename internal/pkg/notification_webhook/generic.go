@@ -0,0 +1,93 @@
+package notification_webhook
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/go-errors/errors"
+	"github.com/samber/lo"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification"
+	"strings"
+	"time"
+)
+
+// GenericNotification 通用格式的通知. 请求体可以是单个通知或通知数组.
+type GenericNotification struct {
+	// UniqueId 通知的唯一标识, 相同 UniqueId 的通知会更新已有的通知. 为空时每次请求都会创建新的通知.
+	UniqueId string `json:"uniqueId"`
+	Title    string `json:"title"`
+	Caption  string `json:"caption"`
+	Link     string `json:"link"`
+	// Kind 通知的类型. 为空时根据 Status 决定: firing 为 error, resolved 为 success, 未设置 Status 时为 info.
+	Kind notification.UserNotificationKind `json:"kind"`
+	// Status 告警的状态, firing 或 resolved. resolved 的通知标记为已读.
+	Status string `json:"status"`
+	// CreatedAt 通知的创建时间(毫秒时间戳), 为 0 时使用接收的时间.
+	CreatedAt int64 `json:"createdAt"`
+}
+
+var genericKinds = []notification.UserNotificationKind{
+	notification.UserNotificationKindError,
+	notification.UserNotificationKindWarning,
+	notification.UserNotificationKindInfo,
+	notification.UserNotificationKindSuccess,
+}
+
+func parseGeneric(body []byte) ([]notification.UserNotification, error) {
+	payload := make([]GenericNotification, 0)
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &payload); err != nil {
+			return nil, errors.Errorf("%w, %w", ErrorInvalidPayload, err)
+		}
+	} else {
+		single := GenericNotification{}
+		if err := json.Unmarshal(trimmed, &single); err != nil {
+			return nil, errors.Errorf("%w, %w", ErrorInvalidPayload, err)
+		}
+		payload = append(payload, single)
+	}
+
+	now := time.Now().UnixMilli()
+	userNotifications := make([]notification.UserNotification, 0, len(payload))
+	for _, item := range payload {
+		if len(strings.TrimSpace(item.Title)) <= 0 {
+			return nil, errors.Errorf("%w, title is required", ErrorInvalidPayload)
+		}
+		if len(item.Kind) > 0 && !lo.Contains(genericKinds, item.Kind) {
+			return nil, errors.Errorf("%w, unknown kind %s", ErrorInvalidPayload, item.Kind)
+		}
+
+		userNotification := notification.UserNotification{
+			UniqueId:       item.UniqueId,
+			Title:          item.Title,
+			Caption:        item.Caption,
+			Link:           item.Link,
+			OriginCreateAt: lo.Ternary(item.CreatedAt > 0, item.CreatedAt, now),
+		}
+		switch strings.ToLower(item.Status) {
+		case "":
+			userNotification.Kind = notification.UserNotificationKindInfo
+			userNotification.Unread = true
+		case statusFiring, statusResolved:
+			userNotification = stateNotification(userNotification, item.Status, notification.UserNotificationKindError)
+		default:
+			return nil, errors.Errorf("%w, status should be %s or %s", ErrorInvalidPayload, statusFiring, statusResolved)
+		}
+		if len(item.Kind) > 0 {
+			userNotification.Kind = item.Kind
+		}
+
+		if len(userNotification.UniqueId) <= 0 {
+			buf := make([]byte, 16)
+			if _, err := rand.Read(buf); err != nil {
+				return nil, errors.New(err)
+			}
+			userNotification.UniqueId = hex.EncodeToString(buf)
+		}
+
+		userNotifications = append(userNotifications, userNotification)
+	}
+
+	return userNotifications, nil
+}
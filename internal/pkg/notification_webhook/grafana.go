@@ -0,0 +1,62 @@
+package notification_webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-errors/errors"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification"
+	"time"
+)
+
+// GrafanaLegacyPayload 旧版 Grafana 告警(Grafana 8 之前的面板告警)的 Webhook 请求体.
+type GrafanaLegacyPayload struct {
+	Title    string `json:"title"`
+	RuleId   int64  `json:"ruleId"`
+	RuleName string `json:"ruleName"`
+	RuleUrl  string `json:"ruleUrl"`
+	// State alerting, ok, no_data, paused 或 pending.
+	State   string `json:"state"`
+	Message string `json:"message"`
+}
+
+// parseGrafana 解析 Grafana 告警的请求体. 包含 alerts 字段时按 Alertmanager 格式解析, 否则按旧版告警格式解析.
+func parseGrafana(body []byte) ([]notification.UserNotification, error) {
+	var probe struct {
+		Alerts json.RawMessage `json:"alerts"`
+	}
+	if err := json.Unmarshal(body, &probe); err != nil {
+		return nil, errors.Errorf("%w, %w", ErrorInvalidPayload, err)
+	}
+	if len(probe.Alerts) > 0 {
+		return parseAlertmanager(body)
+	}
+
+	payload := GrafanaLegacyPayload{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, errors.Errorf("%w, %w", ErrorInvalidPayload, err)
+	}
+	if len(payload.State) <= 0 {
+		return nil, errors.Errorf("%w, alerts or state is required", ErrorInvalidPayload)
+	}
+
+	// 旧版告警没有开始时间, 同一条规则的通知使用相同的 UniqueId, 再次触发时更新为未读
+	userNotification := notification.UserNotification{
+		UniqueId:       fmt.Sprintf("rule-%d", payload.RuleId),
+		Title:          firstNonEmpty(payload.RuleName, payload.Title, "Grafana alert"),
+		Caption:        payload.Message,
+		Link:           payload.RuleUrl,
+		OriginCreateAt: time.Now().UnixMilli(),
+	}
+
+	status, firingKind := statusFiring, notification.UserNotificationKindError
+	switch payload.State {
+	case "ok":
+		status = statusResolved
+	case "no_data", "pending":
+		firingKind = notification.UserNotificationKindWarning
+	case "paused":
+		firingKind = notification.UserNotificationKindInfo
+	}
+
+	return []notification.UserNotification{stateNotification(userNotification, status, firingKind)}, nil
+}
@@ -0,0 +1,97 @@
+package notification_webhook
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/go-errors/errors"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification"
+	"strings"
+)
+
+// Format 入站 Webhook 请求体的格式.
+type Format = string
+
+const (
+	// FormatGeneric 通用格式, 字段与 notification.UserNotification 对应, 见 GenericNotification.
+	FormatGeneric Format = "generic"
+	// FormatAlertmanager Prometheus Alertmanager 的 webhook_configs 格式.
+	FormatAlertmanager Format = "alertmanager"
+	// FormatGrafana Grafana 告警的 Webhook 联络点格式, 同时支持旧版告警的格式.
+	FormatGrafana Format = "grafana"
+)
+
+// Formats 所有支持的请求体格式.
+var Formats = []Format{FormatGeneric, FormatAlertmanager, FormatGrafana}
+
+// 告警的状态.
+const (
+	statusFiring   = "firing"
+	statusResolved = "resolved"
+)
+
+// TokenPrefix 入站 Webhook 令牌的前缀, 便于识别和扫描泄露的令牌.
+const TokenPrefix = "hdw_"
+
+// tokenLength 令牌随机部分的字节数.
+const tokenLength = 32
+
+// ErrorInvalidPayload 请求体无法解析或缺少必需的字段.
+var ErrorInvalidPayload = errors.New("invalid webhook payload")
+
+// Parse 将 format 格式的请求体转换为用户通知. 返回的通知未设置来源, UniqueId 只在同一个 Webhook 内唯一.
+func Parse(format Format, body []byte) ([]notification.UserNotification, error) {
+	switch format {
+	case FormatGeneric:
+		return parseGeneric(body)
+	case FormatAlertmanager:
+		return parseAlertmanager(body)
+	case FormatGrafana:
+		return parseGrafana(body)
+	default:
+		return nil, errors.Errorf("%w, unknown format %s", ErrorInvalidPayload, format)
+	}
+}
+
+// GenerateToken 生成新的入站 Webhook 令牌. 令牌明文只应返回给用户一次, 数据库中只存储 HashToken 计算的哈希值.
+func GenerateToken() (string, error) {
+	buf := make([]byte, tokenLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.New(err)
+	}
+
+	return TokenPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashToken 计算令牌的哈希值.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// stateNotification 根据告警状态设置通知的类型和已读状态. 恢复的告警为已读的 success 通知, 触发中的告警为未读通知, 类型为 firingKind.
+func stateNotification(userNotification notification.UserNotification, status string, firingKind notification.UserNotificationKind) notification.UserNotification {
+	if strings.EqualFold(status, statusResolved) {
+		userNotification.Kind = notification.UserNotificationKindSuccess
+		userNotification.Unread = false
+	} else {
+		userNotification.Kind = firingKind
+		userNotification.Unread = true
+	}
+
+	return userNotification
+}
+
+// severityKind 将告警标签中的 severity 转换为通知类型, 无法识别时返回 notification.UserNotificationKindError.
+func severityKind(severity string) notification.UserNotificationKind {
+	switch strings.ToLower(severity) {
+	case "warning", "warn", "minor":
+		return notification.UserNotificationKindWarning
+	case "info", "informational", "none", "low":
+		return notification.UserNotificationKindInfo
+	default:
+		return notification.UserNotificationKindError
+	}
+}
@@ -0,0 +1,164 @@
+package notification_webhook
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/siaikin/home-dashboard/internal/pkg/notification"
+	"strings"
+	"testing"
+)
+
+func TestParseGeneric(t *testing.T) {
+	notifications, err := Parse(FormatGeneric, []byte(`{"uniqueId":"backup-1","title":"Backup started","caption":"nightly","createdAt":1700000000000}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := notifications[0]; n.UniqueId != "backup-1" || !n.Unread || n.Kind != notification.UserNotificationKindInfo || n.OriginCreateAt != 1700000000000 {
+		t.Errorf("unexpected notification %+v", n)
+	}
+
+	notifications, err = Parse(FormatGeneric, []byte(`[
+		{"uniqueId":"ci-1","title":"Build failed","status":"firing"},
+		{"uniqueId":"ci-1","title":"Build fixed","status":"resolved"},
+		{"title":"Disk almost full","kind":"warning","status":"firing"},
+		{"title":"Cron done"},
+		{"title":"Cron done"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := notifications[0]; !n.Unread || n.Kind != notification.UserNotificationKindError {
+		t.Errorf("firing notification should be unread error, got %+v", n)
+	}
+	if n := notifications[1]; n.Unread || n.Kind != notification.UserNotificationKindSuccess {
+		t.Errorf("resolved notification should be read success, got %+v", n)
+	}
+	if n := notifications[2]; n.Kind != notification.UserNotificationKindWarning {
+		t.Errorf("explicit kind should be kept, got %+v", n)
+	}
+	if notifications[3].UniqueId == "" || notifications[3].UniqueId == notifications[4].UniqueId {
+		t.Errorf("notifications without uniqueId should get distinct ids, got %s and %s", notifications[3].UniqueId, notifications[4].UniqueId)
+	}
+	if notifications[3].OriginCreateAt <= 0 {
+		t.Errorf("createdAt should default to now")
+	}
+
+	for _, body := range []string{
+		`not json`,
+		`{"caption":"missing title"}`,
+		`{"title":"bad kind","kind":"fatal"}`,
+		`{"title":"bad status","status":"exploded"}`,
+	} {
+		if _, err := Parse(FormatGeneric, []byte(body)); !errors.Is(err, ErrorInvalidPayload) {
+			t.Errorf("payload %s should be rejected, got %v", body, err)
+		}
+	}
+}
+
+func TestParseAlertmanager(t *testing.T) {
+	body := `{
+		"version": "4",
+		"status": "firing",
+		"externalURL": "http://alertmanager:9093",
+		"alerts": [
+			{
+				"status": "firing",
+				"labels": {"alertname": "HighLoad", "instance": "node-1", "severity": "warning"},
+				"annotations": {"summary": "load is above 10"},
+				"startsAt": "2024-01-02T03:04:05Z",
+				"generatorURL": "http://prometheus/graph",
+				"fingerprint": "abc123"
+			},
+			{
+				"status": "resolved",
+				"labels": {"alertname": "NodeDown", "severity": "critical"},
+				"annotations": {"description": "node-2 is back"},
+				"startsAt": "2024-01-02T03:00:00Z"
+			}
+		]
+	}`
+
+	notifications, err := Parse(FormatAlertmanager, []byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notifications) != 2 {
+		t.Fatalf("expected 2 notifications, got %d", len(notifications))
+	}
+
+	firing := notifications[0]
+	if firing.UniqueId != "abc123-1704164645000" || firing.Title != "HighLoad on node-1" || firing.Caption != "load is above 10" ||
+		firing.Link != "http://prometheus/graph" || !firing.Unread || firing.Kind != notification.UserNotificationKindWarning || firing.OriginCreateAt != 1704164645000 {
+		t.Errorf("unexpected firing notification %+v", firing)
+	}
+
+	resolved := notifications[1]
+	if resolved.Unread || resolved.Kind != notification.UserNotificationKindSuccess || resolved.Caption != "node-2 is back" || resolved.Link != "http://alertmanager:9093" {
+		t.Errorf("unexpected resolved notification %+v", resolved)
+	}
+	// 缺少 fingerprint 时根据标签生成, 触发和恢复时保持一致
+	again, _ := Parse(FormatAlertmanager, []byte(strings.Replace(body, `"status": "resolved"`, `"status": "firing"`, 1)))
+	if again[1].UniqueId != resolved.UniqueId || !again[1].Unread || again[1].Kind != notification.UserNotificationKindError {
+		t.Errorf("unexpected firing notification %+v for resolved %+v", again[1], resolved)
+	}
+
+	if _, err := Parse(FormatAlertmanager, []byte(`{"status":"firing","alerts":[]}`)); !errors.Is(err, ErrorInvalidPayload) {
+		t.Errorf("payload without alerts should be rejected, got %v", err)
+	}
+}
+
+func TestParseGrafana(t *testing.T) {
+	notifications, err := Parse(FormatGrafana, []byte(`{
+		"receiver": "home-dashboard",
+		"status": "firing",
+		"alerts": [{
+			"status": "firing",
+			"labels": {"alertname": "Disk usage", "grafana_folder": "Servers"},
+			"annotations": {},
+			"startsAt": "2024-01-02T03:04:05Z",
+			"fingerprint": "def456",
+			"dashboardURL": "http://grafana/d/abc",
+			"panelURL": "http://grafana/d/abc?viewPanel=2",
+			"valueString": "[ var='A' value=97 ]"
+		}],
+		"title": "[FIRING:1] Disk usage",
+		"state": "alerting"
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := notifications[0]; n.UniqueId != "def456-1704164645000" || n.Caption != "[ var='A' value=97 ]" || n.Link != "http://grafana/d/abc?viewPanel=2" || n.Kind != notification.UserNotificationKindError {
+		t.Errorf("unexpected grafana notification %+v", n)
+	}
+
+	for state, expected := range map[string]notification.UserNotification{
+		"alerting": {Unread: true, Kind: notification.UserNotificationKindError},
+		"no_data":  {Unread: true, Kind: notification.UserNotificationKindWarning},
+		"ok":       {Unread: false, Kind: notification.UserNotificationKindSuccess},
+	} {
+		notifications, err := Parse(FormatGrafana, []byte(`{"title":"[Alerting] CPU","ruleId":7,"ruleName":"CPU","ruleUrl":"http://grafana/d/cpu","state":"`+state+`","message":"cpu is high"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := notifications[0]; n.UniqueId != "rule-7" || n.Title != "CPU" || n.Unread != expected.Unread || n.Kind != expected.Kind {
+			t.Errorf("unexpected legacy grafana notification for state %s: %+v", state, n)
+		}
+	}
+
+	if _, err := Parse(FormatGrafana, []byte(`{"title":"nothing"}`)); !errors.Is(err, ErrorInvalidPayload) {
+		t.Errorf("payload without alerts or state should be rejected, got %v", err)
+	}
+}
+
+func TestToken(t *testing.T) {
+	token, err := GenerateToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := GenerateToken()
+	if !strings.HasPrefix(token, TokenPrefix) || token == other {
+		t.Errorf("unexpected tokens %s and %s", token, other)
+	}
+	if HashToken(token) == token || HashToken(token) != HashToken(token) || HashToken(token) == HashToken(other) {
+		t.Errorf("unexpected token hash")
+	}
+}
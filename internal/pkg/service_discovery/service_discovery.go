@@ -0,0 +1,244 @@
+package service_discovery
+
+import (
+	"context"
+	"crypto/tls"
+	"github.com/go-errors/errors"
+	psuNet "github.com/shirou/gopsutil/v3/net"
+	"io"
+	"net"
+	"net/http"
+	url2 "net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Source 表示服务是如何被发现的.
+type Source = string
+
+const (
+	// SourceLocal 本机正在监听的 TCP 端口.
+	SourceLocal Source = "local"
+	// SourceLan 配置的局域网网段.
+	SourceLan Source = "lan"
+)
+
+// Target 待探测的地址.
+type Target struct {
+	Host   string `json:"host"`
+	Port   uint16 `json:"port"`
+	Source Source `json:"source"`
+}
+
+func (t Target) address() string {
+	return net.JoinHostPort(t.Host, strconv.Itoa(int(t.Port)))
+}
+
+// Endpoint 探测到的 HTTP(S) 服务.
+type Endpoint struct {
+	Target
+	// Url 服务的地址, 如 https://192.168.1.2:8443/
+	Url        string `json:"url"`
+	StatusCode int    `json:"statusCode"`
+	// Server 响应头中的 Server 字段.
+	Server string `json:"server"`
+}
+
+// LocalTargets 获取本机正在监听的 TCP 端口. 监听所有地址(0.0.0.0 或 ::)的端口使用 advertiseHost 作为主机地址,
+// advertiseHost 为空时使用 127.0.0.1.
+func LocalTargets(ctx context.Context, advertiseHost string) ([]Target, error) {
+	if len(advertiseHost) <= 0 {
+		advertiseHost = "127.0.0.1"
+	}
+
+	connections, err := psuNet.ConnectionsWithContext(ctx, "tcp")
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]Target, 0)
+	for _, connection := range connections {
+		if connection.Status != "LISTEN" {
+			continue
+		}
+
+		host := connection.Laddr.IP
+		if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+			host = advertiseHost
+		}
+
+		targets = append(targets, Target{Host: host, Port: uint16(connection.Laddr.Port), Source: SourceLocal})
+	}
+
+	return uniqTargets(targets), nil
+}
+
+// CidrTargets 将 cidr 网段内的每个主机与 ports 组合为待探测的地址. IPv4 网段将排除网络地址和广播地址.
+// 网段内的主机数量超过 maxHosts 时返回错误, 以免扫描过大的网段.
+func CidrTargets(cidr string, ports []uint16, maxHosts int) ([]Target, error) {
+	ip, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+
+	ones, bits := network.Mask.Size()
+	if bits-ones >= 31 || 1<<(bits-ones) > maxHosts+2 {
+		return nil, errors.Errorf("cidr %s contains more than %d hosts", cidr, maxHosts)
+	}
+
+	hosts := make([]net.IP, 0)
+	for current := ip.Mask(network.Mask); network.Contains(current); current = nextIP(current) {
+		hosts = append(hosts, current)
+	}
+	// 排除 IPv4 的网络地址和广播地址. /31 和 /32 没有这两个地址.
+	if ip.To4() != nil && len(hosts) > 2 {
+		hosts = hosts[1 : len(hosts)-1]
+	}
+
+	targets := make([]Target, 0, len(hosts)*len(ports))
+	for _, host := range hosts {
+		for _, port := range ports {
+			targets = append(targets, Target{Host: host.String(), Port: port, Source: SourceLan})
+		}
+	}
+
+	return targets, nil
+}
+
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+
+	return next
+}
+
+func uniqTargets(targets []Target) []Target {
+	seen := map[string]bool{}
+	uniq := make([]Target, 0, len(targets))
+	for _, target := range targets {
+		if seen[target.address()] {
+			continue
+		}
+		seen[target.address()] = true
+		uniq = append(uniq, target)
+	}
+
+	sort.SliceStable(uniq, func(i, j int) bool {
+		if uniq[i].Host == uniq[j].Host {
+			return uniq[i].Port < uniq[j].Port
+		}
+		return uniq[i].Host < uniq[j].Host
+	})
+
+	return uniq
+}
+
+// Prober 探测地址上是否运行着 HTTP(S) 服务.
+type Prober struct {
+	client  *http.Client
+	timeout time.Duration
+}
+
+// NewProber 创建 Prober. 局域网服务通常使用自签名证书, 因此不校验证书.
+func NewProber(timeout time.Duration) *Prober {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	transport.DisableKeepAlives = true
+
+	return &Prober{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			// 只关心端口上是否有 HTTP 服务, 不跟随重定向.
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		timeout: timeout,
+	}
+}
+
+// Probe 依次使用 HTTPS 和 HTTP 探测 target, 只要收到任意 HTTP 响应就视为存在服务.
+// 先尝试 HTTPS 是因为 HTTPS 服务通常也会对 HTTP 请求返回 400 响应.
+func (p *Prober) Probe(ctx context.Context, target Target) (Endpoint, bool) {
+	// 先建立 TCP 连接, 快速排除未开放的端口.
+	dialer := net.Dialer{Timeout: p.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", target.address())
+	if err != nil {
+		return Endpoint{}, false
+	}
+	_ = conn.Close()
+
+	for _, scheme := range []string{"https", "http"} {
+		url := (&url2.URL{Scheme: scheme, Host: target.address(), Path: "/"}).String()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return Endpoint{}, false
+		}
+
+		res, err := p.client.Do(req)
+		if err != nil {
+			continue
+		}
+		_, _ = io.CopyN(io.Discard, res.Body, 4096)
+		_ = res.Body.Close()
+
+		return Endpoint{Target: target, Url: url, StatusCode: res.StatusCode, Server: res.Header.Get("Server")}, true
+	}
+
+	return Endpoint{}, false
+}
+
+// Scan 使用 concurrency 个 goroutine 并发探测 targets, 返回探测到的服务.
+func (p *Prober) Scan(ctx context.Context, targets []Target, concurrency int) []Endpoint {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	queue := make(chan Target)
+	endpoints := make([]Endpoint, 0)
+	mutex := sync.Mutex{}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for target := range queue {
+				if endpoint, ok := p.Probe(ctx, target); ok {
+					mutex.Lock()
+					endpoints = append(endpoints, endpoint)
+					mutex.Unlock()
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, target := range uniqTargets(targets) {
+		select {
+		case <-ctx.Done():
+			break feed
+		case queue <- target:
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		return endpoints[i].Url < endpoints[j].Url
+	})
+
+	return endpoints
+}
@@ -0,0 +1,93 @@
+package service_discovery
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	url2 "net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func targetOf(t *testing.T, serverUrl string) Target {
+	t.Helper()
+
+	parsed, err := url2.Parse(serverUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(parsed.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return Target{Host: parsed.Hostname(), Port: uint16(port), Source: SourceLocal}
+}
+
+func TestScan(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "test")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	httpServer := httptest.NewServer(handler)
+	t.Cleanup(httpServer.Close)
+	tlsServer := httptest.NewTLSServer(handler)
+	t.Cleanup(tlsServer.Close)
+
+	// 获取一个未被监听的端口.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedTarget := Target{Host: "127.0.0.1", Port: uint16(listener.Addr().(*net.TCPAddr).Port), Source: SourceLocal}
+	_ = listener.Close()
+
+	httpTarget := targetOf(t, httpServer.URL)
+	tlsTarget := targetOf(t, tlsServer.URL)
+
+	endpoints := NewProber(time.Second*2).Scan(context.Background(), []Target{httpTarget, tlsTarget, closedTarget, httpTarget}, 2)
+
+	urls := map[string]Endpoint{}
+	for _, endpoint := range endpoints {
+		urls[endpoint.Url] = endpoint
+	}
+
+	if len(endpoints) != 2 {
+		t.Fatalf("expect 2 endpoints, got %+v", endpoints)
+	}
+	if endpoint, ok := urls[httpServer.URL+"/"]; !ok || endpoint.Server != "test" {
+		t.Errorf("http server should be probed with http, got %+v", endpoints)
+	}
+	if _, ok := urls[tlsServer.URL+"/"]; !ok {
+		t.Errorf("tls server should be probed with https, got %+v", endpoints)
+	}
+}
+
+func TestCidrTargets(t *testing.T) {
+	targets, err := CidrTargets("192.168.1.0/30", []uint16{80, 443}, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Target{
+		{Host: "192.168.1.1", Port: 80, Source: SourceLan},
+		{Host: "192.168.1.1", Port: 443, Source: SourceLan},
+		{Host: "192.168.1.2", Port: 80, Source: SourceLan},
+		{Host: "192.168.1.2", Port: 443, Source: SourceLan},
+	}
+	if len(targets) != len(want) {
+		t.Fatalf("targets = %+v, want %+v", targets, want)
+	}
+	for i := range want {
+		if targets[i] != want[i] {
+			t.Errorf("targets[%d] = %+v, want %+v", i, targets[i], want[i])
+		}
+	}
+
+	if _, err := CidrTargets("10.0.0.0/8", []uint16{80}, 1024); err == nil {
+		t.Error("cidr larger than maxHosts should be rejected")
+	}
+}
@@ -0,0 +1,140 @@
+package sessions
+
+import (
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sync"
+	"time"
+)
+
+// touchInterval 同一 session 的活动记录的最小更新间隔, 避免每个请求都写入数据库.
+const touchInterval = time.Minute
+
+// Activity 已登录的 session 的活动记录, 用于展示和撤销用户的登录会话.
+type Activity struct {
+	ID uint `json:"id" gorm:"primaryKey"`
+	// SessionId 对应的 session id, 不返回给客户端.
+	SessionId string `json:"-" gorm:"uniqueIndex"`
+	Username  string `json:"username" gorm:"index"`
+	// UserAgent 登录设备的 User-Agent.
+	UserAgent string `json:"userAgent"`
+	Ip        string `json:"ip"`
+	// CreatedAt 登录时间(毫秒时间戳).
+	CreatedAt int64 `json:"createdAt" gorm:"autoCreateTime:milli"`
+	// LastSeenAt 最后一次请求的时间(毫秒时间戳), 精度为 touchInterval.
+	LastSeenAt int64 `json:"lastSeenAt"`
+	// Current 是否为发起请求的 session, 不会存储到数据库中.
+	Current bool `json:"current" gorm:"-"`
+}
+
+func (Activity) TableName() string {
+	return "session_activities"
+}
+
+// lastTouched session id 到最后一次更新活动记录的时间.
+var lastTouched sync.Map
+
+// Touch 记录当前 session 的活动, 应在登录成功或通过登录校验后调用. 未保存的 session(没有 session id)不会被记录.
+func Touch(c *gin.Context, username string) error {
+	id := sessions.Default(c).ID()
+	if len(id) <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	if last, ok := lastTouched.Load(id); ok && now.Sub(last.(time.Time)) < touchInterval {
+		return nil
+	}
+
+	activity := Activity{
+		SessionId:  id,
+		Username:   username,
+		UserAgent:  c.Request.UserAgent(),
+		Ip:         c.ClientIP(),
+		LastSeenAt: now.UnixMilli(),
+	}
+	result := getDB().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "session_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"username", "user_agent", "ip", "last_seen_at"}),
+	}).Create(&activity)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	lastTouched.Store(id, now)
+
+	return nil
+}
+
+// ListActivities 获取用户所有未过期的 session 的活动记录, 按最后活动时间倒序排列. 发起请求的 session 的 Current 为 true.
+func ListActivities(c *gin.Context, username string) ([]Activity, error) {
+	db := getDB()
+
+	activities := make([]Activity, 0)
+	result := db.Model(&Activity{}).
+		Where("username = ? AND session_id IN (?)", username, liveSessionIds(db)).
+		Order("last_seen_at DESC").
+		Find(&activities)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	current := sessions.Default(c).ID()
+	for i := range activities {
+		activities[i].Current = activities[i].SessionId == current
+	}
+
+	return activities, nil
+}
+
+// Revoke 撤销用户的 session, 被撤销的 session 需要重新登录. ids 为 Activity.ID.
+func Revoke(username string, ids []uint) error {
+	return revoke(getDB().Where("username = ? AND id IN ?", username, ids))
+}
+
+// RevokeOthers 撤销用户除发起请求的 session 以外的所有 session.
+func RevokeOthers(c *gin.Context, username string) error {
+	return revoke(getDB().Where("username = ? AND session_id != ?", username, sessions.Default(c).ID()))
+}
+
+// RevokeAll 撤销用户的所有 session.
+func RevokeAll(username string) error {
+	return revoke(getDB().Where("username = ?", username))
+}
+
+// revoke 删除 query 匹配的活动记录及对应的 session.
+func revoke(query *gorm.DB) error {
+	sessionIds := make([]string, 0)
+	if result := query.Model(&Activity{}).Pluck("session_id", &sessionIds); result.Error != nil {
+		return result.Error
+	} else if len(sessionIds) <= 0 {
+		return nil
+	}
+
+	return getDB().Transaction(func(tx *gorm.DB) error {
+		if result := tx.Table(sessionTableName).Where("id IN ?", sessionIds).Delete(nil); result.Error != nil {
+			return result.Error
+		}
+		if result := tx.Where("session_id IN ?", sessionIds).Delete(&Activity{}); result.Error != nil {
+			return result.Error
+		}
+
+		for _, id := range sessionIds {
+			lastTouched.Delete(id)
+		}
+
+		return nil
+	})
+}
+
+// cleanupActivities 删除已过期或已登出的 session 的活动记录.
+func cleanupActivities(db *gorm.DB) error {
+	return db.Where("session_id NOT IN (?)", liveSessionIds(db)).Delete(&Activity{}).Error
+}
+
+// liveSessionIds 未过期的 session id 的子查询.
+func liveSessionIds(db *gorm.DB) *gorm.DB {
+	return db.Table(sessionTableName).Select("id").Where("expires_at > ?", time.Now())
+}
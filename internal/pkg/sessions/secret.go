@@ -0,0 +1,127 @@
+package sessions
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/gorilla/securecookie"
+	"gorm.io/gorm"
+	"sync"
+	"time"
+)
+
+// secretKeyLength 签名密钥的长度, securecookie 推荐使用 32 或 64 字节.
+const secretKeyLength = 64
+
+// sessionSecret 持久化的 session 签名密钥.
+type sessionSecret struct {
+	ID      uint   `gorm:"primaryKey"`
+	HashKey []byte `gorm:"not null"`
+	// CreatedAt 创建时间(毫秒时间戳).
+	CreatedAt int64 `gorm:"autoCreateTime:milli"`
+	// RetiredAt 被新密钥替换的时间(毫秒时间戳), 为 0 时表示当前使用的密钥.
+	// 被替换的密钥在宽限期内仍可用于校验, 以免已登录的用户因密钥轮换而需要重新登录.
+	RetiredAt int64 `gorm:"index"`
+}
+
+// rotatingCodec 支持密钥轮换的 securecookie.Codec. 使用最新的密钥签名, 使用所有未过期的密钥校验.
+type rotatingCodec struct {
+	mutex  sync.RWMutex
+	codecs []securecookie.Codec
+}
+
+func (r *rotatingCodec) Encode(name string, value interface{}) (string, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return securecookie.EncodeMulti(name, value, r.codecs...)
+}
+
+func (r *rotatingCodec) Decode(name string, value string, dst interface{}) error {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return securecookie.DecodeMulti(name, value, dst, r.codecs...)
+}
+
+// reload 从数据库中加载未过期的密钥, 没有可用的密钥时生成新的密钥. 被替换超过 grace 的密钥将被删除.
+func (r *rotatingCodec) reload(db *gorm.DB, grace time.Duration) error {
+	secrets, err := loadSecrets(db, grace)
+	if err != nil {
+		return err
+	}
+
+	keyPairs := make([][]byte, 0, len(secrets)*2)
+	for _, secret := range secrets {
+		keyPairs = append(keyPairs, secret.HashKey, nil)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.codecs = securecookie.CodecsFromPairs(keyPairs...)
+
+	return nil
+}
+
+// loadSecrets 获取当前使用的密钥和宽限期内的旧密钥, 当前使用的密钥排在最前面.
+func loadSecrets(db *gorm.DB, grace time.Duration) ([]sessionSecret, error) {
+	secrets := make([]sessionSecret, 0)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		expiredBefore := time.Now().Add(-grace).UnixMilli()
+		if result := tx.Where("retired_at != 0 AND retired_at <= ?", expiredBefore).Delete(&sessionSecret{}); result.Error != nil {
+			return result.Error
+		}
+
+		if result := tx.Order("retired_at = 0 DESC, id DESC").Find(&secrets); result.Error != nil {
+			return result.Error
+		}
+
+		if len(secrets) > 0 && secrets[0].RetiredAt == 0 {
+			return nil
+		}
+
+		// 第一次启动或当前密钥丢失时生成新的密钥
+		secret, err := createSecret(tx)
+		if err != nil {
+			return err
+		}
+		secrets = append([]sessionSecret{secret}, secrets...)
+
+		return nil
+	})
+
+	return secrets, err
+}
+
+func createSecret(tx *gorm.DB) (sessionSecret, error) {
+	secret := sessionSecret{HashKey: securecookie.GenerateRandomKey(secretKeyLength)}
+	if secret.HashKey == nil {
+		return sessionSecret{}, errors.New("generate session secret failed")
+	}
+
+	result := tx.Create(&secret)
+
+	return secret, result.Error
+}
+
+// rotateSecret 生成新的密钥替换当前的密钥.
+func rotateSecret(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if result := tx.Model(&sessionSecret{}).Where("retired_at = 0").Update("retired_at", time.Now().UnixMilli()); result.Error != nil {
+			return result.Error
+		}
+
+		_, err := createSecret(tx)
+		return err
+	})
+}
+
+// currentSecretCreatedAt 获取当前使用的密钥的创建时间, 没有密钥时返回 0.
+func currentSecretCreatedAt(db *gorm.DB) (int64, error) {
+	secrets := make([]sessionSecret, 0)
+	result := db.Where("retired_at = 0").Order("id DESC").Limit(1).Find(&secrets)
+	if result.Error != nil || len(secrets) <= 0 {
+		return 0, result.Error
+	}
+
+	return secrets[0].CreatedAt, nil
+}
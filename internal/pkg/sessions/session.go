@@ -1,36 +1,163 @@
 package sessions
 
 import (
+	"context"
 	"github.com/gin-contrib/sessions"
-	gormSessions "github.com/gin-contrib/sessions/gorm"
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/securecookie"
+	"github.com/siaikin/home-dashboard/internal/pkg/comfy_log"
+	"github.com/siaikin/home-dashboard/internal/pkg/configuration"
 	"github.com/siaikin/home-dashboard/internal/pkg/database"
+	"github.com/wader/gormstore/v2"
+	"gorm.io/gorm"
+	"time"
 )
 
+var logger = comfy_log.New("[sessions]")
+
 var sessionName = "notificationSession"
 
-var store *gormSessions.Store
+// sessionTableName gormstore 存储 session 的表名.
+const sessionTableName = "sessions"
+
+// 清理过期 session 和检查是否需要轮换密钥的时间间隔.
+const checkInterval = time.Hour
+
+// store 基于 gormstore 的 session 存储, 使用 rotatingCodec 签名 session id.
+type store struct {
+	*gormstore.Store
+}
 
-func initialStore() *gormSessions.Store {
-	store := gormSessions.NewStore(database.GetDB(), true, []byte("secret"))
-	return &store
+func (s *store) Options(options sessions.Options) {
+	s.Store.SessionOpts = options.ToGorillaOptions()
 }
 
-//func GetStore() *gormSessions.Store {
-//	return store
-//}
+var sessionStore *store
+
+var codec = &rotatingCodec{}
+
+func getDB() *gorm.DB {
+	return database.GetDB()
+}
+
+func initialStore() *store {
+	db := getDB()
+
+	if err := db.AutoMigrate(&sessionSecret{}, &Activity{}); err != nil {
+		logger.Fatal("migrate session tables failed, %s\n", err)
+	}
+	if err := codec.reload(db, gracePeriod()); err != nil {
+		logger.Fatal("load session secrets failed, %s\n", err)
+	}
+
+	s := gormstore.NewOptions(db, gormstore.Options{TableName: sessionTableName})
+	s.Codecs = []securecookie.Codec{codec}
+	// 登录前创建的 session(如 OpenID Connect 授权请求)同样不允许被页面脚本读取
+	s.SessionOpts.HttpOnly = true
+
+	return &store{s}
+}
 
 var middleware gin.HandlerFunc
 
+// GetSessionName 获取 session 的名称, 即保存 session id 的 Cookie 名称.
+func GetSessionName() string {
+	return sessionName
+}
+
+// CookieOptions 获取 session Cookie 的选项, maxAge 为有效期(秒), 小于 0 时删除 Cookie.
+// Cookie 对整个站点有效, 配置了 CookieDomain 时同时对该域名的子域名有效.
+// Cookie 设置为 HttpOnly, 避免通过代理嵌入的同源页面读取 session.
+func CookieOptions(maxAge int) sessions.Options {
+	return sessions.Options{
+		Path:     "/",
+		Domain:   configuration.Get().ServerMonitor.Session.CookieDomain,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+	}
+}
+
 // GetSessionMiddleware 获取自定义的 session 中间件
 func GetSessionMiddleware() gin.HandlerFunc {
-	if store == nil {
-		store = initialStore()
+	if sessionStore == nil {
+		sessionStore = initialStore()
 	}
 
 	if middleware == nil {
-		middleware = sessions.Sessions(sessionName, *store)
+		middleware = sessions.Sessions(sessionName, sessionStore)
 	}
 
 	return middleware
 }
+
+// RotateSecret 立即轮换 session 签名密钥. 旧密钥在宽限期内仍可用于校验, 已登录的用户不需要重新登录.
+func RotateSecret() error {
+	db := getDB()
+
+	if err := rotateSecret(db); err != nil {
+		return err
+	}
+	logger.Info("session secret rotated\n")
+
+	return codec.reload(db, gracePeriod())
+}
+
+// Loop 定期清理过期的 session 和活动记录, 并在配置了自动轮换时按时轮换 session 签名密钥.
+func Loop(ctx context.Context) {
+	go func() {
+		defer logger.Info("stop session clean loop\n")
+
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			check()
+		}
+	}()
+}
+
+func check() {
+	db := getDB()
+
+	if sessionStore != nil {
+		sessionStore.Cleanup()
+	}
+	if err := cleanupActivities(db); err != nil {
+		logger.Error("cleanup session activities failed, %s\n", err)
+	}
+
+	interval := configuration.Get().ServerMonitor.Session.SecretRotationInterval * time.Second
+	if interval <= 0 {
+		// 仍需重新加载以删除超过宽限期的旧密钥
+		if err := codec.reload(db, gracePeriod()); err != nil {
+			logger.Error("reload session secrets failed, %s\n", err)
+		}
+		return
+	}
+
+	if createdAt, err := currentSecretCreatedAt(db); err != nil {
+		logger.Error("get session secret failed, %s\n", err)
+	} else if time.Since(time.UnixMilli(createdAt)) >= interval {
+		if err := RotateSecret(); err != nil {
+			logger.Error("rotate session secret failed, %s\n", err)
+		}
+	} else if err := codec.reload(db, gracePeriod()); err != nil {
+		logger.Error("reload session secrets failed, %s\n", err)
+	}
+}
+
+// gracePeriod 被替换的密钥仍可用于校验的宽限期, 默认为 7 天.
+func gracePeriod() time.Duration {
+	grace := configuration.Get().ServerMonitor.Session.SecretGracePeriod * time.Second
+	if grace <= 0 {
+		grace = time.Hour * 24 * 7
+	}
+
+	return grace
+}
@@ -0,0 +1,113 @@
+package sessions
+
+import (
+	"encoding/json"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(GetSessionMiddleware())
+	router.POST("login", func(c *gin.Context) {
+		session := sessions.Default(c)
+		session.Set("username", "session-user")
+		if err := session.Save(); err != nil {
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		_ = Touch(c, "session-user")
+	})
+	router.GET("list", func(c *gin.Context) {
+		if sessions.Default(c).Get("username") == nil {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		activities, _ := ListActivities(c, "session-user")
+		c.JSON(http.StatusOK, activities)
+	})
+	router.DELETE("others", func(c *gin.Context) {
+		_ = RevokeOthers(c, "session-user")
+	})
+
+	return router
+}
+
+func serve(router *gin.Engine, method string, url string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(method, url, nil)
+	if cookie != nil {
+		request.AddCookie(cookie)
+	}
+	router.ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+func login(t *testing.T, router *gin.Engine) *http.Cookie {
+	t.Helper()
+
+	cookies := serve(router, http.MethodPost, "/login", nil).Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("login should set session cookie, got %+v", cookies)
+	}
+
+	return cookies[0]
+}
+
+func TestSession(t *testing.T) {
+	router := newTestRouter()
+
+	first := login(t, router)
+	second := login(t, router)
+
+	recorder := serve(router, http.MethodGet, "/list", first)
+	var activities []Activity
+	_ = json.Unmarshal(recorder.Body.Bytes(), &activities)
+	if recorder.Code != http.StatusOK || len(activities) != 2 {
+		t.Fatalf("unexpected activities %d, %s", recorder.Code, recorder.Body.String())
+	}
+	currentCount := 0
+	for _, activity := range activities {
+		if activity.Current {
+			currentCount++
+		}
+	}
+	if currentCount != 1 {
+		t.Errorf("exactly one activity should be current, got %+v", activities)
+	}
+
+	// 轮换密钥后旧的 Cookie 在宽限期内仍然有效
+	if err := RotateSecret(); err != nil {
+		t.Fatal(err)
+	}
+	if code := serve(router, http.MethodGet, "/list", first).Code; code != http.StatusOK {
+		t.Errorf("cookie signed with retired secret should be valid in grace period, got %d", code)
+	}
+
+	// 撤销其他会话
+	serve(router, http.MethodDelete, "/others", first)
+	if code := serve(router, http.MethodGet, "/list", second).Code; code != http.StatusUnauthorized {
+		t.Errorf("revoked session should be unauthorized, got %d", code)
+	}
+	if code := serve(router, http.MethodGet, "/list", first).Code; code != http.StatusOK {
+		t.Errorf("current session should not be revoked, got %d", code)
+	}
+
+	// 超过宽限期后旧的 Cookie 失效
+	if err := codec.reload(getDB(), 0); err != nil {
+		t.Fatal(err)
+	}
+	if code := serve(router, http.MethodGet, "/list", first).Code; code != http.StatusUnauthorized {
+		t.Errorf("cookie signed with expired secret should be invalid, got %d", code)
+	}
+	if code := serve(router, http.MethodGet, "/list", login(t, router)).Code; code != http.StatusOK {
+		t.Errorf("new session should be valid, got %d", code)
+	}
+}
@@ -0,0 +1,278 @@
+//go:build ignore
+
+// 从 simple-icons 的 npm 包生成打包进二进制文件的数据集.
+//
+//	go run gen/main.go -version 13.0.0 -output data/simple-icons.json
+//
+// 无法访问 npm registry 时, 可以通过 -source 指定已下载的 npm 包(.tgz)或解压后的目录.
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/siaikin/home-dashboard/internal/pkg/simple_icons"
+	"golang.org/x/text/unicode/norm"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const registry = "https://registry.npmjs.org/simple-icons"
+
+// 与 simple-icons 的 titleToSlug 保持一致.
+var titleToSlugReplacer = strings.NewReplacer(
+	"+", "plus", ".", "dot", "&", "and",
+	"đ", "d", "ħ", "h", "ı", "i", "ĸ", "k", "ŀ", "l", "ł", "l", "ß", "ss", "ŧ", "t",
+)
+
+var svgPathRegexp = regexp.MustCompile(`\sd="([^"]+)"`)
+
+// entry simple-icons 中 _data/simple-icons.json 的一项.
+type entry struct {
+	Title   string `json:"title"`
+	Slug    string `json:"slug"`
+	Hex     string `json:"hex"`
+	Aliases struct {
+		Aka []string          `json:"aka"`
+		Loc map[string]string `json:"loc"`
+		Dup []struct {
+			Title string `json:"title"`
+		} `json:"dup"`
+	} `json:"aliases"`
+}
+
+func main() {
+	version := flag.String("version", "latest", "simple-icons version on npm")
+	source := flag.String("source", "", "path of a downloaded npm package (.tgz) or an extracted package directory")
+	output := flag.String("output", "data/simple-icons.json", "output file")
+	flag.Parse()
+
+	var files map[string][]byte
+	var err error
+	if len(*source) <= 0 {
+		files, err = download(*version)
+	} else if info, statErr := os.Stat(*source); statErr != nil {
+		err = statErr
+	} else if info.IsDir() {
+		files, err = readDir(*source)
+	} else {
+		files, err = readTarballFile(*source)
+	}
+	if err != nil {
+		log.Fatalf("read simple-icons package failed, %s", err)
+	}
+
+	dataset, err := build(files)
+	if err != nil {
+		log.Fatalf("build dataset failed, %s", err)
+	}
+
+	if err := write(*output, dataset); err != nil {
+		log.Fatalf("write dataset failed, %s", err)
+	}
+	log.Printf("generated %d icons of simple-icons %s", len(dataset.Icons), dataset.Version)
+}
+
+// download 从 npm registry 下载指定版本的 simple-icons 包.
+func download(version string) (map[string][]byte, error) {
+	res, err := http.Get(registry + "/" + version)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var manifest struct {
+		Dist struct {
+			Tarball string `json:"tarball"`
+		} `json:"dist"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&manifest); err != nil {
+		return nil, err
+	}
+
+	tarball, err := http.Get(manifest.Dist.Tarball)
+	if err != nil {
+		return nil, err
+	}
+	defer tarball.Body.Close()
+
+	return readTarball(tarball.Body)
+}
+
+func readTarballFile(name string) (map[string][]byte, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return readTarball(file)
+}
+
+// readTarball 读取 npm 包中需要的文件, 返回的文件路径不包含 package/ 前缀.
+func readTarball(reader io.Reader) (map[string][]byte, error) {
+	gzipReader, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte)
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		name := strings.TrimPrefix(header.Name, "package/")
+		if !needed(name) {
+			continue
+		}
+
+		if files[name], err = io.ReadAll(tarReader); err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+func readDir(dir string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+
+	err := filepath.Walk(dir, func(name string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		relative, err := filepath.Rel(dir, name)
+		if err != nil {
+			return err
+		}
+		relative = filepath.ToSlash(relative)
+		if !needed(relative) {
+			return nil
+		}
+
+		files[relative], err = os.ReadFile(name)
+		return err
+	})
+
+	return files, err
+}
+
+func needed(name string) bool {
+	return name == "package.json" || name == "_data/simple-icons.json" || (path.Dir(name) == "icons" && path.Ext(name) == ".svg")
+}
+
+func build(files map[string][]byte) (*simple_icons.Dataset, error) {
+	var pkg struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(files["package.json"], &pkg); err != nil {
+		return nil, fmt.Errorf("parse package.json failed, %w", err)
+	}
+
+	data := files["_data/simple-icons.json"]
+	entries := make([]entry, 0)
+	// simple-icons v14 之前的数据为 {"icons": [...]}, 之后为 [...].
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var wrapped struct {
+			Icons []entry `json:"icons"`
+		}
+		if err := json.Unmarshal(data, &wrapped); err != nil {
+			return nil, err
+		}
+		entries = wrapped.Icons
+	} else if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+
+	dataset := &simple_icons.Dataset{Version: pkg.Version, Icons: make([]simple_icons.Icon, 0, len(entries))}
+	for _, e := range entries {
+		slug := e.Slug
+		if len(slug) <= 0 {
+			slug = titleToSlug(e.Title)
+		}
+
+		svg, ok := files["icons/"+slug+".svg"]
+		if !ok {
+			log.Printf("svg of %s (%s) not found, skipped", e.Title, slug)
+			continue
+		}
+		matches := svgPathRegexp.FindSubmatch(svg)
+		if matches == nil {
+			log.Printf("path of %s (%s) not found, skipped", e.Title, slug)
+			continue
+		}
+
+		aliases := append([]string{}, e.Aliases.Aka...)
+		for _, dup := range e.Aliases.Dup {
+			aliases = append(aliases, dup.Title)
+		}
+		for _, loc := range e.Aliases.Loc {
+			aliases = append(aliases, loc)
+		}
+		sort.Strings(aliases)
+
+		dataset.Icons = append(dataset.Icons, simple_icons.Icon{
+			Title:   e.Title,
+			Slug:    slug,
+			Hex:     strings.ToUpper(e.Hex),
+			Path:    string(matches[1]),
+			Aliases: aliases,
+		})
+	}
+
+	sort.Slice(dataset.Icons, func(i, j int) bool {
+		return dataset.Icons[i].Slug < dataset.Icons[j].Slug
+	})
+
+	return dataset, nil
+}
+
+func titleToSlug(title string) string {
+	slug := norm.NFD.String(titleToSlugReplacer.Replace(strings.ToLower(title)))
+
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return -1
+	}, slug)
+}
+
+// write 写入数据集, 每个图标一行以便于查看版本间的差异.
+func write(output string, dataset *simple_icons.Dataset) error {
+	buffer := bytes.Buffer{}
+
+	version, _ := json.Marshal(dataset.Version)
+	buffer.WriteString(`{"version":` + string(version) + `,"icons":[`)
+	for i, icon := range dataset.Icons {
+		line, err := json.Marshal(icon)
+		if err != nil {
+			return err
+		}
+
+		if i > 0 {
+			buffer.WriteString(",")
+		}
+		buffer.WriteString("\n")
+		buffer.Write(line)
+	}
+	buffer.WriteString("\n]}\n")
+
+	return os.WriteFile(output, buffer.Bytes(), 0644)
+}
@@ -0,0 +1,202 @@
+package simple_icons
+
+//go:generate go run gen/main.go -output data/simple-icons.json
+
+import (
+	_ "embed"
+	"encoding/json"
+	"github.com/samber/lo"
+	"html"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// dataset 打包进二进制文件的 simple-icons 数据集, 由 gen/main.go 从 simple-icons 的 npm 包生成.
+//
+//go:embed data/simple-icons.json
+var dataset []byte
+
+// Icon simple-icons 中的一个品牌图标.
+type Icon struct {
+	// Title 品牌名称.
+	Title string `json:"title"`
+	Slug  string `json:"slug"`
+	// Hex 品牌颜色, 不包含 # 前缀.
+	Hex string `json:"hex"`
+	// Path SVG 图标的路径数据, viewBox 为 0 0 24 24.
+	Path string `json:"path"`
+	// Aliases 品牌的别名, 包括曾用名, 缩写和本地化名称.
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// Svg 生成图标的 SVG 文件内容.
+func (i Icon) Svg() string {
+	return `<svg role="img" viewBox="0 0 24 24" xmlns="http://www.w3.org/2000/svg"><title>` + html.EscapeString(i.Title) + `</title><path d="` + html.EscapeString(i.Path) + `"/></svg>`
+}
+
+// Dataset simple-icons 数据集.
+type Dataset struct {
+	// Version 生成数据集时使用的 simple-icons 版本.
+	Version string `json:"version"`
+	Icons   []Icon `json:"icons"`
+}
+
+var loaded struct {
+	once    sync.Once
+	dataset Dataset
+	err     error
+}
+
+// Load 解析打包的数据集, 只在第一次调用时解析.
+func Load() (*Dataset, error) {
+	loaded.once.Do(func() {
+		loaded.err = json.Unmarshal(dataset, &loaded.dataset)
+	})
+
+	return &loaded.dataset, loaded.err
+}
+
+// Get 根据 slug 获取打包的数据集中的图标.
+func Get(slug string) (Icon, bool) {
+	dataset, err := Load()
+	if err != nil {
+		return Icon{}, false
+	}
+
+	return dataset.Get(slug)
+}
+
+// Search 在打包的数据集中模糊搜索图标, 见 Dataset.Search.
+func Search(keyword string, limit int) ([]Icon, error) {
+	dataset, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	return dataset.Search(keyword, limit), nil
+}
+
+// Get 根据 slug 获取图标.
+func (d *Dataset) Get(slug string) (Icon, bool) {
+	slug = strings.ToLower(slug)
+	for _, icon := range d.Icons {
+		if icon.Slug == slug {
+			return icon, true
+		}
+	}
+
+	return Icon{}, false
+}
+
+// 不同匹配方式的得分, 得分越高越靠前.
+const (
+	scoreExact     = 100
+	scorePrefix    = 80
+	scoreWordStart = 60
+	scoreContains  = 40
+	// scoreSubsequence 关键字的字符按顺序出现在名称中, 实际得分会根据字符的紧凑程度降低.
+	scoreSubsequence = 20
+	// 别名匹配的得分低于品牌名称和 slug.
+	aliasPenalty = 5
+)
+
+// Search 在品牌名称, slug 和别名中模糊搜索图标, 按匹配程度排序. limit 小于等于 0 时返回所有匹配的图标.
+func (d *Dataset) Search(keyword string, limit int) []Icon {
+	keyword = normalize(keyword)
+	if len(keyword) <= 0 {
+		return []Icon{}
+	}
+
+	type scored struct {
+		index int
+		score int
+	}
+	matched := make([]scored, 0)
+	for i, icon := range d.Icons {
+		score := lo.Max([]int{matchScore(keyword, icon.Title), matchScore(keyword, icon.Slug)})
+		for _, alias := range icon.Aliases {
+			score = lo.Max([]int{score, matchScore(keyword, alias) - aliasPenalty})
+		}
+
+		if score > 0 {
+			matched = append(matched, scored{index: i, score: score})
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		if matched[i].score != matched[j].score {
+			return matched[i].score > matched[j].score
+		}
+		// 得分相同时名称较短的更可能是用户想要的图标.
+		return len(d.Icons[matched[i].index].Title) < len(d.Icons[matched[j].index].Title)
+	})
+
+	if limit > 0 && len(matched) > limit {
+		matched = matched[:limit]
+	}
+
+	icons := make([]Icon, len(matched))
+	for i, m := range matched {
+		icons[i] = d.Icons[m.index]
+	}
+
+	return icons
+}
+
+// matchScore 计算 keyword (已规范化) 与 name 的匹配得分, 不匹配时返回 0.
+func matchScore(keyword string, name string) int {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	normalized := strings.Join(words, "")
+
+	switch {
+	case len(normalized) <= 0:
+		return 0
+	case normalized == keyword:
+		return scoreExact
+	case strings.HasPrefix(normalized, keyword):
+		return scorePrefix
+	}
+
+	for _, word := range words {
+		if strings.HasPrefix(word, keyword) {
+			return scoreWordStart
+		}
+	}
+
+	if strings.Contains(normalized, keyword) {
+		return scoreContains
+	}
+
+	// 子序列匹配, 如 "gh" 匹配 "GitHub". 匹配的字符越分散得分越低.
+	keywordRunes := []rune(keyword)
+	matchedCount, first, last := 0, -1, -1
+	for i, r := range []rune(normalized) {
+		if matchedCount < len(keywordRunes) && r == keywordRunes[matchedCount] {
+			if first < 0 {
+				first = i
+			}
+			last = i
+			matchedCount++
+		}
+	}
+	if matchedCount < len(keywordRunes) {
+		return 0
+	}
+
+	span := last - first + 1
+	return lo.Max([]int{1, scoreSubsequence - (span - len(keywordRunes))})
+}
+
+// normalize 将关键字转为小写并移除空白和标点符号.
+func normalize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
@@ -0,0 +1,83 @@
+package simple_icons
+
+import (
+	"strings"
+	"testing"
+)
+
+var testDataset = &Dataset{
+	Version: "test",
+	Icons: []Icon{
+		{Title: "GitHub", Slug: "github", Hex: "181717", Path: "M0 0h24v24H0z"},
+		{Title: "GitHub Actions", Slug: "githubactions", Hex: "2088FF", Path: "M0 0h24v24H0z"},
+		{Title: "GitLab", Slug: "gitlab", Hex: "FC6D26", Path: "M0 0h24v24H0z"},
+		{Title: "Home Assistant", Slug: "homeassistant", Hex: "18BCF2", Path: "M0 0h24v24H0z", Aliases: []string{"HA"}},
+		{Title: "Grafana", Slug: "grafana", Hex: "F46800", Path: "M0 0h24v24H0z"},
+	},
+}
+
+func titles(icons []Icon) string {
+	result := make([]string, len(icons))
+	for i, icon := range icons {
+		result[i] = icon.Title
+	}
+
+	return strings.Join(result, ",")
+}
+
+func TestDatasetSearch(t *testing.T) {
+	cases := map[string]string{
+		"github":    "GitHub,GitHub Actions",
+		"Git Hub":   "GitHub,GitHub Actions",
+		"actions":   "GitHub Actions",
+		"assistant": "Home Assistant",
+		"gl":        "GitLab",
+		"":          "",
+		"zzz":       "",
+	}
+
+	for keyword, want := range cases {
+		if got := titles(testDataset.Search(keyword, 0)); got != want {
+			t.Errorf("Search(%q) = %q, want %q", keyword, got, want)
+		}
+	}
+
+	// 别名完全匹配的得分高于其他图标的子序列匹配.
+	if got := testDataset.Search("ha", 0); len(got) <= 0 || got[0].Title != "Home Assistant" {
+		t.Errorf("alias should be matched, got %q", titles(got))
+	}
+
+	if got := testDataset.Search("git", 2); len(got) != 2 {
+		t.Errorf("limit not applied, got %d icons", len(got))
+	}
+}
+
+func TestDatasetGet(t *testing.T) {
+	if icon, ok := testDataset.Get("GitHub"); !ok || icon.Slug != "github" {
+		t.Errorf("unexpected icon %+v", icon)
+	}
+	if _, ok := testDataset.Get("unknown"); ok {
+		t.Error("unknown slug should not be found")
+	}
+}
+
+func TestIconSvg(t *testing.T) {
+	svg := Icon{Title: "A&B", Path: "M0 0z"}.Svg()
+	if !strings.Contains(svg, "<title>A&amp;B</title>") || !strings.Contains(svg, `d="M0 0z"`) {
+		t.Errorf("unexpected svg %s", svg)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dataset, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 数据集需要通过 go generate ./internal/pkg/simple_icons 生成
+	if len(dataset.Icons) <= 0 || len(dataset.Version) <= 0 {
+		t.Fatalf("bundled dataset is empty, version %q", dataset.Version)
+	}
+	if icon, ok := dataset.Get("github"); !ok || len(icon.Path) <= 0 {
+		t.Errorf("bundled dataset should contain github, got %+v", icon)
+	}
+}
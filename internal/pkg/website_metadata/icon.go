@@ -0,0 +1,189 @@
+package website_metadata
+
+import (
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// IconSource 表示候选图标的来源.
+type IconSource = string
+
+const (
+	// IconSourceManifest 来自 manifest.json 的 icons 字段.
+	IconSourceManifest IconSource = "manifest"
+	// IconSourceAppleTouchIcon 来自 <link rel="apple-touch-icon">.
+	IconSourceAppleTouchIcon IconSource = "apple-touch-icon"
+	// IconSourceLink 来自 <link rel="icon">.
+	IconSourceLink IconSource = "icon"
+	// IconSourceOpenGraph 来自 <meta property="og:image">.
+	IconSourceOpenGraph IconSource = "og:image"
+	// IconSourceFavicon 站点根目录下的 /favicon.ico, 不保证存在.
+	IconSourceFavicon IconSource = "favicon"
+)
+
+// 可缩放图标(svg 或 sizes="any")视为该尺寸. 同时也是参与评分的最大尺寸, 更大的图标不会获得更高的评分.
+const scalableIconSize = 512
+
+// IconCandidate 候选图标.
+type IconCandidate struct {
+	Url    string     `json:"url"`
+	Source IconSource `json:"source"`
+	// Sizes 图标声明的尺寸, 原样保留 sizes 属性的值, 如 "32x32 64x64" 或 "any".
+	Sizes string `json:"sizes"`
+	// Type 图标的 MIME 类型. 未声明时根据文件扩展名推断.
+	Type    string `json:"type"`
+	Purpose string `json:"purpose"`
+	// Size 图标的边长(像素). 取声明的最大尺寸, 未声明时根据来源估算.
+	Size int `json:"size"`
+	// Score 图标评分, 越高越适合作为快捷方式图标.
+	Score int `json:"score"`
+}
+
+func newIconCandidate(url string, source IconSource, sizes string, mimeType string, purpose string) IconCandidate {
+	icon := IconCandidate{
+		Url:     url,
+		Source:  source,
+		Sizes:   strings.TrimSpace(sizes),
+		Type:    strings.ToLower(strings.TrimSpace(mimeType)),
+		Purpose: strings.ToLower(strings.TrimSpace(purpose)),
+	}
+
+	if len(icon.Type) <= 0 {
+		icon.Type = guessIconType(url)
+	}
+
+	icon.Size = declaredIconSize(icon)
+	icon.Score = scoreIcon(icon)
+
+	return icon
+}
+
+// declaredIconSize 解析图标的边长. 对于没有声明尺寸的图标, 按照来源的惯例估算.
+func declaredIconSize(icon IconCandidate) int {
+	if icon.Type == "image/svg+xml" {
+		return scalableIconSize
+	}
+
+	largest := 0
+	for _, size := range strings.Fields(strings.ToLower(icon.Sizes)) {
+		if size == "any" {
+			return scalableIconSize
+		}
+
+		widthAndHeight := strings.SplitN(size, "x", 2)
+		if len(widthAndHeight) != 2 {
+			continue
+		}
+
+		width, widthErr := strconv.Atoi(widthAndHeight[0])
+		height, heightErr := strconv.Atoi(widthAndHeight[1])
+		if widthErr != nil || heightErr != nil {
+			continue
+		}
+
+		// 非正方形的图标以短边为准.
+		if edge := int(math.Min(float64(width), float64(height))); edge > largest {
+			largest = edge
+		}
+	}
+	if largest > 0 {
+		return largest
+	}
+
+	switch icon.Source {
+	case IconSourceAppleTouchIcon:
+		// apple-touch-icon 未声明尺寸时, iOS 默认使用 180x180.
+		return 180
+	case IconSourceManifest:
+		return 192
+	case IconSourceOpenGraph:
+		return 256
+	default:
+		return 32
+	}
+}
+
+// scoreIcon 根据尺寸, 格式和来源对图标评分.
+func scoreIcon(icon IconCandidate) int {
+	size := math.Min(float64(icon.Size), scalableIconSize)
+
+	formatWeight := 1.0
+	switch icon.Type {
+	case "image/svg+xml":
+		formatWeight = 1.2
+	case "image/png", "image/webp":
+		formatWeight = 1.0
+	case "image/x-icon", "image/vnd.microsoft.icon":
+		formatWeight = 0.8
+	case "image/jpeg", "image/gif":
+		formatWeight = 0.6
+	}
+
+	sourceBonus := 0
+	switch icon.Source {
+	case IconSourceManifest, IconSourceAppleTouchIcon:
+		sourceBonus = 20
+	case IconSourceOpenGraph:
+		// og:image 通常是横幅而非图标, 仅在没有更好的选择时使用.
+		sourceBonus = -200
+	case IconSourceFavicon:
+		// 不确定是否存在.
+		sourceBonus = -10
+	}
+
+	// monochrome 图标只有单色轮廓, maskable 图标带有较大的安全边距, 都不适合直接展示.
+	switch {
+	case strings.Contains(icon.Purpose, "monochrome"):
+		sourceBonus -= 500
+	case strings.Contains(icon.Purpose, "maskable") && !strings.Contains(icon.Purpose, "any"):
+		sourceBonus -= 30
+	}
+
+	return int(size*formatWeight) + sourceBonus
+}
+
+func guessIconType(url string) string {
+	ext := strings.ToLower(path.Ext(strings.SplitN(strings.SplitN(url, "?", 2)[0], "#", 2)[0]))
+	switch ext {
+	case ".svg":
+		return "image/svg+xml"
+	case ".png":
+		return "image/png"
+	case ".webp":
+		return "image/webp"
+	case ".ico":
+		return "image/x-icon"
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".gif":
+		return "image/gif"
+	default:
+		return ""
+	}
+}
+
+// appendIcon 添加候选图标, 同一地址的图标仅保留评分最高的一个.
+func appendIcon(icons []IconCandidate, icon IconCandidate) []IconCandidate {
+	for i, existed := range icons {
+		if existed.Url != icon.Url {
+			continue
+		}
+
+		if icon.Score > existed.Score {
+			icons[i] = icon
+		}
+		return icons
+	}
+
+	return append(icons, icon)
+}
+
+// sortIcons 按评分从高到低排序, 评分相同时保持原有顺序.
+func sortIcons(icons []IconCandidate) {
+	sort.SliceStable(icons, func(i, j int) bool {
+		return icons[i].Score > icons[j].Score
+	})
+}
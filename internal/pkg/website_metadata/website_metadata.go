@@ -0,0 +1,268 @@
+package website_metadata
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/json"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/go-errors/errors"
+	"github.com/siaikin/home-dashboard/internal/pkg/comfy_http_client"
+	"github.com/siaikin/home-dashboard/internal/pkg/comfy_log"
+	"golang.org/x/net/html/charset"
+	"io"
+	"net/http"
+	url2 "net/url"
+	"strings"
+)
+
+var logger = comfy_log.New("[website_metadata]")
+
+// forwardedHeaders 允许转发给目标网站的请求头. 其他请求头(如 dashboard 的 Cookie 和 Authorization)不会被转发给第三方网站.
+var forwardedHeaders = []string{"User-Agent", "Accept-Language"}
+
+// OpenGraph 网页中声明的 [OpenGraph] 信息.
+//
+// [OpenGraph]: https://ogp.me/
+type OpenGraph struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Image       string `json:"image"`
+	SiteName    string `json:"siteName"`
+}
+
+// Metadata 从网页(以及网页引用的 manifest.json)中提取到的网站信息.
+type Metadata struct {
+	// Title 网页标题, 依次尝试 <title>, og:title 和 manifest 中的 name.
+	Title string `json:"title"`
+	// Description 网页描述, 依次尝试 <meta name="description"> 和 og:description.
+	Description string `json:"description"`
+	// ThemeColor 网站主题色, 依次尝试 <meta name="theme-color"> 和 manifest 中的 theme_color.
+	ThemeColor string    `json:"themeColor"`
+	OpenGraph  OpenGraph `json:"openGraph"`
+	// ManifestUrl 网站 manifest.json 的地址, 未声明时为空.
+	ManifestUrl string `json:"manifestUrl"`
+	// Icons 所有候选图标, 按 IconCandidate.Score 从高到低排序.
+	Icons []IconCandidate `json:"icons"`
+}
+
+// BestIcon 返回评分最高的图标. 没有任何候选图标时第二个返回值为 false.
+func (m *Metadata) BestIcon() (IconCandidate, bool) {
+	if len(m.Icons) <= 0 {
+		return IconCandidate{}, false
+	}
+
+	return m.Icons[0], true
+}
+
+// webManifest manifest.json 中需要用到的字段. 详见 https://developer.mozilla.org/en-US/docs/Web/Manifest
+type webManifest struct {
+	Name       string `json:"name"`
+	ShortName  string `json:"short_name"`
+	ThemeColor string `json:"theme_color"`
+	Icons      []struct {
+		Src     string `json:"src"`
+		Sizes   string `json:"sizes"`
+		Type    string `json:"type"`
+		Purpose string `json:"purpose"`
+	} `json:"icons"`
+}
+
+// Fetch 请求 url 对应的网页并提取网站信息. header 中只有 forwardedHeaders 会被发送.
+// 如果网页声明了 manifest.json, 也会请求并合并其中的图标和主题色. manifest.json 请求失败不会导致 Fetch 失败.
+func Fetch(client *comfy_http_client.Client, url *url2.URL, header http.Header) (*Metadata, error) {
+	// 与浏览器一致, 即使状态码不是 2xx 也尝试解析网页内容.
+	body, contentType, _, err := get(client, url.String(), header)
+	if err != nil {
+		return nil, err
+	}
+
+	_, charsetValue, _ := charset.DetermineEncoding(body, contentType)
+	decodeReader, err := charset.NewReader(bytes.NewReader(body), charsetValue)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(decodeReader)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+
+	metadata := Parse(doc, url)
+
+	if len(metadata.ManifestUrl) > 0 {
+		if manifestBody, _, statusCode, err := get(client, metadata.ManifestUrl, header); err != nil {
+			logger.Warn("fetch manifest %s failed, %v\n", metadata.ManifestUrl, err)
+		} else if statusCode < 200 || statusCode >= 300 {
+			logger.Warn("fetch manifest %s failed, status code %d\n", metadata.ManifestUrl, statusCode)
+		} else if err := mergeManifest(metadata, manifestBody); err != nil {
+			logger.Warn("parse manifest %s failed, %v\n", metadata.ManifestUrl, err)
+		}
+	}
+
+	sortIcons(metadata.Icons)
+
+	return metadata, nil
+}
+
+// Parse 从已解析的 HTML 文档中提取网站信息, base 用于将相对地址解析为绝对地址.
+// Parse 不会发起任何网络请求, 因此返回结果中不包含 manifest.json 中声明的图标.
+func Parse(doc *goquery.Document, base *url2.URL) *Metadata {
+	metadata := &Metadata{
+		Title:       strings.TrimSpace(doc.Find("title").First().Text()),
+		Description: strings.TrimSpace(doc.Find("meta[name*='description']").AttrOr("content", "")),
+		ThemeColor:  strings.TrimSpace(doc.Find("meta[name='theme-color']").First().AttrOr("content", "")),
+		OpenGraph: OpenGraph{
+			Title:       metaProperty(doc, "og:title"),
+			Description: metaProperty(doc, "og:description"),
+			Image:       metaProperty(doc, "og:image"),
+			SiteName:    metaProperty(doc, "og:site_name"),
+		},
+		Icons: make([]IconCandidate, 0),
+	}
+
+	if len(metadata.Title) <= 0 {
+		metadata.Title = metadata.OpenGraph.Title
+	}
+	if len(metadata.Description) <= 0 {
+		metadata.Description = metadata.OpenGraph.Description
+	}
+
+	if href, ok := doc.Find("link[rel~='manifest']").First().Attr("href"); ok {
+		metadata.ManifestUrl = resolve(base, href)
+	}
+
+	doc.Find("link[rel]").Each(func(i int, s *goquery.Selection) {
+		href, ok := s.Attr("href")
+		if !ok || len(strings.TrimSpace(href)) <= 0 {
+			return
+		}
+
+		var source IconSource
+		rels := strings.Fields(strings.ToLower(s.AttrOr("rel", "")))
+		switch {
+		case containsAny(rels, "apple-touch-icon", "apple-touch-icon-precomposed"):
+			source = IconSourceAppleTouchIcon
+		case containsAny(rels, "icon"):
+			source = IconSourceLink
+		default:
+			return
+		}
+
+		metadata.Icons = appendIcon(metadata.Icons, newIconCandidate(resolve(base, href), source, s.AttrOr("sizes", ""), s.AttrOr("type", ""), ""))
+	})
+
+	if len(metadata.OpenGraph.Image) > 0 {
+		metadata.OpenGraph.Image = resolve(base, metadata.OpenGraph.Image)
+		metadata.Icons = appendIcon(metadata.Icons, newIconCandidate(metadata.OpenGraph.Image, IconSourceOpenGraph, "", metaProperty(doc, "og:image:type"), ""))
+	}
+
+	// 所有网站都可能存在 /favicon.ico, 作为兜底选项.
+	metadata.Icons = appendIcon(metadata.Icons, newIconCandidate(resolve(base, "/favicon.ico"), IconSourceFavicon, "", "", ""))
+
+	sortIcons(metadata.Icons)
+
+	return metadata
+}
+
+// mergeManifest 将 manifest.json 中的图标, 主题色和名称合并到 metadata 中.
+func mergeManifest(metadata *Metadata, body []byte) error {
+	manifest := webManifest{}
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return errors.New(err)
+	}
+
+	manifestUrl, err := url2.Parse(metadata.ManifestUrl)
+	if err != nil {
+		return errors.New(err)
+	}
+
+	for _, icon := range manifest.Icons {
+		if len(strings.TrimSpace(icon.Src)) <= 0 {
+			continue
+		}
+
+		metadata.Icons = appendIcon(metadata.Icons, newIconCandidate(resolve(manifestUrl, icon.Src), IconSourceManifest, icon.Sizes, icon.Type, icon.Purpose))
+	}
+
+	if len(metadata.ThemeColor) <= 0 {
+		metadata.ThemeColor = strings.TrimSpace(manifest.ThemeColor)
+	}
+	if len(metadata.Title) <= 0 {
+		metadata.Title = strings.TrimSpace(manifest.Name)
+	}
+	if len(metadata.Title) <= 0 {
+		metadata.Title = strings.TrimSpace(manifest.ShortName)
+	}
+
+	return nil
+}
+
+// get 发送 GET 请求并返回解压后的响应内容, Content-Type 和状态码.
+func get(client *comfy_http_client.Client, url string, header http.Header) ([]byte, string, int, error) {
+	req, err := client.Get(url)
+	if err != nil {
+		return nil, "", 0, errors.New(err)
+	}
+	req.Header = http.Header{}
+	for _, key := range forwardedHeaders {
+		if values := header.Values(key); len(values) > 0 {
+			req.Header[key] = append([]string{}, values...)
+		}
+	}
+	// 不支持 brotli 压缩, 因为 Golang 没有原生的支持.
+	//req.Header.Set("Accept-Encoding", "gzip, deflate, br")
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+
+	res, err := client.Send(req)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	decompressedReader := res.Body
+	switch res.Header.Get("Content-Encoding") {
+	case "gzip":
+		decompressedReader, err = gzip.NewReader(res.Body)
+		if err != nil {
+			return nil, "", res.StatusCode, errors.New(err)
+		}
+	case "deflate":
+		decompressedReader = flate.NewReader(res.Body)
+	}
+
+	body, err := io.ReadAll(decompressedReader)
+	if err != nil {
+		return nil, "", res.StatusCode, errors.New(err)
+	}
+
+	return body, res.Header.Get("Content-Type"), res.StatusCode, nil
+}
+
+func metaProperty(doc *goquery.Document, property string) string {
+	return strings.TrimSpace(doc.Find("meta[property='"+property+"']").First().AttrOr("content", ""))
+}
+
+// resolve 将 ref 解析为相对于 base 的绝对地址. 解析失败时原样返回 ref.
+func resolve(base *url2.URL, ref string) string {
+	refUrl, err := url2.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ref
+	}
+
+	return base.ResolveReference(refUrl).String()
+}
+
+func containsAny(values []string, targets ...string) bool {
+	for _, value := range values {
+		for _, target := range targets {
+			if value == target {
+				return true
+			}
+		}
+	}
+
+	return false
+}
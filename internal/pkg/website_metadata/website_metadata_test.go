@@ -0,0 +1,167 @@
+package website_metadata
+
+import (
+	"github.com/siaikin/home-dashboard/internal/pkg/comfy_http_client"
+	"net/http"
+	"net/http/httptest"
+	url2 "net/url"
+	"testing"
+	"time"
+)
+
+const testPage = `<!doctype html>
+<html>
+<head>
+	<meta property="og:title" content="Jellyfin Server">
+	<meta property="og:description" content="The Free Software Media System">
+	<meta property="og:image" content="/banner.jpg">
+	<meta name="theme-color" content="#101010">
+	<link rel="manifest" href="/web/manifest.json">
+	<link rel="icon" type="image/png" sizes="16x16" href="/favicon-16.png">
+	<link rel="apple-touch-icon" href="/touchicon.png">
+</head>
+<body></body>
+</html>`
+
+const testManifest = `{
+	"name": "Jellyfin",
+	"theme_color": "#000b25",
+	"icons": [
+		{"src": "icons/icon-72.png", "sizes": "72x72", "type": "image/png"},
+		{"src": "icons/icon-512.png", "sizes": "512x512", "type": "image/png"},
+		{"src": "icons/icon-mono.png", "sizes": "512x512", "type": "image/png", "purpose": "monochrome"}
+	]
+}`
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(testPage))
+	})
+	mux.HandleFunc("/web/manifest.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/manifest+json")
+		_, _ = w.Write([]byte(testManifest))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestFetch(t *testing.T) {
+	server := newTestServer(t)
+
+	client, _ := comfy_http_client.New("", http.Header{}, time.Second*5)
+	pageUrl, _ := url2.Parse(server.URL + "/")
+
+	metadata, err := Fetch(client, pageUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if metadata.Title != "Jellyfin Server" {
+		t.Errorf("title should fall back to og:title, got %q", metadata.Title)
+	}
+	if metadata.Description != "The Free Software Media System" {
+		t.Errorf("description should fall back to og:description, got %q", metadata.Description)
+	}
+	if metadata.ThemeColor != "#101010" {
+		t.Errorf("theme color should prefer <meta name=\"theme-color\">, got %q", metadata.ThemeColor)
+	}
+
+	best, ok := metadata.BestIcon()
+	if !ok {
+		t.Fatal("expect at least one icon candidate")
+	}
+	if best.Url != server.URL+"/web/icons/icon-512.png" {
+		t.Errorf("best icon should be the 512px manifest icon, got %+v", best)
+	}
+
+	scores := map[string]int{}
+	for _, icon := range metadata.Icons {
+		scores[icon.Url] = icon.Score
+	}
+	if scores[server.URL+"/web/icons/icon-mono.png"] >= scores[server.URL+"/web/icons/icon-72.png"] {
+		t.Errorf("monochrome icon should be ranked below a small colored icon, got %+v", metadata.Icons)
+	}
+
+	for i := 1; i < len(metadata.Icons); i++ {
+		if metadata.Icons[i-1].Score < metadata.Icons[i].Score {
+			t.Errorf("icons should be sorted by score, got %+v", metadata.Icons)
+			break
+		}
+	}
+}
+
+func TestFetchForwardedHeaders(t *testing.T) {
+	received := make(chan http.Header, 2)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header
+		_, _ = w.Write([]byte(`<html><head><link rel="manifest" href="/manifest.json"></head></html>`))
+	})
+	mux.HandleFunc("/manifest.json", func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header
+		_, _ = w.Write([]byte(`{}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client, _ := comfy_http_client.New("", http.Header{}, time.Second*5)
+	pageUrl, _ := url2.Parse(server.URL + "/")
+	header := http.Header{
+		"User-Agent":      {"test-agent"},
+		"Accept-Language": {"zh-CN"},
+		"Cookie":          {"session=secret"},
+		"Authorization":   {"Bearer secret"},
+		"X-Api-Token":     {"secret"},
+	}
+	if _, err := Fetch(client, pageUrl, header); err != nil {
+		t.Fatal(err)
+	}
+
+	// 网页和 manifest.json 的请求都只包含允许转发的请求头
+	for i := 0; i < 2; i++ {
+		got := <-received
+		if got.Get("User-Agent") != "test-agent" || got.Get("Accept-Language") != "zh-CN" {
+			t.Errorf("User-Agent and Accept-Language should be forwarded, got %v", got)
+		}
+		for _, key := range []string{"Cookie", "Authorization", "X-Api-Token"} {
+			if len(got.Get(key)) > 0 {
+				t.Errorf("%s should not be forwarded, got %v", key, got)
+			}
+		}
+	}
+}
+
+func TestNewIconCandidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		icon     IconCandidate
+		wantSize int
+	}{
+		{"svg is scalable", newIconCandidate("https://a.com/logo.svg", IconSourceLink, "", "", ""), scalableIconSize},
+		{"sizes any is scalable", newIconCandidate("https://a.com/logo.png", IconSourceManifest, "any", "", ""), scalableIconSize},
+		{"largest declared size", newIconCandidate("https://a.com/logo.png", IconSourceLink, "16x16 64x64 32x32", "", ""), 64},
+		{"apple touch icon default size", newIconCandidate("https://a.com/touch.png", IconSourceAppleTouchIcon, "", "", ""), 180},
+		{"favicon default size", newIconCandidate("https://a.com/favicon.ico", IconSourceFavicon, "", "", ""), 32},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.icon.Size != tt.wantSize {
+				t.Errorf("size = %d, want %d", tt.icon.Size, tt.wantSize)
+			}
+		})
+	}
+
+	svg := newIconCandidate("https://a.com/logo.svg", IconSourceLink, "", "", "")
+	png := newIconCandidate("https://a.com/logo.png", IconSourceLink, "512x512", "", "")
+	if svg.Score <= png.Score {
+		t.Errorf("svg(%d) should score higher than png of the same size(%d)", svg.Score, png.Score)
+	}
+}
@@ -3,6 +3,7 @@ package third_party
 import (
 	"context"
 	"github.com/gin-gonic/gin"
+	"github.com/siaikin/home-dashboard/internal/pkg/authority"
 	"github.com/siaikin/home-dashboard/internal/pkg/comfy_log"
 	"github.com/siaikin/home-dashboard/internal/pkg/configuration"
 	"github.com/siaikin/home-dashboard/third_party/github"
@@ -18,13 +19,13 @@ var cancel context.CancelFunc
 
 var modules = map[string]internal.ThirdPartyModule{}
 
-// Load 启用第三方服务.
+// Load 启用第三方服务. 每个模块的接口只允许拥有对应权限(如 thirdparty.github)的用户访问.
 func Load(router *gin.RouterGroup) error {
 	wakapiConfig := configuration.Get().ServerMonitor.ThirdParty.Wakapi
 	githubConfig := configuration.Get().ServerMonitor.ThirdParty.GitHub
 
 	if wakapiConfig.Enable {
-		if err := wakapi.Use(router.Group("wakapi")); err != nil {
+		if err := wakapi.Use(router.Group("wakapi", authority.PermissionMiddleware(authority.PermissionThirdPartyWakapi))); err != nil {
 			return err
 		}
 	}
@@ -36,7 +37,7 @@ func Load(router *gin.RouterGroup) error {
 
 		if err := githubModule.Load(&internal.ThirdPartyModuleLoadContext{
 			Context: ctx,
-			Router:  router.Group("github"),
+			Router:  router.Group("github", authority.PermissionMiddleware(authority.PermissionThirdPartyGitHub)),
 			Logger:  comfy_log.New("[THIRD-PARTY " + githubModule.GetName() + "]"),
 		}); err != nil {
 			return err